@@ -0,0 +1,184 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"news-service/api/response"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// ValidateRequests answers 400 Bad Request to JSON bodies that do not match
+// the request schema of their operation, and 413 Request Entity Too Large to
+// bodies over response.MaxBodySize. Operations are looked up by the
+// chi route pattern, so the middleware has to run after routing, in a
+// chi.Router.Group or With. Bodies that are not valid JSON are left to the
+// handlers, like routes the document does not describe.
+func (d *Document) ValidateRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		schema := d.requestSchema(r)
+		if schema == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, response.MaxBodySize))
+		r.Body.Close()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			render.Status(r, http.StatusRequestEntityTooLarge)
+			render.JSON(w, r, response.Error("request body too large"))
+			return
+		}
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to read request"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value any
+		if decoder.Decode(&value) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := d.Validate(schema, value); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestSchema returns the schema of a JSON request body, nil when the
+// route takes none.
+func (d *Document) requestSchema(r *http.Request) *Schema {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return nil
+	}
+	op := d.Paths[rctx.RoutePattern()][strings.ToLower(r.Method)]
+	if op == nil || op.RequestBody == nil {
+		return nil
+	}
+	// Clients that send no Content-Type get JSON, like render.DecodeJSON.
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mediaType, _, _ := mime.ParseMediaType(ct); mediaType != "application/json" {
+			return nil
+		}
+	}
+	return d.Resolve(op.RequestBody.Content["application/json"].Schema)
+}
+
+// Validate checks a value decoded with json.Decoder.UseNumber against
+// schema. Nulls are treated like missing properties.
+func (d *Document) Validate(schema *Schema, value any) error {
+	return d.validate(schema, value, "")
+}
+
+func (d *Document) validate(schema *Schema, value any, path string) error {
+	schema = d.Resolve(schema)
+	if schema == nil || value == nil {
+		return nil
+	}
+	field := path
+	if field == "" {
+		field = "body"
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("field %s must be an object", field)
+		}
+		for _, name := range schema.Required {
+			if object[name] == nil {
+				return fmt.Errorf("field %s is a required field", join(path, name))
+			}
+		}
+		for name, v := range object {
+			property, ok := schema.Properties[name]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if err := d.validate(property, v, join(path, name)); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("field %s must be an array", field)
+		}
+		if schema.MinItems != nil && len(array) < *schema.MinItems {
+			return fmt.Errorf("field %s must have at least %d items", field, *schema.MinItems)
+		}
+		for i, v := range array {
+			if err := d.validate(schema.Items, v, fmt.Sprintf("%s[%d]", field, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("field %s must be a string", field)
+		}
+		if n := utf8.RuneCountInString(s); schema.MinLength != nil && n < *schema.MinLength {
+			return fmt.Errorf("field %s must be at least %d characters long", field, *schema.MinLength)
+		} else if schema.MaxLength != nil && n > *schema.MaxLength {
+			return fmt.Errorf("field %s must be at most %d characters long", field, *schema.MaxLength)
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("field %s must be a number", field)
+		}
+		f, err := number.Float64()
+		if err != nil || (schema.Type == "integer" && f != math.Trunc(f)) {
+			return fmt.Errorf("field %s must be an integer", field)
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			return fmt.Errorf("field %s must be at least %v", field, *schema.Minimum)
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			return fmt.Errorf("field %s must be at most %v", field, *schema.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("field %s must be a boolean", field)
+		}
+	}
+
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, value) {
+		values := make([]string, len(schema.Enum))
+		for i, v := range schema.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		return fmt.Errorf("field %s must be one of %s", field, strings.Join(values, ", "))
+	}
+	return nil
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
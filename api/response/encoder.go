@@ -0,0 +1,240 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Listing is handed to RenderList by list endpoints. Every encoder picks the
+// representation it needs, so adding a format does not touch the handlers.
+type Listing interface {
+	// Envelope is the document served as application/json.
+	Envelope() any
+	// Records are the rows of the listing, one JSON value per NDJSON line.
+	Records() []any
+	// Table returns a header and rows for CSV.
+	Table() (header []string, rows [][]string)
+	// JSONFeed describes the listing as a JSON Feed 1.1 document.
+	JSONFeed() JSONFeed
+}
+
+// JSONFeed is a JSON Feed 1.1 document, see https://jsonfeed.org/version/1.1.
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            string     `json:"id"`
+	URL           string     `json:"url,omitempty"`
+	Title         string     `json:"title,omitempty"`
+	ContentHTML   string     `json:"content_html"`
+	Summary       string     `json:"summary,omitempty"`
+	DatePublished *time.Time `json:"date_published,omitempty"`
+	DateModified  *time.Time `json:"date_modified,omitempty"`
+	Tags          []string   `json:"tags,omitempty"`
+}
+
+// Encoder writes a Listing in one format.
+type Encoder interface {
+	// Format is the name used in ?format= and as URL extension.
+	Format() string
+	MediaType() string
+	Encode(w io.Writer, l Listing) error
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]Encoder{}
+	// defaultFormat answers requests that express no preference.
+	defaultFormat = "json"
+)
+
+func init() {
+	for _, e := range []Encoder{JSONEncoder{}, JSONFeedEncoder{}, CSVEncoder{}, NDJSONEncoder{}} {
+		RegisterEncoder(e)
+	}
+}
+
+// RegisterEncoder makes a format available to RenderList, replacing an
+// encoder registered earlier under the same format.
+func RegisterEncoder(e Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[e.Format()] = e
+}
+
+// Negotiate picks the encoder for r. The ?format= query parameter wins over
+// a URL extension (see middleware.URLFormat), which wins over the Accept
+// header. An explicitly requested but unknown format is an error; an Accept
+// header without a supported type falls back to JSON.
+func Negotiate(r *http.Request) (Encoder, error) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format, _ = r.Context().Value(middleware.URLFormatCtxKey).(string)
+	}
+	if format != "" {
+		e, ok := encoders[format]
+		if !ok {
+			return nil, fmt.Errorf("unsupported format %q", format)
+		}
+		return e, nil
+	}
+
+	for _, mediaType := range acceptedTypes(r.Header.Get("Accept")) {
+		for _, e := range encoders {
+			if e.MediaType() == mediaType {
+				return e, nil
+			}
+		}
+	}
+	return encoders[defaultFormat], nil
+}
+
+// acceptedTypes returns the media types of an Accept header ordered by
+// preference. Wildcards and types with q=0 are skipped.
+func acceptedTypes(header string) []string {
+	type accepted struct {
+		mediaType string
+		q         float64
+	}
+
+	var types []accepted
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || strings.Contains(mediaType, "*") {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			types = append(types, accepted{mediaType, q})
+		}
+	}
+	sort.SliceStable(types, func(i, j int) bool { return types[i].q > types[j].q })
+
+	result := make([]string, len(types))
+	for i, t := range types {
+		result[i] = t.mediaType
+	}
+	return result
+}
+
+// RenderList writes l in the format negotiated for r.
+func RenderList(w http.ResponseWriter, r *http.Request, l Listing) {
+	e, err := Negotiate(r)
+	if err != nil {
+		render.Status(r, http.StatusNotAcceptable)
+		render.JSON(w, r, Error(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", e.MediaType()+"; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	if status, ok := r.Context().Value(render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+	// Headers are already sent, an error here can only be a broken
+	// connection.
+	e.Encode(w, l)
+}
+
+type JSONEncoder struct{}
+
+func (JSONEncoder) Format() string    { return "json" }
+func (JSONEncoder) MediaType() string { return "application/json" }
+
+func (JSONEncoder) Encode(w io.Writer, l Listing) error {
+	return json.NewEncoder(w).Encode(l.Envelope())
+}
+
+type JSONFeedEncoder struct{}
+
+func (JSONFeedEncoder) Format() string    { return "jsonfeed" }
+func (JSONFeedEncoder) MediaType() string { return "application/feed+json" }
+
+func (JSONFeedEncoder) Encode(w io.Writer, l Listing) error {
+	feed := l.JSONFeed()
+	feed.Version = "https://jsonfeed.org/version/1.1"
+	if feed.Items == nil {
+		feed.Items = []JSONFeedItem{}
+	}
+	return json.NewEncoder(w).Encode(feed)
+}
+
+type CSVEncoder struct{}
+
+func (CSVEncoder) Format() string    { return "csv" }
+func (CSVEncoder) MediaType() string { return "text/csv" }
+
+func (CSVEncoder) Encode(w io.Writer, l Listing) error {
+	header, rows := l.Table()
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		escaped := make([]string, len(row))
+		for i, cell := range row {
+			escaped[i] = escapeFormula(cell)
+		}
+		if err := cw.Write(escaped); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// escapeFormula keeps spreadsheets from evaluating a cell as a formula, as
+// they do for cells starting with =, +, -, @, a tab or a carriage return.
+// The quote prefix makes them text.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// NDJSONEncoder writes one record per line and flushes after every line so
+// clients can start processing before the listing is complete.
+type NDJSONEncoder struct{}
+
+func (NDJSONEncoder) Format() string    { return "ndjson" }
+func (NDJSONEncoder) MediaType() string { return "application/x-ndjson" }
+
+func (NDJSONEncoder) Encode(w io.Writer, l Listing) error {
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for _, record := range l.Records() {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
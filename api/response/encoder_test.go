@@ -0,0 +1,58 @@
+package response
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+type tableListing struct {
+	header []string
+	rows   [][]string
+}
+
+func (l tableListing) Envelope() any                 { return nil }
+func (l tableListing) Records() []any                { return nil }
+func (l tableListing) Table() ([]string, [][]string) { return l.header, l.rows }
+func (l tableListing) JSONFeed() JSONFeed            { return JSONFeed{} }
+
+func TestCSVEncoderEscapesFormulas(t *testing.T) {
+	l := tableListing{
+		header: []string{"id", "title"},
+		rows: [][]string{
+			{"1", "=HYPERLINK(\"http://evil.example\",\"click\")"},
+			{"2", "+1+1"},
+			{"3", "-2+3"},
+			{"4", "@SUM(A1:A2)"},
+			{"5", "\t=1"},
+			{"6", "Plain title"},
+			{"7", ""},
+		},
+	}
+	var buf bytes.Buffer
+	if err := (CSVEncoder{}).Encode(&buf, l); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+
+	want := []string{
+		"'=HYPERLINK(\"http://evil.example\",\"click\")",
+		"'+1+1",
+		"'-2+3",
+		"'@SUM(A1:A2)",
+		"'\t=1",
+		"Plain title",
+		"",
+	}
+	if len(records) != len(want)+1 || records[0][1] != "title" {
+		t.Fatalf("records = %q", records)
+	}
+	for i, title := range want {
+		if got := records[i+1][1]; got != title {
+			t.Errorf("row %d: title = %q, want %q", i+1, got, title)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Page sizes of the ?limit= query parameter of list endpoints.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Pagination reads the ?limit= and ?offset= query parameters of r. The
+// error is meant for the client.
+func Pagination(r *http.Request) (limit, offset int, err error) {
+	limit, err = PageLimit(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must not be negative")
+		}
+	}
+	return limit, offset, nil
+}
+
+// PageLimit reads the ?limit= query parameter of r, DefaultPageSize when
+// it is missing.
+func PageLimit(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return DefaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 || limit > MaxPageSize {
+		return 0, fmt.Errorf("limit must be between 1 and %d", MaxPageSize)
+	}
+	return limit, nil
+}
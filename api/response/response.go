@@ -17,6 +17,9 @@ const (
 	StatusError = "Error"
 )
 
+// MaxBodySize bounds the JSON body of a request.
+const MaxBodySize = 1 << 20
+
 func OK() Response {
 	return Response{
 		Status: StatusOK,
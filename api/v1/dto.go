@@ -0,0 +1,92 @@
+package apiv1
+
+import (
+	"news-service/api/response"
+	"time"
+)
+
+// News is a news as rendered by every /v1 route and the news stream.
+type News struct {
+	ID            int            `json:"id"`
+	Title         string         `json:"title"`
+	Slug          string         `json:"slug"`
+	Summary       string         `json:"summary"`
+	Content       string         `json:"content"`
+	ContentFormat string         `json:"content_format"`
+	ContentHTML   string         `json:"content_html"`
+	Published     bool           `json:"published"`
+	Categories    []int          `json:"categories"`
+	Tags          []string       `json:"tags"`
+	Media         []Media        `json:"media"`
+	CommentsCount int            `json:"comments_count"`
+	Reactions     map[string]int `json:"reactions"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// Media is a file attached to a news.
+type Media struct {
+	ID           int    `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
+
+type NewsResponse struct {
+	response.Response
+	News News `json:"news"`
+}
+
+type NewsListResponse struct {
+	response.Response
+	News []News `json:"news"`
+}
+
+// NewsRequest is the body of POST /v1/news.
+type NewsRequest struct {
+	Title string `json:"title"`
+	// Slug is derived from Title when empty.
+	Slug    string `json:"slug"`
+	Summary string `json:"summary"`
+	Content string `json:"content"`
+	// ContentFormat is one of plain (default), markdown or html.
+	ContentFormat string   `json:"content_format"`
+	Categories    []int    `json:"categories"`
+	Tags          []string `json:"tags"`
+	// Published defaults to true.
+	Published *bool `json:"published"`
+}
+
+// NewsUpdateRequest is the body of PATCH /v1/news/edit/{id}. Omitted
+// optional fields keep their current value.
+type NewsUpdateRequest struct {
+	Title         string   `json:"title"`
+	Slug          *string  `json:"slug"`
+	Summary       *string  `json:"summary"`
+	Content       string   `json:"content"`
+	ContentFormat *string  `json:"content_format"`
+	Categories    []int    `json:"categories"`
+	Tags          []string `json:"tags"`
+	Published     *bool    `json:"published"`
+}
+
+type User struct {
+	ID    int    `json:"id"`
+	Email string `json:"email"`
+}
+
+type UserResponse struct {
+	response.Response
+	User User `json:"user"`
+}
+
+// SessionResponse answers POST /v1/login.
+type SessionResponse struct {
+	response.Response
+	User  User   `json:"user"`
+	Token string `json:"token"`
+}
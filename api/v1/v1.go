@@ -0,0 +1,62 @@
+// Package apiv1 is the versioned contract of the REST API: the routes under
+// /v1 and the snake_case DTOs they decode and render. The unversioned routes
+// of the first releases are served next to them during the migration and
+// answer with Deprecated's headers.
+package apiv1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Prefix is the path prefix of the routes of this version.
+const Prefix = "/v1"
+
+type versionKey struct{}
+
+// Versioned marks the requests it serves as /v1 requests, handlers then
+// decode and render the DTOs of this package.
+func Versioned(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, true)))
+	})
+}
+
+// Requested reports whether r went through Versioned.
+func Requested(r *http.Request) bool {
+	v, _ := r.Context().Value(versionKey{}).(bool)
+	return v
+}
+
+// Path prefixes path with Prefix for /v1 requests, links and redirects then
+// stay in the version of the request.
+func Path(r *http.Request, path string) string {
+	if Requested(r) {
+		return Prefix + path
+	}
+	return path
+}
+
+// Deprecated marks responses of the unversioned routes as deprecated: a
+// Deprecation header (RFC 9745) dated deprecated, "true" when it is zero, a
+// Sunset header (RFC 8594) when sunset is set and a Link to the /v1
+// successor of the route.
+func Deprecated(deprecated, sunset time.Time) func(http.Handler) http.Handler {
+	deprecation := "true"
+	if !deprecated.IsZero() {
+		deprecation = fmt.Sprintf("@%d", deprecated.Unix())
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			if !sunset.IsZero() {
+				h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			h.Add("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", Prefix, r.URL.EscapedPath()))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
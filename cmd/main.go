@@ -2,96 +2,243 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"news-service/internal/config"
 	"news-service/internal/database"
+	bookmarksrepo "news-service/internal/database/bookmarksRepo"
 	categoriesrepo "news-service/internal/database/categoriesRepo"
+	commentsrepo "news-service/internal/database/commentsRepo"
+	mediarepo "news-service/internal/database/mediaRepo"
+	memoryrepo "news-service/internal/database/memoryRepo"
 	newscategoriesrepo "news-service/internal/database/newsCategoriesRepo"
+	newseventsrepo "news-service/internal/database/newsEventsRepo"
 	newsrepo "news-service/internal/database/newsRepo"
+	outboxrepo "news-service/internal/database/outboxRepo"
+	ratelimitsrepo "news-service/internal/database/rateLimitsRepo"
+	reactionsrepo "news-service/internal/database/reactionsRepo"
+	subscriptionsrepo "news-service/internal/database/subscriptionsRepo"
+	tagsrepo "news-service/internal/database/tagsRepo"
+	usertokensrepo "news-service/internal/database/userTokensRepo"
 	usersrepo "news-service/internal/database/usersRepo"
-	newshandler "news-service/internal/handlers/NewsHandler"
+	viewsrepo "news-service/internal/database/viewsRepo"
+	webhooksrepo "news-service/internal/database/webhooksRepo"
+	"news-service/internal/grpcserver"
 	userhandlers "news-service/internal/handlers/userHandler"
 	"news-service/internal/jwt"
+	"news-service/internal/mailer"
+	"news-service/internal/models"
+	"news-service/internal/outbox"
+	"news-service/internal/ratelimit"
+	"news-service/internal/router"
+	"news-service/internal/service"
+	"news-service/internal/storage"
+	"news-service/internal/stream"
+	"news-service/internal/views"
+	"news-service/internal/webhook"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	errMsg "news-service/internal/err"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 )
 
 func main() {
+	backend := flag.String("storage", "postgres", "storage backend: postgres or memory")
+	flag.Parse()
+
 	cfg := config.MustLoad()
 
 	log := setupLogger()
 	log.Debug("debug messages are active")
 
-	pg, err := connectToPostgres(cfg, log)
-	if err != nil {
-		log.Error("failed to create postgres db", errMsg.Err(err))
-		os.Exit(1)
-	}
+	var (
+		repos      service.Repositories
+		outboxRepo models.OutboxRepository
+		err        error
+	)
+	switch *backend {
+	case "postgres":
+		pg, err := connectToPostgres(cfg, log)
+		if err != nil {
+			log.Error("failed to create postgres db", errMsg.Err(err))
+			os.Exit(1)
+		}
+
+		log.Info("connecting to postgres")
 
-	log.Info("connecting to postgres")
+		defer pg.Close()
 
-	defer pg.Close()
-	if pg == nil {
-		log.Error("failed to connect to postgres")
+		if err := pg.Ping(context.Background()); err != nil {
+			log.Error("failed to ping postgres db", errMsg.Err(err))
+			os.Exit(1)
+		}
+
+		log.Info("postgres db connected successfully")
+
+		repos = postgresRepositories(pg, log)
+		outboxRepo = outboxrepo.NewOutboxRepository(pg.Db, log)
+		if cfg.RateLimit.Store == "postgres" {
+			repos.RateLimits = ratelimitsrepo.NewRateLimitsRepository(pg.Db, log)
+		}
+	case "memory":
+		log.Warn("using in-memory storage, data will be lost on restart")
+		repos, outboxRepo = memoryRepositories(log)
+	default:
+		log.Error("unknown storage backend", slog.String("storage", *backend))
 		os.Exit(1)
 	}
 
-	if err := pg.Ping(context.Background()); err != nil {
-		log.Error("failed to ping postgres db", errMsg.Err(err))
+	// The HTTP and the gRPC API share the buckets of the rate limits.
+	switch cfg.RateLimit.Store {
+	case "", "memory":
+		repos.RateLimits = ratelimit.NewMemory()
+	case "postgres":
+		if repos.RateLimits == nil {
+			log.Error("the postgres rate limit store needs the postgres storage")
+			os.Exit(1)
+		}
+	default:
+		log.Error("unknown rate limit store", slog.String("store", cfg.RateLimit.Store))
 		os.Exit(1)
 	}
 
-	log.Info("postgres db connected successfully")
+	repos.MediaStorage, err = newMediaStorage(cfg.Media)
+	if err != nil {
+		log.Error("failed to set up media storage", errMsg.Err(err))
+		os.Exit(1)
+	}
+	repos.Mailer, err = newMailer(cfg.Mail)
+	if err != nil {
+		log.Error("failed to set up mailer", errMsg.Err(err))
+		os.Exit(1)
+	}
 
 	log.Info("application started")
 
-	router := chi.NewRouter()
-	router.Use(middleware.RequestID)
-	router.Use(middleware.RealIP)
-	router.Use(middleware.Logger)
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
+	jwtManager, err := jwt.NewFromConfig(cfg.JWT, log)
+	if err != nil {
+		log.Error("failed to load jwt keys", errMsg.Err(err))
+		os.Exit(1)
+	}
+
+	// Changing a password logs the user out everywhere.
+	jwtManager.RevokeBefore(userhandlers.PasswordChangedAt(repos.Users))
 
-	newsRepository := newsrepo.NewNewsRepository(pg.Db, log)
-	categoriesRepository := categoriesrepo.NewCategoriesRepository(pg.Db, log)
-	newsCategoriesRepository := newscategoriesrepo.NewNewsCategoriesRepository(pg.Db, log)
-	userRepository := usersrepo.NewUserRepository(pg.Db, log)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	jwtManager := jwt.NewJWTManager(cfg.JWT.Secret, log)
+	repos.PendingMails = &sync.WaitGroup{}
+	repos.ViewCounter = views.NewCounter(repos.Views, cfg.Views.DedupWindow, log)
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		repos.ViewCounter.Run(ctx, cfg.Views.FlushInterval)
+	}()
 
-	router.Post("/users/new", userhandlers.NewUser(log, userRepository))
-	router.Post("/login", userhandlers.LoginFunc(log, userRepository, jwtManager))
+	dispatcher := webhook.NewDispatcher(repos.Webhooks, webhook.Options{
+		MaxAttempts:          cfg.Webhooks.MaxAttempts,
+		RetryBase:            cfg.Webhooks.RetryBase,
+		RetryMax:             cfg.Webhooks.RetryMax,
+		Timeout:              cfg.Webhooks.Timeout,
+		BatchSize:            cfg.Webhooks.BatchSize,
+		AllowPrivateNetworks: cfg.Webhooks.AllowPrivateNetworks,
+	}, log)
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		dispatcher.Run(ctx, cfg.Webhooks.PollInterval)
+	}()
 
-	router.With(func(next http.Handler) http.Handler {
-		return jwt.TokenAuthMiddleware(jwtManager, next)
-	}).Post("/news", newshandler.NewNews(log, newsRepository, categoriesRepository, newsCategoriesRepository))
+	sink, err := newOutboxSink(cfg.Outbox)
+	if err != nil {
+		log.Error("failed to set up outbox sink", errMsg.Err(err))
+		os.Exit(1)
+	}
+	relayed := make(chan struct{})
+	go func() {
+		defer close(relayed)
+		if sink == nil {
+			log.Warn("outbox sink is disabled, domain events stay unpublished")
+			return
+		}
+		outbox.NewRelay(outboxRepo, sink, outbox.Options{
+			BatchSize: cfg.Outbox.BatchSize,
+			Timeout:   cfg.Outbox.Timeout,
+			RetryBase: cfg.Outbox.RetryBase,
+			RetryMax:  cfg.Outbox.RetryMax,
+			Retention: cfg.Outbox.Retention,
+		}, log).Run(ctx, cfg.Outbox.PollInterval)
+	}()
 
-	router.With(func(next http.Handler) http.Handler {
-		return jwt.TokenAuthMiddleware(jwtManager, next)
-	}).Get("/list", newshandler.ListAllNews(log, newsRepository, newsCategoriesRepository))
+	repos.NewsStream = stream.NewHub(repos.NewsEvents, stream.Options{
+		Buffer:    cfg.Stream.Buffer,
+		Retention: cfg.Stream.Retention,
+	}, log)
+	streamed := make(chan struct{})
+	go func() {
+		defer close(streamed)
+		repos.NewsStream.Run(ctx)
+	}()
 
-	router.With(func(next http.Handler) http.Handler {
-		return jwt.TokenAuthMiddleware(jwtManager, next)
-	}).Patch("/news/edit/{id}", newshandler.UpdateNews(log, newsRepository, categoriesRepository, newsCategoriesRepository))
+	mux := router.New(log, cfg, repos, jwtManager)
 
 	server := &http.Server{
 		Addr:              cfg.HTTPServer.Addr,
-		Handler:           router,
+		Handler:           mux,
 		ReadHeaderTimeout: cfg.HTTPServer.Timeout,
 		WriteTimeout:      cfg.HTTPServer.Timeout,
 		IdleTimeout:       cfg.HTTPServer.IdleTimeout,
 	}
 
-	if err := server.ListenAndServe(); err != nil {
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if cfg.GRPC.Address == "" {
+			return
+		}
+		listener, err := net.Listen("tcp", cfg.GRPC.Address)
+		if err != nil {
+			log.Error("failed to start grpc server", errMsg.Err(err))
+			return
+		}
+		grpcServer := grpcserver.New(log, repos, jwtManager, router.NewThrottle(cfg.RateLimit, repos.RateLimits), service.NewAccounts(cfg.Mail, repos))
+		go func() {
+			<-ctx.Done()
+			grpcServer.GracefulStop()
+		}()
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Error("failed to serve grpc", errMsg.Err(err))
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.Timeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error("failed to shut down server", errMsg.Err(err))
+		}
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Error("failed to start server", errMsg.Err(err))
 	}
 
+	// gRPC calls, mails, buffered views, webhook attempts and outbox
+	// messages in flight are finished before the database is closed.
+	stop()
+	<-served
+	repos.PendingMails.Wait()
+	<-flushed
+	<-dispatched
+	<-relayed
+	<-streamed
 }
 
 func setupLogger() *slog.Logger {
@@ -100,9 +247,106 @@ func setupLogger() *slog.Logger {
 	return log
 }
 
+func postgresRepositories(pg *database.Postgres, log *slog.Logger) service.Repositories {
+	return service.Repositories{
+		News:           newsrepo.NewNewsRepository(pg.Db, log, newsrepo.WithReader(pg.Reader())),
+		Categories:     categoriesrepo.NewCategoriesRepository(pg.Db, log),
+		NewsCategories: newscategoriesrepo.NewNewsCategoriesRepository(pg.Db, log),
+		Users:          usersrepo.NewUserRepository(pg.Db, log),
+		UserTokens:     usertokensrepo.NewUserTokensRepository(pg.Db, log),
+		Media:          mediarepo.NewMediaRepository(pg.Db, log),
+		Tags:           tagsrepo.NewTagsRepository(pg.Db, log),
+		Views:          viewsrepo.NewViewsRepository(pg.Db, log),
+		Comments:       commentsrepo.NewCommentsRepository(pg.Db, log),
+		Reactions:      reactionsrepo.NewReactionsRepository(pg.Db, log),
+		Bookmarks:      bookmarksrepo.NewBookmarksRepository(pg.Db, log),
+		Subscriptions:  subscriptionsrepo.NewSubscriptionsRepository(pg.Db, log),
+		Webhooks:       webhooksrepo.NewWebhooksRepository(pg.Db, log),
+		NewsEvents:     newseventsrepo.NewNewsEventsRepository(pg.Db, log),
+	}
+}
+
+func memoryRepositories(log *slog.Logger) (service.Repositories, models.OutboxRepository) {
+	store := memoryrepo.NewStore()
+	return service.Repositories{
+		News:           memoryrepo.NewNewsRepository(store, log),
+		Categories:     memoryrepo.NewCategoriesRepository(store, log),
+		NewsCategories: memoryrepo.NewNewsCategoriesRepository(store, log),
+		Users:          memoryrepo.NewUserRepository(store, log),
+		UserTokens:     memoryrepo.NewUserTokensRepository(store, log),
+		Media:          memoryrepo.NewMediaRepository(store, log),
+		Tags:           memoryrepo.NewTagsRepository(store, log),
+		Views:          memoryrepo.NewViewsRepository(store, log),
+		Comments:       memoryrepo.NewCommentsRepository(store, log),
+		Reactions:      memoryrepo.NewReactionsRepository(store, log),
+		Bookmarks:      memoryrepo.NewBookmarksRepository(store, log),
+		Subscriptions:  memoryrepo.NewSubscriptionsRepository(store, log),
+		Webhooks:       memoryrepo.NewWebhooksRepository(store, log),
+		NewsEvents:     memoryrepo.NewNewsEventsRepository(store, log),
+	}, memoryrepo.NewOutboxRepository(store, log)
+}
+
+func newMediaStorage(cfg config.MediaCfg) (storage.Storage, error) {
+	switch cfg.Storage {
+	case "", "local":
+		return storage.NewLocal(cfg.Dir, cfg.BaseURL)
+	case "s3":
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Bucket:    cfg.S3.Bucket,
+			Region:    cfg.S3.Region,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			PublicURL: cfg.S3.PublicURL,
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown media storage %q", cfg.Storage)
+	}
+}
+
+func newMailer(cfg config.MailCfg) (mailer.Mailer, error) {
+	switch cfg.Mailer {
+	case "", "log":
+		return mailer.NewWriter(os.Stdout), nil
+	case "file":
+		return mailer.NewDir(cfg.Dir, cfg.From)
+	case "smtp":
+		return mailer.NewSMTP(cfg.SMTP.Address, cfg.SMTP.Username, cfg.SMTP.Password, cfg.From, cfg.SMTP.Timeout)
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+	}
+}
+
+// newOutboxSink returns nil when publishing is disabled.
+func newOutboxSink(cfg config.OutboxCfg) (outbox.Sink, error) {
+	switch cfg.Sink {
+	case "", "stdout":
+		return outbox.NewWriterSink(os.Stdout), nil
+	case "nats":
+		return outbox.NewNATSSink(cfg.NATS.URL, cfg.NATS.SubjectPrefix)
+	case "kafka":
+		return outbox.NewKafkaRESTSink(cfg.Kafka.RESTURL, cfg.Kafka.TopicPrefix, &http.Client{Timeout: cfg.Timeout}), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown outbox sink %q", cfg.Sink)
+	}
+}
+
 func connectToPostgres(cfg *config.Config, log *slog.Logger) (*database.Postgres, error) {
 	connString := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s",
 		cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.DBName)
-	pg, err := database.NewPG(context.Background(), connString, log, cfg)
+
+	var opts []database.Option
+	if cfg.Database.MaxConns > 0 {
+		opts = append(opts, database.WithMaxConns(cfg.Database.MaxConns))
+	}
+	if cfg.Database.Replica.Host != "" {
+		replicaConnString := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s",
+			cfg.Database.Replica.Host, cfg.Database.Replica.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.DBName)
+		opts = append(opts, database.WithReplica(replicaConnString))
+	}
+
+	pg, err := database.NewPG(context.Background(), connString, log, cfg, opts...)
 	return pg, err
 }
@@ -1,6 +1,10 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -13,3 +17,16 @@ func HashPassword(password string) (string, error) {
 func ComparePasswordHash(password, hash string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
+
+// dummyHash has the cost of the stored hashes, it is computed on first use.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// CompareDummyHash spends the time of ComparePasswordHash without a hash to
+// compare with, so that a login with an unknown email takes as long as one
+// with a wrong password.
+func CompareDummyHash(password string) {
+	bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+}
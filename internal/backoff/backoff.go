@@ -0,0 +1,17 @@
+// Package backoff computes retry delays.
+package backoff
+
+import "time"
+
+// Exponential returns the delay before the next attempt after attempts
+// failures: base doubled for every failure after the first, at most max.
+func Exponential(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
@@ -0,0 +1,21 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponential(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		7:  32 * time.Minute,
+		8:  time.Hour,
+		50: time.Hour,
+	} {
+		if got := Exponential(attempts, 30*time.Second, time.Hour); got != want {
+			t.Errorf("Exponential(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"net/netip"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
@@ -9,27 +10,278 @@ import (
 
 type Config struct {
 	HTTPServer       ServerCfg      `yaml:"http_server"`
+	GRPC             GRPCCfg        `yaml:"grpc"`
 	Database         DatabaseConfig `yaml:"database"`
-	JWT              JWTCfg         `yaml:"auth"`
+	JWT              JWTCfg         `yaml:"jwt"`
 	DefaultAdminPass string         `yaml:"default_admin_pass"`
+	RateLimit        RateLimitCfg   `yaml:"rate_limit"`
+	Feed             FeedCfg        `yaml:"feed"`
+	Media            MediaCfg       `yaml:"media"`
+	Related          RelatedCfg     `yaml:"related"`
+	Views            ViewsCfg       `yaml:"views"`
+	Comments         CommentsCfg    `yaml:"comments"`
+	Webhooks         WebhooksCfg    `yaml:"webhooks"`
+	Outbox           OutboxCfg      `yaml:"outbox"`
+	Stream           StreamCfg      `yaml:"stream"`
+	LegacyAPI        LegacyAPICfg   `yaml:"legacy_api"`
+	GraphQL          GraphQLCfg     `yaml:"graphql"`
+	Mail             MailCfg        `yaml:"mail"`
 }
 
 type DatabaseConfig struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	DBName   string `yaml:"dbname"`
+	Host     string        `yaml:"host"`
+	Port     int           `yaml:"port"`
+	User     string        `yaml:"user"`
+	Password string        `yaml:"password"`
+	DBName   string        `yaml:"dbname"`
+	MaxConns int32         `yaml:"max_conns"`
+	Replica  ReplicaConfig `yaml:"replica"`
 }
 
+type ReplicaConfig struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+}
+
+// ServerCfg configures the HTTP server. The client address is taken from
+// X-Forwarded-For or X-Real-IP only for requests coming from one of the
+// TrustedProxies, see ratelimit.RealIP.
 type ServerCfg struct {
-	Addr        string        `yaml:"address" env-default:"localhost:8080"`
-	Timeout     time.Duration `yaml:"timeout" env-default:"10s"`
-	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"120s"`
+	Addr           string         `yaml:"address" env-default:"localhost:8080"`
+	Timeout        time.Duration  `yaml:"timeout" env-default:"10s"`
+	IdleTimeout    time.Duration  `yaml:"idle_timeout" env-default:"120s"`
+	TrustedProxies []netip.Prefix `yaml:"trusted_proxies"`
+}
+
+// GRPCCfg enables the gRPC API on its own Address, an empty Address
+// disables it.
+type GRPCCfg struct {
+	Address string `yaml:"address"`
+}
+
+// RateLimitCfg holds per-IP limits. Public applies to the anonymous read
+// API, Authenticated to routes behind a token and Login to sign-ins and
+// sign-ups. Store keeps the buckets, "memory" for each instance or
+// "postgres" shared by all instances.
+type RateLimitCfg struct {
+	Public        LimitCfg      `yaml:"public"`
+	Authenticated LimitCfg      `yaml:"authenticated"`
+	Login         LoginLimitCfg `yaml:"login"`
+	Store         string        `yaml:"store" env-default:"memory"`
+}
+
+// LoginLimitCfg protects POST /login and POST /users/new from brute force.
+// PerIP limits attempts by client address and PerAccount by email. After
+// Lockout.Threshold consecutive failed logins an email is locked out for
+// Lockout.Base, doubled after every further failure up to Lockout.Max.
+type LoginLimitCfg struct {
+	PerIP      LimitCfg   `yaml:"per_ip"`
+	PerAccount LimitCfg   `yaml:"per_account"`
+	Lockout    LockoutCfg `yaml:"lockout"`
+}
+
+// LockoutCfg is described by LoginLimitCfg, zero Threshold disables the
+// lockout.
+type LockoutCfg struct {
+	Threshold int           `yaml:"threshold"`
+	Base      time.Duration `yaml:"base" env-default:"1m"`
+	Max       time.Duration `yaml:"max" env-default:"1h"`
+}
+
+// LimitCfg allows Requests per Per with bursts of up to Burst requests.
+// Zero Requests disables the limit.
+type LimitCfg struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per" env-default:"1m"`
+	Burst    int           `yaml:"burst"`
+}
+
+// FeedCfg describes the RSS and Atom feeds. BaseURL is the public address
+// of the service used in links, the request host is used when it is empty.
+type FeedCfg struct {
+	Title       string `yaml:"title" env-default:"News"`
+	Description string `yaml:"description"`
+	BaseURL     string `yaml:"base_url"`
+	Items       int    `yaml:"items" env-default:"20"`
+}
+
+// MediaCfg limits uploads and picks where they are stored. Storage is
+// "local", files in Dir served by the service under /media, or "s3".
+// BaseURL is prefixed to the keys of locally stored files in links.
+// MaxPixels caps width×height of images, which are decoded in full to make
+// thumbnails.
+type MediaCfg struct {
+	Storage       string   `yaml:"storage" env-default:"local"`
+	Dir           string   `yaml:"dir" env-default:"./media"`
+	BaseURL       string   `yaml:"base_url" env-default:"/media"`
+	MaxSize       int64    `yaml:"max_size" env-default:"10485760"`
+	MaxFiles      int      `yaml:"max_files" env-default:"10"`
+	AllowedTypes  []string `yaml:"allowed_types" env-default:"image/jpeg,image/png,image/gif,image/webp,application/pdf"`
+	ThumbnailSize int      `yaml:"thumbnail_size" env-default:"320"`
+	MaxPixels     int64    `yaml:"max_pixels" env-default:"40000000"`
+	S3            S3Cfg    `yaml:"s3"`
+}
+
+// S3Cfg points at a bucket of an S3-compatible store. PublicURL is the
+// address clients download objects from, Endpoint/Bucket when empty.
+type S3Cfg struct {
+	Endpoint  string `yaml:"endpoint"`
+	Bucket    string `yaml:"bucket"`
+	Region    string `yaml:"region" env-default:"us-east-1"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	PublicURL string `yaml:"public_url"`
+}
+
+// RelatedCfg tunes GET /news/{id}/related. Limit is the default number of
+// news returned and HalfLife halves the score of older news, zero keeps
+// news of any age equal.
+type RelatedCfg struct {
+	Limit    int           `yaml:"limit" env-default:"5"`
+	HalfLife time.Duration `yaml:"half_life" env-default:"720h"`
+}
+
+// ViewsCfg tunes view counting. A client is counted once per news within
+// DedupWindow, buffered views are written every FlushInterval and the
+// weight of a view in GET /news/trending halves every HalfLife.
+type ViewsCfg struct {
+	DedupWindow   time.Duration `yaml:"dedup_window" env-default:"30m"`
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"10s"`
+	HalfLife      time.Duration `yaml:"half_life" env-default:"6h"`
 }
 
+// CommentsCfg tunes comments. Authors may edit a comment within
+// EditWindow of posting it, RateLimit applies per user to new comments and
+// Moderators lists the emails allowed to moderate; nobody may moderate when
+// it is empty.
+type CommentsCfg struct {
+	EditWindow time.Duration `yaml:"edit_window" env-default:"15m"`
+	RateLimit  LimitCfg      `yaml:"rate_limit"`
+	Moderators []string      `yaml:"moderators"`
+}
+
+// WebhooksCfg tunes webhook deliveries. Due deliveries are looked for every
+// PollInterval and sent BatchSize at a time, each request bounded by
+// Timeout. A failed delivery is retried after RetryBase, doubled after every
+// failure up to RetryMax, and dead-lettered after MaxAttempts attempts.
+// Webhooks may only reach public addresses unless AllowPrivateNetworks.
+type WebhooksCfg struct {
+	PollInterval         time.Duration `yaml:"poll_interval" env-default:"5s"`
+	BatchSize            int           `yaml:"batch_size" env-default:"20"`
+	Timeout              time.Duration `yaml:"timeout" env-default:"10s"`
+	RetryBase            time.Duration `yaml:"retry_base" env-default:"30s"`
+	RetryMax             time.Duration `yaml:"retry_max" env-default:"1h"`
+	MaxAttempts          int           `yaml:"max_attempts" env-default:"10"`
+	AllowPrivateNetworks bool          `yaml:"allow_private_networks" env-default:"false"`
+}
+
+// OutboxCfg tunes the relay publishing domain events. Sink is "stdout",
+// "nats", "kafka" (through a Kafka REST Proxy) or "none", which keeps the
+// events in the outbox unpublished. Messages are looked for every
+// PollInterval, failures are retried after RetryBase doubled up to RetryMax
+// and published messages are kept for Retention.
+type OutboxCfg struct {
+	Sink         string        `yaml:"sink" env-default:"stdout"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+	RetryBase    time.Duration `yaml:"retry_base" env-default:"1s"`
+	RetryMax     time.Duration `yaml:"retry_max" env-default:"5m"`
+	Retention    time.Duration `yaml:"retention" env-default:"168h"`
+	NATS         NATSCfg       `yaml:"nats"`
+	Kafka        KafkaCfg      `yaml:"kafka"`
+}
+
+// NATSCfg points at a NATS server, events go to SubjectPrefix+event.
+type NATSCfg struct {
+	URL           string `yaml:"url" env-default:"nats://localhost:4222"`
+	SubjectPrefix string `yaml:"subject_prefix"`
+}
+
+// KafkaCfg points at a Kafka REST Proxy, events go to TopicPrefix+event.
+type KafkaCfg struct {
+	RESTURL     string `yaml:"rest_url" env-default:"http://localhost:8082"`
+	TopicPrefix string `yaml:"topic_prefix"`
+}
+
+// StreamCfg tunes GET /news/stream. A heartbeat is sent after Heartbeat
+// without events, clients reconnect after Retry, a client more than Buffer
+// events behind is disconnected and events are kept for resuming clients
+// for Retention.
+type StreamCfg struct {
+	Heartbeat time.Duration `yaml:"heartbeat" env-default:"15s"`
+	Retry     time.Duration `yaml:"retry" env-default:"3s"`
+	Buffer    int           `yaml:"buffer" env-default:"64"`
+	Retention time.Duration `yaml:"retention" env-default:"24h"`
+}
+
+// LegacyAPICfg dates the deprecation of the unversioned routes, served
+// next to /v1 for the migration period. Deprecated is sent in their
+// Deprecation header and Sunset, when set, is the date they are removed.
+type LegacyAPICfg struct {
+	Deprecated time.Time `yaml:"deprecated"`
+	Sunset     time.Time `yaml:"sunset"`
+}
+
+// GraphQLCfg bounds queries of POST /graphql. MaxDepth is the deepest
+// nesting of fields and MaxComplexity the cost of a query, fields of lists
+// weighing as much as their limit argument. Zero disables a limit.
+type GraphQLCfg struct {
+	MaxDepth      int `yaml:"max_depth" env-default:"8"`
+	MaxComplexity int `yaml:"max_complexity" env-default:"1000"`
+}
+
+// MailCfg configures the mails of email verification and password reset.
+// Mailer is "smtp", "file", which saves every mail under Dir, or "log",
+// which writes them to stdout. The links of the mails are VerifyURL and
+// ResetURL followed by the token, pages of the front end posting it to
+// POST /users/verify and POST /password/reset. Users must verify their
+// email before they log in when RequireVerification is set.
+type MailCfg struct {
+	Mailer              string        `yaml:"mailer" env-default:"log"`
+	From                string        `yaml:"from" env-default:"news-service@localhost"`
+	Dir                 string        `yaml:"dir" env-default:"mail"`
+	VerifyURL           string        `yaml:"verify_url" env-default:"http://localhost:3000/verify-email?token="`
+	ResetURL            string        `yaml:"reset_url" env-default:"http://localhost:3000/reset-password?token="`
+	VerificationTTL     time.Duration `yaml:"verification_ttl" env-default:"24h"`
+	ResetTTL            time.Duration `yaml:"reset_ttl" env-default:"1h"`
+	RequireVerification bool          `yaml:"require_verification"`
+	SMTP                SMTPCfg       `yaml:"smtp"`
+}
+
+// SMTPCfg points at the SMTP server of the smtp mailer. STARTTLS is used
+// when the server offers it, and PLAIN authentication when Username is
+// set.
+type SMTPCfg struct {
+	Address  string        `yaml:"address" env-default:"localhost:25"`
+	Username string        `yaml:"username"`
+	Password string        `yaml:"password"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+// JWTCfg configures the tokens of POST /login. They are signed with the
+// key SigningKey names, the first of Keys by default, and verified with
+// the key named by their kid header, so that a new key can be published in
+// /.well-known/jwks.json before it signs and a retired one keeps verifying
+// the tokens it signed until they expire. Secret is an HS256 key without
+// ID, which signs when there are no Keys and is never published. Issuer
+// and Audience are set in the tokens and required from them when not
+// empty, Leeway tolerates clock skew.
 type JWTCfg struct {
-	Secret string `yaml:"secret"`
+	Secret     string        `yaml:"secret"`
+	Keys       []JWTKeyCfg   `yaml:"keys"`
+	SigningKey string        `yaml:"signing_key"`
+	Issuer     string        `yaml:"issuer"`
+	Audience   string        `yaml:"audience"`
+	Leeway     time.Duration `yaml:"leeway" env-default:"30s"`
+}
+
+// JWTKeyCfg is an RSA (RS256) or Ed25519 (EdDSA) key in PEM files. Keys
+// that no longer sign only need PublicKeyFile.
+type JWTKeyCfg struct {
+	ID             string `yaml:"id"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file"`
 }
 
 func MustLoad() *Config {
@@ -0,0 +1,67 @@
+// Package content turns the source of a news article into HTML that is safe
+// to embed in a page.
+package content
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Format is the markup the source of an article is written in.
+type Format string
+
+const (
+	Plain    Format = "plain"
+	Markdown Format = "markdown"
+	HTML     Format = "html"
+)
+
+// ParseFormat validates a format name; an empty name means Plain.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return Plain, nil
+	case Plain, Markdown, HTML:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown content format %q, want one of plain, markdown, html", s)
+	}
+}
+
+// Render returns the sanitized HTML for source written in format.
+func Render(format Format, source string) string {
+	switch format {
+	case Markdown:
+		return Sanitize(markdown(source))
+	case HTML:
+		return Sanitize(source)
+	default:
+		return plain(source)
+	}
+}
+
+// plain escapes text and keeps its paragraphs and line breaks.
+func plain(text string) string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	for i, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		lines := strings.Split(para, "\n")
+		for j := range lines {
+			lines[j] = html.EscapeString(lines[j])
+		}
+		b.WriteString("<p>" + strings.Join(lines, "<br>\n") + "</p>")
+	}
+	return b.String()
+}
@@ -0,0 +1,455 @@
+package content
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// markdown renders the commonly used subset of CommonMark to HTML:
+// ATX and setext headings, paragraphs, hard line breaks, emphasis, strong,
+// ~~strikethrough~~, code spans, fenced code blocks, block quotes, nested
+// ordered and unordered lists, horizontal rules, links, images and
+// autolinks. Inline HTML is passed through, so the result must be run
+// through Sanitize before it is shown to anybody.
+func markdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	// NUL marks hard line breaks internally, see joinLines.
+	src = strings.ReplaceAll(src, "\x00", "\uFFFD")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"))
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+var (
+	atxHeading = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*?))?(?:[ ]+#+)?[ ]*$`)
+	fence      = regexp.MustCompile("^ {0,3}(```+|~~~+)[ ]*([^`\\s]*)")
+	thematic   = regexp.MustCompile(`^ {0,3}(?:(?:\*[ ]*){3,}|(?:-[ ]*){3,}|(?:_[ ]*){3,})$`)
+	setextH1   = regexp.MustCompile(`^ {0,3}=+[ ]*$`)
+	setextH2   = regexp.MustCompile(`^ {0,3}-+[ ]*$`)
+	bulletItem = regexp.MustCompile(`^( {0,3})([-*+])( +|$)`)
+	orderItem  = regexp.MustCompile(`^( {0,3})([0-9]{1,9})([.)])( +|$)`)
+	quoteLine  = regexp.MustCompile(`^ {0,3}> ?`)
+)
+
+func renderBlocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case fence.MatchString(line):
+			i = renderFence(b, lines, i)
+
+		case atxHeading.MatchString(line):
+			m := atxHeading.FindStringSubmatch(line)
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + renderInline(strings.TrimSpace(m[2])) + "</h" + level + ">\n")
+			i++
+
+		case thematic.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case quoteLine.MatchString(line):
+			var inner []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				inner = append(inner, quoteLine.ReplaceAllString(lines[i], ""))
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, inner)
+			b.WriteString("</blockquote>\n")
+
+		case bulletItem.MatchString(line) || orderItem.MatchString(line):
+			i = renderList(b, lines, i)
+
+		default:
+			i = renderParagraph(b, lines, i)
+		}
+	}
+}
+
+func renderFence(b *strings.Builder, lines []string, i int) int {
+	m := fence.FindStringSubmatch(lines[i])
+	marker, lang := m[1], m[2]
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, marker[:1]) && strings.Trim(trimmed, marker[:1]) == "" && len(trimmed) >= len(marker) {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+
+	b.WriteString("<pre><code")
+	if lang != "" {
+		b.WriteString(` class="language-` + html.EscapeString(lang) + `"`)
+	}
+	b.WriteString(">")
+	for _, line := range code {
+		b.WriteString(html.EscapeString(line) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// listMarker returns the list type, the width of the marker including the
+// following spaces and the start number of an ordered list.
+func listMarker(line string) (ordered bool, width int, start int, ok bool) {
+	if m := bulletItem.FindStringSubmatch(line); m != nil {
+		return false, len(m[0]), 0, true
+	}
+	if m := orderItem.FindStringSubmatch(line); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return true, len(m[0]), n, true
+	}
+	return false, 0, 0, false
+}
+
+func renderList(b *strings.Builder, lines []string, i int) int {
+	ordered, _, start, _ := listMarker(lines[i])
+	if ordered {
+		if start != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(start) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+
+	for i < len(lines) {
+		itemOrdered, width, _, ok := listMarker(lines[i])
+		if !ok || itemOrdered != ordered {
+			break
+		}
+		item := []string{lines[i][width:]}
+		indent := max(width, 2)
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// A blank line continues the item only when indented
+				// content follows.
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) >= indent {
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			if leadingSpaces(line) >= indent {
+				item = append(item, line[indent:])
+				continue
+			}
+			if _, _, _, isItem := listMarker(line); isItem || isBlockStart(line) {
+				break
+			}
+			// A lazy continuation line of the item's paragraph.
+			item = append(item, strings.TrimSpace(line))
+		}
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			if i+1 < len(lines) {
+				if _, _, _, isItem := listMarker(lines[i+1]); isItem {
+					i++
+					continue
+				}
+			}
+			break
+		}
+
+		var inner strings.Builder
+		renderBlocks(&inner, item)
+		body := strings.TrimSuffix(inner.String(), "\n")
+		// Tight lists render their single paragraph without <p>.
+		if strings.HasPrefix(body, "<p>") && strings.Count(body, "<p>") == 1 {
+			body = strings.Replace(body, "<p>", "", 1)
+			body = strings.Replace(body, "</p>", "", 1)
+		}
+		b.WriteString("<li>" + body + "</li>\n")
+	}
+
+	if ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+func leadingSpaces(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+// isBlockStart reports whether line interrupts a paragraph.
+func isBlockStart(line string) bool {
+	return fence.MatchString(line) || atxHeading.MatchString(line) ||
+		thematic.MatchString(line) || quoteLine.MatchString(line)
+}
+
+func renderParagraph(b *strings.Builder, lines []string, i int) int {
+	var text []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		if len(text) > 0 {
+			if setextH1.MatchString(line) || setextH2.MatchString(line) {
+				tag := "h1"
+				if setextH2.MatchString(line) {
+					tag = "h2"
+				}
+				b.WriteString("<" + tag + ">" + renderInline(joinLines(text)) + "</" + tag + ">\n")
+				return i + 1
+			}
+			if isBlockStart(line) {
+				break
+			}
+			if _, _, _, isItem := listMarker(line); isItem {
+				break
+			}
+		}
+		text = append(text, line)
+	}
+	b.WriteString("<p>" + renderInline(joinLines(text)) + "</p>\n")
+	return i
+}
+
+// joinLines joins the lines of a paragraph, turning two trailing spaces or
+// a trailing backslash into a hard line break.
+func joinLines(lines []string) string {
+	var b strings.Builder
+	for n, line := range lines {
+		line = strings.TrimLeft(line, " ")
+		last := n == len(lines)-1
+		switch {
+		case !last && strings.HasSuffix(line, "  "):
+			b.WriteString(strings.TrimRight(line, " ") + "\x00")
+		case !last && strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`):
+			b.WriteString(strings.TrimSuffix(line, `\`) + "\x00")
+		case last:
+			b.WriteString(strings.TrimRight(line, " "))
+		default:
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+var (
+	inlineTag = regexp.MustCompile(`^</?[a-zA-Z][a-zA-Z0-9-]*(?:\s+[a-zA-Z_:][a-zA-Z0-9_.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>`)
+	entity    = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+	autolink  = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*)>`)
+	linkDest  = regexp.MustCompile(`^\(\s*(<[^<>\n]*>|(?:[^\s()]|\([^\s()]*\))*)(?:\s+("[^"]*"|'[^']*'))?\s*\)`)
+)
+
+const asciiPunct = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// renderInline renders the inline content of a block. A NUL byte marks a
+// hard line break inserted by joinLines.
+func renderInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == 0:
+			b.WriteString("<br>\n")
+			i++
+
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(asciiPunct, s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+
+		case c == '`':
+			n := runLength(s[i:], '`')
+			marker := s[i : i+n]
+			end := strings.Index(s[i+n:], marker)
+			if end < 0 {
+				b.WriteString(marker)
+				i += n
+				break
+			}
+			code := strings.ReplaceAll(s[i+n:i+n+end], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+				code = code[1 : len(code)-1]
+			}
+			b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			i += n + end + n
+
+		case c == '<':
+			if m := autolink.FindStringSubmatch(s[i:]); m != nil {
+				b.WriteString(`<a href="` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + "</a>")
+				i += len(m[0])
+			} else if m := inlineTag.FindString(s[i:]); m != "" {
+				b.WriteString(m)
+				i += len(m)
+			} else {
+				b.WriteString("&lt;")
+				i++
+			}
+
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if text, dest, title, n, ok := parseLink(s[i+1:]); ok {
+				b.WriteString(`<img src="` + html.EscapeString(dest) + `" alt="` + html.EscapeString(plainText(text)) + `"`)
+				if title != "" {
+					b.WriteString(` title="` + html.EscapeString(title) + `"`)
+				}
+				b.WriteString(">")
+				i += 1 + n
+			} else {
+				b.WriteString("!")
+				i++
+			}
+
+		case c == '[':
+			if text, dest, title, n, ok := parseLink(s[i:]); ok {
+				b.WriteString(`<a href="` + html.EscapeString(dest) + `"`)
+				if title != "" {
+					b.WriteString(` title="` + html.EscapeString(title) + `"`)
+				}
+				b.WriteString(">" + renderInline(text) + "</a>")
+				i += n
+			} else {
+				b.WriteString("[")
+				i++
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			if n, ok := renderEmphasis(&b, s, i); ok {
+				i += n
+			} else {
+				n := runLength(s[i:], c)
+				b.WriteString(s[i : i+n])
+				i += n
+			}
+
+		case c == '&':
+			// Keep entities such as &copy; as they are.
+			if m := entity.FindString(s[i:]); m != "" && html.UnescapeString(m) != m {
+				b.WriteString(m)
+				i += len(m)
+			} else {
+				b.WriteString("&amp;")
+				i++
+			}
+
+		default:
+			next := i + 1
+			for next < len(s) && strings.IndexByte("\x00\\`<![*_~&", s[next]) < 0 {
+				next++
+			}
+			b.WriteString(html.EscapeString(s[i:next]))
+			i = next
+		}
+	}
+	return b.String()
+}
+
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+// renderEmphasis renders *em*, **strong**, _em_, __strong__ and ~~del~~
+// starting at s[i] and returns the number of bytes consumed.
+func renderEmphasis(b *strings.Builder, s string, i int) (int, bool) {
+	c := s[i]
+	n := runLength(s[i:], c)
+	if c == '~' && n != 2 {
+		return 0, false
+	}
+	if n > 3 {
+		return 0, false
+	}
+	// An opening delimiter must be followed by a non-space, and "_" does
+	// not open emphasis inside a word.
+	if i+n >= len(s) || s[i+n] == ' ' || s[i+n] == '\n' {
+		return 0, false
+	}
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return 0, false
+	}
+
+	marker := s[i : i+n]
+	for from := i + n; from < len(s); {
+		end := strings.Index(s[from:], marker)
+		if end < 0 {
+			return 0, false
+		}
+		end += from
+		// The closing delimiter must follow a non-space, must not be part
+		// of a longer run and "_" must not close inside a word.
+		if s[end-1] != ' ' && s[end-1] != '\n' && runLength(s[end:], c) == n &&
+			(c != '_' || end+n >= len(s) || !isWordByte(s[end+n])) {
+			inner := renderInline(s[i+n : end])
+			switch {
+			case c == '~':
+				b.WriteString("<del>" + inner + "</del>")
+			case n == 1:
+				b.WriteString("<em>" + inner + "</em>")
+			case n == 2:
+				b.WriteString("<strong>" + inner + "</strong>")
+			default:
+				b.WriteString("<em><strong>" + inner + "</strong></em>")
+			}
+			return end + n - i, true
+		}
+		from = end + runLength(s[end:], c)
+	}
+	return 0, false
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// parseLink parses "[text](destination "title")" at the start of s.
+func parseLink(s string) (text, dest, title string, n int, ok bool) {
+	depth := 0
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				m := linkDest.FindStringSubmatch(s[j+1:])
+				if m == nil {
+					return "", "", "", 0, false
+				}
+				dest = strings.TrimSuffix(strings.TrimPrefix(m[1], "<"), ">")
+				if m[2] != "" {
+					title = m[2][1 : len(m[2])-1]
+				}
+				return s[1:j], dest, title, j + 1 + len(m[0]), true
+			}
+		}
+	}
+	return "", "", "", 0, false
+}
+
+// plainText strips markup from s for use in attributes such as alt.
+func plainText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case strings.IndexByte("*_`~[]", c) >= 0:
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
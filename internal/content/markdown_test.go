@@ -0,0 +1,57 @@
+package content
+
+import "testing"
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hello *world*", "<p>Hello <em>world</em></p>"},
+		{"**bold** __also__ ***both*** ~~gone~~", "<p><strong>bold</strong> <strong>also</strong> <em><strong>both</strong></em> <del>gone</del></p>"},
+		{"snake_case_name and 2 * 3 * 4", "<p>snake_case_name and 2 * 3 * 4</p>"},
+		{"# Title\n\n## Sub ##\n\nSetext\n===", "<h1>Title</h1>\n<h2>Sub</h2>\n<h1>Setext</h1>"},
+		{"one\ntwo  \nthree\\\nfour", "<p>one\ntwo<br>\nthree<br>\nfour</p>"},
+		{"`a <b>` and ``x ` y``", "<p><code>a &lt;b&gt;</code> and <code>x ` y</code></p>"},
+		{"```go\nif a < b {\n}\n```", "<pre><code class=\"language-go\">if a &lt; b {\n}\n</code></pre>"},
+		{"> quoted\n> *text*", "<blockquote>\n<p>quoted\n<em>text</em></p>\n</blockquote>"},
+		{"- a\n- b\n  - c\n\n3. x\n4. y", "<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul></li>\n</ul>\n<ol start=\"3\">\n<li>x</li>\n<li>y</li>\n</ol>"},
+		{"a\n\n---\n\n* * *", "<p>a</p>\n<hr>\n<hr>"},
+		{`[site](https://example.com "Title") ![pic *1*](/a.png)`, `<p><a href="https://example.com" title="Title">site</a> <img src="/a.png" alt="pic 1"></p>`},
+		{"<https://example.com> [not a link] \\*lit\\*", `<p><a href="https://example.com">https://example.com</a> [not a link] *lit*</p>`},
+		{"AT&T &copy; 1 < 2", "<p>AT&amp;T &copy; 1 &lt; 2</p>"},
+		{"text <span>inline</span>", "<p>text <span>inline</span></p>"},
+	}
+	for _, tt := range tests {
+		if got := markdown(tt.in); got != tt.want {
+			t.Errorf("markdown(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		format   Format
+		in, want string
+	}{
+		{Plain, "a <b>\nline\n\npara", "<p>a &lt;b&gt;<br>\nline</p>\n<p>para</p>"},
+		{Plain, "  ", ""},
+		{Markdown, "[x](javascript:alert(1)) <script>alert(1)</script>", "<p><a>x</a> </p>"},
+		{HTML, `<p onmouseover="x()">hi</p><script>x()</script>`, "<p>hi</p>"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.format, tt.in); got != tt.want {
+			t.Errorf("Render(%s, %q)\n got %q\nwant %q", tt.format, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": Plain, "Markdown": Markdown, " html ": HTML, "plain": Plain} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("rtf"); err == nil {
+		t.Error("ParseFormat(rtf) should fail")
+	}
+}
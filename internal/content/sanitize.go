@@ -0,0 +1,240 @@
+package content
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// allowedTags maps the elements kept by Sanitize to the attributes they may
+// carry. Everything else is dropped, keeping its text.
+var allowedTags = map[string]map[string]bool{
+	"a":          {"href": true, "title": true},
+	"img":        {"src": true, "alt": true, "title": true, "width": true, "height": true},
+	"p":          {},
+	"br":         {},
+	"hr":         {},
+	"h1":         {},
+	"h2":         {},
+	"h3":         {},
+	"h4":         {},
+	"h5":         {},
+	"h6":         {},
+	"blockquote": {"cite": true},
+	"pre":        {},
+	"code":       {"class": true},
+	"em":         {},
+	"strong":     {},
+	"b":          {},
+	"i":          {},
+	"u":          {},
+	"s":          {},
+	"del":        {},
+	"ins":        {},
+	"sub":        {},
+	"sup":        {},
+	"ul":         {},
+	"ol":         {"start": true},
+	"li":         {},
+	"table":      {},
+	"thead":      {},
+	"tbody":      {},
+	"tr":         {},
+	"th":         {"colspan": true, "rowspan": true},
+	"td":         {"colspan": true, "rowspan": true},
+	"figure":     {},
+	"figcaption": {},
+	"span":       {},
+	"div":        {},
+}
+
+// droppedTags are removed together with everything inside them.
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"template": true, "noscript": true, "textarea": true, "select": true,
+	"svg": true, "math": true, "title": true, "head": true,
+}
+
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+// urlAttrs are checked against allowedSchemes.
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true}
+
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+var (
+	tagName        = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]*`)
+	attribute      = regexp.MustCompile(`^\s*([^\s"'<>/=]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+	codeClass      = regexp.MustCompile(`^language-[a-zA-Z0-9_+-]+$`)
+	numericAttr    = regexp.MustCompile(`^[0-9]{1,5}$`)
+	numericAttrs   = map[string]bool{"width": true, "height": true, "start": true, "colspan": true, "rowspan": true}
+	controlOrSpace = regexp.MustCompile(`[\x00-\x20\x7f]+`)
+)
+
+// Sanitize returns s with every element, attribute and URL scheme that is
+// not explicitly allowed removed. Scripts, styles and embedded objects are
+// dropped with their content, event handlers and inline styles never pass,
+// and links may only point to http, https, mailto or relative URLs. The
+// result is well-formed: unclosed elements are closed at the end.
+func Sanitize(s string) string {
+	var (
+		b    strings.Builder
+		open []string
+	)
+
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			writeText(&b, s)
+			break
+		}
+		writeText(&b, s[:lt])
+		s = s[lt:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s[4:], "-->")
+			if end < 0 {
+				return closeAll(&b, open)
+			}
+			s = s[4+end+3:]
+			continue
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return closeAll(&b, open)
+			}
+			s = s[end+1:]
+			continue
+		}
+
+		closing := strings.HasPrefix(s, "</")
+		rest := s[1:]
+		if closing {
+			rest = s[2:]
+		}
+		name := tagName.FindString(rest)
+		end := strings.IndexByte(rest, '>')
+		if name == "" || end < 0 {
+			// Not a tag, the "<" is text.
+			b.WriteString("&lt;")
+			s = s[1:]
+			continue
+		}
+		attrs := rest[len(name):end]
+		s = rest[end+1:]
+		name = strings.ToLower(name)
+
+		if closing {
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == name {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		if droppedTags[name] {
+			s = skipElement(s, name)
+			continue
+		}
+		allowed, ok := allowedTags[name]
+		if !ok {
+			continue
+		}
+
+		b.WriteString("<" + name)
+		writeAttrs(&b, name, attrs, allowed)
+		b.WriteString(">")
+		if !voidTags[name] {
+			open = append(open, name)
+		}
+	}
+
+	return closeAll(&b, open)
+}
+
+func closeAll(b *strings.Builder, open []string) string {
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+// writeText escapes text, decoding entities first so that they are neither
+// double-escaped nor able to smuggle markup.
+func writeText(b *strings.Builder, text string) {
+	b.WriteString(html.EscapeString(html.UnescapeString(text)))
+}
+
+// skipElement returns s after the end tag of the element name, or an empty
+// string when it is never closed.
+func skipElement(s, name string) string {
+	for {
+		i := strings.Index(s, "</")
+		if i < 0 {
+			return ""
+		}
+		s = s[i+2:]
+		if len(s) < len(name) || !strings.EqualFold(s[:len(name)], name) {
+			continue
+		}
+		after := s[len(name):]
+		if after != "" && strings.IndexByte(" \t\n\r\f/>", after[0]) < 0 {
+			continue
+		}
+		end := strings.IndexByte(after, '>')
+		if end < 0 {
+			return ""
+		}
+		return after[end+1:]
+	}
+}
+
+func writeAttrs(b *strings.Builder, tag, attrs string, allowed map[string]bool) {
+	seen := map[string]bool{}
+	for {
+		attrs = strings.TrimLeft(attrs, " \t\n\r\f/")
+		m := attribute.FindStringSubmatch(attrs)
+		if m == nil {
+			return
+		}
+		attrs = attrs[len(m[0]):]
+
+		name := strings.ToLower(m[1])
+		value := html.UnescapeString(m[2] + m[3] + m[4])
+		if !allowed[name] || seen[name] {
+			continue
+		}
+		switch {
+		case urlAttrs[name] && !safeURL(value):
+			continue
+		case numericAttrs[name] && !numericAttr.MatchString(value):
+			continue
+		case tag == "code" && name == "class" && !codeClass.MatchString(value):
+			continue
+		}
+		seen[name] = true
+		b.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+	}
+}
+
+// safeURL reports whether u is relative or uses an allowed scheme. Control
+// characters and whitespace are ignored the way browsers ignore them, so
+// "java\tscript:" is recognised as a scheme.
+func safeURL(u string) bool {
+	u = controlOrSpace.ReplaceAllString(u, "")
+	colon := strings.IndexByte(u, ':')
+	if colon < 0 {
+		return true
+	}
+	if strings.ContainsAny(u[:colon], "/?#") {
+		// The colon is part of the path, query or fragment.
+		return true
+	}
+	return allowedSchemes[strings.ToLower(u[:colon])]
+}
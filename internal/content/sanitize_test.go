@@ -0,0 +1,36 @@
+package content
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`<p>Hello <b>world</b></p>`, `<p>Hello <b>world</b></p>`},
+		{`<p onclick="alert(1)" style="color:red">x</p>`, `<p>x</p>`},
+		{`before<script>alert("x")</script>after`, `beforeafter`},
+		{`<SCRIPT src=x></SCRIPT >ok`, `ok`},
+		{`<style>p{}</style><iframe src="//evil"></iframe>text`, `text`},
+		{`<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="java&#x09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href=" JAVASCRIPT:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="https://example.com/?a=1&amp;b=2" title='t' target=_blank>x</a>`, `<a href="https://example.com/?a=1&amp;b=2" title="t">x</a>`},
+		{`<a href="/news/by-slug/a:b">x</a>`, `<a href="/news/by-slug/a:b">x</a>`},
+		{`<img src="data:image/png;base64,AAA" alt="a"><img src=/a.png width=10 height="x">`, `<img alt="a"><img src="/a.png" width="10">`},
+		{`<code class="language-go">x</code><code class="evil">y</code>`, `<code class="language-go">x</code><code>y</code>`},
+		{`<unknown>text</unknown>`, `text`},
+		{`<!-- comment --><!DOCTYPE html>text`, `text`},
+		{`<b><i>unclosed`, `<b><i>unclosed</i></b>`},
+		{`<b><i>x</b>y</i>`, `<b><i>x</i></b>y`},
+		{`</p>stray`, `stray`},
+		{`1 < 2 & 3 > 2`, `1 &lt; 2 &amp; 3 &gt; 2`},
+		{`&lt;script&gt; &amp; &copy;`, `&lt;script&gt; &amp; ©`},
+		{`<a href="x" href="javascript:y">x</a>`, `<a href="x">x</a>`},
+		{`<p title="a>b">x</p>`, `<p>b&#34;&gt;x</p>`},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.in); got != tt.want {
+			t.Errorf("Sanitize(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+		}
+	}
+}
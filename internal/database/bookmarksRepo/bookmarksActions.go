@@ -0,0 +1,66 @@
+package bookmarksrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/database"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type BookmarksRepository struct {
+	db  database.DBTX
+	log *slog.Logger
+}
+
+func NewBookmarksRepository(db database.DBTX, log *slog.Logger) *BookmarksRepository {
+	return &BookmarksRepository{db, log}
+}
+
+// AddBookmark keeps the time of the first bookmark when news is bookmarked
+// again.
+func (b *BookmarksRepository) AddBookmark(ctx context.Context, userID, newsID int) error {
+	_, err := b.db.Exec(ctx, `INSERT INTO Bookmarks (user_id, news_id) VALUES ($1, $2)
+	ON CONFLICT DO NOTHING`, userID, newsID)
+	if err != nil {
+		b.log.Error("failed to add bookmark", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (b *BookmarksRepository) RemoveBookmark(ctx context.Context, userID, newsID int) error {
+	_, err := b.db.Exec(ctx, `DELETE FROM Bookmarks WHERE user_id = $1 AND news_id = $2`, userID, newsID)
+	if err != nil {
+		b.log.Error("failed to remove bookmark", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (b *BookmarksRepository) ListBookmarkedNews(ctx context.Context, userID, limit, offset int) ([]entities.News, error) {
+	rows, err := b.db.Query(ctx, `
+	SELECT n.id, n.title, n.slug, n.summary, n.content, n.content_format, n.content_html, n.published, n.created_at, n.updated_at
+	FROM Bookmarks b
+	JOIN News n ON n.id = b.news_id
+	WHERE b.user_id = $1 AND n.published
+	ORDER BY b.created_at DESC, b.news_id DESC
+	LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		b.log.Error("failed to list bookmarks", errMsg.Err(err))
+		return nil, err
+	}
+	news, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.News, error) {
+		var news entities.News
+		err := row.Scan(&news.ID, &news.Title, &news.Slug, &news.Summary, &news.Content, &news.ContentFormat,
+			&news.ContentHTML, &news.Published, &news.CreatedAt, &news.UpdatedAt)
+		return news, err
+	})
+	if err != nil {
+		b.log.Error("failed to scan bookmarked news", errMsg.Err(err))
+		return nil, err
+	}
+	return news, nil
+}
@@ -0,0 +1,41 @@
+package bookmarksrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	newsrepo "news-service/internal/database/newsRepo"
+	usersrepo "news-service/internal/database/usersRepo"
+	"news-service/internal/entities"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestBookmarksAreDeletedWithNews(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	news := newsrepo.NewNewsRepository(pg.Db, dbtest.Logger())
+	users := usersrepo.NewUserRepository(pg.Db, dbtest.Logger())
+	repo := NewBookmarksRepository(pg.Db, dbtest.Logger())
+
+	item := entities.News{Title: "title", Content: "content", Published: true}
+	if err := news.CreateNews(ctx, &item); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	user := entities.User{Email: "reader@example.com", Password: "hash"}
+	if err := users.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := repo.AddBookmark(ctx, user.ID, item.ID); err != nil {
+		t.Fatalf("AddBookmark: %v", err)
+	}
+
+	if _, err := pg.Db.Exec(ctx, `DELETE FROM News WHERE id = $1`, item.ID); err != nil {
+		t.Fatalf("delete news: %v", err)
+	}
+	var count int
+	if err := pg.Db.QueryRow(ctx, `SELECT count(*) FROM Bookmarks`).Scan(&count); err != nil || count != 0 {
+		t.Fatalf("bookmarks should be deleted with their news: %d, %v", count, err)
+	}
+}
@@ -3,18 +3,17 @@ package categoriesrepo
 import (
 	"context"
 	"log/slog"
+	"news-service/internal/database"
 	"news-service/internal/entities"
 	errMsg "news-service/internal/err"
-
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type CategoriesRepository struct {
-	db  *pgxpool.Pool
+	db  database.DBTX
 	log *slog.Logger
 }
 
-func NewCategoriesRepository(db *pgxpool.Pool, log *slog.Logger) *CategoriesRepository {
+func NewCategoriesRepository(db database.DBTX, log *slog.Logger) *CategoriesRepository {
 	return &CategoriesRepository{db: db, log: log}
 }
 
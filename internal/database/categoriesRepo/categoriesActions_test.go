@@ -0,0 +1,36 @@
+package categoriesrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	"news-service/internal/entities"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestCreateCategorieIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCategoriesRepository(dbtest.New(t).Db, dbtest.Logger())
+
+	first := entities.Categorie{Name: 7}
+	if err := repo.CreateCategorie(ctx, &first); err != nil {
+		t.Fatalf("CreateCategorie: %v", err)
+	}
+	second := entities.Categorie{Name: 7}
+	if err := repo.CreateCategorie(ctx, &second); err != nil {
+		t.Fatalf("CreateCategorie again: %v", err)
+	}
+	if first.ID == 0 || first.ID != second.ID {
+		t.Fatalf("expected the same id, got %d and %d", first.ID, second.ID)
+	}
+
+	other := entities.Categorie{Name: 8}
+	if err := repo.CreateCategorie(ctx, &other); err != nil {
+		t.Fatalf("CreateCategorie: %v", err)
+	}
+	if other.ID == first.ID {
+		t.Fatal("different names must get different ids")
+	}
+}
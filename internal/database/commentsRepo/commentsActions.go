@@ -0,0 +1,165 @@
+package commentsrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"news-service/internal/database"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type CommentsRepository struct {
+	db  database.DBTX
+	log *slog.Logger
+}
+
+func NewCommentsRepository(db database.DBTX, log *slog.Logger) *CommentsRepository {
+	return &CommentsRepository{db, log}
+}
+
+// commentColumns is the column list scanned by scanComment.
+const commentColumns = `id, news_id, user_id, coalesce(parent_id, 0), body, status, created_at, updated_at, deleted_at`
+
+func scanComment(row pgx.Row, comment *entities.Comment) error {
+	return row.Scan(&comment.ID, &comment.NewsID, &comment.UserID, &comment.ParentID, &comment.Body,
+		&comment.Status, &comment.CreatedAt, &comment.UpdatedAt, &comment.DeletedAt)
+}
+
+func (c *CommentsRepository) CreateComment(ctx context.Context, comment *entities.Comment) error {
+	if comment.Status == "" {
+		comment.Status = entities.CommentPending
+	}
+	err := c.db.QueryRow(ctx, `INSERT INTO Comments (news_id, user_id, parent_id, body, status)
+	VALUES ($1, $2, nullif($3, 0), $4, $5)
+	RETURNING id, created_at, updated_at`,
+		comment.NewsID, comment.UserID, comment.ParentID, comment.Body, comment.Status,
+	).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		c.log.Error("failed to create comment", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (c *CommentsRepository) FindComment(ctx context.Context, id int) (entities.Comment, error) {
+	var comment entities.Comment
+	err := scanComment(c.db.QueryRow(ctx, `SELECT `+commentColumns+` FROM Comments WHERE id = $1`, id), &comment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entities.Comment{}, fmt.Errorf("comment not found")
+	}
+	if err != nil {
+		c.log.Error("failed to find comment", errMsg.Err(err))
+		return entities.Comment{}, err
+	}
+	return comment, nil
+}
+
+func (c *CommentsRepository) EditComment(ctx context.Context, comment *entities.Comment) error {
+	err := c.db.QueryRow(ctx, `UPDATE Comments SET body = $1, status = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $3 AND deleted_at IS NULL RETURNING updated_at`,
+		comment.Body, entities.CommentPending, comment.ID,
+	).Scan(&comment.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("comment not found")
+	}
+	if err != nil {
+		c.log.Error("failed to edit comment", errMsg.Err(err))
+		return err
+	}
+	comment.Status = entities.CommentPending
+	return nil
+}
+
+func (c *CommentsRepository) SetCommentStatus(ctx context.Context, id int, updatedAt time.Time, status string) error {
+	tag, err := c.db.Exec(ctx, `UPDATE Comments SET status = $1
+	WHERE id = $2 AND status = 'pending' AND updated_at = $3 AND deleted_at IS NULL`, status, id, updatedAt)
+	if err != nil {
+		c.log.Error("failed to set comment status", errMsg.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrCommentChanged
+	}
+	return nil
+}
+
+func (c *CommentsRepository) DeleteComment(ctx context.Context, id int) error {
+	_, err := c.db.Exec(ctx, `UPDATE Comments SET body = '', deleted_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		c.log.Error("failed to delete comment", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (c *CommentsRepository) ListComments(ctx context.Context, newsID int) ([]entities.Comment, error) {
+	return c.queryComments(ctx, `SELECT `+commentColumns+` FROM Comments
+	WHERE news_id = $1 AND status = 'approved' ORDER BY id`, newsID)
+}
+
+func (c *CommentsRepository) ListPendingComments(ctx context.Context, limit, offset int) ([]entities.Comment, error) {
+	return c.queryComments(ctx, `SELECT `+commentColumns+` FROM Comments
+	WHERE status = 'pending' AND deleted_at IS NULL ORDER BY id LIMIT $1 OFFSET $2`, limit, offset)
+}
+
+func (c *CommentsRepository) queryComments(ctx context.Context, sql string, args ...any) ([]entities.Comment, error) {
+	rows, err := c.db.Query(ctx, sql, args...)
+	if err != nil {
+		c.log.Error("failed to query comments", errMsg.Err(err))
+		return nil, err
+	}
+	comments, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.Comment, error) {
+		var comment entities.Comment
+		err := scanComment(row, &comment)
+		return comment, err
+	})
+	if err != nil {
+		c.log.Error("failed to scan comments", errMsg.Err(err))
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (c *CommentsRepository) CountComments(ctx context.Context, newsID int) (int, error) {
+	var count int
+	err := c.db.QueryRow(ctx, `SELECT count(*) FROM Comments
+	WHERE news_id = $1 AND status = 'approved' AND deleted_at IS NULL`, newsID).Scan(&count)
+	if err != nil {
+		c.log.Error("failed to count comments", errMsg.Err(err))
+		return 0, err
+	}
+	return count, nil
+}
+
+func (c *CommentsRepository) CountCommentsByNews(ctx context.Context, newsIDs []int) (map[int]int, error) {
+	rows, err := c.db.Query(ctx, `SELECT news_id, count(*) FROM Comments
+	WHERE news_id = ANY($1) AND status = 'approved' AND deleted_at IS NULL
+	GROUP BY news_id`, newsIDs)
+	if err != nil {
+		c.log.Error("failed to count comments", errMsg.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int, len(newsIDs))
+	for rows.Next() {
+		var newsID, count int
+		if err := rows.Scan(&newsID, &count); err != nil {
+			c.log.Error("failed to scan comment count", errMsg.Err(err))
+			return nil, err
+		}
+		counts[newsID] = count
+	}
+	if err := rows.Err(); err != nil {
+		c.log.Error("failed to count comments", errMsg.Err(err))
+		return nil, err
+	}
+	return counts, nil
+}
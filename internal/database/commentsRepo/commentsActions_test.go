@@ -0,0 +1,41 @@
+package commentsrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	newsrepo "news-service/internal/database/newsRepo"
+	usersrepo "news-service/internal/database/usersRepo"
+	"news-service/internal/entities"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestCommentsAreDeletedWithNews(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	news := newsrepo.NewNewsRepository(pg.Db, dbtest.Logger())
+	users := usersrepo.NewUserRepository(pg.Db, dbtest.Logger())
+	repo := NewCommentsRepository(pg.Db, dbtest.Logger())
+
+	item := entities.News{Title: "title", Content: "content", Published: true}
+	if err := news.CreateNews(ctx, &item); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	user := entities.User{Email: "reader@example.com", Password: "hash"}
+	if err := users.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	comment := entities.Comment{NewsID: item.ID, UserID: user.ID, Body: "hi"}
+	if err := repo.CreateComment(ctx, &comment); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+
+	if _, err := pg.Db.Exec(ctx, `DELETE FROM News WHERE id = $1`, item.ID); err != nil {
+		t.Fatalf("delete news: %v", err)
+	}
+	if _, err := repo.FindComment(ctx, comment.ID); err == nil {
+		t.Fatal("comments should be deleted with their news")
+	}
+}
@@ -0,0 +1,46 @@
+package database_test
+
+import (
+	bookmarksrepo "news-service/internal/database/bookmarksRepo"
+	categoriesrepo "news-service/internal/database/categoriesRepo"
+	commentsrepo "news-service/internal/database/commentsRepo"
+	"news-service/internal/database/dbtest"
+	mediarepo "news-service/internal/database/mediaRepo"
+	newscategoriesrepo "news-service/internal/database/newsCategoriesRepo"
+	newseventsrepo "news-service/internal/database/newsEventsRepo"
+	newsrepo "news-service/internal/database/newsRepo"
+	outboxrepo "news-service/internal/database/outboxRepo"
+	reactionsrepo "news-service/internal/database/reactionsRepo"
+	"news-service/internal/database/repotest"
+	subscriptionsrepo "news-service/internal/database/subscriptionsRepo"
+	tagsrepo "news-service/internal/database/tagsRepo"
+	usertokensrepo "news-service/internal/database/userTokensRepo"
+	usersrepo "news-service/internal/database/usersRepo"
+	viewsrepo "news-service/internal/database/viewsRepo"
+	webhooksrepo "news-service/internal/database/webhooksRepo"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		pg := dbtest.New(t)
+		log := dbtest.Logger()
+		return repotest.Repositories{
+			News:           newsrepo.NewNewsRepository(pg.Db, log),
+			Categories:     categoriesrepo.NewCategoriesRepository(pg.Db, log),
+			NewsCategories: newscategoriesrepo.NewNewsCategoriesRepository(pg.Db, log),
+			Users:          usersrepo.NewUserRepository(pg.Db, log),
+			UserTokens:     usertokensrepo.NewUserTokensRepository(pg.Db, log),
+			Media:          mediarepo.NewMediaRepository(pg.Db, log),
+			Tags:           tagsrepo.NewTagsRepository(pg.Db, log),
+			Views:          viewsrepo.NewViewsRepository(pg.Db, log),
+			Comments:       commentsrepo.NewCommentsRepository(pg.Db, log),
+			Reactions:      reactionsrepo.NewReactionsRepository(pg.Db, log),
+			Bookmarks:      bookmarksrepo.NewBookmarksRepository(pg.Db, log),
+			Subscriptions:  subscriptionsrepo.NewSubscriptionsRepository(pg.Db, log),
+			Webhooks:       webhooksrepo.NewWebhooksRepository(pg.Db, log),
+			Outbox:         outboxrepo.NewOutboxRepository(pg.Db, log),
+			NewsEvents:     newseventsrepo.NewNewsEventsRepository(pg.Db, log),
+		}
+	})
+}
@@ -0,0 +1,250 @@
+// Package dbtest provides throwaway Postgres databases for tests.
+//
+// A server is taken from TEST_DATABASE_URL when it is set. Otherwise a
+// temporary cluster is started from the initdb and postgres binaries found
+// in PATH (or in TEST_POSTGRES_BIN). When neither is available the tests
+// that need a database are skipped.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"news-service/internal/config"
+	"news-service/internal/database"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	mu       sync.Mutex
+	adminURL string
+	startErr error
+	local    *localServer
+)
+
+// Main runs the tests and stops the local server if one was started.
+// Packages using New call it from TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+func Main(m *testing.M) int {
+	code := m.Run()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if local != nil {
+		local.stop()
+		local = nil
+	}
+	return code
+}
+
+// Logger returns a logger that discards everything.
+func Logger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// New creates an empty database with the schema applied and drops it when
+// the test finishes.
+func New(t testing.TB) *database.Postgres {
+	t.Helper()
+
+	pg, err := database.NewPG(context.Background(), URL(t), Logger(), &config.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(pg.Close)
+
+	return pg
+}
+
+// URL creates an empty database without the schema and returns its
+// connection string.
+func URL(t testing.TB) string {
+	t.Helper()
+
+	admin, err := serverURL()
+	if err != nil {
+		t.Skipf("postgres is not available: %v", err)
+	}
+
+	ctx := context.Background()
+	name := "news_test_" + randomSuffix()
+
+	conn, err := pgx.Connect(ctx, admin)
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("failed to create database %s: %v", name, err)
+	}
+	t.Cleanup(func() { dropDatabase(admin, name) })
+
+	return WithDatabase(admin, name)
+}
+
+// WithDatabase returns connString pointing at the database name.
+func WithDatabase(connString, name string) string {
+	if u, err := url.Parse(connString); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		u.Path = "/" + name
+		return u.String()
+	}
+	return connString + " dbname=" + name
+}
+
+func dropDatabase(admin, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, admin)
+	if err != nil {
+		return
+	}
+	defer conn.Close(ctx)
+	conn.Exec(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)")
+}
+
+func serverURL() (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if adminURL != "" || startErr != nil {
+		return adminURL, startErr
+	}
+
+	if u := os.Getenv("TEST_DATABASE_URL"); u != "" {
+		adminURL = u
+		return adminURL, nil
+	}
+
+	local, startErr = startLocal()
+	if startErr == nil {
+		adminURL = local.url
+	}
+	return adminURL, startErr
+}
+
+type localServer struct {
+	dir string
+	url string
+	cmd *exec.Cmd
+}
+
+func startLocal() (*localServer, error) {
+	initdb, err := findBinary("initdb")
+	if err != nil {
+		return nil, err
+	}
+	postgres, err := findBinary("postgres")
+	if err != nil {
+		return nil, err
+	}
+	if os.Geteuid() == 0 {
+		return nil, errors.New("postgres refuses to run as root; set TEST_DATABASE_URL")
+	}
+
+	dir, err := os.MkdirTemp("", "news-service-pg-")
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := exec.Command(initdb, "-D", filepath.Join(dir, "data"), "-U", "postgres", "-A", "trust", "--no-sync").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("initdb failed: %w: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	cmd := exec.Command(postgres,
+		"-D", filepath.Join(dir, "data"),
+		"-p", fmt.Sprint(port),
+		"-k", dir,
+		"-c", "listen_addresses=127.0.0.1",
+		"-c", "fsync=off",
+	)
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to start postgres: %w", err)
+	}
+
+	s := &localServer{
+		dir: dir,
+		url: fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port),
+		cmd: cmd,
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		conn, err := pgx.Connect(ctx, s.url)
+		cancel()
+		if err == nil {
+			conn.Close(context.Background())
+			return s, nil
+		}
+		if time.Now().After(deadline) {
+			s.stop()
+			return nil, fmt.Errorf("postgres did not become ready: %w", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (s *localServer) stop() {
+	if s.cmd.Process != nil {
+		s.cmd.Process.Signal(os.Interrupt)
+		s.cmd.Wait()
+	}
+	os.RemoveAll(s.dir)
+}
+
+func findBinary(name string) (string, error) {
+	if dir := os.Getenv("TEST_POSTGRES_BIN"); dir != "" {
+		return filepath.Join(dir, name), nil
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	if out, err := exec.Command("pg_config", "--bindir").Output(); err == nil {
+		path := filepath.Join(strings.TrimSpace(string(out)), name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in PATH", name)
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
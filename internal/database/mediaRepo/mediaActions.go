@@ -0,0 +1,83 @@
+package mediarepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/database"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type MediaRepository struct {
+	db  database.DBTX
+	log *slog.Logger
+}
+
+func NewMediaRepository(db database.DBTX, log *slog.Logger) *MediaRepository {
+	return &MediaRepository{db, log}
+}
+
+func (m *MediaRepository) CreateMedia(ctx context.Context, media *entities.Media) error {
+	err := m.db.QueryRow(ctx, `
+	INSERT INTO NewsMedia (news_id, key, url, thumbnail_key, thumbnail_url, file_name, content_type, size, width, height)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id, created_at`,
+		media.NewsID, media.Key, media.URL, media.ThumbnailKey, media.ThumbnailURL,
+		media.FileName, media.ContentType, media.Size, media.Width, media.Height,
+	).Scan(&media.ID, &media.CreatedAt)
+	if err != nil {
+		m.log.Error("failed to create media", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (m *MediaRepository) ListMedia(ctx context.Context, newsID int) ([]entities.Media, error) {
+	rows, err := m.db.Query(ctx, `
+	SELECT id, news_id, key, url, thumbnail_key, thumbnail_url, file_name, content_type, size, width, height, created_at
+	FROM NewsMedia WHERE news_id = $1 ORDER BY id`, newsID)
+	if err != nil {
+		m.log.Error("failed to list media", errMsg.Err(err))
+		return nil, err
+	}
+	media, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.Media, error) {
+		var media entities.Media
+		err := row.Scan(&media.ID, &media.NewsID, &media.Key, &media.URL, &media.ThumbnailKey, &media.ThumbnailURL,
+			&media.FileName, &media.ContentType, &media.Size, &media.Width, &media.Height, &media.CreatedAt)
+		return media, err
+	})
+	if err != nil {
+		m.log.Error("failed to scan media", errMsg.Err(err))
+		return nil, err
+	}
+	return media, nil
+}
+
+func (m *MediaRepository) ListMediaByNews(ctx context.Context, newsIDs []int) (map[int][]entities.Media, error) {
+	rows, err := m.db.Query(ctx, `
+	SELECT id, news_id, key, url, thumbnail_key, thumbnail_url, file_name, content_type, size, width, height, created_at
+	FROM NewsMedia WHERE news_id = ANY($1) ORDER BY id`, newsIDs)
+	if err != nil {
+		m.log.Error("failed to list media", errMsg.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := make(map[int][]entities.Media, len(newsIDs))
+	for rows.Next() {
+		var item entities.Media
+		if err := rows.Scan(&item.ID, &item.NewsID, &item.Key, &item.URL, &item.ThumbnailKey, &item.ThumbnailURL,
+			&item.FileName, &item.ContentType, &item.Size, &item.Width, &item.Height, &item.CreatedAt); err != nil {
+			m.log.Error("failed to scan media", errMsg.Err(err))
+			return nil, err
+		}
+		media[item.NewsID] = append(media[item.NewsID], item)
+	}
+	if err := rows.Err(); err != nil {
+		m.log.Error("failed to list media", errMsg.Err(err))
+		return nil, err
+	}
+	return media, nil
+}
@@ -0,0 +1,36 @@
+package mediarepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	newsrepo "news-service/internal/database/newsRepo"
+	"news-service/internal/entities"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestMediaIsDeletedWithNews(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	news := newsrepo.NewNewsRepository(pg.Db, dbtest.Logger())
+	repo := NewMediaRepository(pg.Db, dbtest.Logger())
+
+	item := entities.News{Title: "title", Content: "content"}
+	if err := news.CreateNews(ctx, &item); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	media := entities.Media{NewsID: item.ID, Key: "news/1/a.png", URL: "/media/news/1/a.png", FileName: "a.png", ContentType: "image/png", Size: 1}
+	if err := repo.CreateMedia(ctx, &media); err != nil {
+		t.Fatalf("CreateMedia: %v", err)
+	}
+
+	if _, err := pg.Db.Exec(ctx, `DELETE FROM News WHERE id = $1`, item.ID); err != nil {
+		t.Fatalf("delete news: %v", err)
+	}
+	list, err := repo.ListMedia(ctx, item.ID)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("ListMedia after deleting the news = %v, %v", list, err)
+	}
+}
@@ -0,0 +1,65 @@
+package memoryrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"sort"
+	"time"
+)
+
+type BookmarksRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewBookmarksRepository(store *Store, log *slog.Logger) *BookmarksRepository {
+	return &BookmarksRepository{store: store, log: log}
+}
+
+func (b *BookmarksRepository) AddBookmark(ctx context.Context, userID, newsID int) error {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+
+	if err := b.store.checkNewsAndUser(newsID, userID); err != nil {
+		b.log.Error("failed to add bookmark", errMsg.Err(err))
+		return err
+	}
+	if b.store.bookmarks[userID] == nil {
+		b.store.bookmarks[userID] = make(map[int]time.Time)
+	}
+	if _, ok := b.store.bookmarks[userID][newsID]; !ok {
+		b.store.bookmarks[userID][newsID] = now()
+	}
+	return nil
+}
+
+func (b *BookmarksRepository) RemoveBookmark(ctx context.Context, userID, newsID int) error {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+
+	delete(b.store.bookmarks[userID], newsID)
+	return nil
+}
+
+func (b *BookmarksRepository) ListBookmarkedNews(ctx context.Context, userID, limit, offset int) ([]entities.News, error) {
+	b.store.mu.RLock()
+	defer b.store.mu.RUnlock()
+
+	bookmarks := b.store.bookmarks[userID]
+	news := []entities.News{}
+	for newsID := range bookmarks {
+		if n, ok := b.store.news[newsID]; ok && n.Published {
+			news = append(news, n)
+		}
+	}
+	sort.Slice(news, func(i, j int) bool {
+		ti, tj := bookmarks[news[i].ID], bookmarks[news[j].ID]
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return news[i].ID > news[j].ID
+	})
+	return page(news, limit, offset), nil
+}
@@ -0,0 +1,33 @@
+package memoryrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/entities"
+)
+
+type CategoriesRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewCategoriesRepository(store *Store, log *slog.Logger) *CategoriesRepository {
+	return &CategoriesRepository{store: store, log: log}
+}
+
+func (c *CategoriesRepository) CreateCategorie(ctx context.Context, categorie *entities.Categorie) error {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	for id, existing := range c.store.categories {
+		if existing.Name == categorie.Name {
+			categorie.ID = id
+			return nil
+		}
+	}
+
+	c.store.lastCategory++
+	categorie.ID = c.store.lastCategory
+	c.store.categories[categorie.ID] = *categorie
+	return nil
+}
@@ -0,0 +1,150 @@
+package memoryrepo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"slices"
+	"sort"
+	"time"
+)
+
+type CommentsRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewCommentsRepository(store *Store, log *slog.Logger) *CommentsRepository {
+	return &CommentsRepository{store: store, log: log}
+}
+
+func (c *CommentsRepository) CreateComment(ctx context.Context, comment *entities.Comment) error {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	var err error
+	if _, ok := c.store.news[comment.NewsID]; !ok {
+		err = fmt.Errorf("news %d does not exist", comment.NewsID)
+	} else if _, ok := c.store.users[comment.UserID]; !ok {
+		err = fmt.Errorf("user %d does not exist", comment.UserID)
+	} else if _, ok := c.store.comments[comment.ParentID]; comment.ParentID != 0 && !ok {
+		err = fmt.Errorf("comment %d does not exist", comment.ParentID)
+	}
+	if err != nil {
+		c.log.Error("failed to create comment", errMsg.Err(err))
+		return err
+	}
+
+	if comment.Status == "" {
+		comment.Status = entities.CommentPending
+	}
+	c.store.lastCommentID++
+	comment.ID = c.store.lastCommentID
+	comment.CreatedAt = now()
+	comment.UpdatedAt = comment.CreatedAt
+	c.store.comments[comment.ID] = *comment
+	return nil
+}
+
+func (c *CommentsRepository) FindComment(ctx context.Context, id int) (entities.Comment, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	comment, ok := c.store.comments[id]
+	if !ok {
+		return entities.Comment{}, fmt.Errorf("comment not found")
+	}
+	return comment, nil
+}
+
+func (c *CommentsRepository) EditComment(ctx context.Context, comment *entities.Comment) error {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	stored, ok := c.store.comments[comment.ID]
+	if !ok || stored.DeletedAt != nil {
+		return fmt.Errorf("comment not found")
+	}
+	stored.Body = comment.Body
+	stored.Status = entities.CommentPending
+	stored.UpdatedAt = now()
+	c.store.comments[comment.ID] = stored
+	comment.Status, comment.UpdatedAt = stored.Status, stored.UpdatedAt
+	return nil
+}
+
+func (c *CommentsRepository) SetCommentStatus(ctx context.Context, id int, updatedAt time.Time, status string) error {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	comment, ok := c.store.comments[id]
+	if !ok || comment.Status != entities.CommentPending || !comment.UpdatedAt.Equal(updatedAt) || comment.DeletedAt != nil {
+		return models.ErrCommentChanged
+	}
+	comment.Status = status
+	c.store.comments[id] = comment
+	return nil
+}
+
+func (c *CommentsRepository) DeleteComment(ctx context.Context, id int) error {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	comment, ok := c.store.comments[id]
+	if !ok || comment.DeletedAt != nil {
+		return nil
+	}
+	deleted := now()
+	comment.Body = ""
+	comment.DeletedAt = &deleted
+	c.store.comments[id] = comment
+	return nil
+}
+
+func (c *CommentsRepository) ListComments(ctx context.Context, newsID int) ([]entities.Comment, error) {
+	return c.list(func(comment entities.Comment) bool {
+		return comment.NewsID == newsID && comment.Status == entities.CommentApproved
+	}), nil
+}
+
+func (c *CommentsRepository) ListPendingComments(ctx context.Context, limit, offset int) ([]entities.Comment, error) {
+	return page(c.list(func(comment entities.Comment) bool {
+		return comment.Status == entities.CommentPending && comment.DeletedAt == nil
+	}), limit, offset), nil
+}
+
+func (c *CommentsRepository) CountComments(ctx context.Context, newsID int) (int, error) {
+	return len(c.list(func(comment entities.Comment) bool {
+		return comment.NewsID == newsID && comment.Status == entities.CommentApproved && comment.DeletedAt == nil
+	})), nil
+}
+
+func (c *CommentsRepository) CountCommentsByNews(ctx context.Context, newsIDs []int) (map[int]int, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	counts := make(map[int]int, len(newsIDs))
+	for _, comment := range c.store.comments {
+		if comment.Status == entities.CommentApproved && comment.DeletedAt == nil && slices.Contains(newsIDs, comment.NewsID) {
+			counts[comment.NewsID]++
+		}
+	}
+	return counts, nil
+}
+
+func (c *CommentsRepository) list(match func(entities.Comment) bool) []entities.Comment {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	comments := []entities.Comment{}
+	for _, comment := range c.store.comments {
+		if match(comment) {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	return comments
+}
@@ -0,0 +1,64 @@
+package memoryrepo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+)
+
+type MediaRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewMediaRepository(store *Store, log *slog.Logger) *MediaRepository {
+	return &MediaRepository{store: store, log: log}
+}
+
+func (m *MediaRepository) CreateMedia(ctx context.Context, media *entities.Media) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if _, ok := m.store.news[media.NewsID]; !ok {
+		err := fmt.Errorf("news %d does not exist", media.NewsID)
+		m.log.Error("failed to create media", errMsg.Err(err))
+		return err
+	}
+	for _, list := range m.store.media {
+		for _, existing := range list {
+			if existing.Key == media.Key {
+				err := fmt.Errorf("media key %q already exists", media.Key)
+				m.log.Error("failed to create media", errMsg.Err(err))
+				return err
+			}
+		}
+	}
+
+	m.store.lastMediaID++
+	media.ID = m.store.lastMediaID
+	media.CreatedAt = now()
+	m.store.media[media.NewsID] = append(m.store.media[media.NewsID], *media)
+	return nil
+}
+
+func (m *MediaRepository) ListMedia(ctx context.Context, newsID int) ([]entities.Media, error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	return append([]entities.Media(nil), m.store.media[newsID]...), nil
+}
+
+func (m *MediaRepository) ListMediaByNews(ctx context.Context, newsIDs []int) (map[int][]entities.Media, error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	media := make(map[int][]entities.Media, len(newsIDs))
+	for _, newsID := range newsIDs {
+		if len(m.store.media[newsID]) > 0 {
+			media[newsID] = append([]entities.Media(nil), m.store.media[newsID]...)
+		}
+	}
+	return media, nil
+}
@@ -0,0 +1,32 @@
+package memoryrepo
+
+import (
+	"io"
+	"log/slog"
+	"news-service/internal/database/repotest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		store := NewStore()
+		log := slog.New(slog.NewTextHandler(io.Discard, nil))
+		return repotest.Repositories{
+			News:           NewNewsRepository(store, log),
+			Categories:     NewCategoriesRepository(store, log),
+			NewsCategories: NewNewsCategoriesRepository(store, log),
+			Users:          NewUserRepository(store, log),
+			UserTokens:     NewUserTokensRepository(store, log),
+			Media:          NewMediaRepository(store, log),
+			Tags:           NewTagsRepository(store, log),
+			Views:          NewViewsRepository(store, log),
+			Comments:       NewCommentsRepository(store, log),
+			Reactions:      NewReactionsRepository(store, log),
+			Bookmarks:      NewBookmarksRepository(store, log),
+			Subscriptions:  NewSubscriptionsRepository(store, log),
+			Webhooks:       NewWebhooksRepository(store, log),
+			Outbox:         NewOutboxRepository(store, log),
+			NewsEvents:     NewNewsEventsRepository(store, log),
+		}
+	})
+}
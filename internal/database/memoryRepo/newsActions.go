@@ -0,0 +1,227 @@
+package memoryrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/entities"
+	"news-service/internal/models"
+	"news-service/internal/outbox"
+	"news-service/internal/related"
+	"news-service/internal/slug"
+	"sort"
+	"time"
+)
+
+type NewsRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewNewsRepository(store *Store, log *slog.Logger) *NewsRepository {
+	return &NewsRepository{store: store, log: log}
+}
+
+func (n *NewsRepository) CreateNews(ctx context.Context, news *entities.News) error {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+
+	base := news.Slug
+	if base == "" {
+		base = slug.Make(news.Title)
+	}
+	if news.ContentFormat == "" {
+		news.ContentFormat = entities.DefaultContentFormat
+	}
+
+	created := *news
+	created.ID = n.store.lastNewsID + 1
+	created.CreatedAt = now()
+	created.UpdatedAt = created.CreatedAt
+	created.Slug = n.store.uniqueSlug(base, created.ID)
+	if created.Published {
+		msg, err := outbox.NewsPublished(created)
+		if err != nil {
+			return err
+		}
+		n.store.addOutbox(msg)
+	}
+	n.store.lastNewsID++
+	n.store.news[created.ID] = created
+	n.store.addNewsEvent(created.ID, entities.EventNewsCreated)
+	*news = created
+	return nil
+}
+
+// uniqueSlug mirrors the Postgres repository: slugs of other news, current
+// or redirected, are taken. The caller must hold the lock.
+func (s *Store) uniqueSlug(base string, newsID int) string {
+	var taken []string
+	for id, news := range s.news {
+		if id != newsID {
+			taken = append(taken, news.Slug)
+		}
+	}
+	for old, id := range s.slugRedirects {
+		if id != newsID {
+			taken = append(taken, old)
+		}
+	}
+	return slug.Next(base, taken)
+}
+
+func (n *NewsRepository) ListNews(ctx context.Context) ([]entities.News, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+
+	var newsArray []entities.News
+	for _, news := range n.store.news {
+		newsArray = append(newsArray, news)
+	}
+	sort.Slice(newsArray, func(i, j int) bool { return newsArray[i].ID > newsArray[j].ID })
+	return newsArray, nil
+}
+
+func (n *NewsRepository) ListPublishedNews(ctx context.Context, limit, offset int) ([]entities.News, error) {
+	return n.listPublished(func(news entities.News) bool { return true }, limit, offset)
+}
+
+func (n *NewsRepository) ListPublishedNewsByCategory(ctx context.Context, category, limit, offset int) ([]entities.News, error) {
+	return n.listPublished(func(news entities.News) bool {
+		for categoryID := range n.store.newsCategories[news.ID] {
+			if n.store.categories[categoryID].Name == category {
+				return true
+			}
+		}
+		return false
+	}, limit, offset)
+}
+
+func (n *NewsRepository) listPublished(match func(entities.News) bool, limit, offset int) ([]entities.News, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+
+	var newsArray []entities.News
+	for _, news := range n.store.news {
+		if news.Published && match(news) {
+			newsArray = append(newsArray, news)
+		}
+	}
+	sort.Slice(newsArray, func(i, j int) bool { return newsArray[i].ID > newsArray[j].ID })
+	return page(newsArray, limit, offset), nil
+}
+
+// ListRelatedNews mirrors the Postgres repository with related.Terms in
+// place of the text search lexemes.
+func (n *NewsRepository) ListRelatedNews(ctx context.Context, newsID int, opts related.Options) ([]entities.News, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+
+	src, ok := n.store.news[newsID]
+	if !ok {
+		return nil, nil
+	}
+	srcTerms := related.Terms(src.Title + " " + src.Summary)
+	current := time.Now()
+
+	type candidate struct {
+		news  entities.News
+		score float64
+	}
+	var candidates []candidate
+	for id, news := range n.store.news {
+		if id == newsID || !news.Published {
+			continue
+		}
+		categories := 0
+		for categoryID := range n.store.newsCategories[id] {
+			if _, ok := n.store.newsCategories[newsID][categoryID]; ok {
+				categories++
+			}
+		}
+		tags := 0
+		for name := range n.store.newsTags[id] {
+			if _, ok := n.store.newsTags[newsID][name]; ok {
+				tags++
+			}
+		}
+		text := related.Jaccard(srcTerms, related.Terms(news.Title+" "+news.Summary))
+		score := related.Score(categories, tags, text, current.Sub(news.CreatedAt), opts.HalfLife)
+		if score > 0 {
+			candidates = append(candidates, candidate{news, score})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].news.ID > candidates[j].news.ID
+	})
+
+	var newsArray []entities.News
+	for _, c := range page(candidates, opts.Limit, 0) {
+		newsArray = append(newsArray, c.news)
+	}
+	return newsArray, nil
+}
+
+func (n *NewsRepository) UpdateNews(ctx context.Context, news *entities.News) error {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+
+	old, ok := n.store.news[news.ID]
+	if !ok {
+		return nil
+	}
+
+	if news.Slug == "" {
+		news.Slug = old.Slug
+	}
+	if news.Slug != old.Slug {
+		news.Slug = n.store.uniqueSlug(news.Slug, news.ID)
+	}
+	if news.ContentFormat == "" {
+		news.ContentFormat = entities.DefaultContentFormat
+	}
+	news.CreatedAt = old.CreatedAt
+	news.UpdatedAt = now()
+	if news.Published && !old.Published {
+		msg, err := outbox.NewsPublished(*news)
+		if err != nil {
+			return err
+		}
+		n.store.addOutbox(msg)
+	}
+	if news.Slug != old.Slug {
+		delete(n.store.slugRedirects, news.Slug)
+		n.store.slugRedirects[old.Slug] = news.ID
+	}
+	n.store.news[news.ID] = *news
+	n.store.addNewsEvent(news.ID, entities.EventNewsUpdated)
+	return nil
+}
+
+func (n *NewsRepository) FindNewsByID(ctx context.Context, id int) (entities.News, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+
+	news, ok := n.store.news[id]
+	if !ok {
+		return entities.News{}, models.ErrNewsNotFound
+	}
+	return news, nil
+}
+
+func (n *NewsRepository) FindNewsBySlug(ctx context.Context, newsSlug string) (entities.News, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+
+	for _, news := range n.store.news {
+		if news.Slug == newsSlug {
+			return news, nil
+		}
+	}
+	if id, ok := n.store.slugRedirects[newsSlug]; ok {
+		return n.store.news[id], nil
+	}
+	return entities.News{}, models.ErrNewsNotFound
+}
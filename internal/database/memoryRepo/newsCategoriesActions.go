@@ -0,0 +1,94 @@
+package memoryrepo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"sort"
+)
+
+type NewsCategoriesRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewNewsCategoriesRepository(store *Store, log *slog.Logger) *NewsCategoriesRepository {
+	return &NewsCategoriesRepository{store: store, log: log}
+}
+
+func (n *NewsCategoriesRepository) Create(ctx context.Context, NC *entities.NewsCategories) error {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+
+	if err := n.store.linkCategory(NC.NewsID, NC.CategoryID); err != nil {
+		n.log.Error("failed to create newsCategorie", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (n *NewsCategoriesRepository) ListCategories(ctx context.Context, id int) ([]int, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+
+	var names []int
+	for categoryID := range n.store.newsCategories[id] {
+		names = append(names, n.store.categories[categoryID].Name)
+	}
+	sort.Ints(names)
+	return names, nil
+}
+
+func (n *NewsCategoriesRepository) ListCategoriesByNews(ctx context.Context, newsIDs []int) (map[int][]int, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+
+	categories := make(map[int][]int, len(newsIDs))
+	for _, newsID := range newsIDs {
+		for categoryID := range n.store.newsCategories[newsID] {
+			categories[newsID] = append(categories[newsID], n.store.categories[categoryID].Name)
+		}
+		sort.Ints(categories[newsID])
+	}
+	return categories, nil
+}
+
+func (n *NewsCategoriesRepository) UpdateNewsCategories(ctx context.Context, categoryID, newsID int) error {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+
+	if err := n.store.linkCategory(newsID, categoryID); err != nil {
+		n.log.Error("failed to insert new news category", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (n *NewsCategoriesRepository) DeleteCategories(ctx context.Context, newsID int) error {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+
+	delete(n.store.newsCategories, newsID)
+	return nil
+}
+
+// linkCategory mirrors the foreign keys and primary key of the
+// NewsCategories table. The caller must hold the write lock.
+func (s *Store) linkCategory(newsID, categoryID int) error {
+	if _, ok := s.news[newsID]; !ok {
+		return fmt.Errorf("news %d does not exist", newsID)
+	}
+	if _, ok := s.categories[categoryID]; !ok {
+		return fmt.Errorf("category %d does not exist", categoryID)
+	}
+	if _, ok := s.newsCategories[newsID][categoryID]; ok {
+		return fmt.Errorf("news %d already has category %d", newsID, categoryID)
+	}
+	if s.newsCategories[newsID] == nil {
+		s.newsCategories[newsID] = make(map[int]struct{})
+	}
+	s.newsCategories[newsID][categoryID] = struct{}{}
+	return nil
+}
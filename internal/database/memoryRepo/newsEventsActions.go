@@ -0,0 +1,86 @@
+package memoryrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/entities"
+	"sort"
+	"time"
+)
+
+type NewsEventsRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewNewsEventsRepository(store *Store, log *slog.Logger) *NewsEventsRepository {
+	return &NewsEventsRepository{store: store, log: log}
+}
+
+// addNewsEvent saves an event of the change the caller is making and wakes
+// up the listeners. The caller must hold the lock.
+func (s *Store) addNewsEvent(newsID int, kind string) {
+	s.lastEventID++
+	s.newsEvents = append(s.newsEvents, entities.NewsEvent{ID: s.lastEventID, NewsID: newsID, Kind: kind, CreatedAt: now()})
+	for listener := range s.newsListeners {
+		select {
+		case listener <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *NewsEventsRepository) ListNewsEvents(ctx context.Context, after, limit int) ([]entities.NewsEvent, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+
+	i := sort.Search(len(n.store.newsEvents), func(i int) bool { return n.store.newsEvents[i].ID > after })
+	return append([]entities.NewsEvent{}, page(n.store.newsEvents[i:], limit, 0)...), nil
+}
+
+func (n *NewsEventsRepository) LastNewsEventID(ctx context.Context) (int, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+
+	if len(n.store.newsEvents) == 0 {
+		return 0, nil
+	}
+	return n.store.newsEvents[len(n.store.newsEvents)-1].ID, nil
+}
+
+func (n *NewsEventsRepository) DeleteNewsEvents(ctx context.Context, before time.Time) (int, error) {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+
+	kept := n.store.newsEvents[:0]
+	for _, event := range n.store.newsEvents {
+		if !event.CreatedAt.Before(before) {
+			kept = append(kept, event)
+		}
+	}
+	deleted := len(n.store.newsEvents) - len(kept)
+	n.store.newsEvents = kept
+	return deleted, nil
+}
+
+func (n *NewsEventsRepository) Listen(ctx context.Context, notify func()) error {
+	listener := make(chan struct{}, 1)
+	n.store.mu.Lock()
+	n.store.newsListeners[listener] = struct{}{}
+	n.store.mu.Unlock()
+	defer func() {
+		n.store.mu.Lock()
+		delete(n.store.newsListeners, listener)
+		n.store.mu.Unlock()
+	}()
+
+	notify()
+	for {
+		select {
+		case <-listener:
+			notify()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
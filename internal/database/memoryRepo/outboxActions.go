@@ -0,0 +1,92 @@
+package memoryrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/entities"
+	"sort"
+	"time"
+)
+
+type OutboxRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewOutboxRepository(store *Store, log *slog.Logger) *OutboxRepository {
+	return &OutboxRepository{store: store, log: log}
+}
+
+// addOutbox saves msg along with the change the caller is making under the
+// same lock, like the Postgres repositories do in one transaction.
+func (s *Store) addOutbox(msg entities.OutboxMessage) {
+	s.lastOutboxID++
+	msg.ID = s.lastOutboxID
+	msg.CreatedAt = now()
+	msg.NextAttemptAt = msg.CreatedAt
+	s.outbox[msg.ID] = msg
+}
+
+func (o *OutboxRepository) ClaimOutbox(ctx context.Context, at time.Time, lease time.Duration, limit int) ([]entities.OutboxMessage, error) {
+	o.store.mu.Lock()
+	defer o.store.mu.Unlock()
+
+	due := []entities.OutboxMessage{}
+	for _, msg := range o.store.outbox {
+		if msg.PublishedAt == nil && !msg.NextAttemptAt.After(at) {
+			due = append(due, msg)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	due = page(due, limit, 0)
+	for i := range due {
+		due[i].NextAttemptAt = at.Add(lease).Truncate(time.Microsecond)
+		o.store.outbox[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (o *OutboxRepository) MarkPublished(ctx context.Context, id int) error {
+	o.store.mu.Lock()
+	defer o.store.mu.Unlock()
+
+	msg, ok := o.store.outbox[id]
+	if !ok || msg.PublishedAt != nil {
+		return nil
+	}
+	published := now()
+	msg.PublishedAt = &published
+	msg.Attempts++
+	msg.LastError = ""
+	o.store.outbox[id] = msg
+	return nil
+}
+
+func (o *OutboxRepository) MarkFailed(ctx context.Context, id int, lastError string, next time.Time) error {
+	o.store.mu.Lock()
+	defer o.store.mu.Unlock()
+
+	msg, ok := o.store.outbox[id]
+	if !ok || msg.PublishedAt != nil {
+		return nil
+	}
+	msg.Attempts++
+	msg.LastError = lastError
+	msg.NextAttemptAt = next.Truncate(time.Microsecond)
+	o.store.outbox[id] = msg
+	return nil
+}
+
+func (o *OutboxRepository) PurgePublished(ctx context.Context, before time.Time) (int, error) {
+	o.store.mu.Lock()
+	defer o.store.mu.Unlock()
+
+	purged := 0
+	for id, msg := range o.store.outbox {
+		if msg.PublishedAt != nil && msg.PublishedAt.Before(before) {
+			delete(o.store.outbox, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
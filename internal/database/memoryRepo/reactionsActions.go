@@ -0,0 +1,100 @@
+package memoryrepo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	errMsg "news-service/internal/err"
+	"sort"
+)
+
+// reaction is a reaction left on a news.
+type reaction struct {
+	userID int
+	kind   string
+}
+
+type ReactionsRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewReactionsRepository(store *Store, log *slog.Logger) *ReactionsRepository {
+	return &ReactionsRepository{store: store, log: log}
+}
+
+func (re *ReactionsRepository) AddReaction(ctx context.Context, newsID, userID int, kind string) error {
+	re.store.mu.Lock()
+	defer re.store.mu.Unlock()
+
+	if err := re.store.checkNewsAndUser(newsID, userID); err != nil {
+		re.log.Error("failed to add reaction", errMsg.Err(err))
+		return err
+	}
+	if re.store.reactions[newsID] == nil {
+		re.store.reactions[newsID] = make(map[reaction]struct{})
+	}
+	re.store.reactions[newsID][reaction{userID, kind}] = struct{}{}
+	return nil
+}
+
+func (re *ReactionsRepository) RemoveReaction(ctx context.Context, newsID, userID int, kind string) error {
+	re.store.mu.Lock()
+	defer re.store.mu.Unlock()
+
+	delete(re.store.reactions[newsID], reaction{userID, kind})
+	return nil
+}
+
+func (re *ReactionsRepository) CountReactions(ctx context.Context, newsID int) (map[string]int, error) {
+	re.store.mu.RLock()
+	defer re.store.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for r := range re.store.reactions[newsID] {
+		counts[r.kind]++
+	}
+	return counts, nil
+}
+
+func (re *ReactionsRepository) CountReactionsByNews(ctx context.Context, newsIDs []int) (map[int]map[string]int, error) {
+	re.store.mu.RLock()
+	defer re.store.mu.RUnlock()
+
+	counts := make(map[int]map[string]int, len(newsIDs))
+	for _, newsID := range newsIDs {
+		for r := range re.store.reactions[newsID] {
+			if counts[newsID] == nil {
+				counts[newsID] = make(map[string]int)
+			}
+			counts[newsID][r.kind]++
+		}
+	}
+	return counts, nil
+}
+
+func (re *ReactionsRepository) ListUserReactions(ctx context.Context, newsID, userID int) ([]string, error) {
+	re.store.mu.RLock()
+	defer re.store.mu.RUnlock()
+
+	var kinds []string
+	for r := range re.store.reactions[newsID] {
+		if r.userID == userID {
+			kinds = append(kinds, r.kind)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds, nil
+}
+
+// checkNewsAndUser mirrors the foreign keys of tables keyed by news and
+// user. The caller holds the lock.
+func (s *Store) checkNewsAndUser(newsID, userID int) error {
+	if _, ok := s.news[newsID]; !ok {
+		return fmt.Errorf("news %d does not exist", newsID)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("user %d does not exist", userID)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Package memoryrepo implements the repositories on top of in-memory maps.
+// It is used by tests and by the --storage=memory demo mode.
+package memoryrepo
+
+import (
+	"news-service/internal/entities"
+	"sync"
+	"time"
+)
+
+// Store holds the data shared by the repositories. Repositories created
+// from the same Store see each other's changes, like tables in one database.
+type Store struct {
+	mu sync.RWMutex
+
+	news       map[int]entities.News
+	lastNewsID int
+	// slugRedirects maps old slugs to the news they belonged to.
+	slugRedirects map[string]int
+	categories    map[int]entities.Categorie
+	lastCategory  int
+	// newsCategories maps a news id to the ids of its categories.
+	newsCategories  map[int]map[int]struct{}
+	users           map[int]entities.User
+	lastUserID      int
+	userTokens      map[int]entities.UserToken
+	lastUserTokenID int
+	// media maps a news id to its media in upload order.
+	media       map[int][]entities.Media
+	lastMediaID int
+	// tags maps a tag name to its id, newsTags a news id to its tag names.
+	tags      map[string]int
+	lastTagID int
+	newsTags  map[int]map[string]struct{}
+	// views maps a news id to its view counts by bucket.
+	views         map[int]map[time.Time]int
+	comments      map[int]entities.Comment
+	lastCommentID int
+	// reactions maps a news id to the reactions left on it, bookmarks a
+	// user id to the news they bookmarked and when.
+	reactions map[int]map[reaction]struct{}
+	bookmarks map[int]map[int]time.Time
+	// subscriptions maps a user id to the ids of the categories they
+	// follow.
+	subscriptions  map[int]map[int]struct{}
+	webhooks       map[int]entities.Webhook
+	lastWebhookID  int
+	deliveries     map[int]entities.WebhookDelivery
+	lastDeliveryID int
+	// attempts maps a delivery id to its attempts in order.
+	attempts      map[int][]entities.WebhookAttempt
+	lastAttemptID int
+	outbox        map[int]entities.OutboxMessage
+	lastOutboxID  int
+	// newsEvents are kept in id order, newsListeners are woken up after
+	// every new event.
+	newsEvents    []entities.NewsEvent
+	lastEventID   int
+	newsListeners map[chan struct{}]struct{}
+}
+
+func NewStore() *Store {
+	return &Store{
+		news:           make(map[int]entities.News),
+		slugRedirects:  make(map[string]int),
+		categories:     make(map[int]entities.Categorie),
+		newsCategories: make(map[int]map[int]struct{}),
+		users:          make(map[int]entities.User),
+		userTokens:     make(map[int]entities.UserToken),
+		media:          make(map[int][]entities.Media),
+		tags:           make(map[string]int),
+		newsTags:       make(map[int]map[string]struct{}),
+		views:          make(map[int]map[time.Time]int),
+		comments:       make(map[int]entities.Comment),
+		reactions:      make(map[int]map[reaction]struct{}),
+		bookmarks:      make(map[int]map[int]time.Time),
+		subscriptions:  make(map[int]map[int]struct{}),
+		webhooks:       make(map[int]entities.Webhook),
+		deliveries:     make(map[int]entities.WebhookDelivery),
+		attempts:       make(map[int][]entities.WebhookAttempt),
+		outbox:         make(map[int]entities.OutboxMessage),
+		newsListeners:  make(map[chan struct{}]struct{}),
+	}
+}
+
+// page applies LIMIT and OFFSET to items.
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// now mirrors the microsecond precision of TIMESTAMPTZ.
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
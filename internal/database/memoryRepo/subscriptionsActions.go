@@ -0,0 +1,94 @@
+package memoryrepo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"sort"
+)
+
+type SubscriptionsRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewSubscriptionsRepository(store *Store, log *slog.Logger) *SubscriptionsRepository {
+	return &SubscriptionsRepository{store: store, log: log}
+}
+
+func (s *SubscriptionsRepository) Subscribe(ctx context.Context, userID, category int) error {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	if _, ok := s.store.users[userID]; !ok {
+		err := fmt.Errorf("user %d does not exist", userID)
+		s.log.Error("failed to subscribe", errMsg.Err(err))
+		return err
+	}
+	categoryID, ok := s.store.categoryID(category)
+	if !ok {
+		s.store.lastCategory++
+		categoryID = s.store.lastCategory
+		s.store.categories[categoryID] = entities.Categorie{ID: categoryID, Name: category}
+	}
+	if s.store.subscriptions[userID] == nil {
+		s.store.subscriptions[userID] = make(map[int]struct{})
+	}
+	s.store.subscriptions[userID][categoryID] = struct{}{}
+	return nil
+}
+
+func (s *SubscriptionsRepository) Unsubscribe(ctx context.Context, userID, category int) error {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	if categoryID, ok := s.store.categoryID(category); ok {
+		delete(s.store.subscriptions[userID], categoryID)
+	}
+	return nil
+}
+
+func (s *SubscriptionsRepository) ListSubscriptions(ctx context.Context, userID int) ([]int, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+
+	categories := []int{}
+	for categoryID := range s.store.subscriptions[userID] {
+		categories = append(categories, s.store.categories[categoryID].Name)
+	}
+	sort.Ints(categories)
+	return categories, nil
+}
+
+func (s *SubscriptionsRepository) ListFeedNews(ctx context.Context, userID, before, limit int) ([]entities.News, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+
+	followed := s.store.subscriptions[userID]
+	news := []entities.News{}
+	for _, n := range s.store.news {
+		if !n.Published || (before > 0 && n.ID >= before) {
+			continue
+		}
+		for categoryID := range s.store.newsCategories[n.ID] {
+			if _, ok := followed[categoryID]; ok {
+				news = append(news, n)
+				break
+			}
+		}
+	}
+	sort.Slice(news, func(i, j int) bool { return news[i].ID > news[j].ID })
+	return page(news, limit, 0), nil
+}
+
+// categoryID looks a category up by name. The caller holds the lock.
+func (s *Store) categoryID(name int) (int, bool) {
+	for id, category := range s.categories {
+		if category.Name == name {
+			return id, true
+		}
+	}
+	return 0, false
+}
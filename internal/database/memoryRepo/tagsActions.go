@@ -0,0 +1,95 @@
+package memoryrepo
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"slices"
+	"strings"
+)
+
+type TagsRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewTagsRepository(store *Store, log *slog.Logger) *TagsRepository {
+	return &TagsRepository{store: store, log: log}
+}
+
+func (t *TagsRepository) SetNewsTags(ctx context.Context, newsID int, tags []string) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	if _, ok := t.store.news[newsID]; !ok {
+		err := fmt.Errorf("news %d does not exist", newsID)
+		t.log.Error("failed to tag news", errMsg.Err(err))
+		return err
+	}
+
+	names := make(map[string]struct{}, len(tags))
+	for _, name := range tags {
+		if _, ok := t.store.tags[name]; !ok {
+			t.store.lastTagID++
+			t.store.tags[name] = t.store.lastTagID
+		}
+		names[name] = struct{}{}
+	}
+	t.store.newsTags[newsID] = names
+	return nil
+}
+
+func (t *TagsRepository) ListNewsTags(ctx context.Context, newsID int) ([]string, error) {
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+
+	tags := make([]string, 0, len(t.store.newsTags[newsID]))
+	for name := range t.store.newsTags[newsID] {
+		tags = append(tags, name)
+	}
+	slices.Sort(tags)
+	return tags, nil
+}
+
+func (t *TagsRepository) ListTagsByNews(ctx context.Context, newsIDs []int) (map[int][]string, error) {
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+
+	tags := make(map[int][]string, len(newsIDs))
+	for _, newsID := range newsIDs {
+		for name := range t.store.newsTags[newsID] {
+			tags[newsID] = append(tags[newsID], name)
+		}
+		slices.Sort(tags[newsID])
+	}
+	return tags, nil
+}
+
+func (t *TagsRepository) ListTags(ctx context.Context, prefix string, limit int) ([]entities.Tag, error) {
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, names := range t.store.newsTags {
+		for name := range names {
+			if strings.HasPrefix(name, prefix) {
+				counts[name]++
+			}
+		}
+	}
+
+	tags := make([]entities.Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, entities.Tag{ID: t.store.tags[name], Name: name, Count: count})
+	}
+	slices.SortFunc(tags, func(a, b entities.Tag) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return page(tags, limit, 0), nil
+}
@@ -0,0 +1,70 @@
+package memoryrepo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"news-service/internal/entities"
+	"news-service/internal/models"
+	"time"
+)
+
+type UserTokensRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewUserTokensRepository(store *Store, log *slog.Logger) *UserTokensRepository {
+	return &UserTokensRepository{store: store, log: log}
+}
+
+func (ut *UserTokensRepository) CreateToken(ctx context.Context, token *entities.UserToken) error {
+	ut.store.mu.Lock()
+	defer ut.store.mu.Unlock()
+
+	if _, ok := ut.store.users[token.UserID]; !ok {
+		ut.log.Error("failed to create user token")
+		return fmt.Errorf("user %d not found", token.UserID)
+	}
+	created := now()
+	for id, t := range ut.store.userTokens {
+		if t.UserID == token.UserID && (t.UsedAt != nil || !t.ExpiresAt.After(created)) {
+			delete(ut.store.userTokens, id)
+		}
+	}
+	ut.store.lastUserTokenID++
+	token.ID = ut.store.lastUserTokenID
+	token.CreatedAt = created
+	token.ExpiresAt = token.ExpiresAt.Truncate(time.Microsecond)
+	token.UsedAt = nil
+	stored := *token
+	stored.Hash = bytes.Clone(token.Hash)
+	ut.store.userTokens[token.ID] = stored
+	return nil
+}
+
+func (ut *UserTokensRepository) ConsumeToken(ctx context.Context, purpose string, hash []byte, at time.Time) (entities.UserToken, error) {
+	ut.store.mu.Lock()
+	defer ut.store.mu.Unlock()
+
+	at = at.Truncate(time.Microsecond)
+	for _, token := range ut.store.userTokens {
+		if token.Purpose != purpose || !bytes.Equal(token.Hash, hash) {
+			continue
+		}
+		if token.UsedAt != nil || !token.ExpiresAt.After(at) {
+			break
+		}
+		for id, t := range ut.store.userTokens {
+			if t.UserID == token.UserID && t.Purpose == purpose && t.UsedAt == nil {
+				t.UsedAt = &at
+				ut.store.userTokens[id] = t
+			}
+		}
+		token.UsedAt = &at
+		token.Hash = bytes.Clone(token.Hash)
+		return token, nil
+	}
+	return entities.UserToken{}, models.ErrTokenInvalid
+}
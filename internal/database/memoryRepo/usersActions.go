@@ -0,0 +1,107 @@
+package memoryrepo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"news-service/internal/entities"
+	"news-service/internal/outbox"
+)
+
+type UserRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewUserRepository(store *Store, log *slog.Logger) *UserRepository {
+	return &UserRepository{store: store, log: log}
+}
+
+func (u *UserRepository) CreateUser(ctx context.Context, user *entities.User) error {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+
+	if u.store.emailTaken(user.Email, 0) {
+		u.log.Error("Failed to create user")
+		return fmt.Errorf("user with email %s already exists", user.Email)
+	}
+
+	msg, err := outbox.UserRegistered(entities.User{ID: u.store.lastUserID + 1, Email: user.Email})
+	if err != nil {
+		return err
+	}
+	u.store.lastUserID++
+	user.ID = u.store.lastUserID
+	u.store.users[user.ID] = *user
+	u.store.addOutbox(msg)
+	return nil
+}
+
+func (u *UserRepository) FindUserByEmail(ctx context.Context, email string) (entities.User, error) {
+	u.store.mu.RLock()
+	defer u.store.mu.RUnlock()
+
+	for _, user := range u.store.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	u.log.Error("user not found")
+	return entities.User{}, fmt.Errorf("user not found")
+}
+
+func (u *UserRepository) FindUserById(ctx context.Context, id int) (entities.User, error) {
+	u.store.mu.RLock()
+	defer u.store.mu.RUnlock()
+
+	user, ok := u.store.users[id]
+	if !ok {
+		u.log.Error("user not found")
+		return entities.User{}, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+func (u *UserRepository) DeleteUserById(ctx context.Context, id int) error {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+
+	delete(u.store.users, id)
+	for tokenID, token := range u.store.userTokens {
+		if token.UserID == id {
+			delete(u.store.userTokens, tokenID)
+		}
+	}
+	for webhookID, webhook := range u.store.webhooks {
+		if webhook.UserID == id {
+			u.store.deleteWebhook(webhookID)
+		}
+	}
+	return nil
+}
+
+func (u *UserRepository) UpdateUser(ctx context.Context, user *entities.User) error {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+
+	if _, ok := u.store.users[user.ID]; !ok {
+		return nil
+	}
+	if u.store.emailTaken(user.Email, user.ID) {
+		u.log.Error("failed to update user")
+		return fmt.Errorf("user with email %s already exists", user.Email)
+	}
+	u.store.users[user.ID] = *user
+	return nil
+}
+
+// emailTaken reports whether a user other than exceptID uses email. The
+// caller must hold the lock.
+func (s *Store) emailTaken(email string, exceptID int) bool {
+	for id, user := range s.users {
+		if id != exceptID && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
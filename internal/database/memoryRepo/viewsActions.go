@@ -0,0 +1,72 @@
+package memoryrepo
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"news-service/internal/entities"
+	"sort"
+	"time"
+)
+
+type ViewsRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewViewsRepository(store *Store, log *slog.Logger) *ViewsRepository {
+	return &ViewsRepository{store: store, log: log}
+}
+
+func (v *ViewsRepository) AddViews(ctx context.Context, counts []entities.ViewCount) error {
+	v.store.mu.Lock()
+	defer v.store.mu.Unlock()
+
+	for _, c := range counts {
+		if _, ok := v.store.news[c.NewsID]; !ok {
+			continue
+		}
+		if v.store.views[c.NewsID] == nil {
+			v.store.views[c.NewsID] = make(map[time.Time]int)
+		}
+		// Bucket keys are normalized like TIMESTAMPTZ values.
+		v.store.views[c.NewsID][c.Bucket.UTC().Truncate(time.Microsecond)] += c.Views
+	}
+	return nil
+}
+
+func (v *ViewsRepository) ListTrendingNews(ctx context.Context, since time.Time, halfLife time.Duration, limit int) ([]entities.News, error) {
+	v.store.mu.RLock()
+	defer v.store.mu.RUnlock()
+
+	current := time.Now()
+	scores := make(map[int]float64)
+	for newsID, buckets := range v.store.views {
+		if !v.store.news[newsID].Published {
+			continue
+		}
+		for bucket, views := range buckets {
+			if bucket.Before(since) {
+				continue
+			}
+			weight := 1.0
+			if age := current.Sub(bucket); halfLife > 0 && age > 0 {
+				weight = math.Pow(0.5, age.Seconds()/halfLife.Seconds())
+			}
+			scores[newsID] += float64(views) * weight
+		}
+	}
+
+	newsArray := make([]entities.News, 0, len(scores))
+	for newsID := range scores {
+		newsArray = append(newsArray, v.store.news[newsID])
+	}
+	sort.Slice(newsArray, func(i, j int) bool {
+		a, b := newsArray[i], newsArray[j]
+		if scores[a.ID] != scores[b.ID] {
+			return scores[a.ID] > scores[b.ID]
+		}
+		return a.ID > b.ID
+	})
+	return page(newsArray, limit, 0), nil
+}
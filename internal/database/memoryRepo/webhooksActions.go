@@ -0,0 +1,221 @@
+package memoryrepo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"slices"
+	"sort"
+	"time"
+)
+
+type WebhooksRepository struct {
+	store *Store
+	log   *slog.Logger
+}
+
+func NewWebhooksRepository(store *Store, log *slog.Logger) *WebhooksRepository {
+	return &WebhooksRepository{store: store, log: log}
+}
+
+func (wh *WebhooksRepository) CreateWebhook(ctx context.Context, webhook *entities.Webhook) error {
+	wh.store.mu.Lock()
+	defer wh.store.mu.Unlock()
+
+	if _, ok := wh.store.users[webhook.UserID]; webhook.UserID != 0 && !ok {
+		err := fmt.Errorf("user %d does not exist", webhook.UserID)
+		wh.log.Error("failed to create webhook", errMsg.Err(err))
+		return err
+	}
+	wh.store.lastWebhookID++
+	webhook.ID = wh.store.lastWebhookID
+	webhook.CreatedAt = now()
+	stored := *webhook
+	stored.Events = slices.Clone(webhook.Events)
+	wh.store.webhooks[webhook.ID] = stored
+	return nil
+}
+
+func (wh *WebhooksRepository) FindWebhook(ctx context.Context, id int) (entities.Webhook, error) {
+	wh.store.mu.RLock()
+	defer wh.store.mu.RUnlock()
+
+	webhook, ok := wh.store.webhooks[id]
+	if !ok {
+		return entities.Webhook{}, fmt.Errorf("webhook not found")
+	}
+	webhook.Events = slices.Clone(webhook.Events)
+	return webhook, nil
+}
+
+func (wh *WebhooksRepository) ListWebhooks(ctx context.Context, userID int) ([]entities.Webhook, error) {
+	wh.store.mu.RLock()
+	defer wh.store.mu.RUnlock()
+
+	webhooks := []entities.Webhook{}
+	for _, webhook := range wh.store.webhooks {
+		if webhook.UserID != userID {
+			continue
+		}
+		webhook.Events = slices.Clone(webhook.Events)
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+func (wh *WebhooksRepository) DeleteWebhook(ctx context.Context, id int) error {
+	wh.store.mu.Lock()
+	defer wh.store.mu.Unlock()
+
+	wh.store.deleteWebhook(id)
+	return nil
+}
+
+// deleteWebhook drops a webhook with its deliveries. The caller must hold
+// the lock.
+func (s *Store) deleteWebhook(id int) {
+	delete(s.webhooks, id)
+	for deliveryID, delivery := range s.deliveries {
+		if delivery.WebhookID == id {
+			delete(s.deliveries, deliveryID)
+			delete(s.attempts, deliveryID)
+		}
+	}
+}
+
+func (wh *WebhooksRepository) EnqueueEvent(ctx context.Context, event string, payload []byte) error {
+	wh.store.mu.Lock()
+	defer wh.store.mu.Unlock()
+
+	ids := make([]int, 0, len(wh.store.webhooks))
+	for id, webhook := range wh.store.webhooks {
+		if slices.Contains(webhook.Events, event) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	created := now()
+	for _, id := range ids {
+		wh.store.lastDeliveryID++
+		wh.store.deliveries[wh.store.lastDeliveryID] = entities.WebhookDelivery{
+			ID:            wh.store.lastDeliveryID,
+			WebhookID:     id,
+			Event:         event,
+			Payload:       slices.Clone(payload),
+			Status:        entities.DeliveryPending,
+			NextAttemptAt: created,
+			CreatedAt:     created,
+		}
+	}
+	return nil
+}
+
+func (wh *WebhooksRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entities.WebhookDelivery, error) {
+	wh.store.mu.Lock()
+	defer wh.store.mu.Unlock()
+
+	due := []entities.WebhookDelivery{}
+	for _, delivery := range wh.store.deliveries {
+		if delivery.Status == entities.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	due = page(due, limit, 0)
+
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease).Truncate(time.Microsecond)
+		wh.store.deliveries[due[i].ID] = due[i]
+		due[i].Payload = slices.Clone(due[i].Payload)
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	return due, nil
+}
+
+func (wh *WebhooksRepository) RecordAttempt(ctx context.Context, delivery *entities.WebhookDelivery, attempt *entities.WebhookAttempt) error {
+	wh.store.mu.Lock()
+	defer wh.store.mu.Unlock()
+
+	stored, ok := wh.store.deliveries[delivery.ID]
+	if !ok {
+		return fmt.Errorf("delivery %d does not exist", delivery.ID)
+	}
+
+	wh.store.lastAttemptID++
+	attempt.ID = wh.store.lastAttemptID
+	attempt.DeliveryID = delivery.ID
+	attempt.CreatedAt = now()
+	logged := *attempt
+	logged.Duration = attempt.Duration.Truncate(time.Millisecond)
+	wh.store.attempts[delivery.ID] = append(wh.store.attempts[delivery.ID], logged)
+
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.NextAttemptAt = delivery.NextAttemptAt.Truncate(time.Microsecond)
+	stored.LastError = delivery.LastError
+	stored.DeliveredAt = nil
+	if delivery.DeliveredAt != nil {
+		delivered := delivery.DeliveredAt.Truncate(time.Microsecond)
+		stored.DeliveredAt = &delivered
+	}
+	wh.store.deliveries[delivery.ID] = stored
+	return nil
+}
+
+func (wh *WebhooksRepository) FindDelivery(ctx context.Context, id int) (entities.WebhookDelivery, error) {
+	wh.store.mu.RLock()
+	defer wh.store.mu.RUnlock()
+
+	delivery, ok := wh.store.deliveries[id]
+	if !ok {
+		return entities.WebhookDelivery{}, fmt.Errorf("delivery not found")
+	}
+	delivery.Payload = slices.Clone(delivery.Payload)
+	return delivery, nil
+}
+
+func (wh *WebhooksRepository) ListDeliveries(ctx context.Context, webhookID, limit, offset int) ([]entities.WebhookDelivery, error) {
+	wh.store.mu.RLock()
+	defer wh.store.mu.RUnlock()
+
+	deliveries := []entities.WebhookDelivery{}
+	for _, delivery := range wh.store.deliveries {
+		if delivery.WebhookID == webhookID {
+			delivery.Payload = slices.Clone(delivery.Payload)
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	return page(deliveries, limit, offset), nil
+}
+
+func (wh *WebhooksRepository) ListAttempts(ctx context.Context, deliveryID int) ([]entities.WebhookAttempt, error) {
+	wh.store.mu.RLock()
+	defer wh.store.mu.RUnlock()
+
+	return append([]entities.WebhookAttempt{}, wh.store.attempts[deliveryID]...), nil
+}
+
+func (wh *WebhooksRepository) RetryDelivery(ctx context.Context, id int) error {
+	wh.store.mu.Lock()
+	defer wh.store.mu.Unlock()
+
+	delivery, ok := wh.store.deliveries[id]
+	if !ok || delivery.Status != entities.DeliveryDead {
+		return fmt.Errorf("no dead delivery %d", id)
+	}
+	delivery.Status = entities.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now()
+	wh.store.deliveries[id] = delivery
+	return nil
+}
@@ -3,18 +3,17 @@ package newscategoriesrepo
 import (
 	"context"
 	"log/slog"
+	"news-service/internal/database"
 	"news-service/internal/entities"
 	errMsg "news-service/internal/err"
-
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type NewsCategoriesRepository struct {
-	db  *pgxpool.Pool
+	db  database.DBTX
 	log *slog.Logger
 }
 
-func NewNewsCategoriesRepository(db *pgxpool.Pool, log *slog.Logger) *NewsCategoriesRepository {
+func NewNewsCategoriesRepository(db database.DBTX, log *slog.Logger) *NewsCategoriesRepository {
 	return &NewsCategoriesRepository{db, log}
 }
 
@@ -43,6 +42,36 @@ func (n *NewsCategoriesRepository) ListCategories(ctx context.Context, id int) (
 	return arrayId, nil
 }
 
+func (n *NewsCategoriesRepository) ListCategoriesByNews(ctx context.Context, newsIDs []int) (map[int][]int, error) {
+	rows, err := n.db.Query(ctx, `
+	SELECT nc.news_id, array_agg(c.name ORDER BY c.name)
+	FROM NewsCategories nc
+	JOIN Categories c ON c.id = nc.category_id
+	WHERE nc.news_id = ANY($1)
+	GROUP BY nc.news_id`, newsIDs)
+	if err != nil {
+		n.log.Error("failed to list categories", errMsg.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make(map[int][]int, len(newsIDs))
+	for rows.Next() {
+		var newsID int
+		var names []int
+		if err := rows.Scan(&newsID, &names); err != nil {
+			n.log.Error("failed to scan categories", errMsg.Err(err))
+			return nil, err
+		}
+		categories[newsID] = names
+	}
+	if err := rows.Err(); err != nil {
+		n.log.Error("failed to list categories", errMsg.Err(err))
+		return nil, err
+	}
+	return categories, nil
+}
+
 func (n *NewsCategoriesRepository) UpdateNewsCategories(ctx context.Context, categoryID, newsID int) error {
 
 	_, err := n.db.Exec(ctx, `INSERT INTO NewsCategories (news_id, category_id) VALUES ($1, $2)`, newsID, categoryID)
@@ -0,0 +1,74 @@
+package newscategoriesrepo
+
+import (
+	"context"
+	categoriesrepo "news-service/internal/database/categoriesRepo"
+	"news-service/internal/database/dbtest"
+	newsrepo "news-service/internal/database/newsRepo"
+	"news-service/internal/entities"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestNewsCategories(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	news := newsrepo.NewNewsRepository(pg.Db, dbtest.Logger())
+	categories := categoriesrepo.NewCategoriesRepository(pg.Db, dbtest.Logger())
+	repo := NewNewsCategoriesRepository(pg.Db, dbtest.Logger())
+
+	item := entities.News{Title: "title", Content: "content"}
+	if err := news.CreateNews(ctx, &item); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+
+	empty, err := repo.ListCategories(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("ListCategories without categories: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Fatalf("expected no categories, got %v", empty)
+	}
+
+	ids := map[int]int{}
+	for _, name := range []int{10, 20, 30} {
+		c := entities.Categorie{Name: name}
+		if err := categories.CreateCategorie(ctx, &c); err != nil {
+			t.Fatalf("CreateCategorie: %v", err)
+		}
+		ids[name] = c.ID
+	}
+
+	if err := repo.Create(ctx, &entities.NewsCategories{NewsID: item.ID, CategoryID: ids[10]}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.UpdateNewsCategories(ctx, ids[20], item.ID); err != nil {
+		t.Fatalf("UpdateNewsCategories: %v", err)
+	}
+	if err := repo.Create(ctx, &entities.NewsCategories{NewsID: item.ID, CategoryID: ids[10]}); err == nil {
+		t.Fatal("expected an error for a duplicate relation")
+	}
+
+	names, err := repo.ListCategories(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("ListCategories: %v", err)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []int{10, 20}) {
+		t.Fatalf("unexpected categories: %v", names)
+	}
+
+	if err := repo.DeleteCategories(ctx, item.ID); err != nil {
+		t.Fatalf("DeleteCategories: %v", err)
+	}
+	names, err = repo.ListCategories(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("ListCategories after delete: %v", err)
+	}
+	if len(names) != 0 {
+		t.Fatalf("expected no categories after delete, got %v", names)
+	}
+}
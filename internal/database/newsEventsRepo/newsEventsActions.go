@@ -0,0 +1,99 @@
+package newseventsrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/database"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Channel is the NOTIFY channel announcing new events, the payload is the
+// event id.
+const Channel = "news_events"
+
+// lockKey is the advisory lock serializing Insert. Without it a transaction
+// could commit an event after another one with a higher id, and a reader
+// that already went past that id would never see it.
+const lockKey = 7310045
+
+type NewsEventsRepository struct {
+	pool *pgxpool.Pool
+	log  *slog.Logger
+}
+
+// NewNewsEventsRepository takes a pool rather than a DBTX, Listen holds a
+// connection of its own.
+func NewNewsEventsRepository(pool *pgxpool.Pool, log *slog.Logger) *NewsEventsRepository {
+	return &NewsEventsRepository{pool: pool, log: log}
+}
+
+// Insert saves an event through db, the transaction of the change, and
+// notifies the listeners when it commits. It should be the last statement
+// of the transaction, the lock it takes is held until the end.
+func Insert(ctx context.Context, db database.DBTX, newsID int, kind string) error {
+	_, err := db.Exec(ctx, `
+	WITH locked AS (SELECT pg_advisory_xact_lock($3)),
+	event AS (INSERT INTO NewsEvents (news_id, kind) SELECT $1, $2 FROM locked RETURNING id)
+	SELECT pg_notify('`+Channel+`', id::text) FROM event`, newsID, kind, lockKey)
+	return err
+}
+
+func (n *NewsEventsRepository) ListNewsEvents(ctx context.Context, after, limit int) ([]entities.NewsEvent, error) {
+	rows, err := n.pool.Query(ctx, `SELECT id, news_id, kind, created_at FROM NewsEvents
+	WHERE id > $1 ORDER BY id LIMIT $2`, after, limit)
+	if err != nil {
+		n.log.Error("failed to list news events", errMsg.Err(err))
+		return nil, err
+	}
+	events, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entities.NewsEvent])
+	if err != nil {
+		n.log.Error("failed to scan news events", errMsg.Err(err))
+		return nil, err
+	}
+	return events, nil
+}
+
+func (n *NewsEventsRepository) LastNewsEventID(ctx context.Context) (int, error) {
+	var id int
+	if err := n.pool.QueryRow(ctx, `SELECT COALESCE(max(id), 0) FROM NewsEvents`).Scan(&id); err != nil {
+		n.log.Error("failed to find the last news event", errMsg.Err(err))
+		return 0, err
+	}
+	return id, nil
+}
+
+func (n *NewsEventsRepository) DeleteNewsEvents(ctx context.Context, before time.Time) (int, error) {
+	tag, err := n.pool.Exec(ctx, `DELETE FROM NewsEvents WHERE created_at < $1`, before)
+	if err != nil {
+		n.log.Error("failed to delete news events", errMsg.Err(err))
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Listen takes a connection out of the pool for good, it is closed rather
+// than returned so that no pooled connection keeps listening.
+func (n *NewsEventsRepository) Listen(ctx context.Context, notify func()) error {
+	pooled, err := n.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, `LISTEN `+Channel); err != nil {
+		return err
+	}
+	notify()
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		notify()
+	}
+}
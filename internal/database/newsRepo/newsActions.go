@@ -2,34 +2,222 @@ package newsrepo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"news-service/internal/database"
+	newseventsrepo "news-service/internal/database/newsEventsRepo"
+	outboxrepo "news-service/internal/database/outboxRepo"
 	"news-service/internal/entities"
 	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"news-service/internal/outbox"
+	"news-service/internal/related"
+	"news-service/internal/slug"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 )
 
 type NewsRepository struct {
-	db  *pgxpool.Pool
-	log *slog.Logger
+	db     database.DBTX
+	reader database.DBTX
+	log    *slog.Logger
 }
 
-func NewNewsRepository(db *pgxpool.Pool, log *slog.Logger) *NewsRepository {
-	return &NewsRepository{db: db, log: log}
+type Option func(*NewsRepository)
+
+// WithReader routes the read-only queries to a read replica.
+func WithReader(reader database.DBTX) Option {
+	return func(n *NewsRepository) {
+		n.reader = reader
+	}
+}
+
+func NewNewsRepository(db database.DBTX, log *slog.Logger, opts ...Option) *NewsRepository {
+	n := &NewsRepository{db: db, reader: db, log: log}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// readerFor returns the pool for the read-only queries of ctx, the primary
+// when models.ReadFromPrimary asks for it.
+func (n *NewsRepository) readerFor(ctx context.Context) database.DBTX {
+	if models.ReadsFromPrimary(ctx) {
+		return n.db
+	}
+	return n.reader
+}
+
+// newsColumns is the column list scanned by scanNews.
+const newsColumns = `id, title, slug, summary, content, content_format, content_html, published, created_at, updated_at`
+
+func scanNews(row pgx.Row, news *entities.News) error {
+	return row.Scan(&news.ID, &news.Title, &news.Slug, &news.Summary, &news.Content, &news.ContentFormat,
+		&news.ContentHTML, &news.Published, &news.CreatedAt, &news.UpdatedAt)
 }
 
+// defaultContentFormat treats news without a declared format as plain text.
+func defaultContentFormat(news *entities.News) {
+	if news.ContentFormat == "" {
+		news.ContentFormat = entities.DefaultContentFormat
+	}
+}
+
+// maxSlugAttempts bounds retries when a concurrent insert takes the slug
+// picked by uniqueSlug.
+const maxSlugAttempts = 3
+
+// CreateNews derives the slug from the title unless one is set and appends
+// a numeric suffix when it is already taken. A published news is saved
+// with its news.published outbox message, every news with a news event for
+// the stream.
 func (n *NewsRepository) CreateNews(ctx context.Context, news *entities.News) error {
-	err := n.db.QueryRow(ctx, `INSERT INTO News (content, title) VALUES ($1, $2) RETURNING id`, news.Content, news.Title).Scan(&news.ID)
+	defaultContentFormat(news)
+	base := news.Slug
+	if base == "" {
+		base = slug.Make(news.Title)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := n.createNews(ctx, news, base)
+		if database.IsUniqueViolation(err) && attempt < maxSlugAttempts {
+			continue
+		}
+		if err != nil {
+			n.log.Error("failed to create news", errMsg.Err(err))
+			return err
+		}
+		return nil
+	}
+}
+
+func (n *NewsRepository) createNews(ctx context.Context, news *entities.News, base string) error {
+	tx, err := n.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	candidate, err := uniqueSlug(ctx, tx, base, 0)
+	if err != nil {
+		return fmt.Errorf("failed to pick news slug: %w", err)
+	}
+
+	created := *news
+	created.Slug = candidate
+	err = tx.QueryRow(ctx, `INSERT INTO News (content, title, slug, published, summary, content_format, content_html)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, created_at, updated_at`,
+		created.Content, created.Title, created.Slug, created.Published, created.Summary, created.ContentFormat, created.ContentHTML,
+	).Scan(&created.ID, &created.CreatedAt, &created.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if created.Published {
+		if err := insertPublished(ctx, tx, created); err != nil {
+			return err
+		}
+	}
+	if err := newseventsrepo.Insert(ctx, tx, created.ID, entities.EventNewsCreated); err != nil {
+		return fmt.Errorf("failed to save news event: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	*news = created
+	return nil
+}
+
+// insertPublished saves the news.published message of news through tx.
+func insertPublished(ctx context.Context, tx database.DBTX, news entities.News) error {
+	msg, err := outbox.NewsPublished(news)
 	if err != nil {
-		n.log.Error("failed to create news", errMsg.Err(err))
 		return err
 	}
+	if err := outboxrepo.Insert(ctx, tx, &msg); err != nil {
+		return fmt.Errorf("failed to save outbox message: %w", err)
+	}
 	return nil
 }
 
+// uniqueSlug returns base or base-N, whichever is the first one not used
+// by another news, currently or as a redirect. newsID is the news being
+// renamed, its own old slugs may be reused.
+func uniqueSlug(ctx context.Context, db database.DBTX, base string, newsID int) (string, error) {
+	rows, err := db.Query(ctx, `
+	SELECT slug FROM News WHERE (slug = $1 OR slug LIKE $1 || '-%') AND id <> $2
+	UNION
+	SELECT slug FROM NewsSlugRedirects WHERE (slug = $1 OR slug LIKE $1 || '-%') AND news_id <> $2`, base, newsID)
+	if err != nil {
+		return "", err
+	}
+	taken, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return "", err
+	}
+	return slug.Next(base, taken), nil
+}
+
 func (n *NewsRepository) ListNews(ctx context.Context) ([]entities.News, error) {
-	query, err := n.db.Query(ctx, `SELECT id, title, content FROM News ORDER BY id DESC`)
+	return n.queryNews(ctx, `SELECT `+newsColumns+` FROM News ORDER BY id DESC`)
+}
+
+func (n *NewsRepository) ListPublishedNews(ctx context.Context, limit, offset int) ([]entities.News, error) {
+	return n.queryNews(ctx, `SELECT `+newsColumns+` FROM News WHERE published
+	ORDER BY id DESC LIMIT $1 OFFSET $2`, limit, offset)
+}
+
+func (n *NewsRepository) ListPublishedNewsByCategory(ctx context.Context, category, limit, offset int) ([]entities.News, error) {
+	return n.queryNews(ctx, `SELECT `+newsColumns+` FROM News WHERE published AND id IN (
+		SELECT nc.news_id
+		FROM NewsCategories nc
+		JOIN Categories c ON c.id = nc.category_id
+		WHERE c.name = $1)
+	ORDER BY id DESC LIMIT $2 OFFSET $3`, category, limit, offset)
+}
+
+// ListRelatedNews ranks the other published news by related.Score. Text
+// similarity is the Jaccard index of the "simple" text search lexemes of
+// the titles and summaries. News sharing nothing with newsID are skipped.
+func (n *NewsRepository) ListRelatedNews(ctx context.Context, newsID int, opts related.Options) ([]entities.News, error) {
+	halfLife := opts.HalfLife.Seconds()
+	if halfLife <= 0 {
+		halfLife = math.Inf(1)
+	}
+	return n.queryNews(ctx, `
+	WITH src AS (
+		SELECT id, tsvector_to_array(to_tsvector('simple', title || ' ' || summary)) AS terms
+		FROM News WHERE id = $1
+	), candidates AS (
+		SELECT n.id,
+			(SELECT count(*) FROM NewsCategories a JOIN NewsCategories b ON b.category_id = a.category_id
+			WHERE a.news_id = src.id AND b.news_id = n.id) AS categories,
+			(SELECT count(*) FROM NewsTags a JOIN NewsTags b ON b.tag_id = a.tag_id
+			WHERE a.news_id = src.id AND b.news_id = n.id) AS tags,
+			tsvector_to_array(to_tsvector('simple', n.title || ' ' || n.summary)) AS terms,
+			src.terms AS src_terms,
+			greatest(extract(epoch FROM now() - n.created_at), 0) AS age
+		FROM News n, src
+		WHERE n.published AND n.id <> src.id
+	), scored AS (
+		SELECT id, ($2::float8 * categories + $3::float8 * tags + $4::float8 * coalesce(
+			cardinality(ARRAY(SELECT unnest(terms) INTERSECT SELECT unnest(src_terms)))::float8 /
+			nullif(cardinality(ARRAY(SELECT unnest(terms) UNION SELECT unnest(src_terms))), 0), 0)
+		) * power(0.5, age::float8 / $5::float8) AS score
+		FROM candidates
+	)
+	SELECT `+newsColumns+` FROM News JOIN scored USING (id)
+	WHERE score > 0
+	ORDER BY score DESC, id DESC LIMIT $6`,
+		newsID, related.CategoryWeight, related.TagWeight, related.TextWeight, halfLife, opts.Limit)
+}
+
+func (n *NewsRepository) queryNews(ctx context.Context, sql string, args ...any) ([]entities.News, error) {
+	query, err := n.readerFor(ctx).Query(ctx, sql, args...)
 	if err != nil {
 		n.log.Error("Error querying news", errMsg.Err(err))
 		return nil, err
@@ -39,7 +227,7 @@ func (n *NewsRepository) ListNews(ctx context.Context) ([]entities.News, error)
 	var newsArray []entities.News
 	for query.Next() {
 		var news entities.News
-		err := query.Scan(&news.ID, &news.Title, &news.Content)
+		err := scanNews(query, &news)
 		if err != nil {
 			n.log.Error("Error scanning news", errMsg.Err(err))
 			return nil, err
@@ -56,19 +244,84 @@ func (n *NewsRepository) ListNews(ctx context.Context) ([]entities.News, error)
 
 }
 
+// UpdateNews keeps the current slug when news.Slug is empty. A new slug is
+// made unique like in CreateNews and the old one is kept as a redirect.
+// Publishing a draft saves a news.published outbox message; every update
+// saves a news event for the stream.
 func (n *NewsRepository) UpdateNews(ctx context.Context, news *entities.News) error {
-	_, err := n.db.Exec(ctx, `UPDATE News SET content = $1, title = $2 WHERE id = $3`, news.Content, news.Title, news.ID)
+	defaultContentFormat(news)
+	tx, err := n.db.Begin(ctx)
+	if err != nil {
+		n.log.Error("failed to begin transaction", errMsg.Err(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		oldSlug      string
+		wasPublished bool
+	)
+	err = tx.QueryRow(ctx, `SELECT slug, published FROM News WHERE id = $1 FOR UPDATE`, news.ID).Scan(&oldSlug, &wasPublished)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		n.log.Error("failed to find news", errMsg.Err(err))
+		return err
+	}
+
+	if news.Slug == "" {
+		news.Slug = oldSlug
+	}
+	if news.Slug != oldSlug {
+		news.Slug, err = uniqueSlug(ctx, tx, news.Slug, news.ID)
+		if err != nil {
+			n.log.Error("failed to pick news slug", errMsg.Err(err))
+			return err
+		}
+	}
+
+	if news.Slug != oldSlug {
+		_, err = tx.Exec(ctx, `
+		WITH reclaimed AS (DELETE FROM NewsSlugRedirects WHERE slug = $1 AND news_id = $3)
+		INSERT INTO NewsSlugRedirects (slug, news_id) VALUES ($2, $3)
+		ON CONFLICT (slug) DO UPDATE SET news_id = EXCLUDED.news_id`, news.Slug, oldSlug, news.ID)
+		if err != nil {
+			n.log.Error("failed to keep old news slug", errMsg.Err(err))
+			return err
+		}
+	}
+
+	err = tx.QueryRow(ctx, `UPDATE News SET content = $1, title = $2, published = $3, slug = $4,
+	summary = $5, content_format = $6, content_html = $7, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $8 RETURNING created_at, updated_at`,
+		news.Content, news.Title, news.Published, news.Slug, news.Summary, news.ContentFormat, news.ContentHTML, news.ID,
+	).Scan(&news.CreatedAt, &news.UpdatedAt)
 	if err != nil {
 		n.log.Error("failed to update news", errMsg.Err(err))
 		return err
 	}
+	if news.Published && !wasPublished {
+		if err := insertPublished(ctx, tx, *news); err != nil {
+			n.log.Error("failed to publish news", errMsg.Err(err))
+			return err
+		}
+	}
+	if err := newseventsrepo.Insert(ctx, tx, news.ID, entities.EventNewsUpdated); err != nil {
+		n.log.Error("failed to save news event", errMsg.Err(err))
+		return err
+	}
 
+	if err := tx.Commit(ctx); err != nil {
+		n.log.Error("failed to commit news update", errMsg.Err(err))
+		return err
+	}
 	return nil
 
 }
 
 func (n *NewsRepository) FindNewsByID(ctx context.Context, id int) (entities.News, error) {
-	query, err := n.db.Query(ctx, `SELECT content, title FROM News WHERE id = $1`, id)
+	query, err := n.readerFor(ctx).Query(ctx, `SELECT `+newsColumns+` FROM News WHERE id = $1`, id)
 	if err != nil {
 		n.log.Error("error querying news", errMsg.Err(err))
 		return entities.News{}, err
@@ -76,10 +329,9 @@ func (n *NewsRepository) FindNewsByID(ctx context.Context, id int) (entities.New
 	defer query.Close()
 	row := entities.News{}
 	if !query.Next() {
-		n.log.Error("news not found")
-		return entities.News{}, fmt.Errorf("news not found")
+		return entities.News{}, models.ErrNewsNotFound
 	} else {
-		err := query.Scan(&row.Content, &row.Title)
+		err := scanNews(query, &row)
 		if err != nil {
 			n.log.Error("error scanning news", errMsg.Err(err))
 			return entities.News{}, err
@@ -87,3 +339,20 @@ func (n *NewsRepository) FindNewsByID(ctx context.Context, id int) (entities.New
 	}
 	return row, nil
 }
+
+func (n *NewsRepository) FindNewsBySlug(ctx context.Context, newsSlug string) (entities.News, error) {
+	var news entities.News
+	err := scanNews(n.readerFor(ctx).QueryRow(ctx, `SELECT `+newsColumns+` FROM News WHERE id = (
+		SELECT id FROM News WHERE slug = $1
+		UNION ALL
+		SELECT news_id FROM NewsSlugRedirects WHERE slug = $1
+		LIMIT 1)`, newsSlug), &news)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entities.News{}, models.ErrNewsNotFound
+	}
+	if err != nil {
+		n.log.Error("error querying news by slug", errMsg.Err(err))
+		return entities.News{}, err
+	}
+	return news, nil
+}
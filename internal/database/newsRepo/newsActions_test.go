@@ -0,0 +1,114 @@
+package newsrepo
+
+import (
+	"context"
+	"errors"
+	"news-service/internal/database/dbtest"
+	"news-service/internal/entities"
+	"news-service/internal/models"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestCreateAndFindNews(t *testing.T) {
+	ctx := context.Background()
+	repo := NewNewsRepository(dbtest.New(t).Db, dbtest.Logger())
+
+	news := entities.News{Title: "title", Content: "content"}
+	if err := repo.CreateNews(ctx, &news); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	if news.ID == 0 {
+		t.Fatal("CreateNews did not set the id")
+	}
+
+	found, err := repo.FindNewsByID(ctx, news.ID)
+	if err != nil {
+		t.Fatalf("FindNewsByID: %v", err)
+	}
+	if found.Title != "title" || found.Content != "content" {
+		t.Fatalf("unexpected news: %+v", found)
+	}
+
+	if _, err := repo.FindNewsByID(ctx, news.ID+1); err == nil {
+		t.Fatal("expected an error for a missing news")
+	}
+}
+
+func TestListNewsNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	repo := NewNewsRepository(dbtest.New(t).Db, dbtest.Logger())
+
+	list, err := repo.ListNews(ctx)
+	if err != nil {
+		t.Fatalf("ListNews on empty table: %v", err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("expected no news, got %d", len(list))
+	}
+
+	for _, title := range []string{"first", "second", "third"} {
+		if err := repo.CreateNews(ctx, &entities.News{Title: title, Content: title}); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+	}
+
+	list, err = repo.ListNews(ctx)
+	if err != nil {
+		t.Fatalf("ListNews: %v", err)
+	}
+	if len(list) != 3 || list[0].Title != "third" || list[2].Title != "first" {
+		t.Fatalf("unexpected order: %+v", list)
+	}
+}
+
+func TestUpdateNews(t *testing.T) {
+	ctx := context.Background()
+	repo := NewNewsRepository(dbtest.New(t).Db, dbtest.Logger())
+
+	news := entities.News{Title: "old", Content: "old"}
+	if err := repo.CreateNews(ctx, &news); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+
+	news.Title, news.Content = "new", "new content"
+	if err := repo.UpdateNews(ctx, &news); err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+
+	found, err := repo.FindNewsByID(ctx, news.ID)
+	if err != nil {
+		t.Fatalf("FindNewsByID: %v", err)
+	}
+	if found.Title != "new" || found.Content != "new content" {
+		t.Fatalf("update was not applied: %+v", found)
+	}
+}
+
+func TestWithReaderRoutesReads(t *testing.T) {
+	ctx := context.Background()
+	primary := dbtest.New(t)
+	replica := dbtest.New(t)
+	repo := NewNewsRepository(primary.Db, dbtest.Logger(), WithReader(replica.Db))
+
+	news := entities.News{Title: "title", Content: "content"}
+	if err := repo.CreateNews(ctx, &news); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+
+	list, err := repo.ListNews(ctx)
+	if err != nil {
+		t.Fatalf("ListNews: %v", err)
+	}
+	if len(list) != 0 {
+		t.Fatal("ListNews should read from the replica")
+	}
+	if _, err := repo.FindNewsByID(ctx, news.ID); !errors.Is(err, models.ErrNewsNotFound) {
+		t.Fatalf("FindNewsByID = %v, should read from the replica", err)
+	}
+	if _, err := repo.FindNewsByID(models.ReadFromPrimary(ctx), news.ID); err != nil {
+		t.Fatalf("FindNewsByID should read from the primary when asked: %v", err)
+	}
+}
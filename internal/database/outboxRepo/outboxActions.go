@@ -0,0 +1,93 @@
+package outboxrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/database"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type OutboxRepository struct {
+	db  database.DBTX
+	log *slog.Logger
+}
+
+func NewOutboxRepository(db database.DBTX, log *slog.Logger) *OutboxRepository {
+	return &OutboxRepository{db, log}
+}
+
+// Insert saves msg through db, which is the transaction of the change the
+// message describes.
+func Insert(ctx context.Context, db database.DBTX, msg *entities.OutboxMessage) error {
+	return db.QueryRow(ctx, `INSERT INTO Outbox (idempotency_key, topic, payload) VALUES ($1, $2, $3)
+	RETURNING id, next_attempt_at, created_at`, msg.Key, msg.Topic, msg.Payload,
+	).Scan(&msg.ID, &msg.NextAttemptAt, &msg.CreatedAt)
+}
+
+// ClaimOutbox skips rows locked by a concurrent claim instead of waiting
+// for them.
+func (o *OutboxRepository) ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entities.OutboxMessage, error) {
+	rows, err := o.db.Query(ctx, `
+	UPDATE Outbox m SET next_attempt_at = $2
+	FROM (
+		SELECT id FROM Outbox
+		WHERE published_at IS NULL AND next_attempt_at <= $1
+		ORDER BY id
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	) due
+	WHERE m.id = due.id
+	RETURNING m.id, m.idempotency_key, m.topic, m.payload, m.attempts, m.next_attempt_at, m.last_error, m.created_at, m.published_at`,
+		now, now.Add(lease), limit)
+	if err != nil {
+		o.log.Error("failed to claim outbox messages", errMsg.Err(err))
+		return nil, err
+	}
+	messages, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.OutboxMessage, error) {
+		var msg entities.OutboxMessage
+		err := row.Scan(&msg.ID, &msg.Key, &msg.Topic, &msg.Payload, &msg.Attempts, &msg.NextAttemptAt,
+			&msg.LastError, &msg.CreatedAt, &msg.PublishedAt)
+		return msg, err
+	})
+	if err != nil {
+		o.log.Error("failed to scan outbox messages", errMsg.Err(err))
+		return nil, err
+	}
+	// RETURNING does not keep the order of the subquery.
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+func (o *OutboxRepository) MarkPublished(ctx context.Context, id int) error {
+	_, err := o.db.Exec(ctx, `UPDATE Outbox SET published_at = CURRENT_TIMESTAMP, attempts = attempts + 1, last_error = ''
+	WHERE id = $1 AND published_at IS NULL`, id)
+	if err != nil {
+		o.log.Error("failed to mark outbox message published", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (o *OutboxRepository) MarkFailed(ctx context.Context, id int, lastError string, next time.Time) error {
+	_, err := o.db.Exec(ctx, `UPDATE Outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+	WHERE id = $1 AND published_at IS NULL`, id, lastError, next)
+	if err != nil {
+		o.log.Error("failed to record outbox failure", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (o *OutboxRepository) PurgePublished(ctx context.Context, before time.Time) (int, error) {
+	tag, err := o.db.Exec(ctx, `DELETE FROM Outbox WHERE published_at < $1`, before)
+	if err != nil {
+		o.log.Error("failed to purge outbox", errMsg.Err(err))
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
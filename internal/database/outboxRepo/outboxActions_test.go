@@ -0,0 +1,42 @@
+package outboxrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	"news-service/internal/entities"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestInsertFollowsTransaction(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	repo := NewOutboxRepository(pg.Db, dbtest.Logger())
+
+	for i, commit := range []bool{false, true} {
+		tx, err := pg.Db.Begin(ctx)
+		if err != nil {
+			t.Fatalf("Begin: %v", err)
+		}
+		msg := entities.OutboxMessage{Key: []string{"rolled-back", "committed"}[i], Topic: entities.EventNewsPublished, Payload: []byte(`{}`)}
+		if err := Insert(ctx, tx, &msg); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+		if commit {
+			err = tx.Commit(ctx)
+		} else {
+			err = tx.Rollback(ctx)
+		}
+		if err != nil {
+			t.Fatalf("end transaction: %v", err)
+		}
+	}
+
+	claimed, err := repo.ClaimOutbox(ctx, time.Now().Add(time.Minute), time.Minute, 10)
+	if err != nil || len(claimed) != 1 || claimed[0].Key != "committed" {
+		t.Fatalf("ClaimOutbox = %+v, %v, want only the committed message", claimed, err)
+	}
+}
@@ -2,53 +2,113 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"news-service/internal/config"
-	"sync"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// DBTX is the subset of pgx used by the repositories. It is satisfied by
+// *pgxpool.Pool, *pgx.Conn and pgx.Tx; Begin on a pgx.Tx starts a savepoint.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// IsUniqueViolation reports whether err was caused by a UNIQUE constraint.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 type Postgres struct {
-	Db     *pgxpool.Pool
-	log    *slog.Logger
-	Config *config.Config
+	Db      *pgxpool.Pool
+	Replica *pgxpool.Pool
+	log     *slog.Logger
+	Config  *config.Config
 }
 
-var (
-	pgInstance *Postgres
-	pgOnce     sync.Once
-)
+type options struct {
+	replicaConnString string
+	maxConns          int32
+	skipMigrations    bool
+}
+
+type Option func(*options)
+
+// WithReplica adds a read-only pool used by Reader.
+func WithReplica(connString string) Option {
+	return func(o *options) {
+		o.replicaConnString = connString
+	}
+}
+
+// WithMaxConns limits the size of the primary and replica pools.
+func WithMaxConns(n int32) Option {
+	return func(o *options) {
+		o.maxConns = n
+	}
+}
 
-func NewPG(ctx context.Context, connString string, log *slog.Logger, cfg *config.Config) (*Postgres, error) {
-	var err error
+// WithoutMigrations skips CreateTable on startup.
+func WithoutMigrations() Option {
+	return func(o *options) {
+		o.skipMigrations = true
+	}
+}
+
+func NewPG(ctx context.Context, connString string, log *slog.Logger, cfg *config.Config, opts ...Option) (*Postgres, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	db, err := newPool(ctx, connString, o)
+	if err != nil {
+		log.Error("unable to create connection pool", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
+	}
 
-	pgOnce.Do(func() {
-		var db *pgxpool.Pool
-		db, err = pgxpool.New(ctx, connString)
+	pg := &Postgres{Db: db, log: log, Config: cfg}
 
+	if o.replicaConnString != "" {
+		pg.Replica, err = newPool(ctx, o.replicaConnString, o)
 		if err != nil {
-			log.Error("unable to create connection pool", slog.String("error", err.Error()))
-			err = fmt.Errorf("unable to create connection pool: %w", err)
-			return
+			log.Error("unable to create replica connection pool", slog.String("error", err.Error()))
+			db.Close()
+			return nil, fmt.Errorf("unable to create replica connection pool: %w", err)
 		}
+	}
 
-		pgInstance = &Postgres{db, log, cfg}
-
+	if !o.skipMigrations {
 		if err = CreateTable(ctx, db, log, cfg); err != nil {
 			log.Error("failed to create tables", slog.String("error", err.Error()))
-			return
+			pg.Close()
+			return nil, err
 		}
-	})
+	}
 
+	return pg, nil
+}
+
+func newPool(ctx context.Context, connString string, o options) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, err
 	}
-	return pgInstance, nil
+	if o.maxConns > 0 {
+		poolCfg.MaxConns = o.maxConns
+	}
+	return pgxpool.NewWithConfig(ctx, poolCfg)
 }
 
-func CreateTable(ctx context.Context, db *pgxpool.Pool, log *slog.Logger, cfg *config.Config) error {
+func CreateTable(ctx context.Context, db DBTX, log *slog.Logger, cfg *config.Config) error {
 
 	_, err := db.Exec(ctx, `
 	CREATE TABLE IF NOT EXISTS News (
@@ -62,9 +122,53 @@ func CreateTable(ctx context.Context, db *pgxpool.Pool, log *slog.Logger, cfg *c
 		return fmt.Errorf("failed to create news table")
 	}
 
+	_, err = db.Exec(ctx, `
+	ALTER TABLE News ADD COLUMN IF NOT EXISTS published BOOLEAN NOT NULL DEFAULT TRUE;
+	CREATE INDEX IF NOT EXISTS news_published_idx ON News (id DESC) WHERE published`)
+	if err != nil {
+		log.Error("failed to add published column to news table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to add published column to news table: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	ALTER TABLE News ADD COLUMN IF NOT EXISTS slug TEXT;
+	UPDATE News SET slug = 'news-' || id WHERE slug IS NULL;
+	ALTER TABLE News ALTER COLUMN slug SET NOT NULL;
+	CREATE UNIQUE INDEX IF NOT EXISTS news_slug_idx ON News (slug);
+	CREATE TABLE IF NOT EXISTS NewsSlugRedirects (
+	slug TEXT PRIMARY KEY,
+	news_id INT NOT NULL REFERENCES News(id) ON DELETE CASCADE
+	)`)
+	if err != nil {
+		log.Error("failed to create news slugs", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create news slugs: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	UPDATE News SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+	ALTER TABLE News ALTER COLUMN created_at SET NOT NULL;
+	ALTER TABLE News ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+	UPDATE News SET updated_at = created_at WHERE updated_at IS NULL;
+	ALTER TABLE News ALTER COLUMN updated_at SET DEFAULT CURRENT_TIMESTAMP;
+	ALTER TABLE News ALTER COLUMN updated_at SET NOT NULL`)
+	if err != nil {
+		log.Error("failed to add timestamps to news table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to add timestamps to news table: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	ALTER TABLE News ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '';
+	ALTER TABLE News ADD COLUMN IF NOT EXISTS content_format TEXT NOT NULL DEFAULT 'plain'
+		CHECK (content_format IN ('plain', 'markdown', 'html'));
+	ALTER TABLE News ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT ''`)
+	if err != nil {
+		log.Error("failed to add rich content to news table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to add rich content to news table: %w", err)
+	}
+
 	_, err = db.Exec(ctx, `CREATE TABLE IF NOT EXISTS Categories (
 	id SERIAL PRIMARY KEY,
-	name INT NOT NULL UNIQUE 
+	name INT NOT NULL UNIQUE
 	)`)
 	if err != nil {
 		log.Error("failed to create categories table", slog.String("error", err.Error()))
@@ -76,31 +180,268 @@ func CreateTable(ctx context.Context, db *pgxpool.Pool, log *slog.Logger, cfg *c
     news_id INT REFERENCES News(id) ON DELETE CASCADE,
     category_id INT REFERENCES Categories(id) ON DELETE CASCADE,
     PRIMARY KEY (news_id, category_id)
-	)`)
+	);
+	CREATE INDEX IF NOT EXISTS news_categories_category_idx ON NewsCategories (category_id, news_id)`)
 	if err != nil {
 		log.Error("failed to create newsCategories table", slog.String("error", err.Error()))
 		return fmt.Errorf("failed to create newsCategories table")
 	}
 
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS Tags (
+	    id SERIAL PRIMARY KEY,
+	    name TEXT NOT NULL UNIQUE
+	);
+	CREATE INDEX IF NOT EXISTS tags_name_pattern_idx ON Tags (name text_pattern_ops);
+	CREATE TABLE IF NOT EXISTS NewsTags (
+	    news_id INT REFERENCES News(id) ON DELETE CASCADE,
+	    tag_id INT REFERENCES Tags(id) ON DELETE CASCADE,
+	    PRIMARY KEY (news_id, tag_id)
+	);
+	CREATE INDEX IF NOT EXISTS news_tags_tag_idx ON NewsTags (tag_id)`)
+	if err != nil {
+		log.Error("failed to create tags tables", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create tags tables: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS NewsViews (
+	    news_id INT REFERENCES News(id) ON DELETE CASCADE,
+	    bucket TIMESTAMPTZ NOT NULL,
+	    views INT NOT NULL,
+	    PRIMARY KEY (news_id, bucket)
+	);
+	CREATE INDEX IF NOT EXISTS news_views_bucket_idx ON NewsViews (bucket)`)
+	if err != nil {
+		log.Error("failed to create views table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create views table: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS NewsMedia (
+	    id SERIAL PRIMARY KEY,
+	    news_id INT NOT NULL REFERENCES News(id) ON DELETE CASCADE,
+	    key TEXT NOT NULL UNIQUE,
+	    url TEXT NOT NULL,
+	    thumbnail_key TEXT NOT NULL DEFAULT '',
+	    thumbnail_url TEXT NOT NULL DEFAULT '',
+	    file_name TEXT NOT NULL,
+	    content_type TEXT NOT NULL,
+	    size BIGINT NOT NULL,
+	    width INT NOT NULL DEFAULT 0,
+	    height INT NOT NULL DEFAULT 0,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS news_media_news_idx ON NewsMedia (news_id, id)`)
+	if err != nil {
+		log.Error("failed to create newsMedia table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create newsMedia table: %w", err)
+	}
+
 	_, err = db.Exec(ctx, `
 	CREATE TABLE IF NOT EXISTS Users (
-	    id SERIAL PRIMARY KEY, 
-	    email VARCHAR(100) UNIQUE NOT NULL, 
+	    id SERIAL PRIMARY KEY,
+	    email VARCHAR(100) UNIQUE NOT NULL,
 	    password VARCHAR(255) NOT NULL,
-	    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP)
-	`)
+	    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP);
+	-- Users signed up before email verification count as verified.
+	ALTER TABLE Users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT true;
+	ALTER TABLE Users ALTER COLUMN email_verified SET DEFAULT false;
+	ALTER TABLE Users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMPTZ`)
 	if err != nil {
 		log.Error("failed to create users table", slog.String("error", err.Error()))
 		return fmt.Errorf("failed to create users table: %w", err)
 	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS UserTokens (
+	    id SERIAL PRIMARY KEY,
+	    user_id INT NOT NULL REFERENCES Users(id) ON DELETE CASCADE,
+	    purpose TEXT NOT NULL,
+	    email VARCHAR(100) NOT NULL,
+	    hash BYTEA NOT NULL UNIQUE,
+	    expires_at TIMESTAMPTZ NOT NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	    used_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS user_tokens_user_idx ON UserTokens (user_id, purpose)`)
+	if err != nil {
+		log.Error("failed to create user tokens table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create user tokens table: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS Comments (
+	    id SERIAL PRIMARY KEY,
+	    news_id INT NOT NULL REFERENCES News(id) ON DELETE CASCADE,
+	    user_id INT NOT NULL REFERENCES Users(id) ON DELETE CASCADE,
+	    parent_id INT REFERENCES Comments(id) ON DELETE CASCADE,
+	    body TEXT NOT NULL,
+	    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	    deleted_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS comments_news_idx ON Comments (news_id, status, id);
+	CREATE INDEX IF NOT EXISTS comments_pending_idx ON Comments (id) WHERE status = 'pending' AND deleted_at IS NULL`)
+	if err != nil {
+		log.Error("failed to create comments table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create comments table: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS Reactions (
+	    news_id INT NOT NULL REFERENCES News(id) ON DELETE CASCADE,
+	    user_id INT NOT NULL REFERENCES Users(id) ON DELETE CASCADE,
+	    kind TEXT NOT NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	    PRIMARY KEY (news_id, user_id, kind)
+	)`)
+	if err != nil {
+		log.Error("failed to create reactions table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create reactions table: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS Bookmarks (
+	    user_id INT NOT NULL REFERENCES Users(id) ON DELETE CASCADE,
+	    news_id INT NOT NULL REFERENCES News(id) ON DELETE CASCADE,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	    PRIMARY KEY (user_id, news_id)
+	);
+	CREATE INDEX IF NOT EXISTS bookmarks_user_idx ON Bookmarks (user_id, created_at DESC, news_id DESC)`)
+	if err != nil {
+		log.Error("failed to create bookmarks table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create bookmarks table: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS Subscriptions (
+	    user_id INT NOT NULL REFERENCES Users(id) ON DELETE CASCADE,
+	    category_id INT NOT NULL REFERENCES Categories(id) ON DELETE CASCADE,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	    PRIMARY KEY (user_id, category_id)
+	)`)
+	if err != nil {
+		log.Error("failed to create subscriptions table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create subscriptions table: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS Webhooks (
+	    id SERIAL PRIMARY KEY,
+	    url TEXT NOT NULL,
+	    secret TEXT NOT NULL,
+	    events TEXT[] NOT NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS WebhookDeliveries (
+	    id SERIAL PRIMARY KEY,
+	    webhook_id INT NOT NULL REFERENCES Webhooks(id) ON DELETE CASCADE,
+	    event TEXT NOT NULL,
+	    payload BYTEA NOT NULL,
+	    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+	    attempts INT NOT NULL DEFAULT 0,
+	    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	    last_error TEXT NOT NULL DEFAULT '',
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	    delivered_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON WebhookDeliveries (next_attempt_at, id) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON WebhookDeliveries (webhook_id, id);
+	CREATE TABLE IF NOT EXISTS WebhookAttempts (
+	    id SERIAL PRIMARY KEY,
+	    delivery_id INT NOT NULL REFERENCES WebhookDeliveries(id) ON DELETE CASCADE,
+	    status_code INT NOT NULL DEFAULT 0,
+	    error TEXT NOT NULL DEFAULT '',
+	    duration_ms BIGINT NOT NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_idx ON WebhookAttempts (delivery_id, id);
+	ALTER TABLE Webhooks ADD COLUMN IF NOT EXISTS user_id INT REFERENCES Users(id) ON DELETE CASCADE;
+	CREATE INDEX IF NOT EXISTS webhooks_user_idx ON Webhooks (user_id)`)
+	if err != nil {
+		log.Error("failed to create webhook tables", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create webhook tables: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS Outbox (
+	    id BIGSERIAL PRIMARY KEY,
+	    idempotency_key TEXT NOT NULL UNIQUE,
+	    topic TEXT NOT NULL,
+	    payload BYTEA NOT NULL,
+	    attempts INT NOT NULL DEFAULT 0,
+	    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	    last_error TEXT NOT NULL DEFAULT '',
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	    published_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS outbox_due_idx ON Outbox (next_attempt_at, id) WHERE published_at IS NULL;
+	CREATE INDEX IF NOT EXISTS outbox_published_idx ON Outbox (published_at) WHERE published_at IS NOT NULL`)
+	if err != nil {
+		log.Error("failed to create outbox table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create outbox table: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS NewsEvents (
+	    id BIGSERIAL PRIMARY KEY,
+	    news_id INT NOT NULL,
+	    kind TEXT NOT NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS news_events_created_idx ON NewsEvents (created_at)`)
+	if err != nil {
+		log.Error("failed to create news events table", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create news events table: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS RateLimits (
+	    key TEXT PRIMARY KEY,
+	    tokens DOUBLE PRECISION NOT NULL,
+	    updated_at TIMESTAMPTZ NOT NULL,
+	    full_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS rate_limits_full_idx ON RateLimits (full_at);
+	CREATE TABLE IF NOT EXISTS RateLimitFailures (
+	    key TEXT PRIMARY KEY,
+	    count INT NOT NULL,
+	    last_failure TIMESTAMPTZ NOT NULL,
+	    expires_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS rate_limit_failures_expires_idx ON RateLimitFailures (expires_at)`)
+	if err != nil {
+		log.Error("failed to create rate limit tables", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create rate limit tables: %w", err)
+	}
 	return nil
 
 }
 
+// Reader returns the replica pool when one is configured and the primary
+// pool otherwise.
+func (pg *Postgres) Reader() DBTX {
+	if pg.Replica != nil {
+		return pg.Replica
+	}
+	return pg.Db
+}
+
 func (pg *Postgres) Ping(ctx context.Context) error {
-	return pg.Db.Ping(ctx)
+	if err := pg.Db.Ping(ctx); err != nil {
+		return err
+	}
+	if pg.Replica != nil {
+		return pg.Replica.Ping(ctx)
+	}
+	return nil
 }
 
 func (pg *Postgres) Close() {
+	if pg.Replica != nil {
+		pg.Replica.Close()
+	}
 	pg.Db.Close()
 }
@@ -0,0 +1,85 @@
+package database_test
+
+import (
+	"context"
+	"news-service/internal/config"
+	"news-service/internal/database"
+	"news-service/internal/database/dbtest"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestNewPGReturnsIndependentPools(t *testing.T) {
+	ctx := context.Background()
+	url := dbtest.URL(t)
+
+	first, err := database.NewPG(ctx, url, dbtest.Logger(), &config.Config{})
+	if err != nil {
+		t.Fatalf("first NewPG: %v", err)
+	}
+	second, err := database.NewPG(ctx, url, dbtest.Logger(), &config.Config{})
+	if err != nil {
+		t.Fatalf("second NewPG: %v", err)
+	}
+	defer second.Close()
+
+	if first.Db == second.Db {
+		t.Fatal("NewPG returned the same pool twice")
+	}
+
+	first.Close()
+	if err := second.Ping(ctx); err != nil {
+		t.Fatalf("closing one pool broke the other: %v", err)
+	}
+}
+
+func TestNewPGCanBeRetriedAfterError(t *testing.T) {
+	ctx := context.Background()
+	url := dbtest.URL(t)
+
+	if _, err := database.NewPG(ctx, "host=127.0.0.1 port=1 connect_timeout=1", dbtest.Logger(), &config.Config{}); err == nil {
+		t.Fatal("expected an error for an unreachable server")
+	}
+
+	pg, err := database.NewPG(ctx, url, dbtest.Logger(), &config.Config{})
+	if err != nil {
+		t.Fatalf("NewPG after a failed attempt: %v", err)
+	}
+	pg.Close()
+}
+
+func TestReader(t *testing.T) {
+	ctx := context.Background()
+	url := dbtest.URL(t)
+
+	pg, err := database.NewPG(ctx, url, dbtest.Logger(), &config.Config{})
+	if err != nil {
+		t.Fatalf("NewPG: %v", err)
+	}
+	defer pg.Close()
+	if pg.Reader() != pg.Db {
+		t.Fatal("Reader should fall back to the primary pool")
+	}
+
+	withReplica, err := database.NewPG(ctx, url, dbtest.Logger(), &config.Config{}, database.WithReplica(url), database.WithMaxConns(2))
+	if err != nil {
+		t.Fatalf("NewPG with replica: %v", err)
+	}
+	defer withReplica.Close()
+	if withReplica.Replica == nil || withReplica.Reader() != withReplica.Replica {
+		t.Fatal("Reader should return the replica pool")
+	}
+	if err := withReplica.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+func TestCreateTableIsIdempotent(t *testing.T) {
+	pg := dbtest.New(t)
+
+	if err := database.CreateTable(context.Background(), pg.Db, dbtest.Logger(), pg.Config); err != nil {
+		t.Fatalf("second CreateTable: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimitsrepo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"news-service/internal/database"
+	errMsg "news-service/internal/err"
+	"news-service/internal/ratelimit"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RateLimitsRepository is a ratelimit.Store shared by the instances of the
+// service. Times come from the database clock.
+type RateLimitsRepository struct {
+	db    database.DBTX
+	log   *slog.Logger
+	calls atomic.Int64
+}
+
+func NewRateLimitsRepository(db database.DBTX, log *slog.Logger) *RateLimitsRepository {
+	return &RateLimitsRepository{db: db, log: log}
+}
+
+// Take locks the row of key for the transaction, concurrent takes from
+// other instances wait for it.
+func (rl *RateLimitsRepository) Take(ctx context.Context, key string, rate, burst float64) (bool, time.Duration, error) {
+	if rl.calls.Add(1)%1024 == 0 {
+		rl.sweep(ctx)
+	}
+
+	tx, err := rl.db.Begin(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var tokens, elapsed float64
+	var now time.Time
+	err = tx.QueryRow(ctx, `
+	INSERT INTO RateLimits (key, tokens, updated_at, full_at) VALUES ($1, $2, now(), now())
+	ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+	RETURNING tokens, greatest(extract(epoch FROM now() - updated_at), 0)::float8, now()`, key, burst,
+	).Scan(&tokens, &elapsed, &now)
+	if err != nil {
+		rl.log.Error("failed to take a token", errMsg.Err(err))
+		return false, 0, err
+	}
+
+	tokens, ok, wait := ratelimit.Refill(tokens, time.Duration(elapsed*float64(time.Second)), rate, burst)
+	_, err = tx.Exec(ctx, `UPDATE RateLimits SET tokens = $2, updated_at = now(), full_at = $3 WHERE key = $1`,
+		key, tokens, ratelimit.FullAt(now, tokens, rate, burst))
+	if err != nil {
+		rl.log.Error("failed to take a token", errMsg.Err(err))
+		return false, 0, err
+	}
+	return ok, wait, tx.Commit(ctx)
+}
+
+func (rl *RateLimitsRepository) Fail(ctx context.Context, key string, window time.Duration) (ratelimit.Failures, error) {
+	var f ratelimit.Failures
+	err := rl.db.QueryRow(ctx, `
+	INSERT INTO RateLimitFailures (key, count, last_failure, expires_at)
+	VALUES ($1, 1, now(), now() + make_interval(secs => $2))
+	ON CONFLICT (key) DO UPDATE SET
+		count = CASE WHEN RateLimitFailures.expires_at < now() THEN 1 ELSE RateLimitFailures.count + 1 END,
+		last_failure = now(),
+		expires_at = EXCLUDED.expires_at
+	RETURNING count, last_failure`, key, window.Seconds(),
+	).Scan(&f.Count, &f.Last)
+	if err != nil {
+		rl.log.Error("failed to record a failure", errMsg.Err(err))
+	}
+	return f, err
+}
+
+func (rl *RateLimitsRepository) Failures(ctx context.Context, key string, window time.Duration) (ratelimit.Failures, error) {
+	var f ratelimit.Failures
+	err := rl.db.QueryRow(ctx, `
+	SELECT count, last_failure FROM RateLimitFailures
+	WHERE key = $1 AND last_failure >= now() - make_interval(secs => $2)`, key, window.Seconds(),
+	).Scan(&f.Count, &f.Last)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ratelimit.Failures{}, nil
+	}
+	if err != nil {
+		rl.log.Error("failed to retrieve failures", errMsg.Err(err))
+	}
+	return f, err
+}
+
+func (rl *RateLimitsRepository) Reset(ctx context.Context, key string) error {
+	_, err := rl.db.Exec(ctx, `DELETE FROM RateLimitFailures WHERE key = $1`, key)
+	if err != nil {
+		rl.log.Error("failed to reset failures", errMsg.Err(err))
+	}
+	return err
+}
+
+// sweep deletes buckets that have refilled completely and expired
+// failures, like ratelimit.Memory does.
+func (rl *RateLimitsRepository) sweep(ctx context.Context) {
+	_, err := rl.db.Exec(ctx, `
+	DELETE FROM RateLimits WHERE full_at <= now();
+	DELETE FROM RateLimitFailures WHERE expires_at <= now()`)
+	if err != nil {
+		rl.log.Error("failed to sweep rate limits", errMsg.Err(err))
+	}
+}
@@ -0,0 +1,76 @@
+package ratelimitsrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	"news-service/internal/ratelimit"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestTake(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRateLimitsRepository(dbtest.New(t).Db, dbtest.Logger())
+	// Shared by the limiters of every instance.
+	a := ratelimit.NewShared(repo, "login", 1, time.Hour, 2)
+	b := ratelimit.NewShared(repo, "login", 1, time.Hour, 2)
+
+	for _, l := range []*ratelimit.Limiter{a, b} {
+		if ok, _, err := l.Allow(ctx, "10.0.0.1"); !ok || err != nil {
+			t.Fatalf("Allow within burst = %v, %v", ok, err)
+		}
+	}
+	ok, wait, err := a.Allow(ctx, "10.0.0.1")
+	if ok || err != nil {
+		t.Fatalf("Allow over burst = %v, %v", ok, err)
+	}
+	if wait < 59*time.Minute || wait > time.Hour {
+		t.Fatalf("wait = %v, want about an hour", wait)
+	}
+	if ok, _, _ := a.Allow(ctx, "10.0.0.2"); !ok {
+		t.Fatal("keys must not share a bucket")
+	}
+	if ok, _, _ := ratelimit.NewShared(repo, "users", 1, time.Hour, 1).Allow(ctx, "10.0.0.1"); !ok {
+		t.Fatal("limiters with different names must not share a bucket")
+	}
+}
+
+func TestFailures(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRateLimitsRepository(dbtest.New(t).Db, dbtest.Logger())
+
+	if f, err := repo.Failures(ctx, "a", time.Hour); f.Count != 0 || err != nil {
+		t.Fatalf("Failures without failures = %+v, %v", f, err)
+	}
+	for i := 1; i <= 3; i++ {
+		if f, err := repo.Fail(ctx, "a", time.Hour); f.Count != i || f.Last.IsZero() || err != nil {
+			t.Fatalf("Fail = %+v, %v, want %d failures", f, err, i)
+		}
+	}
+	if f, _ := repo.Failures(ctx, "a", time.Hour); f.Count != 3 {
+		t.Fatalf("Failures = %+v, want 3", f)
+	}
+	if f, _ := repo.Failures(ctx, "b", time.Hour); f.Count != 0 {
+		t.Fatal("keys must not share failures")
+	}
+
+	if err := repo.Reset(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if f, _ := repo.Failures(ctx, "a", time.Hour); f.Count != 0 {
+		t.Fatalf("Failures after Reset = %+v", f)
+	}
+
+	// Failures more than the window apart start over.
+	repo.Fail(ctx, "c", time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if f, _ := repo.Failures(ctx, "c", time.Millisecond); f.Count != 0 {
+		t.Fatalf("Failures older than the window = %+v", f)
+	}
+	if f, _ := repo.Fail(ctx, "c", time.Millisecond); f.Count != 1 {
+		t.Fatalf("Fail after the window = %+v, want 1", f)
+	}
+}
@@ -0,0 +1,114 @@
+package reactionsrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/database"
+	errMsg "news-service/internal/err"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ReactionsRepository struct {
+	db  database.DBTX
+	log *slog.Logger
+}
+
+func NewReactionsRepository(db database.DBTX, log *slog.Logger) *ReactionsRepository {
+	return &ReactionsRepository{db, log}
+}
+
+func (re *ReactionsRepository) AddReaction(ctx context.Context, newsID, userID int, kind string) error {
+	_, err := re.db.Exec(ctx, `INSERT INTO Reactions (news_id, user_id, kind) VALUES ($1, $2, $3)
+	ON CONFLICT DO NOTHING`, newsID, userID, kind)
+	if err != nil {
+		re.log.Error("failed to add reaction", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (re *ReactionsRepository) RemoveReaction(ctx context.Context, newsID, userID int, kind string) error {
+	_, err := re.db.Exec(ctx, `DELETE FROM Reactions WHERE news_id = $1 AND user_id = $2 AND kind = $3`,
+		newsID, userID, kind)
+	if err != nil {
+		re.log.Error("failed to remove reaction", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (re *ReactionsRepository) CountReactions(ctx context.Context, newsID int) (map[string]int, error) {
+	rows, err := re.db.Query(ctx, `SELECT kind, count(*) FROM Reactions WHERE news_id = $1 GROUP BY kind`, newsID)
+	if err != nil {
+		re.log.Error("failed to count reactions", errMsg.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			kind  string
+			count int
+		)
+		if err := rows.Scan(&kind, &count); err != nil {
+			re.log.Error("failed to scan reaction count", errMsg.Err(err))
+			return nil, err
+		}
+		counts[kind] = count
+	}
+	if err := rows.Err(); err != nil {
+		re.log.Error("failed to count reactions", errMsg.Err(err))
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (re *ReactionsRepository) CountReactionsByNews(ctx context.Context, newsIDs []int) (map[int]map[string]int, error) {
+	rows, err := re.db.Query(ctx, `SELECT news_id, kind, count(*) FROM Reactions
+	WHERE news_id = ANY($1)
+	GROUP BY news_id, kind`, newsIDs)
+	if err != nil {
+		re.log.Error("failed to count reactions", errMsg.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]map[string]int, len(newsIDs))
+	for rows.Next() {
+		var (
+			newsID int
+			kind   string
+			count  int
+		)
+		if err := rows.Scan(&newsID, &kind, &count); err != nil {
+			re.log.Error("failed to scan reaction count", errMsg.Err(err))
+			return nil, err
+		}
+		if counts[newsID] == nil {
+			counts[newsID] = make(map[string]int)
+		}
+		counts[newsID][kind] = count
+	}
+	if err := rows.Err(); err != nil {
+		re.log.Error("failed to count reactions", errMsg.Err(err))
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (re *ReactionsRepository) ListUserReactions(ctx context.Context, newsID, userID int) ([]string, error) {
+	rows, err := re.db.Query(ctx, `SELECT kind FROM Reactions WHERE news_id = $1 AND user_id = $2
+	ORDER BY kind COLLATE "C"`, newsID, userID)
+	if err != nil {
+		re.log.Error("failed to list user reactions", errMsg.Err(err))
+		return nil, err
+	}
+	kinds, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		re.log.Error("failed to scan user reactions", errMsg.Err(err))
+		return nil, err
+	}
+	return kinds, nil
+}
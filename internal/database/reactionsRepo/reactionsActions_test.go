@@ -0,0 +1,40 @@
+package reactionsrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	newsrepo "news-service/internal/database/newsRepo"
+	usersrepo "news-service/internal/database/usersRepo"
+	"news-service/internal/entities"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestReactionsAreDeletedWithUser(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	news := newsrepo.NewNewsRepository(pg.Db, dbtest.Logger())
+	users := usersrepo.NewUserRepository(pg.Db, dbtest.Logger())
+	repo := NewReactionsRepository(pg.Db, dbtest.Logger())
+
+	item := entities.News{Title: "title", Content: "content", Published: true}
+	if err := news.CreateNews(ctx, &item); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	user := entities.User{Email: "reader@example.com", Password: "hash"}
+	if err := users.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := repo.AddReaction(ctx, item.ID, user.ID, "like"); err != nil {
+		t.Fatalf("AddReaction: %v", err)
+	}
+
+	if err := users.DeleteUserById(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUserById: %v", err)
+	}
+	if counts, err := repo.CountReactions(ctx, item.ID); err != nil || len(counts) != 0 {
+		t.Fatalf("reactions should be deleted with their user: %v, %v", counts, err)
+	}
+}
@@ -0,0 +1,1358 @@
+// Package repotest is a conformance suite shared by every repository
+// backend. A backend test calls Run with a constructor for fresh, empty
+// repositories.
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"news-service/internal/entities"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+	"news-service/internal/related"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type Repositories struct {
+	News           models.NewsRepository
+	Categories     models.CategoriesRepository
+	NewsCategories models.NewsCategoriesRepository
+	Users          userhandlers.User
+	UserTokens     models.UserTokensRepository
+	Media          models.MediaRepository
+	Tags           models.TagsRepository
+	Views          models.ViewsRepository
+	Comments       models.CommentsRepository
+	Reactions      models.ReactionsRepository
+	Bookmarks      models.BookmarksRepository
+	Subscriptions  models.SubscriptionsRepository
+	Webhooks       models.WebhooksRepository
+	Outbox         models.OutboxRepository
+	NewsEvents     models.NewsEventsRepository
+}
+
+func Run(t *testing.T, newRepos func(t *testing.T) Repositories) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repos Repositories)
+	}{
+		{"News", testNews},
+		{"Categories", testCategories},
+		{"NewsCategories", testNewsCategories},
+		{"PublishedNews", testPublishedNews},
+		{"Slugs", testSlugs},
+		{"RichContent", testRichContent},
+		{"Media", testMedia},
+		{"Tags", testTags},
+		{"RelatedNews", testRelatedNews},
+		{"Views", testViews},
+		{"Comments", testComments},
+		{"Reactions", testReactions},
+		{"Bookmarks", testBookmarks},
+		{"Subscriptions", testSubscriptions},
+		{"Webhooks", testWebhooks},
+		{"Outbox", testOutbox},
+		{"NewsEvents", testNewsEvents},
+		{"Users", testUsers},
+		{"UserTokens", testUserTokens},
+		{"ConcurrentWrites", testConcurrentWrites},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepos(t))
+		})
+	}
+}
+
+func testNews(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	list, err := repos.News.ListNews(ctx)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("ListNews on empty repository = %v, %v", list, err)
+	}
+
+	first := entities.News{Title: "first", Content: "one"}
+	second := entities.News{Title: "second", Content: "two"}
+	for _, news := range []*entities.News{&first, &second} {
+		if err := repos.News.CreateNews(ctx, news); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+	}
+	if first.ID == 0 || second.ID <= first.ID {
+		t.Fatalf("ids must be positive and increasing, got %d and %d", first.ID, second.ID)
+	}
+
+	list, err = repos.News.ListNews(ctx)
+	if err != nil {
+		t.Fatalf("ListNews: %v", err)
+	}
+	if len(list) != 2 || list[0] != second || list[1] != first {
+		t.Fatalf("ListNews = %+v, want newest first", list)
+	}
+
+	found, err := repos.News.FindNewsByID(ctx, first.ID)
+	if err != nil || found != first {
+		t.Fatalf("FindNewsByID = %+v, %v", found, err)
+	}
+	if _, err := repos.News.FindNewsByID(ctx, second.ID+1); !errors.Is(err, models.ErrNewsNotFound) {
+		t.Fatalf("FindNewsByID of a missing id = %v, want %v", err, models.ErrNewsNotFound)
+	}
+
+	first.Title, first.Content = "updated", "updated content"
+	if err := repos.News.UpdateNews(ctx, &first); err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+	found, err = repos.News.FindNewsByID(ctx, first.ID)
+	if err != nil || found != first {
+		t.Fatalf("FindNewsByID after update = %+v, %v", found, err)
+	}
+}
+
+func testCategories(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	a := entities.Categorie{Name: 1}
+	b := entities.Categorie{Name: 1}
+	c := entities.Categorie{Name: 2}
+	for _, categorie := range []*entities.Categorie{&a, &b, &c} {
+		if err := repos.Categories.CreateCategorie(ctx, categorie); err != nil {
+			t.Fatalf("CreateCategorie: %v", err)
+		}
+	}
+	if a.ID == 0 || a.ID != b.ID {
+		t.Fatalf("same name must map to the same id, got %d and %d", a.ID, b.ID)
+	}
+	if c.ID == a.ID {
+		t.Fatal("different names must map to different ids")
+	}
+}
+
+func testNewsCategories(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	news := entities.News{Title: "title", Content: "content"}
+	if err := repos.News.CreateNews(ctx, &news); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+
+	names, err := repos.NewsCategories.ListCategories(ctx, news.ID)
+	if err != nil || len(names) != 0 {
+		t.Fatalf("ListCategories without categories = %v, %v", names, err)
+	}
+
+	ids := map[int]int{}
+	for _, name := range []int{5, 6, 7} {
+		categorie := entities.Categorie{Name: name}
+		if err := repos.Categories.CreateCategorie(ctx, &categorie); err != nil {
+			t.Fatalf("CreateCategorie: %v", err)
+		}
+		ids[name] = categorie.ID
+	}
+
+	if err := repos.NewsCategories.Create(ctx, &entities.NewsCategories{NewsID: news.ID, CategoryID: ids[5]}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repos.NewsCategories.UpdateNewsCategories(ctx, ids[7], news.ID); err != nil {
+		t.Fatalf("UpdateNewsCategories: %v", err)
+	}
+	if err := repos.NewsCategories.Create(ctx, &entities.NewsCategories{NewsID: news.ID, CategoryID: ids[5]}); err == nil {
+		t.Fatal("Create should reject a duplicate relation")
+	}
+	if err := repos.NewsCategories.Create(ctx, &entities.NewsCategories{NewsID: news.ID + 1, CategoryID: ids[5]}); err == nil {
+		t.Fatal("Create should reject a missing news")
+	}
+
+	names, err = repos.NewsCategories.ListCategories(ctx, news.ID)
+	if err != nil {
+		t.Fatalf("ListCategories: %v", err)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []int{5, 7}) {
+		t.Fatalf("ListCategories = %v, want [5 7]", names)
+	}
+
+	other := entities.News{Title: "other", Content: "content"}
+	if err := repos.News.CreateNews(ctx, &other); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	if err := repos.NewsCategories.Create(ctx, &entities.NewsCategories{NewsID: other.ID, CategoryID: ids[6]}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	byNews, err := repos.NewsCategories.ListCategoriesByNews(ctx, []int{news.ID, other.ID, other.ID + 1})
+	if err != nil || len(byNews) != 2 || !slices.Equal(byNews[news.ID], []int{5, 7}) || !slices.Equal(byNews[other.ID], []int{6}) {
+		t.Fatalf("ListCategoriesByNews = %v, %v", byNews, err)
+	}
+
+	if err := repos.NewsCategories.DeleteCategories(ctx, news.ID); err != nil {
+		t.Fatalf("DeleteCategories: %v", err)
+	}
+	names, err = repos.NewsCategories.ListCategories(ctx, news.ID)
+	if err != nil || len(names) != 0 {
+		t.Fatalf("ListCategories after delete = %v, %v", names, err)
+	}
+}
+
+func testPublishedNews(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	var published []entities.News
+	for i, title := range []string{"a", "draft", "b", "c"} {
+		news := entities.News{Title: title, Content: "content", Published: title != "draft"}
+		if err := repos.News.CreateNews(ctx, &news); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+		if news.Published {
+			published = append(published, news)
+		}
+
+		categorie := entities.Categorie{Name: 100 + i%2}
+		if err := repos.Categories.CreateCategorie(ctx, &categorie); err != nil {
+			t.Fatalf("CreateCategorie: %v", err)
+		}
+		if err := repos.NewsCategories.Create(ctx, &entities.NewsCategories{NewsID: news.ID, CategoryID: categorie.ID}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	list, err := repos.News.ListPublishedNews(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListPublishedNews: %v", err)
+	}
+	if len(list) != 3 || list[0] != published[2] || list[2] != published[0] {
+		t.Fatalf("ListPublishedNews = %+v", list)
+	}
+
+	list, err = repos.News.ListPublishedNews(ctx, 1, 1)
+	if err != nil || len(list) != 1 || list[0] != published[1] {
+		t.Fatalf("ListPublishedNews(1, 1) = %+v, %v", list, err)
+	}
+	list, err = repos.News.ListPublishedNews(ctx, 10, 10)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("ListPublishedNews past the end = %+v, %v", list, err)
+	}
+
+	// "a" and "b" are in category 100, the draft and "c" in 101.
+	list, err = repos.News.ListPublishedNewsByCategory(ctx, 100, 10, 0)
+	if err != nil || len(list) != 2 || list[0] != published[1] || list[1] != published[0] {
+		t.Fatalf("ListPublishedNewsByCategory(100) = %+v, %v", list, err)
+	}
+	list, err = repos.News.ListPublishedNewsByCategory(ctx, 101, 10, 0)
+	if err != nil || len(list) != 1 || list[0] != published[2] {
+		t.Fatalf("ListPublishedNewsByCategory(101) = %+v, %v", list, err)
+	}
+}
+
+func testSlugs(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	create := func(title, newsSlug string) entities.News {
+		t.Helper()
+		news := entities.News{Title: title, Slug: newsSlug, Content: "content"}
+		if err := repos.News.CreateNews(ctx, &news); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+		return news
+	}
+
+	first := create("Новости дня", "")
+	second := create("Новости дня", "")
+	custom := create("Other", "custom")
+	if first.Slug != "novosti-dnya" || second.Slug != "novosti-dnya-2" || custom.Slug != "custom" {
+		t.Fatalf("unexpected slugs %q, %q, %q", first.Slug, second.Slug, custom.Slug)
+	}
+
+	found, err := repos.News.FindNewsBySlug(ctx, "novosti-dnya-2")
+	if err != nil || found.ID != second.ID {
+		t.Fatalf("FindNewsBySlug = %+v, %v", found, err)
+	}
+	if _, err := repos.News.FindNewsBySlug(ctx, "missing"); err == nil {
+		t.Fatal("FindNewsBySlug should fail for an unknown slug")
+	}
+
+	// Updating without a slug keeps the current one.
+	first.Slug = ""
+	if err := repos.News.UpdateNews(ctx, &first); err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+	if first.Slug != "novosti-dnya" {
+		t.Fatalf("slug changed to %q", first.Slug)
+	}
+
+	// Renaming onto a taken slug gets a suffix, the old slug redirects.
+	first.Slug = "custom"
+	if err := repos.News.UpdateNews(ctx, &first); err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+	if first.Slug != "custom-2" {
+		t.Fatalf("renamed slug = %q, want custom-2", first.Slug)
+	}
+	found, err = repos.News.FindNewsBySlug(ctx, "novosti-dnya")
+	if err != nil || found.ID != first.ID || found.Slug != "custom-2" {
+		t.Fatalf("FindNewsBySlug by old slug = %+v, %v", found, err)
+	}
+
+	// Old slugs stay reserved for their news.
+	third := create("Новости дня", "")
+	if third.Slug != "novosti-dnya-3" {
+		t.Fatalf("slug of a redirect was reused: %q", third.Slug)
+	}
+
+	// A news can take its own old slug back.
+	first.Slug = "novosti-dnya"
+	if err := repos.News.UpdateNews(ctx, &first); err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+	if first.Slug != "novosti-dnya" {
+		t.Fatalf("reclaimed slug = %q", first.Slug)
+	}
+	found, err = repos.News.FindNewsBySlug(ctx, "custom-2")
+	if err != nil || found.ID != first.ID || found.Slug != "novosti-dnya" {
+		t.Fatalf("FindNewsBySlug after reclaim = %+v, %v", found, err)
+	}
+}
+
+func testRichContent(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	plain := entities.News{Title: "plain", Content: "text"}
+	if err := repos.News.CreateNews(ctx, &plain); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	if plain.ContentFormat != entities.DefaultContentFormat {
+		t.Fatalf("ContentFormat = %q, want the default", plain.ContentFormat)
+	}
+
+	news := entities.News{
+		Title:         "rich",
+		Summary:       "lede",
+		Content:       "*text*",
+		ContentFormat: "markdown",
+		ContentHTML:   "<p><em>text</em></p>",
+	}
+	if err := repos.News.CreateNews(ctx, &news); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	found, err := repos.News.FindNewsByID(ctx, news.ID)
+	if err != nil || found != news {
+		t.Fatalf("FindNewsByID = %+v, %v; want %+v", found, err, news)
+	}
+
+	news.Summary, news.Content, news.ContentFormat, news.ContentHTML = "", "<b>x</b>", "html", "<b>x</b>"
+	if err := repos.News.UpdateNews(ctx, &news); err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+	found, err = repos.News.FindNewsBySlug(ctx, news.Slug)
+	if err != nil || found != news {
+		t.Fatalf("FindNewsBySlug after update = %+v, %v; want %+v", found, err, news)
+	}
+}
+
+func testMedia(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	news := entities.News{Title: "with media", Content: "c"}
+	if err := repos.News.CreateNews(ctx, &news); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+
+	empty, err := repos.Media.ListMedia(ctx, news.ID)
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("ListMedia without media = %v, %v", empty, err)
+	}
+
+	image := entities.Media{
+		NewsID: news.ID, Key: "news/1/a.png", URL: "/media/news/1/a.png",
+		ThumbnailKey: "news/1/a_thumb.png", ThumbnailURL: "/media/news/1/a_thumb.png",
+		FileName: "a.png", ContentType: "image/png", Size: 1234, Width: 640, Height: 480,
+	}
+	pdf := entities.Media{NewsID: news.ID, Key: "news/1/b.pdf", URL: "/media/news/1/b.pdf", FileName: "b.pdf", ContentType: "application/pdf", Size: 10}
+	for _, media := range []*entities.Media{&image, &pdf} {
+		if err := repos.Media.CreateMedia(ctx, media); err != nil {
+			t.Fatalf("CreateMedia: %v", err)
+		}
+	}
+	if image.ID == 0 || pdf.ID <= image.ID || image.CreatedAt.IsZero() {
+		t.Fatalf("CreateMedia should set ids and timestamps: %+v %+v", image, pdf)
+	}
+
+	list, err := repos.Media.ListMedia(ctx, news.ID)
+	if err != nil || !slices.Equal(list, []entities.Media{image, pdf}) {
+		t.Fatalf("ListMedia = %+v, %v", list, err)
+	}
+	byNews, err := repos.Media.ListMediaByNews(ctx, []int{news.ID, news.ID + 100})
+	if err != nil || len(byNews) != 1 || !slices.Equal(byNews[news.ID], list) {
+		t.Fatalf("ListMediaByNews = %+v, %v", byNews, err)
+	}
+
+	duplicate := entities.Media{NewsID: news.ID, Key: image.Key, URL: "x", FileName: "x", ContentType: "x"}
+	if err := repos.Media.CreateMedia(ctx, &duplicate); err == nil {
+		t.Fatal("CreateMedia should reject a duplicate key")
+	}
+	orphan := entities.Media{NewsID: news.ID + 100, Key: "orphan", URL: "x", FileName: "x", ContentType: "x"}
+	if err := repos.Media.CreateMedia(ctx, &orphan); err == nil {
+		t.Fatal("CreateMedia should reject a missing news")
+	}
+}
+
+func testTags(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	var news [3]entities.News
+	for i := range news {
+		news[i] = entities.News{Title: "tagged", Content: "c"}
+		if err := repos.News.CreateNews(ctx, &news[i]); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+	}
+
+	tags, err := repos.Tags.ListNewsTags(ctx, news[0].ID)
+	if err != nil || len(tags) != 0 {
+		t.Fatalf("ListNewsTags without tags = %v, %v", tags, err)
+	}
+
+	set := [][]string{
+		{"go", "golang", "release"},
+		{"go", "go_lang"},
+		{"go", "release"},
+	}
+	for i, names := range set {
+		if err := repos.Tags.SetNewsTags(ctx, news[i].ID, names); err != nil {
+			t.Fatalf("SetNewsTags: %v", err)
+		}
+	}
+	tags, err = repos.Tags.ListNewsTags(ctx, news[0].ID)
+	if err != nil || !slices.Equal(tags, []string{"go", "golang", "release"}) {
+		t.Fatalf("ListNewsTags = %v, %v", tags, err)
+	}
+
+	names := func(tags []entities.Tag) []string {
+		var out []string
+		for _, tag := range tags {
+			out = append(out, tag.Name)
+		}
+		return out
+	}
+	all, err := repos.Tags.ListTags(ctx, "", 10)
+	if err != nil || !slices.Equal(names(all), []string{"go", "release", "go_lang", "golang"}) {
+		t.Fatalf("ListTags = %+v, %v, want most used first", all, err)
+	}
+	if all[0].Count != 3 || all[1].Count != 2 || all[0].ID == 0 {
+		t.Fatalf("ListTags counts = %+v", all)
+	}
+	// "_" is not a wildcard.
+	prefixed, err := repos.Tags.ListTags(ctx, "go_", 10)
+	if err != nil || !slices.Equal(names(prefixed), []string{"go_lang"}) {
+		t.Fatalf("ListTags(go_) = %+v, %v", prefixed, err)
+	}
+	limited, err := repos.Tags.ListTags(ctx, "go", 2)
+	if err != nil || !slices.Equal(names(limited), []string{"go", "go_lang"}) {
+		t.Fatalf("ListTags(go, 2) = %+v, %v", limited, err)
+	}
+
+	// Replacing the tags drops the old links, unused tags are not listed.
+	if err := repos.Tags.SetNewsTags(ctx, news[1].ID, nil); err != nil {
+		t.Fatalf("SetNewsTags: %v", err)
+	}
+	tags, err = repos.Tags.ListNewsTags(ctx, news[1].ID)
+	if err != nil || len(tags) != 0 {
+		t.Fatalf("ListNewsTags after clearing = %v, %v", tags, err)
+	}
+	byNews, err := repos.Tags.ListTagsByNews(ctx, []int{news[0].ID, news[1].ID, news[2].ID})
+	if err != nil || len(byNews) != 2 || !slices.Equal(byNews[news[0].ID], []string{"go", "golang", "release"}) ||
+		!slices.Equal(byNews[news[2].ID], []string{"go", "release"}) {
+		t.Fatalf("ListTagsByNews = %v, %v", byNews, err)
+	}
+	all, err = repos.Tags.ListTags(ctx, "", 10)
+	if err != nil || !slices.Equal(names(all), []string{"go", "release", "golang"}) {
+		t.Fatalf("ListTags after clearing = %+v, %v", all, err)
+	}
+
+	if err := repos.Tags.SetNewsTags(ctx, news[2].ID+100, []string{"orphan"}); err == nil {
+		t.Fatal("SetNewsTags should reject a missing news")
+	}
+}
+
+func testRelatedNews(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	var categories []int
+	for _, name := range []int{1, 2} {
+		categorie := entities.Categorie{Name: name}
+		if err := repos.Categories.CreateCategorie(ctx, &categorie); err != nil {
+			t.Fatalf("CreateCategorie: %v", err)
+		}
+		categories = append(categories, categorie.ID)
+	}
+	create := func(title string, published bool, categories []int, tags []string) entities.News {
+		news := entities.News{Title: title, Content: "c", Published: published}
+		if err := repos.News.CreateNews(ctx, &news); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+		for _, id := range categories {
+			if err := repos.NewsCategories.Create(ctx, &entities.NewsCategories{NewsID: news.ID, CategoryID: id}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		if err := repos.Tags.SetNewsTags(ctx, news.ID, tags); err != nil {
+			t.Fatalf("SetNewsTags: %v", err)
+		}
+		return news
+	}
+
+	src := create("Go release notes", true, categories, []string{"go", "release"})
+	sameCategories := create("Alpha", true, categories, nil)
+	sameTag := create("Go conference", true, nil, []string{"go"})
+	sameWords := create("Release notes", true, nil, nil)
+	create("Weather", true, nil, []string{"weather"})
+	create("Draft", false, categories, []string{"go"})
+
+	ids := func(list []entities.News) []int {
+		var out []int
+		for _, news := range list {
+			out = append(out, news.ID)
+		}
+		return out
+	}
+	list, err := repos.News.ListRelatedNews(ctx, src.ID, related.Options{Limit: 10})
+	if err != nil || !slices.Equal(ids(list), []int{sameCategories.ID, sameTag.ID, sameWords.ID}) {
+		t.Fatalf("ListRelatedNews = %v, %v, want %v", ids(list), err,
+			[]int{sameCategories.ID, sameTag.ID, sameWords.ID})
+	}
+	if list[0] != sameCategories {
+		t.Fatalf("ListRelatedNews returned %+v, want %+v", list[0], sameCategories)
+	}
+
+	list, err = repos.News.ListRelatedNews(ctx, src.ID, related.Options{Limit: 2, HalfLife: 24 * time.Hour})
+	if err != nil || !slices.Equal(ids(list), []int{sameCategories.ID, sameTag.ID}) {
+		t.Fatalf("ListRelatedNews with a limit = %v, %v", ids(list), err)
+	}
+
+	list, err = repos.News.ListRelatedNews(ctx, src.ID+100, related.Options{Limit: 10})
+	if err != nil || len(list) != 0 {
+		t.Fatalf("ListRelatedNews of a missing news = %v, %v", list, err)
+	}
+}
+
+func testViews(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	var fresh, old, draft entities.News
+	for _, news := range []*entities.News{&fresh, &old, &draft} {
+		news.Title, news.Content, news.Published = "viewed", "c", news != &draft
+		if err := repos.News.CreateNews(ctx, news); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+	}
+
+	now := time.Now().Truncate(time.Hour)
+	err := repos.Views.AddViews(ctx, []entities.ViewCount{
+		{NewsID: fresh.ID, Bucket: now, Views: 5},
+		{NewsID: old.ID, Bucket: now, Views: 3},
+		{NewsID: old.ID, Bucket: now.Add(-48 * time.Hour), Views: 10},
+		{NewsID: draft.ID, Bucket: now, Views: 100},
+		{NewsID: draft.ID + 100, Bucket: now, Views: 1},
+	})
+	if err != nil {
+		t.Fatalf("AddViews: %v", err)
+	}
+	if err := repos.Views.AddViews(ctx, []entities.ViewCount{{NewsID: old.ID, Bucket: now, Views: 1}}); err != nil {
+		t.Fatalf("AddViews: %v", err)
+	}
+
+	ids := func(list []entities.News) []int {
+		var out []int
+		for _, news := range list {
+			out = append(out, news.ID)
+		}
+		return out
+	}
+	tests := []struct {
+		name     string
+		since    time.Time
+		halfLife time.Duration
+		limit    int
+		want     []int
+	}{
+		{"last day", now.Add(-24 * time.Hour), 0, 10, []int{fresh.ID, old.ID}},
+		{"last days", now.Add(-72 * time.Hour), 0, 10, []int{old.ID, fresh.ID}},
+		{"old views decayed", now.Add(-72 * time.Hour), 6 * time.Hour, 10, []int{fresh.ID, old.ID}},
+		{"limit", now.Add(-72 * time.Hour), 0, 1, []int{old.ID}},
+		{"future", now.Add(time.Hour), 0, 10, nil},
+	}
+	for _, tt := range tests {
+		list, err := repos.Views.ListTrendingNews(ctx, tt.since, tt.halfLife, tt.limit)
+		if err != nil || !slices.Equal(ids(list), tt.want) {
+			t.Errorf("%s: ListTrendingNews = %v, %v, want %v", tt.name, ids(list), err, tt.want)
+		}
+	}
+	list, _ := repos.Views.ListTrendingNews(ctx, now.Add(-time.Hour), 0, 1)
+	if len(list) != 1 || list[0] != fresh {
+		t.Fatalf("ListTrendingNews returned %+v, want %+v", list, fresh)
+	}
+}
+
+func testComments(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	news := entities.News{Title: "discussed", Content: "c", Published: true}
+	if err := repos.News.CreateNews(ctx, &news); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	user := entities.User{Email: "reader@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	first := entities.Comment{NewsID: news.ID, UserID: user.ID, Body: "first"}
+	if err := repos.Comments.CreateComment(ctx, &first); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	if first.ID == 0 || first.Status != entities.CommentPending || first.CreatedAt.IsZero() || first.DeletedAt != nil {
+		t.Fatalf("CreateComment should set id, status and timestamps: %+v", first)
+	}
+	found, err := repos.Comments.FindComment(ctx, first.ID)
+	if err != nil || found != first {
+		t.Fatalf("FindComment = %+v, %v, want %+v", found, err, first)
+	}
+	if _, err := repos.Comments.FindComment(ctx, first.ID+100); err == nil {
+		t.Fatal("FindComment should fail for a missing id")
+	}
+
+	reply := entities.Comment{NewsID: news.ID, UserID: user.ID, ParentID: first.ID, Body: "reply"}
+	if err := repos.Comments.CreateComment(ctx, &reply); err != nil {
+		t.Fatalf("CreateComment reply: %v", err)
+	}
+	for _, bad := range []entities.Comment{
+		{NewsID: news.ID + 100, UserID: user.ID, Body: "x"},
+		{NewsID: news.ID, UserID: user.ID + 100, Body: "x"},
+		{NewsID: news.ID, UserID: user.ID, ParentID: reply.ID + 100, Body: "x"},
+	} {
+		if err := repos.Comments.CreateComment(ctx, &bad); err == nil {
+			t.Fatalf("CreateComment(%+v) should fail", bad)
+		}
+	}
+
+	ids := func(list []entities.Comment) []int {
+		var out []int
+		for _, comment := range list {
+			out = append(out, comment.ID)
+		}
+		return out
+	}
+	pending, err := repos.Comments.ListPendingComments(ctx, 10, 0)
+	if err != nil || !slices.Equal(ids(pending), []int{first.ID, reply.ID}) {
+		t.Fatalf("ListPendingComments = %v, %v", ids(pending), err)
+	}
+	if pending, _ := repos.Comments.ListPendingComments(ctx, 1, 1); !slices.Equal(ids(pending), []int{reply.ID}) {
+		t.Fatalf("ListPendingComments(1, 1) = %v", ids(pending))
+	}
+	if list, err := repos.Comments.ListComments(ctx, news.ID); err != nil || len(list) != 0 {
+		t.Fatalf("ListComments before moderation = %v, %v", list, err)
+	}
+
+	for _, c := range []entities.Comment{first, reply} {
+		if err := repos.Comments.SetCommentStatus(ctx, c.ID, c.UpdatedAt, entities.CommentApproved); err != nil {
+			t.Fatalf("SetCommentStatus: %v", err)
+		}
+	}
+	if err := repos.Comments.SetCommentStatus(ctx, reply.ID+100, reply.UpdatedAt, entities.CommentApproved); !errors.Is(err, models.ErrCommentChanged) {
+		t.Fatalf("SetCommentStatus of a missing id = %v, want ErrCommentChanged", err)
+	}
+	if err := repos.Comments.SetCommentStatus(ctx, first.ID, first.UpdatedAt, entities.CommentRejected); !errors.Is(err, models.ErrCommentChanged) {
+		t.Fatalf("SetCommentStatus of a moderated comment = %v, want ErrCommentChanged", err)
+	}
+	list, err := repos.Comments.ListComments(ctx, news.ID)
+	if err != nil || !slices.Equal(ids(list), []int{first.ID, reply.ID}) || list[1].ParentID != first.ID {
+		t.Fatalf("ListComments = %+v, %v", list, err)
+	}
+	if count, err := repos.Comments.CountComments(ctx, news.ID); err != nil || count != 2 {
+		t.Fatalf("CountComments = %d, %v", count, err)
+	}
+	if counts, err := repos.Comments.CountCommentsByNews(ctx, []int{news.ID, news.ID + 100}); err != nil || len(counts) != 1 || counts[news.ID] != 2 {
+		t.Fatalf("CountCommentsByNews = %v, %v", counts, err)
+	}
+
+	reply.Body = "edited"
+	if err := repos.Comments.EditComment(ctx, &reply); err != nil {
+		t.Fatalf("EditComment: %v", err)
+	}
+	if reply.Status != entities.CommentPending || reply.UpdatedAt.Before(reply.CreatedAt) {
+		t.Fatalf("EditComment should send the comment back to moderation: %+v", reply)
+	}
+	found, _ = repos.Comments.FindComment(ctx, reply.ID)
+	if found.Body != "edited" || found.Status != entities.CommentPending {
+		t.Fatalf("FindComment after edit = %+v", found)
+	}
+	reply.Body = "edited again"
+	seen := found.UpdatedAt
+	time.Sleep(time.Millisecond)
+	if err := repos.Comments.EditComment(ctx, &reply); err != nil {
+		t.Fatalf("EditComment: %v", err)
+	}
+	if err := repos.Comments.SetCommentStatus(ctx, reply.ID, seen, entities.CommentApproved); !errors.Is(err, models.ErrCommentChanged) {
+		t.Fatalf("SetCommentStatus of a comment edited since = %v, want ErrCommentChanged", err)
+	}
+
+	if err := repos.Comments.DeleteComment(ctx, first.ID); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	found, _ = repos.Comments.FindComment(ctx, first.ID)
+	if found.Body != "" || found.DeletedAt == nil || found.Status != entities.CommentApproved {
+		t.Fatalf("FindComment after delete = %+v", found)
+	}
+	if list, _ := repos.Comments.ListComments(ctx, news.ID); !slices.Equal(ids(list), []int{first.ID}) {
+		t.Fatalf("deleted comments should keep their place: %v", ids(list))
+	}
+	if count, _ := repos.Comments.CountComments(ctx, news.ID); count != 0 {
+		t.Fatalf("CountComments after delete = %d", count)
+	}
+	if counts, _ := repos.Comments.CountCommentsByNews(ctx, []int{news.ID}); len(counts) != 0 {
+		t.Fatalf("CountCommentsByNews after delete = %v", counts)
+	}
+	if err := repos.Comments.EditComment(ctx, &first); err == nil {
+		t.Fatal("EditComment should fail for a deleted comment")
+	}
+}
+
+func testReactions(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	news := entities.News{Title: "liked", Content: "c", Published: true}
+	if err := repos.News.CreateNews(ctx, &news); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	var users [2]entities.User
+	for i, email := range []string{"a@example.com", "b@example.com"} {
+		users[i] = entities.User{Email: email, Password: "hash"}
+		if err := repos.Users.CreateUser(ctx, &users[i]); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+
+	if counts, err := repos.Reactions.CountReactions(ctx, news.ID); err != nil || len(counts) != 0 {
+		t.Fatalf("CountReactions without reactions = %v, %v", counts, err)
+	}
+	for _, r := range []struct {
+		user int
+		kind string
+	}{
+		{users[0].ID, "like"}, {users[0].ID, "like"}, {users[0].ID, "wow"}, {users[1].ID, "like"},
+	} {
+		if err := repos.Reactions.AddReaction(ctx, news.ID, r.user, r.kind); err != nil {
+			t.Fatalf("AddReaction(%d, %s): %v", r.user, r.kind, err)
+		}
+	}
+	if err := repos.Reactions.AddReaction(ctx, news.ID+100, users[0].ID, "like"); err == nil {
+		t.Fatal("AddReaction should fail for a missing news")
+	}
+	if err := repos.Reactions.AddReaction(ctx, news.ID, users[1].ID+100, "like"); err == nil {
+		t.Fatal("AddReaction should fail for a missing user")
+	}
+
+	counts, err := repos.Reactions.CountReactions(ctx, news.ID)
+	if err != nil || len(counts) != 2 || counts["like"] != 2 || counts["wow"] != 1 {
+		t.Fatalf("CountReactions = %v, %v", counts, err)
+	}
+	byNews, err := repos.Reactions.CountReactionsByNews(ctx, []int{news.ID, news.ID + 100})
+	if err != nil || len(byNews) != 1 || !maps.Equal(byNews[news.ID], counts) {
+		t.Fatalf("CountReactionsByNews = %v, %v", byNews, err)
+	}
+	kinds, err := repos.Reactions.ListUserReactions(ctx, news.ID, users[0].ID)
+	if err != nil || !slices.Equal(kinds, []string{"like", "wow"}) {
+		t.Fatalf("ListUserReactions = %v, %v", kinds, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := repos.Reactions.RemoveReaction(ctx, news.ID, users[0].ID, "like"); err != nil {
+			t.Fatalf("RemoveReaction: %v", err)
+		}
+	}
+	if counts, _ := repos.Reactions.CountReactions(ctx, news.ID); counts["like"] != 1 || counts["wow"] != 1 {
+		t.Fatalf("CountReactions after remove = %v", counts)
+	}
+	if kinds, _ := repos.Reactions.ListUserReactions(ctx, news.ID, users[0].ID); !slices.Equal(kinds, []string{"wow"}) {
+		t.Fatalf("ListUserReactions after remove = %v", kinds)
+	}
+}
+
+func testBookmarks(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	user := entities.User{Email: "reader@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	var news [3]entities.News
+	for i := range news {
+		news[i] = entities.News{Title: "news", Content: "c", Published: i != 1}
+		if err := repos.News.CreateNews(ctx, &news[i]); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+	}
+
+	// news[0] is bookmarked twice, the first bookmark counts.
+	for _, i := range []int{0, 1, 2, 0} {
+		if err := repos.Bookmarks.AddBookmark(ctx, user.ID, news[i].ID); err != nil {
+			t.Fatalf("AddBookmark: %v", err)
+		}
+	}
+	if err := repos.Bookmarks.AddBookmark(ctx, user.ID, news[2].ID+100); err == nil {
+		t.Fatal("AddBookmark should fail for a missing news")
+	}
+
+	ids := func(list []entities.News) []int {
+		var out []int
+		for _, n := range list {
+			out = append(out, n.ID)
+		}
+		return out
+	}
+	list, err := repos.Bookmarks.ListBookmarkedNews(ctx, user.ID, 10, 0)
+	if err != nil || !slices.Equal(ids(list), []int{news[2].ID, news[0].ID}) {
+		t.Fatalf("ListBookmarkedNews = %v, %v", ids(list), err)
+	}
+	if list[0] != news[2] {
+		t.Fatalf("ListBookmarkedNews should return whole news: %+v", list[0])
+	}
+	if list, _ := repos.Bookmarks.ListBookmarkedNews(ctx, user.ID, 1, 1); !slices.Equal(ids(list), []int{news[0].ID}) {
+		t.Fatalf("ListBookmarkedNews(1, 1) = %v", ids(list))
+	}
+	if list, _ := repos.Bookmarks.ListBookmarkedNews(ctx, user.ID+100, 10, 0); len(list) != 0 {
+		t.Fatalf("ListBookmarkedNews of another user = %v", ids(list))
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := repos.Bookmarks.RemoveBookmark(ctx, user.ID, news[2].ID); err != nil {
+			t.Fatalf("RemoveBookmark: %v", err)
+		}
+	}
+	if list, _ := repos.Bookmarks.ListBookmarkedNews(ctx, user.ID, 10, 0); !slices.Equal(ids(list), []int{news[0].ID}) {
+		t.Fatalf("ListBookmarkedNews after remove = %v", ids(list))
+	}
+}
+
+func testSubscriptions(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	user := entities.User{Email: "reader@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	// Category 2 has no news yet, subscribing creates it.
+	for _, category := range []int{3, 1, 2, 1} {
+		if err := repos.Subscriptions.Subscribe(ctx, user.ID, category); err != nil {
+			t.Fatalf("Subscribe(%d): %v", category, err)
+		}
+	}
+	if err := repos.Subscriptions.Subscribe(ctx, user.ID+100, 1); err == nil {
+		t.Fatal("Subscribe should fail for a missing user")
+	}
+	categories, err := repos.Subscriptions.ListSubscriptions(ctx, user.ID)
+	if err != nil || !slices.Equal(categories, []int{1, 2, 3}) {
+		t.Fatalf("ListSubscriptions = %v, %v", categories, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := repos.Subscriptions.Unsubscribe(ctx, user.ID, 3); err != nil {
+			t.Fatalf("Unsubscribe: %v", err)
+		}
+	}
+	if err := repos.Subscriptions.Unsubscribe(ctx, user.ID, 42); err != nil {
+		t.Fatalf("Unsubscribe from a missing category: %v", err)
+	}
+	if categories, _ := repos.Subscriptions.ListSubscriptions(ctx, user.ID); !slices.Equal(categories, []int{1, 2}) {
+		t.Fatalf("ListSubscriptions after unsubscribe = %v", categories)
+	}
+
+	// News 0 and 3 are in followed categories, news 4 in two of them,
+	// news 1 is a draft and news 2 in an unfollowed category.
+	var news [5]entities.News
+	for i, categories := range [][]int{{1}, {2}, {3}, {2}, {1, 2}} {
+		news[i] = entities.News{Title: "news", Content: "c", Published: i != 1}
+		if err := repos.News.CreateNews(ctx, &news[i]); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+		for _, name := range categories {
+			category := entities.Categorie{Name: name}
+			if err := repos.Categories.CreateCategorie(ctx, &category); err != nil {
+				t.Fatalf("CreateCategorie: %v", err)
+			}
+			if err := repos.NewsCategories.Create(ctx, &entities.NewsCategories{CategoryID: category.ID, NewsID: news[i].ID}); err != nil {
+				t.Fatalf("Create news category: %v", err)
+			}
+		}
+	}
+
+	ids := func(list []entities.News) []int {
+		var out []int
+		for _, n := range list {
+			out = append(out, n.ID)
+		}
+		return out
+	}
+	feed, err := repos.Subscriptions.ListFeedNews(ctx, user.ID, 0, 10)
+	if err != nil || !slices.Equal(ids(feed), []int{news[4].ID, news[3].ID, news[0].ID}) {
+		t.Fatalf("ListFeedNews = %v, %v", ids(feed), err)
+	}
+	if feed[0] != news[4] {
+		t.Fatalf("ListFeedNews should return whole news: %+v", feed[0])
+	}
+	if feed, _ := repos.Subscriptions.ListFeedNews(ctx, user.ID, 0, 2); !slices.Equal(ids(feed), []int{news[4].ID, news[3].ID}) {
+		t.Fatalf("first page of ListFeedNews = %v", ids(feed))
+	}
+	if feed, _ := repos.Subscriptions.ListFeedNews(ctx, user.ID, news[3].ID, 2); !slices.Equal(ids(feed), []int{news[0].ID}) {
+		t.Fatalf("ListFeedNews before %d = %v", news[3].ID, ids(feed))
+	}
+	if feed, _ := repos.Subscriptions.ListFeedNews(ctx, user.ID+100, 0, 10); len(feed) != 0 {
+		t.Fatalf("ListFeedNews without subscriptions = %v", ids(feed))
+	}
+}
+
+func testWebhooks(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	owner := entities.User{Email: "hooks@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, &owner); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	first := entities.Webhook{UserID: owner.ID, URL: "https://a.example.com/hook", Secret: "s1", Events: []string{entities.EventNewsCreated, entities.EventNewsUpdated}}
+	second := entities.Webhook{UserID: owner.ID, URL: "https://b.example.com/hook", Secret: "s2", Events: []string{entities.EventNewsUpdated}}
+	for _, webhook := range []*entities.Webhook{&first, &second} {
+		if err := repos.Webhooks.CreateWebhook(ctx, webhook); err != nil {
+			t.Fatalf("CreateWebhook: %v", err)
+		}
+	}
+	if first.ID == 0 || first.CreatedAt.IsZero() {
+		t.Fatalf("CreateWebhook should set id and created_at: %+v", first)
+	}
+	found, err := repos.Webhooks.FindWebhook(ctx, first.ID)
+	if err != nil || found.UserID != owner.ID || found.URL != first.URL || found.Secret != first.Secret || !slices.Equal(found.Events, first.Events) {
+		t.Fatalf("FindWebhook = %+v, %v", found, err)
+	}
+	if err := repos.Webhooks.CreateWebhook(ctx, &entities.Webhook{UserID: owner.ID + 100, URL: "https://c.example.com/hook", Secret: "s", Events: []string{entities.EventNewsCreated}}); err == nil {
+		t.Fatal("CreateWebhook should fail for a missing owner")
+	}
+	if _, err := repos.Webhooks.FindWebhook(ctx, second.ID+100); err == nil {
+		t.Fatal("FindWebhook should fail for a missing id")
+	}
+
+	if err := repos.Webhooks.EnqueueEvent(ctx, entities.EventNewsCreated, []byte(`{"n":1}`)); err != nil {
+		t.Fatalf("EnqueueEvent: %v", err)
+	}
+	if err := repos.Webhooks.EnqueueEvent(ctx, entities.EventNewsUpdated, []byte(`{"n":2}`)); err != nil {
+		t.Fatalf("EnqueueEvent: %v", err)
+	}
+	if err := repos.Webhooks.EnqueueEvent(ctx, "news.archived", []byte(`{"n":3}`)); err != nil {
+		t.Fatalf("EnqueueEvent without subscribers: %v", err)
+	}
+
+	ids := func(list []entities.WebhookDelivery) []int {
+		var out []int
+		for _, d := range list {
+			out = append(out, d.ID)
+		}
+		return out
+	}
+	// The clocks of the test and the database may differ a little.
+	now := time.Now().Add(time.Minute)
+	claimed, err := repos.Webhooks.ClaimDeliveries(ctx, now, time.Hour, 2)
+	if err != nil || len(claimed) != 2 || claimed[0].WebhookID != first.ID || string(claimed[0].Payload) != `{"n":1}` ||
+		claimed[0].Status != entities.DeliveryPending || claimed[0].Event != entities.EventNewsCreated {
+		t.Fatalf("ClaimDeliveries = %+v, %v", claimed, err)
+	}
+	rest, err := repos.Webhooks.ClaimDeliveries(ctx, now, time.Hour, 2)
+	if err != nil || len(rest) != 1 || slices.Contains(ids(claimed), rest[0].ID) {
+		t.Fatalf("second ClaimDeliveries = %v, %v after %v", ids(rest), err, ids(claimed))
+	}
+	if again, _ := repos.Webhooks.ClaimDeliveries(ctx, now, time.Hour, 2); len(again) != 0 {
+		t.Fatalf("claimed deliveries are leased, got %v", ids(again))
+	}
+	if again, _ := repos.Webhooks.ClaimDeliveries(ctx, now.Add(2*time.Hour), time.Hour, 10); len(again) != 3 {
+		t.Fatalf("expired leases should be claimed again, got %v", ids(again))
+	}
+
+	delivered := claimed[0]
+	deliveredAt := time.Now()
+	delivered.Status, delivered.Attempts, delivered.DeliveredAt = entities.DeliveryDelivered, 1, &deliveredAt
+	attempt := entities.WebhookAttempt{StatusCode: 200, Duration: 15 * time.Millisecond}
+	if err := repos.Webhooks.RecordAttempt(ctx, &delivered, &attempt); err != nil {
+		t.Fatalf("RecordAttempt: %v", err)
+	}
+	if attempt.ID == 0 || attempt.DeliveryID != delivered.ID || attempt.CreatedAt.IsZero() {
+		t.Fatalf("RecordAttempt should set id, delivery and created_at: %+v", attempt)
+	}
+	stored, err := repos.Webhooks.FindDelivery(ctx, delivered.ID)
+	if err != nil || stored.Status != entities.DeliveryDelivered || stored.Attempts != 1 || stored.DeliveredAt == nil {
+		t.Fatalf("FindDelivery after success = %+v, %v", stored, err)
+	}
+	attempts, err := repos.Webhooks.ListAttempts(ctx, delivered.ID)
+	if err != nil || len(attempts) != 1 || attempts[0].StatusCode != 200 || attempts[0].Duration != 15*time.Millisecond {
+		t.Fatalf("ListAttempts = %+v, %v", attempts, err)
+	}
+
+	dead := claimed[1]
+	dead.Status, dead.Attempts, dead.LastError = entities.DeliveryDead, 3, "HTTP 500"
+	if err := repos.Webhooks.RecordAttempt(ctx, &dead, &entities.WebhookAttempt{StatusCode: 500, Error: "HTTP 500"}); err != nil {
+		t.Fatalf("RecordAttempt: %v", err)
+	}
+	if stored, _ := repos.Webhooks.FindDelivery(ctx, dead.ID); stored.Status != entities.DeliveryDead || stored.LastError != "HTTP 500" {
+		t.Fatalf("FindDelivery after failure = %+v", stored)
+	}
+	if err := repos.Webhooks.RetryDelivery(ctx, dead.ID); err != nil {
+		t.Fatalf("RetryDelivery: %v", err)
+	}
+	if stored, _ := repos.Webhooks.FindDelivery(ctx, dead.ID); stored.Status != entities.DeliveryPending || stored.Attempts != 0 {
+		t.Fatalf("FindDelivery after retry = %+v", stored)
+	}
+	if err := repos.Webhooks.RetryDelivery(ctx, delivered.ID); err == nil {
+		t.Fatal("RetryDelivery should only accept dead deliveries")
+	}
+
+	list, err := repos.Webhooks.ListDeliveries(ctx, first.ID, 10, 0)
+	if err != nil || !slices.Equal(ids(list), []int{dead.ID, delivered.ID}) {
+		t.Fatalf("ListDeliveries = %v, %v", ids(list), err)
+	}
+	if list, _ := repos.Webhooks.ListDeliveries(ctx, second.ID, 10, 0); len(list) != 1 || string(list[0].Payload) != `{"n":2}` {
+		t.Fatalf("ListDeliveries of the second webhook = %+v", list)
+	}
+
+	if err := repos.Webhooks.DeleteWebhook(ctx, first.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if _, err := repos.Webhooks.FindDelivery(ctx, delivered.ID); err == nil {
+		t.Fatal("deliveries should be deleted with their webhook")
+	}
+	webhooks, err := repos.Webhooks.ListWebhooks(ctx, owner.ID)
+	if err != nil || len(webhooks) != 1 || webhooks[0].ID != second.ID {
+		t.Fatalf("ListWebhooks = %+v, %v", webhooks, err)
+	}
+	if webhooks, err := repos.Webhooks.ListWebhooks(ctx, owner.ID+100); err != nil || len(webhooks) != 0 {
+		t.Fatalf("ListWebhooks of another user = %+v, %v", webhooks, err)
+	}
+	if err := repos.Users.DeleteUserById(ctx, owner.ID); err != nil {
+		t.Fatalf("DeleteUserById: %v", err)
+	}
+	if _, err := repos.Webhooks.FindWebhook(ctx, second.ID); err == nil {
+		t.Fatal("webhooks should be deleted with their owner")
+	}
+}
+
+func testOutbox(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	// The clocks of the test and the database may differ a little.
+	now := time.Now().Add(time.Minute)
+
+	user := entities.User{Email: "outbox@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	published := entities.News{Title: "published", Published: true}
+	draft := entities.News{Title: "draft"}
+	for _, news := range []*entities.News{&published, &draft} {
+		if err := repos.News.CreateNews(ctx, news); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+	}
+	draft.Published = true
+	if err := repos.News.UpdateNews(ctx, &draft); err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+	draft.Title = "edited"
+	if err := repos.News.UpdateNews(ctx, &draft); err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+
+	type event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			ID    int    `json:"id"`
+			Email string `json:"email"`
+			Slug  string `json:"slug"`
+		} `json:"data"`
+	}
+	claimed, err := repos.Outbox.ClaimOutbox(ctx, now, time.Hour, 10)
+	if err != nil || len(claimed) != 3 {
+		t.Fatalf("ClaimOutbox = %+v, %v, want user.registered and two news.published", claimed, err)
+	}
+	want := []struct {
+		topic string
+		id    int
+	}{{entities.EventUserRegistered, user.ID}, {entities.EventNewsPublished, published.ID}, {entities.EventNewsPublished, draft.ID}}
+	for i, msg := range claimed {
+		var e event
+		if err := json.Unmarshal(msg.Payload, &e); err != nil {
+			t.Fatalf("payload %s: %v", msg.Payload, err)
+		}
+		if msg.Topic != want[i].topic || e.Type != msg.Topic || e.Data.ID != want[i].id || msg.Key == "" || e.ID != msg.Key {
+			t.Fatalf("message %d = %+v with payload %s, want %s of %d", i, msg, msg.Payload, want[i].topic, want[i].id)
+		}
+	}
+	if claimed[0].Key == claimed[1].Key {
+		t.Fatal("messages must have distinct idempotency keys")
+	}
+	if again, _ := repos.Outbox.ClaimOutbox(ctx, now, time.Hour, 10); len(again) != 0 {
+		t.Fatalf("claimed messages are leased, got %+v", again)
+	}
+
+	if err := repos.Outbox.MarkPublished(ctx, claimed[0].ID); err != nil {
+		t.Fatalf("MarkPublished: %v", err)
+	}
+	if err := repos.Outbox.MarkFailed(ctx, claimed[1].ID, "sink down", now.Add(3*time.Hour)); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	later, err := repos.Outbox.ClaimOutbox(ctx, now.Add(2*time.Hour), 10*time.Hour, 10)
+	if err != nil || len(later) != 1 || later[0].ID != claimed[2].ID {
+		t.Fatalf("ClaimOutbox after the lease = %+v, %v, want only the unpublished message without a failure", later, err)
+	}
+	retried, err := repos.Outbox.ClaimOutbox(ctx, now.Add(3*time.Hour), time.Hour, 10)
+	if err != nil || len(retried) != 1 || retried[0].ID != claimed[1].ID || retried[0].Attempts != 1 || retried[0].LastError != "sink down" {
+		t.Fatalf("ClaimOutbox after the backoff = %+v, %v", retried, err)
+	}
+
+	if n, err := repos.Outbox.PurgePublished(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("PurgePublished of recent messages = %d, %v", n, err)
+	}
+	if n, err := repos.Outbox.PurgePublished(ctx, now); err != nil || n != 1 {
+		t.Fatalf("PurgePublished = %d, %v", n, err)
+	}
+}
+
+func testNewsEvents(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	if last, err := repos.NewsEvents.LastNewsEventID(ctx); err != nil || last != 0 {
+		t.Fatalf("LastNewsEventID on empty repository = %d, %v", last, err)
+	}
+
+	listenCtx, cancel := context.WithCancel(ctx)
+	notified := make(chan struct{}, 10)
+	listened := make(chan error, 1)
+	go func() {
+		listened <- repos.NewsEvents.Listen(listenCtx, func() { notified <- struct{}{} })
+	}()
+	waitNotified := func(what string) {
+		t.Helper()
+		select {
+		case <-notified:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no notification %s", what)
+		}
+	}
+	waitNotified("once listening")
+
+	first := entities.News{Title: "first"}
+	second := entities.News{Title: "second", Published: true}
+	for _, news := range []*entities.News{&first, &second} {
+		if err := repos.News.CreateNews(ctx, news); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+	}
+	waitNotified("after CreateNews")
+	first.Title = "edited"
+	if err := repos.News.UpdateNews(ctx, &first); err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+	waitNotified("after UpdateNews")
+	cancel()
+	if err := <-listened; err == nil {
+		t.Fatal("Listen should return the error of the cancelled context")
+	}
+
+	events, err := repos.NewsEvents.ListNewsEvents(ctx, 0, 10)
+	if err != nil || len(events) != 3 {
+		t.Fatalf("ListNewsEvents = %+v, %v", events, err)
+	}
+	want := []struct {
+		newsID int
+		kind   string
+	}{{first.ID, entities.EventNewsCreated}, {second.ID, entities.EventNewsCreated}, {first.ID, entities.EventNewsUpdated}}
+	for i, event := range events {
+		if event.NewsID != want[i].newsID || event.Kind != want[i].kind || event.CreatedAt.IsZero() || (i > 0 && event.ID <= events[i-1].ID) {
+			t.Fatalf("event %d = %+v, want %+v in id order", i, event, want[i])
+		}
+	}
+	if after, _ := repos.NewsEvents.ListNewsEvents(ctx, events[0].ID, 1); len(after) != 1 || after[0].ID != events[1].ID {
+		t.Fatalf("ListNewsEvents after the first = %+v", after)
+	}
+	if last, err := repos.NewsEvents.LastNewsEventID(ctx); err != nil || last != events[2].ID {
+		t.Fatalf("LastNewsEventID = %d, %v", last, err)
+	}
+
+	if n, err := repos.NewsEvents.DeleteNewsEvents(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("DeleteNewsEvents of recent events = %d, %v", n, err)
+	}
+	if n, err := repos.NewsEvents.DeleteNewsEvents(ctx, time.Now().Add(time.Hour)); err != nil || n != 3 {
+		t.Fatalf("DeleteNewsEvents = %d, %v", n, err)
+	}
+}
+
+func testUsers(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	user := entities.User{Email: "a@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	other := entities.User{Email: "b@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, &other); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if user.ID == 0 || other.ID == user.ID {
+		t.Fatalf("unexpected ids %d and %d", user.ID, other.ID)
+	}
+	if err := repos.Users.CreateUser(ctx, &entities.User{Email: "a@example.com", Password: "x"}); err == nil {
+		t.Fatal("CreateUser should reject a duplicate email")
+	}
+
+	found, err := repos.Users.FindUserByEmail(ctx, "a@example.com")
+	if err != nil || found != user {
+		t.Fatalf("FindUserByEmail = %+v, %v", found, err)
+	}
+	if _, err := repos.Users.FindUserByEmail(ctx, "missing@example.com"); err == nil {
+		t.Fatal("FindUserByEmail should fail for a missing email")
+	}
+
+	other.Email = "a@example.com"
+	if err := repos.Users.UpdateUser(ctx, &other); err == nil {
+		t.Fatal("UpdateUser should reject a duplicate email")
+	}
+	other.Email = "c@example.com"
+	other.EmailVerified = true
+	other.PasswordChangedAt = time.Now().Truncate(time.Microsecond)
+	if err := repos.Users.UpdateUser(ctx, &other); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	found, err = repos.Users.FindUserById(ctx, other.ID)
+	if err != nil || !found.PasswordChangedAt.Equal(other.PasswordChangedAt) {
+		t.Fatalf("FindUserById = %+v, %v", found, err)
+	}
+	found.PasswordChangedAt = other.PasswordChangedAt
+	if found != other {
+		t.Fatalf("FindUserById = %+v, want %+v", found, other)
+	}
+
+	if err := repos.Users.DeleteUserById(ctx, other.ID); err != nil {
+		t.Fatalf("DeleteUserById: %v", err)
+	}
+	if _, err := repos.Users.FindUserById(ctx, other.ID); err == nil {
+		t.Fatal("FindUserById should fail for a deleted user")
+	}
+}
+
+func testUserTokens(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	start := time.Now()
+
+	user := entities.User{Email: "a@example.com", Password: "hash"}
+	other := entities.User{Email: "b@example.com", Password: "hash"}
+	for _, u := range []*entities.User{&user, &other} {
+		if err := repos.Users.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	create := func(userID int, purpose, hash string, ttl time.Duration) entities.UserToken {
+		t.Helper()
+		token := entities.UserToken{UserID: userID, Purpose: purpose, Email: "a@example.com",
+			Hash: []byte(hash), ExpiresAt: start.Add(ttl)}
+		if err := repos.UserTokens.CreateToken(ctx, &token); err != nil {
+			t.Fatalf("CreateToken: %v", err)
+		}
+		if token.ID == 0 || token.CreatedAt.IsZero() {
+			t.Fatalf("CreateToken did not fill in %+v", token)
+		}
+		return token
+	}
+	consume := func(purpose, hash string, at time.Time) (entities.UserToken, error) {
+		return repos.UserTokens.ConsumeToken(ctx, purpose, []byte(hash), at)
+	}
+
+	first := create(user.ID, entities.TokenResetPassword, "first", time.Hour)
+	create(user.ID, entities.TokenResetPassword, "second", time.Hour)
+	create(user.ID, entities.TokenVerifyEmail, "verify", time.Hour)
+	create(other.ID, entities.TokenResetPassword, "other", time.Hour)
+	create(user.ID, entities.TokenResetPassword, "expired", time.Minute)
+
+	if _, err := consume(entities.TokenResetPassword, "missing", start); !errors.Is(err, models.ErrTokenInvalid) {
+		t.Fatalf("ConsumeToken of a missing token = %v", err)
+	}
+	if _, err := consume(entities.TokenVerifyEmail, "first", start); !errors.Is(err, models.ErrTokenInvalid) {
+		t.Fatalf("ConsumeToken for another purpose = %v", err)
+	}
+	if _, err := consume(entities.TokenResetPassword, "expired", start.Add(time.Minute)); !errors.Is(err, models.ErrTokenInvalid) {
+		t.Fatalf("ConsumeToken of an expired token = %v", err)
+	}
+
+	token, err := consume(entities.TokenResetPassword, "first", start)
+	if err != nil {
+		t.Fatalf("ConsumeToken: %v", err)
+	}
+	if token.ID != first.ID || token.UserID != user.ID || token.Email != "a@example.com" ||
+		string(token.Hash) != "first" || token.UsedAt == nil || !token.ExpiresAt.Equal(first.ExpiresAt.Truncate(time.Microsecond)) {
+		t.Fatalf("ConsumeToken = %+v", token)
+	}
+	if _, err := consume(entities.TokenResetPassword, "first", start); !errors.Is(err, models.ErrTokenInvalid) {
+		t.Fatalf("ConsumeToken of a used token = %v", err)
+	}
+	// Using a token uses up the other tokens of the user for the purpose.
+	if _, err := consume(entities.TokenResetPassword, "second", start); !errors.Is(err, models.ErrTokenInvalid) {
+		t.Fatalf("ConsumeToken of a used up token = %v", err)
+	}
+	if _, err := consume(entities.TokenVerifyEmail, "verify", start); err != nil {
+		t.Fatalf("ConsumeToken for another purpose of the user: %v", err)
+	}
+	if _, err := consume(entities.TokenResetPassword, "other", start); err != nil {
+		t.Fatalf("ConsumeToken of another user: %v", err)
+	}
+
+	if err := repos.UserTokens.CreateToken(ctx, &entities.UserToken{UserID: user.ID + other.ID + 1,
+		Purpose: entities.TokenVerifyEmail, Hash: []byte("orphan"), ExpiresAt: start.Add(time.Hour)}); err == nil {
+		t.Fatal("CreateToken should fail for a missing user")
+	}
+	create(user.ID, entities.TokenVerifyEmail, "deleted", time.Hour)
+	if err := repos.Users.DeleteUserById(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUserById: %v", err)
+	}
+	if _, err := consume(entities.TokenVerifyEmail, "deleted", start); !errors.Is(err, models.ErrTokenInvalid) {
+		t.Fatalf("ConsumeToken of a deleted user = %v", err)
+	}
+}
+
+func testConcurrentWrites(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	const writers = 20
+
+	var wg sync.WaitGroup
+	ids := make([]int, writers)
+	errs := make([]error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			news := entities.News{Title: "concurrent", Content: "content"}
+			errs[i] = repos.News.CreateNews(ctx, &news)
+			ids[i] = news.ID
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+	}
+	slices.Sort(ids)
+	if len(slices.Compact(ids)) != writers {
+		t.Fatal("concurrent CreateNews returned duplicate ids")
+	}
+
+	list, err := repos.News.ListNews(ctx)
+	if err != nil || len(list) != writers {
+		t.Fatalf("ListNews returned %d news, %v", len(list), err)
+	}
+}
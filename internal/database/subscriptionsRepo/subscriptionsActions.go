@@ -0,0 +1,91 @@
+package subscriptionsrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/database"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type SubscriptionsRepository struct {
+	db  database.DBTX
+	log *slog.Logger
+}
+
+func NewSubscriptionsRepository(db database.DBTX, log *slog.Logger) *SubscriptionsRepository {
+	return &SubscriptionsRepository{db, log}
+}
+
+func (s *SubscriptionsRepository) Subscribe(ctx context.Context, userID, category int) error {
+	_, err := s.db.Exec(ctx, `
+	WITH c AS (
+		INSERT INTO Categories (name) VALUES ($2)
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id
+	)
+	INSERT INTO Subscriptions (user_id, category_id) SELECT $1, id FROM c
+	ON CONFLICT DO NOTHING`, userID, category)
+	if err != nil {
+		s.log.Error("failed to subscribe", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (s *SubscriptionsRepository) Unsubscribe(ctx context.Context, userID, category int) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM Subscriptions
+	WHERE user_id = $1 AND category_id IN (SELECT id FROM Categories WHERE name = $2)`, userID, category)
+	if err != nil {
+		s.log.Error("failed to unsubscribe", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (s *SubscriptionsRepository) ListSubscriptions(ctx context.Context, userID int) ([]int, error) {
+	rows, err := s.db.Query(ctx, `SELECT c.name FROM Subscriptions s
+	JOIN Categories c ON c.id = s.category_id
+	WHERE s.user_id = $1 ORDER BY c.name`, userID)
+	if err != nil {
+		s.log.Error("failed to list subscriptions", errMsg.Err(err))
+		return nil, err
+	}
+	categories, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		s.log.Error("failed to scan subscriptions", errMsg.Err(err))
+		return nil, err
+	}
+	return categories, nil
+}
+
+// ListFeedNews pages by id rather than by offset, a page costs the same
+// however deep into the feed it is.
+func (s *SubscriptionsRepository) ListFeedNews(ctx context.Context, userID, before, limit int) ([]entities.News, error) {
+	rows, err := s.db.Query(ctx, `
+	SELECT id, title, slug, summary, content, content_format, content_html, published, created_at, updated_at
+	FROM News
+	WHERE published AND ($2 = 0 OR id < $2) AND id IN (
+		SELECT nc.news_id
+		FROM Subscriptions s
+		JOIN NewsCategories nc ON nc.category_id = s.category_id
+		WHERE s.user_id = $1)
+	ORDER BY id DESC LIMIT $3`, userID, before, limit)
+	if err != nil {
+		s.log.Error("failed to list feed news", errMsg.Err(err))
+		return nil, err
+	}
+	news, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.News, error) {
+		var news entities.News
+		err := row.Scan(&news.ID, &news.Title, &news.Slug, &news.Summary, &news.Content, &news.ContentFormat,
+			&news.ContentHTML, &news.Published, &news.CreatedAt, &news.UpdatedAt)
+		return news, err
+	})
+	if err != nil {
+		s.log.Error("failed to scan feed news", errMsg.Err(err))
+		return nil, err
+	}
+	return news, nil
+}
@@ -0,0 +1,39 @@
+package subscriptionsrepo
+
+import (
+	"context"
+	categoriesrepo "news-service/internal/database/categoriesRepo"
+	"news-service/internal/database/dbtest"
+	usersrepo "news-service/internal/database/usersRepo"
+	"news-service/internal/entities"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestSubscribeReusesCategory(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	categories := categoriesrepo.NewCategoriesRepository(pg.Db, dbtest.Logger())
+	users := usersrepo.NewUserRepository(pg.Db, dbtest.Logger())
+	repo := NewSubscriptionsRepository(pg.Db, dbtest.Logger())
+
+	category := entities.Categorie{Name: 7}
+	if err := categories.CreateCategorie(ctx, &category); err != nil {
+		t.Fatalf("CreateCategorie: %v", err)
+	}
+	user := entities.User{Email: "reader@example.com", Password: "hash"}
+	if err := users.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := repo.Subscribe(ctx, user.ID, 7); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	var categoryID, count int
+	err := pg.Db.QueryRow(ctx, `SELECT min(category_id), (SELECT count(*) FROM Categories) FROM Subscriptions`).Scan(&categoryID, &count)
+	if err != nil || categoryID != category.ID || count != 1 {
+		t.Fatalf("subscription to category %d, %d categories, %v; want %d and 1", categoryID, count, err, category.ID)
+	}
+}
@@ -0,0 +1,128 @@
+package tagsrepo
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/database"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type TagsRepository struct {
+	db  database.DBTX
+	log *slog.Logger
+}
+
+func NewTagsRepository(db database.DBTX, log *slog.Logger) *TagsRepository {
+	return &TagsRepository{db, log}
+}
+
+func (t *TagsRepository) SetNewsTags(ctx context.Context, newsID int, tags []string) error {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		t.log.Error("failed to begin transaction", errMsg.Err(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM NewsTags WHERE news_id = $1`, newsID)
+	if err != nil {
+		t.log.Error("failed to delete news tags", errMsg.Err(err))
+		return err
+	}
+
+	if len(tags) > 0 {
+		_, err = tx.Exec(ctx, `INSERT INTO Tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`, tags)
+		if err != nil {
+			t.log.Error("failed to create tags", errMsg.Err(err))
+			return err
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO NewsTags (news_id, tag_id) SELECT $1, id FROM Tags WHERE name = ANY($2)`, newsID, tags)
+		if err != nil {
+			t.log.Error("failed to tag news", errMsg.Err(err))
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.log.Error("failed to commit news tags", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (t *TagsRepository) ListNewsTags(ctx context.Context, newsID int) ([]string, error) {
+	rows, err := t.db.Query(ctx, `
+	SELECT t.name
+	FROM Tags t
+	JOIN NewsTags nt ON nt.tag_id = t.id
+	WHERE nt.news_id = $1
+	ORDER BY t.name COLLATE "C"`, newsID)
+	if err != nil {
+		t.log.Error("failed to list news tags", errMsg.Err(err))
+		return nil, err
+	}
+	tags, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.log.Error("failed to scan news tags", errMsg.Err(err))
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (t *TagsRepository) ListTagsByNews(ctx context.Context, newsIDs []int) (map[int][]string, error) {
+	rows, err := t.db.Query(ctx, `
+	SELECT nt.news_id, array_agg(t.name ORDER BY t.name COLLATE "C")
+	FROM NewsTags nt
+	JOIN Tags t ON t.id = nt.tag_id
+	WHERE nt.news_id = ANY($1)
+	GROUP BY nt.news_id`, newsIDs)
+	if err != nil {
+		t.log.Error("failed to list news tags", errMsg.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[int][]string, len(newsIDs))
+	for rows.Next() {
+		var newsID int
+		var names []string
+		if err := rows.Scan(&newsID, &names); err != nil {
+			t.log.Error("failed to scan news tags", errMsg.Err(err))
+			return nil, err
+		}
+		tags[newsID] = names
+	}
+	if err := rows.Err(); err != nil {
+		t.log.Error("failed to list news tags", errMsg.Err(err))
+		return nil, err
+	}
+	return tags, nil
+}
+
+// likeEscaper escapes the LIKE wildcards of a prefix.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (t *TagsRepository) ListTags(ctx context.Context, prefix string, limit int) ([]entities.Tag, error) {
+	rows, err := t.db.Query(ctx, `
+	SELECT t.id, t.name, count(*)
+	FROM Tags t
+	JOIN NewsTags nt ON nt.tag_id = t.id
+	WHERE t.name LIKE $1 ESCAPE '\'
+	GROUP BY t.id, t.name
+	ORDER BY count(*) DESC, t.name COLLATE "C"
+	LIMIT $2`, likeEscaper.Replace(prefix)+"%", limit)
+	if err != nil {
+		t.log.Error("failed to list tags", errMsg.Err(err))
+		return nil, err
+	}
+	tags, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entities.Tag])
+	if err != nil {
+		t.log.Error("failed to scan tags", errMsg.Err(err))
+		return nil, err
+	}
+	return tags, nil
+}
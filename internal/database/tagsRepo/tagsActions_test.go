@@ -0,0 +1,42 @@
+package tagsrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	newsrepo "news-service/internal/database/newsRepo"
+	"news-service/internal/entities"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestTagsAreUnlinkedWithNews(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	news := newsrepo.NewNewsRepository(pg.Db, dbtest.Logger())
+	repo := NewTagsRepository(pg.Db, dbtest.Logger())
+
+	item := entities.News{Title: "title", Content: "content"}
+	if err := news.CreateNews(ctx, &item); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	if err := repo.SetNewsTags(ctx, item.ID, []string{"go", "100%"}); err != nil {
+		t.Fatalf("SetNewsTags: %v", err)
+	}
+	if tags, err := repo.ListTags(ctx, "1", 10); err != nil || len(tags) != 1 || tags[0].Name != "100%" {
+		t.Fatalf("ListTags(1) = %+v, %v", tags, err)
+	}
+	// "%" is not a wildcard.
+	if tags, err := repo.ListTags(ctx, "%", 10); err != nil || len(tags) != 0 {
+		t.Fatalf("ListTags(%%) = %+v, %v", tags, err)
+	}
+
+	if _, err := pg.Db.Exec(ctx, `DELETE FROM News WHERE id = $1`, item.ID); err != nil {
+		t.Fatalf("delete news: %v", err)
+	}
+	tags, err := repo.ListTags(ctx, "", 10)
+	if err != nil || len(tags) != 0 {
+		t.Fatalf("ListTags after deleting the news = %+v, %v", tags, err)
+	}
+}
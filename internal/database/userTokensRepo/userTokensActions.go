@@ -0,0 +1,88 @@
+package usertokensrepo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"news-service/internal/database"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type UserTokensRepository struct {
+	db  database.DBTX
+	log *slog.Logger
+}
+
+func NewUserTokensRepository(db database.DBTX, log *slog.Logger) *UserTokensRepository {
+	return &UserTokensRepository{db: db, log: log}
+}
+
+func (ut *UserTokensRepository) CreateToken(ctx context.Context, token *entities.UserToken) error {
+	tx, err := ut.db.Begin(ctx)
+	if err != nil {
+		ut.log.Error("failed to begin transaction", errMsg.Err(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM UserTokens
+	    WHERE user_id = $1 AND (used_at IS NOT NULL OR expires_at <= CURRENT_TIMESTAMP)`, token.UserID)
+	if err != nil {
+		ut.log.Error("failed to delete stale user tokens", errMsg.Err(err))
+		return err
+	}
+	err = tx.QueryRow(ctx, `INSERT INTO UserTokens (user_id, purpose, email, hash, expires_at)
+	    VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		token.UserID, token.Purpose, token.Email, token.Hash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		ut.log.Error("failed to create user token", errMsg.Err(err))
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		ut.log.Error("failed to commit user token", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (ut *UserTokensRepository) ConsumeToken(ctx context.Context, purpose string, hash []byte, now time.Time) (entities.UserToken, error) {
+	tx, err := ut.db.Begin(ctx)
+	if err != nil {
+		ut.log.Error("failed to begin transaction", errMsg.Err(err))
+		return entities.UserToken{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Concurrent consumers of the same token wait for the row, the second
+	// one then finds it used.
+	var token entities.UserToken
+	err = tx.QueryRow(ctx, `UPDATE UserTokens SET used_at = $3
+	    WHERE purpose = $1 AND hash = $2 AND used_at IS NULL AND expires_at > $3
+	    RETURNING id, user_id, purpose, email, hash, expires_at, created_at, used_at`, purpose, hash, now).
+		Scan(&token.ID, &token.UserID, &token.Purpose, &token.Email, &token.Hash, &token.ExpiresAt, &token.CreatedAt, &token.UsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entities.UserToken{}, models.ErrTokenInvalid
+	}
+	if err != nil {
+		ut.log.Error("failed to consume user token", errMsg.Err(err))
+		return entities.UserToken{}, err
+	}
+	_, err = tx.Exec(ctx, `UPDATE UserTokens SET used_at = $3
+	    WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, token.UserID, purpose, now)
+	if err != nil {
+		ut.log.Error("failed to use up user tokens", errMsg.Err(err))
+		return entities.UserToken{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		ut.log.Error("failed to commit user token", errMsg.Err(err))
+		return entities.UserToken{}, err
+	}
+	return token, nil
+}
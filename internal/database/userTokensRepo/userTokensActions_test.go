@@ -0,0 +1,47 @@
+package usertokensrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	usersrepo "news-service/internal/database/usersRepo"
+	"news-service/internal/entities"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestConcurrentConsume(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	log := dbtest.Logger()
+	user := entities.User{Email: "a@example.com", Password: "hash"}
+	if err := usersrepo.NewUserRepository(pg.Db, log).CreateUser(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewUserTokensRepository(pg.Db, log)
+	token := entities.UserToken{UserID: user.ID, Purpose: entities.TokenResetPassword, Email: user.Email,
+		Hash: []byte("hash"), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := repo.CreateToken(ctx, &token); err != nil {
+		t.Fatal(err)
+	}
+
+	var consumed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.ConsumeToken(ctx, entities.TokenResetPassword, []byte("hash"), time.Now()); err == nil {
+				consumed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := consumed.Load(); n != 1 {
+		t.Fatalf("token consumed %d times, want once", n)
+	}
+}
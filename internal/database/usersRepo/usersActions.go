@@ -4,32 +4,71 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"news-service/internal/database"
+	outboxrepo "news-service/internal/database/outboxRepo"
 	"news-service/internal/entities"
 	errMsg "news-service/internal/err"
+	"news-service/internal/outbox"
+	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 )
 
 type UserRepository struct {
-	db  *pgxpool.Pool
+	db  database.DBTX
 	log *slog.Logger
 }
 
-func NewUserRepository(db *pgxpool.Pool, log *slog.Logger) *UserRepository {
+func NewUserRepository(db database.DBTX, log *slog.Logger) *UserRepository {
 	return &UserRepository{db: db, log: log}
 }
 
+// CreateUser saves the user with its user.registered outbox message.
 func (u *UserRepository) CreateUser(ctx context.Context, user *entities.User) error {
-	err := u.db.QueryRow(ctx, `INSERT INTO Users (email, password) VALUES ($1, $2) RETURNING id`, user.Email, user.Password).Scan(&user.ID)
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		u.log.Error("failed to begin transaction", errMsg.Err(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `INSERT INTO Users (email, password, email_verified) VALUES ($1, $2, $3) RETURNING id`, user.Email, user.Password, user.EmailVerified).Scan(&user.ID)
 	if err != nil {
 		u.log.Error("Failed to create user", errMsg.Err(err))
 		return err
 	}
+	msg, err := outbox.UserRegistered(*user)
+	if err == nil {
+		err = outboxrepo.Insert(ctx, tx, &msg)
+	}
+	if err != nil {
+		u.log.Error("failed to save outbox message", errMsg.Err(err))
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		u.log.Error("failed to commit user", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+// userColumns is the column list scanned by scanUser.
+const userColumns = `id, email, password, email_verified, password_changed_at`
+
+func scanUser(row pgx.Row, user *entities.User) error {
+	var passwordChangedAt *time.Time
+	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.EmailVerified, &passwordChangedAt); err != nil {
+		return err
+	}
+	if passwordChangedAt != nil {
+		user.PasswordChangedAt = *passwordChangedAt
+	}
 	return nil
 }
 
 func (u *UserRepository) FindUserByEmail(ctx context.Context, email string) (entities.User, error) {
-	query, err := u.db.Query(ctx, `SELECT id, email, password FROM Users WHERE email = $1`, email)
+	query, err := u.db.Query(ctx, `SELECT `+userColumns+` FROM Users WHERE email = $1`, email)
 	if err != nil {
 		u.log.Error("Error querying users table", errMsg.Err(err))
 		return entities.User{}, err
@@ -40,7 +79,7 @@ func (u *UserRepository) FindUserByEmail(ctx context.Context, email string) (ent
 		u.log.Error("user not found")
 		return entities.User{}, fmt.Errorf("user not found")
 	} else {
-		err := query.Scan(&row.ID, &row.Email, &row.Password)
+		err := scanUser(query, &row)
 		if err != nil {
 			u.log.Error("Error scanning users", errMsg.Err(err))
 			return entities.User{}, err
@@ -50,7 +89,7 @@ func (u *UserRepository) FindUserByEmail(ctx context.Context, email string) (ent
 }
 
 func (u *UserRepository) FindUserById(ctx context.Context, id int) (entities.User, error) {
-	query, err := u.db.Query(ctx, `SELECT id, email, password FROM Users WHERE id = $1`, id)
+	query, err := u.db.Query(ctx, `SELECT `+userColumns+` FROM Users WHERE id = $1`, id)
 	if err != nil {
 		u.log.Error("error querying users", errMsg.Err(err))
 		return entities.User{}, err
@@ -61,7 +100,7 @@ func (u *UserRepository) FindUserById(ctx context.Context, id int) (entities.Use
 		u.log.Error("user not found")
 		return entities.User{}, fmt.Errorf("user not found")
 	} else {
-		err := query.Scan(&rowArray.ID, &rowArray.Email, &rowArray.Password)
+		err := scanUser(query, &rowArray)
 		if err != nil {
 			u.log.Error("error scanning users", errMsg.Err(err))
 			return entities.User{}, err
@@ -80,7 +119,12 @@ func (u *UserRepository) DeleteUserById(ctx context.Context, id int) error {
 }
 
 func (u *UserRepository) UpdateUser(ctx context.Context, user *entities.User) error {
-	_, err := u.db.Exec(ctx, `UPDATE Users SET email = $1, password = $2 WHERE id = $3`, user.Email, user.Password, user.ID)
+	var passwordChangedAt *time.Time
+	if !user.PasswordChangedAt.IsZero() {
+		passwordChangedAt = &user.PasswordChangedAt
+	}
+	_, err := u.db.Exec(ctx, `UPDATE Users SET email = $1, password = $2, email_verified = $3, password_changed_at = $4 WHERE id = $5`,
+		user.Email, user.Password, user.EmailVerified, passwordChangedAt, user.ID)
 	if err != nil {
 		u.log.Error("failed to update user", errMsg.Err(err))
 		return err
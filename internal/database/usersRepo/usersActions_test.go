@@ -0,0 +1,57 @@
+package usersrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	"news-service/internal/entities"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestUserRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(dbtest.New(t).Db, dbtest.Logger())
+
+	user := entities.User{Email: "a@example.com", Password: "hash"}
+	if err := repo.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if user.ID == 0 {
+		t.Fatal("CreateUser did not set the id")
+	}
+	if err := repo.CreateUser(ctx, &entities.User{Email: "a@example.com", Password: "x"}); err == nil {
+		t.Fatal("expected an error for a duplicate email")
+	}
+
+	byEmail, err := repo.FindUserByEmail(ctx, "a@example.com")
+	if err != nil {
+		t.Fatalf("FindUserByEmail: %v", err)
+	}
+	if byEmail != user {
+		t.Fatalf("FindUserByEmail = %+v, want %+v", byEmail, user)
+	}
+	if _, err := repo.FindUserByEmail(ctx, "missing@example.com"); err == nil {
+		t.Fatal("expected an error for a missing email")
+	}
+
+	user.Email, user.Password = "b@example.com", "other"
+	if err := repo.UpdateUser(ctx, &user); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	byID, err := repo.FindUserById(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("FindUserById: %v", err)
+	}
+	if byID != user {
+		t.Fatalf("FindUserById = %+v, want %+v", byID, user)
+	}
+
+	if err := repo.DeleteUserById(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUserById: %v", err)
+	}
+	if _, err := repo.FindUserById(ctx, user.ID); err == nil {
+		t.Fatal("expected an error for a deleted user")
+	}
+}
@@ -0,0 +1,87 @@
+package viewsrepo
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"news-service/internal/database"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ViewsRepository struct {
+	db  database.DBTX
+	log *slog.Logger
+}
+
+func NewViewsRepository(db database.DBTX, log *slog.Logger) *ViewsRepository {
+	return &ViewsRepository{db, log}
+}
+
+// AddViews writes a batch in one statement. Counts of the same bucket are
+// summed first, an upsert may not touch a row twice.
+func (v *ViewsRepository) AddViews(ctx context.Context, counts []entities.ViewCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	newsIDs := make([]int32, len(counts))
+	buckets := make([]time.Time, len(counts))
+	views := make([]int32, len(counts))
+	for i, c := range counts {
+		newsIDs[i], buckets[i], views[i] = int32(c.NewsID), c.Bucket, int32(c.Views)
+	}
+
+	_, err := v.db.Exec(ctx, `
+	INSERT INTO NewsViews (news_id, bucket, views)
+	SELECT c.news_id, c.bucket, c.views
+	FROM (
+		SELECT news_id, bucket, sum(views)::int AS views
+		FROM unnest($1::int[], $2::timestamptz[], $3::int[]) AS u(news_id, bucket, views)
+		GROUP BY news_id, bucket
+	) c
+	JOIN News n ON n.id = c.news_id
+	ON CONFLICT (news_id, bucket) DO UPDATE SET views = NewsViews.views + EXCLUDED.views`,
+		newsIDs, buckets, views)
+	if err != nil {
+		v.log.Error("failed to add views", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (v *ViewsRepository) ListTrendingNews(ctx context.Context, since time.Time, halfLife time.Duration, limit int) ([]entities.News, error) {
+	seconds := halfLife.Seconds()
+	if seconds <= 0 {
+		seconds = math.Inf(1)
+	}
+	rows, err := v.db.Query(ctx, `
+	SELECT n.id, n.title, n.slug, n.summary, n.content, n.content_format, n.content_html, n.published, n.created_at, n.updated_at
+	FROM News n
+	JOIN (
+		SELECT news_id, sum(views * power(0.5, greatest(extract(epoch FROM now() - bucket), 0)::float8 / $2::float8)) AS score
+		FROM NewsViews
+		WHERE bucket >= $1
+		GROUP BY news_id
+	) t ON t.news_id = n.id
+	WHERE n.published
+	ORDER BY t.score DESC, n.id DESC
+	LIMIT $3`, since, seconds, limit)
+	if err != nil {
+		v.log.Error("failed to list trending news", errMsg.Err(err))
+		return nil, err
+	}
+	news, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.News, error) {
+		var news entities.News
+		err := row.Scan(&news.ID, &news.Title, &news.Slug, &news.Summary, &news.Content, &news.ContentFormat,
+			&news.ContentHTML, &news.Published, &news.CreatedAt, &news.UpdatedAt)
+		return news, err
+	})
+	if err != nil {
+		v.log.Error("failed to scan trending news", errMsg.Err(err))
+		return nil, err
+	}
+	return news, nil
+}
@@ -0,0 +1,39 @@
+package viewsrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	newsrepo "news-service/internal/database/newsRepo"
+	"news-service/internal/entities"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestAddViewsSumsDuplicatesAndSkipsMissingNews(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	news := newsrepo.NewNewsRepository(pg.Db, dbtest.Logger())
+	repo := NewViewsRepository(pg.Db, dbtest.Logger())
+
+	item := entities.News{Title: "title", Content: "content", Published: true}
+	if err := news.CreateNews(ctx, &item); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	bucket := time.Now().Truncate(time.Hour)
+	err := repo.AddViews(ctx, []entities.ViewCount{
+		{NewsID: item.ID, Bucket: bucket, Views: 2},
+		{NewsID: item.ID, Bucket: bucket, Views: 3},
+		{NewsID: item.ID + 100, Bucket: bucket, Views: 1},
+	})
+	if err != nil {
+		t.Fatalf("AddViews: %v", err)
+	}
+
+	var views int
+	if err := pg.Db.QueryRow(ctx, `SELECT sum(views) FROM NewsViews`).Scan(&views); err != nil || views != 5 {
+		t.Fatalf("stored views = %d, %v, want 5", views, err)
+	}
+}
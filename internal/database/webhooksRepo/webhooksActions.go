@@ -0,0 +1,236 @@
+package webhooksrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"news-service/internal/database"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type WebhooksRepository struct {
+	db  database.DBTX
+	log *slog.Logger
+}
+
+func NewWebhooksRepository(db database.DBTX, log *slog.Logger) *WebhooksRepository {
+	return &WebhooksRepository{db, log}
+}
+
+// webhookColumns is the column list scanned by scanWebhook. Webhooks made
+// before they had owners have none.
+const webhookColumns = `id, COALESCE(user_id, 0), url, secret, events, created_at`
+
+func scanWebhook(row pgx.Row, webhook *entities.Webhook) error {
+	return row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &webhook.Secret, &webhook.Events, &webhook.CreatedAt)
+}
+
+// deliveryColumns is the column list scanned by scanDelivery.
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+
+func scanDelivery(row pgx.Row, delivery *entities.WebhookDelivery) error {
+	return row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Status,
+		&delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt)
+}
+
+func (wh *WebhooksRepository) CreateWebhook(ctx context.Context, webhook *entities.Webhook) error {
+	err := wh.db.QueryRow(ctx, `INSERT INTO Webhooks (user_id, url, secret, events) VALUES (NULLIF($1, 0), $2, $3, $4)
+	RETURNING id, created_at`, webhook.UserID, webhook.URL, webhook.Secret, webhook.Events,
+	).Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		wh.log.Error("failed to create webhook", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (wh *WebhooksRepository) FindWebhook(ctx context.Context, id int) (entities.Webhook, error) {
+	var webhook entities.Webhook
+	err := scanWebhook(wh.db.QueryRow(ctx, `SELECT `+webhookColumns+` FROM Webhooks WHERE id = $1`, id), &webhook)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entities.Webhook{}, fmt.Errorf("webhook not found")
+	}
+	if err != nil {
+		wh.log.Error("failed to find webhook", errMsg.Err(err))
+		return entities.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (wh *WebhooksRepository) ListWebhooks(ctx context.Context, userID int) ([]entities.Webhook, error) {
+	rows, err := wh.db.Query(ctx, `SELECT `+webhookColumns+` FROM Webhooks WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		wh.log.Error("failed to list webhooks", errMsg.Err(err))
+		return nil, err
+	}
+	webhooks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.Webhook, error) {
+		var webhook entities.Webhook
+		err := scanWebhook(row, &webhook)
+		return webhook, err
+	})
+	if err != nil {
+		wh.log.Error("failed to scan webhooks", errMsg.Err(err))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (wh *WebhooksRepository) DeleteWebhook(ctx context.Context, id int) error {
+	if _, err := wh.db.Exec(ctx, `DELETE FROM Webhooks WHERE id = $1`, id); err != nil {
+		wh.log.Error("failed to delete webhook", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (wh *WebhooksRepository) EnqueueEvent(ctx context.Context, event string, payload []byte) error {
+	_, err := wh.db.Exec(ctx, `INSERT INTO WebhookDeliveries (webhook_id, event, payload)
+	SELECT id, $1, $2 FROM Webhooks WHERE $1 = ANY(events) ORDER BY id`, event, payload)
+	if err != nil {
+		wh.log.Error("failed to enqueue webhook event", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+// ClaimDeliveries skips rows locked by a concurrent claim instead of
+// waiting for them.
+func (wh *WebhooksRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entities.WebhookDelivery, error) {
+	rows, err := wh.db.Query(ctx, `
+	UPDATE WebhookDeliveries d SET next_attempt_at = $2
+	FROM (
+		SELECT id FROM WebhookDeliveries
+		WHERE status = 'pending' AND next_attempt_at <= $1
+		ORDER BY next_attempt_at, id
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	) due
+	WHERE d.id = due.id
+	RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_error, d.created_at, d.delivered_at`,
+		now, now.Add(lease), limit)
+	if err != nil {
+		wh.log.Error("failed to claim webhook deliveries", errMsg.Err(err))
+		return nil, err
+	}
+	deliveries, err := collectDeliveries(rows)
+	if err != nil {
+		wh.log.Error("failed to scan webhook deliveries", errMsg.Err(err))
+		return nil, err
+	}
+	// RETURNING does not keep the order of the subquery.
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID < deliveries[j].ID })
+	return deliveries, nil
+}
+
+func (wh *WebhooksRepository) RecordAttempt(ctx context.Context, delivery *entities.WebhookDelivery, attempt *entities.WebhookAttempt) error {
+	tx, err := wh.db.Begin(ctx)
+	if err != nil {
+		wh.log.Error("failed to begin transaction", errMsg.Err(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	attempt.DeliveryID = delivery.ID
+	err = tx.QueryRow(ctx, `INSERT INTO WebhookAttempts (delivery_id, status_code, error, duration_ms)
+	VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		attempt.DeliveryID, attempt.StatusCode, attempt.Error, attempt.Duration.Milliseconds(),
+	).Scan(&attempt.ID, &attempt.CreatedAt)
+	if err != nil {
+		wh.log.Error("failed to log webhook attempt", errMsg.Err(err))
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE WebhookDeliveries
+	SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4, delivered_at = $5
+	WHERE id = $6`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastError, delivery.DeliveredAt, delivery.ID)
+	if err != nil {
+		wh.log.Error("failed to update webhook delivery", errMsg.Err(err))
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		wh.log.Error("failed to commit webhook attempt", errMsg.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (wh *WebhooksRepository) FindDelivery(ctx context.Context, id int) (entities.WebhookDelivery, error) {
+	var delivery entities.WebhookDelivery
+	err := scanDelivery(wh.db.QueryRow(ctx, `SELECT `+deliveryColumns+` FROM WebhookDeliveries WHERE id = $1`, id), &delivery)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entities.WebhookDelivery{}, fmt.Errorf("delivery not found")
+	}
+	if err != nil {
+		wh.log.Error("failed to find webhook delivery", errMsg.Err(err))
+		return entities.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+func (wh *WebhooksRepository) ListDeliveries(ctx context.Context, webhookID, limit, offset int) ([]entities.WebhookDelivery, error) {
+	rows, err := wh.db.Query(ctx, `SELECT `+deliveryColumns+` FROM WebhookDeliveries
+	WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`, webhookID, limit, offset)
+	if err != nil {
+		wh.log.Error("failed to list webhook deliveries", errMsg.Err(err))
+		return nil, err
+	}
+	deliveries, err := collectDeliveries(rows)
+	if err != nil {
+		wh.log.Error("failed to scan webhook deliveries", errMsg.Err(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (wh *WebhooksRepository) ListAttempts(ctx context.Context, deliveryID int) ([]entities.WebhookAttempt, error) {
+	rows, err := wh.db.Query(ctx, `SELECT id, delivery_id, status_code, error, duration_ms, created_at
+	FROM WebhookAttempts WHERE delivery_id = $1 ORDER BY id`, deliveryID)
+	if err != nil {
+		wh.log.Error("failed to list webhook attempts", errMsg.Err(err))
+		return nil, err
+	}
+	attempts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.WebhookAttempt, error) {
+		var (
+			attempt    entities.WebhookAttempt
+			durationMS int64
+		)
+		err := row.Scan(&attempt.ID, &attempt.DeliveryID, &attempt.StatusCode, &attempt.Error, &durationMS, &attempt.CreatedAt)
+		attempt.Duration = time.Duration(durationMS) * time.Millisecond
+		return attempt, err
+	})
+	if err != nil {
+		wh.log.Error("failed to scan webhook attempts", errMsg.Err(err))
+		return nil, err
+	}
+	return attempts, nil
+}
+
+func (wh *WebhooksRepository) RetryDelivery(ctx context.Context, id int) error {
+	tag, err := wh.db.Exec(ctx, `UPDATE WebhookDeliveries
+	SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND status = 'dead'`, id)
+	if err != nil {
+		wh.log.Error("failed to retry webhook delivery", errMsg.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no dead delivery %d", id)
+	}
+	return nil
+}
+
+func collectDeliveries(rows pgx.Rows) ([]entities.WebhookDelivery, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.WebhookDelivery, error) {
+		var delivery entities.WebhookDelivery
+		err := scanDelivery(row, &delivery)
+		return delivery, err
+	})
+}
@@ -0,0 +1,69 @@
+package webhooksrepo
+
+import (
+	"context"
+	"news-service/internal/database/dbtest"
+	"news-service/internal/entities"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestConcurrentClaimsDoNotOverlap(t *testing.T) {
+	ctx := context.Background()
+	pg := dbtest.New(t)
+	repo := NewWebhooksRepository(pg.Db, dbtest.Logger())
+
+	webhook := entities.Webhook{URL: "https://example.com/hook", Secret: "s", Events: []string{entities.EventNewsCreated}}
+	if err := repo.CreateWebhook(ctx, &webhook); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	const deliveries = 40
+	for i := 0; i < deliveries; i++ {
+		if err := repo.EnqueueEvent(ctx, entities.EventNewsCreated, []byte(`{}`)); err != nil {
+			t.Fatalf("EnqueueEvent: %v", err)
+		}
+	}
+
+	const dispatchers = 4
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		claimed = map[int]int{}
+	)
+	now := time.Now().Add(time.Minute)
+	for i := 0; i < dispatchers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				batch, err := repo.ClaimDeliveries(ctx, now, time.Hour, 3)
+				if err != nil {
+					t.Errorf("ClaimDeliveries: %v", err)
+					return
+				}
+				if len(batch) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, d := range batch {
+					claimed[d.ID]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != deliveries {
+		t.Fatalf("claimed %d deliveries, want %d", len(claimed), deliveries)
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Fatalf("delivery %d was claimed %d times", id, n)
+		}
+	}
+}
@@ -1,14 +1,176 @@
+// Package entities holds the domain types stored by the repositories. They
+// have no JSON form of their own, handlers render them through the DTOs of
+// their API version.
 package entities
 
+import "time"
+
+// DefaultContentFormat is the ContentFormat of news that do not declare one.
+const DefaultContentFormat = "plain"
+
 type News struct {
-	ID      int    `json:"news_id"`
-	Title   string `json:"news_title"`
-	Content string `json:"news_content"`
+	ID      int
+	Title   string
+	Slug    string
+	Summary string
+	// Content is the source as written by the editor in ContentFormat,
+	// ContentHTML its sanitized rendering.
+	Content       string
+	ContentFormat string
+	ContentHTML   string
+	Published     bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Media is a file attached to a news. The file itself lives in a
+// storage.Storage under Key, the thumbnail of an image under ThumbnailKey.
+type Media struct {
+	ID           int
+	NewsID       int
+	Key          string
+	URL          string
+	ThumbnailKey string
+	ThumbnailURL string
+	FileName     string
+	ContentType  string
+	Size         int64
+	Width        int
+	Height       int
+	CreatedAt    time.Time
+}
+
+// Comment statuses. New and edited comments wait for moderation, only
+// approved ones are shown to readers.
+const (
+	CommentPending  = "pending"
+	CommentApproved = "approved"
+	CommentRejected = "rejected"
+)
+
+// Comment is a reader's comment on a news. ParentID is the comment it
+// replies to, zero for top-level comments. Deleted comments keep their place
+// in the thread with an empty Body.
+type Comment struct {
+	ID        int
+	NewsID    int
+	UserID    int
+	ParentID  int
+	Body      string
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+}
+
+// ReactionKinds are the reactions users may leave on news. A user may leave
+// several kinds on the same news, each at most once.
+var ReactionKinds = []string{"like", "love", "laugh", "wow", "sad", "angry"}
+
+// Events delivered to webhooks.
+const (
+	EventNewsCreated = "news.created"
+	EventNewsUpdated = "news.updated"
+)
+
+// WebhookEvents are the events a webhook may subscribe to.
+var WebhookEvents = []string{EventNewsCreated, EventNewsUpdated}
+
+// Webhook is an endpoint notified of the Events it subscribed to. Payloads
+// are signed with Secret. Only UserID, its owner, may see and manage it.
+type Webhook struct {
+	ID        int
+	UserID    int
+	URL       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+// Statuses of a webhook delivery. A delivery that failed every attempt is
+// dead-lettered and stays in the log until it is retried by hand.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookDelivery is an event queued for a webhook. A pending delivery is
+// attempted at NextAttemptAt.
+type WebhookDelivery struct {
+	ID            int
+	WebhookID     int
+	Event         string
+	Payload       []byte
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+}
+
+// WebhookAttempt logs one try of a delivery. StatusCode is zero when no
+// response was received.
+type WebhookAttempt struct {
+	ID         int
+	DeliveryID int
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+// NewsEvent records that a news was created or updated, Kind is
+// EventNewsCreated or EventNewsUpdated. Ids grow in commit order and
+// identify the events of GET /news/stream.
+type NewsEvent struct {
+	ID        int
+	NewsID    int
+	Kind      string
+	CreatedAt time.Time
+}
+
+// Domain events written to the outbox.
+const (
+	EventNewsPublished  = "news.published"
+	EventUserRegistered = "user.registered"
+)
+
+// OutboxMessage is a domain event saved in the transaction of the change
+// it describes and published afterwards, at least once. Consumers drop
+// repeated messages by Key.
+type OutboxMessage struct {
+	ID            int
+	Key           string
+	Topic         string
+	Payload       []byte
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	PublishedAt   *time.Time
 }
 
 type Categorie struct {
-	ID   int `json:"categorie_id"`
-	Name int `json:"categorie_name"`
+	ID   int
+	Name int
+}
+
+// Tag is a free-form label of news. Name is normalized, see tag.Normalize,
+// and Count is the number of news tagged with it where it is reported.
+type Tag struct {
+	ID    int
+	Name  string
+	Count int
+}
+
+// ViewCount is the number of views a news got in the hour starting at
+// Bucket.
+type ViewCount struct {
+	NewsID int
+	Bucket time.Time
+	Views  int
 }
 
 type NewsCategories struct {
@@ -18,7 +180,32 @@ type NewsCategories struct {
 }
 
 type User struct {
-	ID       int    `json:"user_id"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	ID       int
+	Email    string
+	Password string
+	// EmailVerified is set once the user followed the link mailed to
+	// Email.
+	EmailVerified bool
+	// PasswordChangedAt is when Password last changed, the tokens issued
+	// before are not accepted anymore. Zero when it never changed.
+	PasswordChangedAt time.Time
+}
+
+// Purposes of user tokens.
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// UserToken is a single-use token mailed to a user. Only the SHA-256 Hash
+// of the token is stored, and Email is the address it was mailed to.
+type UserToken struct {
+	ID        int
+	UserID    int
+	Purpose   string
+	Email     string
+	Hash      []byte
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
 }
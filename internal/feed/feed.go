@@ -0,0 +1,169 @@
+// Package feed renders news as RSS 2.0 and Atom 1.0 documents.
+package feed
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+)
+
+type Channel struct {
+	Title       string
+	Description string
+	// Link is the URL of the page the feed describes, Self the URL of the
+	// feed document itself.
+	Link string
+	Self string
+}
+
+type Item struct {
+	// ID must never change for an item, it becomes the RSS guid and the
+	// Atom id.
+	ID    string
+	Title string
+	Link  string
+	// Summary is plain text, Content is HTML.
+	Summary   string
+	Content   string
+	Published time.Time
+	Updated   time.Time
+}
+
+// Updated returns the most recent update time of items, zero if there are
+// none.
+func Updated(items []Item) time.Time {
+	var latest time.Time
+	for _, item := range items {
+		if item.Updated.After(latest) {
+			latest = item.Updated
+		}
+	}
+	return latest
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders an RSS 2.0 document.
+func RSS(channel Channel, items []Item) ([]byte, error) {
+	doc := rss{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       channel.Title,
+			Link:        channel.Link,
+			Description: channel.Description,
+			AtomLink:    atomLink{Href: channel.Self, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	if updated := Updated(items); !updated.IsZero() {
+		doc.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, item := range items {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Content,
+			GUID:        rssGUID{IsPermaLink: strconv.FormatBool(item.ID == item.Link), Value: item.ID},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return marshal(doc)
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Summary   string      `xml:"summary,omitempty"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Atom renders an Atom 1.0 document. The feed id is the Self URL.
+func Atom(channel Channel, items []Item) ([]byte, error) {
+	updated := Updated(items)
+	if updated.IsZero() {
+		// updated is mandatory in Atom, an empty feed has never changed.
+		updated = time.Unix(0, 0)
+	}
+
+	doc := atomFeed{
+		Title:    channel.Title,
+		Subtitle: channel.Description,
+		ID:       channel.Self,
+		Updated:  updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: channel.Link, Rel: "alternate", Type: "text/html"},
+			{Href: channel.Self, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+	for _, item := range items {
+		doc.Entries = append(doc.Entries, atomEntry{
+			Title:     item.Title,
+			ID:        item.ID,
+			Link:      atomLink{Href: item.Link, Rel: "alternate"},
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Summary:   item.Summary,
+			Content:   atomContent{Type: "html", Value: item.Content},
+		})
+	}
+	return marshal(doc)
+}
+
+func marshal(doc any) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
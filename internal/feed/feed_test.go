@@ -0,0 +1,127 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	channel = Channel{Title: "News", Description: "Latest", Link: "http://example.com/news", Self: "http://example.com/feed.rss"}
+	items   = []Item{
+		{
+			ID:        "http://example.com/news/2",
+			Title:     "Second & last",
+			Link:      "http://example.com/news/by-slug/second",
+			Content:   "<p>body</p>",
+			Published: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC),
+			Updated:   time.Date(2024, 5, 3, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:        "http://example.com/news/1",
+			Title:     "First",
+			Link:      "http://example.com/news/by-slug/first",
+			Published: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			Updated:   time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+	}
+)
+
+func TestRSS(t *testing.T) {
+	body, err := RSS(channel, items)
+	if err != nil {
+		t.Fatalf("RSS: %v", err)
+	}
+	if !strings.HasPrefix(string(body), xml.Header) {
+		t.Fatal("missing XML header")
+	}
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title       string `xml:"title"`
+				Description string `xml:"description"`
+				GUID        struct {
+					IsPermaLink string `xml:"isPermaLink,attr"`
+					Value       string `xml:",chardata"`
+				} `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+
+	if doc.Version != "2.0" || doc.Channel.Title != "News" {
+		t.Fatalf("unexpected channel: %+v", doc)
+	}
+	if doc.Channel.LastBuildDate != "Fri, 03 May 2024 10:00:00 +0000" {
+		t.Fatalf("lastBuildDate = %q", doc.Channel.LastBuildDate)
+	}
+	if len(doc.Channel.Items) != 2 {
+		t.Fatalf("got %d items", len(doc.Channel.Items))
+	}
+	first := doc.Channel.Items[0]
+	if first.Title != "Second & last" || first.Description != "<p>body</p>" {
+		t.Fatalf("unexpected item: %+v", first)
+	}
+	if first.GUID.Value != "http://example.com/news/2" || first.GUID.IsPermaLink != "false" {
+		t.Fatalf("unexpected guid: %+v", first.GUID)
+	}
+	if first.PubDate != "Thu, 02 May 2024 10:00:00 +0000" {
+		t.Fatalf("pubDate = %q", first.PubDate)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := Atom(channel, items)
+	if err != nil {
+		t.Fatalf("Atom: %v", err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Link    struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+
+	if doc.ID != channel.Self || doc.Updated != "2024-05-03T10:00:00Z" {
+		t.Fatalf("unexpected feed: %+v", doc)
+	}
+	if len(doc.Entries) != 2 || doc.Entries[1].ID != "http://example.com/news/1" || doc.Entries[1].Link.Href != "http://example.com/news/by-slug/first" {
+		t.Fatalf("unexpected entries: %+v", doc.Entries)
+	}
+}
+
+func TestEmptyFeeds(t *testing.T) {
+	body, err := RSS(channel, nil)
+	if err != nil {
+		t.Fatalf("RSS: %v", err)
+	}
+	if strings.Contains(string(body), "lastBuildDate") {
+		t.Fatal("an empty RSS feed must not have lastBuildDate")
+	}
+
+	body, err = Atom(channel, nil)
+	if err != nil {
+		t.Fatalf("Atom: %v", err)
+	}
+	if !strings.Contains(string(body), "<updated>1970-01-01T00:00:00Z</updated>") {
+		t.Fatalf("an empty Atom feed still needs updated: %s", body)
+	}
+}
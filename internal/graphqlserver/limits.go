@@ -0,0 +1,142 @@
+package graphqlserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// checkLimits rejects operations nested deeper than maxDepth fields or
+// costing more than maxComplexity, zero disables a limit. Every field costs
+// one, and the selections of a field taking a limit argument are counted
+// limit times, with the default of the argument when it is omitted.
+// Introspection fields are free.
+func checkLimits(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]any, maxDepth, maxComplexity int) error {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[f.Name.Value] = f
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok || (operationName != "" && (op.Name == nil || op.Name.Value != operationName)) {
+			continue
+		}
+		root := schema.QueryType()
+		if op.Operation != ast.OperationTypeQuery {
+			root = nil
+		}
+
+		a := analyzer{schema: schema, fragments: fragments, variables: map[string]any{}, visiting: map[string]bool{}}
+		for _, v := range op.VariableDefinitions {
+			if lit, ok := v.DefaultValue.(*ast.IntValue); ok {
+				a.variables[v.Variable.Name.Value] = lit.Value
+			}
+		}
+		for name, value := range variables {
+			a.variables[name] = value
+		}
+
+		depth, cost := a.walk(op.SelectionSet, root)
+		if maxDepth > 0 && depth > maxDepth {
+			return fmt.Errorf("query depth %d exceeds the limit of %d", depth, maxDepth)
+		}
+		if maxComplexity > 0 && cost > maxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the limit of %d", cost, maxComplexity)
+		}
+	}
+	return nil
+}
+
+type analyzer struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	// visiting guards against fragment cycles, which the validation
+	// reports later.
+	visiting map[string]bool
+}
+
+// walk returns the depth and cost of set selected on typ, a nil typ when
+// the selection does not match the schema.
+func (a *analyzer) walk(set *ast.SelectionSet, typ graphql.Type) (depth, cost int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, c int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			var field *graphql.FieldDefinition
+			var child graphql.Type
+			if object, ok := typ.(*graphql.Object); ok {
+				if field = object.Fields()[s.Name.Value]; field != nil {
+					child, _ = graphql.GetNamed(field.Type).(graphql.Type)
+				}
+			}
+			d, c = a.walk(s.SelectionSet, child)
+			d, c = d+1, 1+a.multiplier(s, field)*c
+		case *ast.InlineFragment:
+			on := typ
+			if s.TypeCondition != nil {
+				on = a.schema.Type(s.TypeCondition.Name.Value)
+			}
+			d, c = a.walk(s.SelectionSet, on)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			fragment := a.fragments[name]
+			if fragment == nil || a.visiting[name] {
+				continue
+			}
+			a.visiting[name] = true
+			d, c = a.walk(fragment.SelectionSet, a.schema.Type(fragment.TypeCondition.Name.Value))
+			delete(a.visiting, name)
+		}
+		depth = max(depth, d)
+		cost += c
+	}
+	return depth, cost
+}
+
+// multiplier is the limit argument of a field, one for fields without one.
+func (a *analyzer) multiplier(f *ast.Field, field *graphql.FieldDefinition) int {
+	limit := 1
+	if field != nil {
+		for _, arg := range field.Args {
+			if n, ok := arg.DefaultValue.(int); ok && arg.Name() == "limit" {
+				limit = n
+			}
+		}
+	}
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		var value any
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			value = v.Value
+		case *ast.Variable:
+			value = a.variables[v.Name.Value]
+		}
+		switch v := value.(type) {
+		case string:
+			limit, _ = strconv.Atoi(v)
+		case float64:
+			limit = int(v)
+		case json.Number:
+			n, _ := v.Int64()
+			limit = int(n)
+		}
+	}
+	return max(limit, 1)
+}
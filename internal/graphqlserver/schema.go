@@ -0,0 +1,390 @@
+package graphqlserver
+
+import (
+	"errors"
+	"fmt"
+	"news-service/internal/entities"
+	newshandler "news-service/internal/handlers/NewsHandler"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type reaction struct {
+	Kind  string
+	Count int
+}
+
+// pageArgs are the limit and offset arguments of list fields.
+var pageArgs = graphql.FieldConfigArgument{
+	"limit":  {Type: graphql.Int, DefaultValue: defaultPageSize},
+	"offset": {Type: graphql.Int, DefaultValue: 0},
+}
+
+// page applies the bounds of the REST pagination.
+func page(p graphql.ResolveParams) (limit, offset int, err error) {
+	limit, _ = p.Args["limit"].(int)
+	offset, _ = p.Args["offset"].(int)
+	if limit < 1 || limit > maxPageSize {
+		return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+	}
+	if offset < 0 {
+		return 0, 0, errors.New("offset must not be negative")
+	}
+	return limit, offset, nil
+}
+
+func (s *Server) queryType() *graphql.Object {
+	var newsType, categoryType, userType *graphql.Object
+
+	mediaType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Media",
+		Fields: graphql.Fields{
+			"id":           {Type: graphql.NewNonNull(graphql.Int)},
+			"url":          {Type: graphql.NewNonNull(graphql.String)},
+			"thumbnailUrl": {Type: graphql.String},
+			"fileName":     {Type: graphql.NewNonNull(graphql.String)},
+			"contentType":  {Type: graphql.NewNonNull(graphql.String)},
+			"size":         {Type: graphql.NewNonNull(graphql.Int)},
+			"width":        {Type: graphql.Int},
+			"height":       {Type: graphql.Int},
+		},
+	})
+
+	reactionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Reaction",
+		Fields: graphql.Fields{
+			"kind":  {Type: graphql.NewNonNull(graphql.String)},
+			"count": {Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	commentType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Comment",
+		Description: "An approved comment. Deleted comments keep their place with an empty body.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": {Type: graphql.NewNonNull(graphql.Int)},
+				"parentId": {
+					Type:        graphql.Int,
+					Description: "The comment replied to, null for top-level comments.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						if c := p.Source.(entities.Comment); c.ParentID != 0 {
+							return c.ParentID, nil
+						}
+						return nil, nil
+					},
+				},
+				"body": {Type: graphql.NewNonNull(graphql.String)},
+				"edited": {
+					Type: graphql.NewNonNull(graphql.Boolean),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						c := p.Source.(entities.Comment)
+						return c.UpdatedAt.After(c.CreatedAt), nil
+					},
+				},
+				"deleted": {
+					Type: graphql.NewNonNull(graphql.Boolean),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return p.Source.(entities.Comment).DeletedAt != nil, nil
+					},
+				},
+				"createdAt": {Type: graphql.NewNonNull(graphql.DateTime)},
+				"updatedAt": {Type: graphql.NewNonNull(graphql.DateTime)},
+				"author": {
+					Type:        userType,
+					Description: "Null when the user no longer exists.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						user := requestState(p.Context).users.load(p.Context, p.Source.(entities.Comment).UserID)
+						return func() (any, error) {
+							u, err := user()
+							if err != nil || u.ID == 0 {
+								return nil, err
+							}
+							return u, nil
+						}, nil
+					},
+				},
+			}
+		}),
+	})
+
+	newsType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "News",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":            {Type: graphql.NewNonNull(graphql.Int)},
+				"title":         {Type: graphql.NewNonNull(graphql.String)},
+				"slug":          {Type: graphql.NewNonNull(graphql.String)},
+				"summary":       {Type: graphql.NewNonNull(graphql.String)},
+				"content":       {Type: graphql.NewNonNull(graphql.String)},
+				"contentFormat": {Type: graphql.NewNonNull(graphql.String)},
+				"contentHtml": {
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return newshandler.ContentHTML(p.Source.(entities.News)), nil
+					},
+				},
+				"published": {Type: graphql.NewNonNull(graphql.Boolean)},
+				"createdAt": {Type: graphql.NewNonNull(graphql.DateTime)},
+				"updatedAt": {Type: graphql.NewNonNull(graphql.DateTime)},
+				"categories": {
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType))),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						categories := requestState(p.Context).categories.load(p.Context, p.Source.(entities.News).ID)
+						return func() (any, error) {
+							names, err := categories()
+							if err != nil {
+								return nil, internalError(p.Context, "failed to retrieve categories", err)
+							}
+							if names == nil {
+								names = []int{}
+							}
+							return names, nil
+						}, nil
+					},
+				},
+				"tags": {
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						tags := requestState(p.Context).tags.load(p.Context, p.Source.(entities.News).ID)
+						return func() (any, error) {
+							names, err := tags()
+							if err != nil {
+								return nil, internalError(p.Context, "failed to retrieve tags", err)
+							}
+							if names == nil {
+								names = []string{}
+							}
+							return names, nil
+						}, nil
+					},
+				},
+				"media": {
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(mediaType))),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						media := requestState(p.Context).media.load(p.Context, p.Source.(entities.News).ID)
+						return func() (any, error) {
+							list, err := media()
+							if err != nil {
+								return nil, internalError(p.Context, "failed to retrieve media", err)
+							}
+							if list == nil {
+								list = []entities.Media{}
+							}
+							return list, nil
+						}, nil
+					},
+				},
+				"commentsCount": {
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						count := requestState(p.Context).comments.load(p.Context, p.Source.(entities.News).ID)
+						return func() (any, error) {
+							n, err := count()
+							if err != nil {
+								return nil, internalError(p.Context, "failed to count comments", err)
+							}
+							return n, nil
+						}, nil
+					},
+				},
+				"comments": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(commentType))),
+					Description: "Approved comments, oldest first.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						comments, err := s.repos.Comments.ListComments(p.Context, p.Source.(entities.News).ID)
+						if err != nil {
+							return nil, internalError(p.Context, "failed to retrieve comments", err)
+						}
+						if comments == nil {
+							comments = []entities.Comment{}
+						}
+						return comments, nil
+					},
+				},
+				"reactions": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(reactionType))),
+					Description: "Counts of the kinds of reactions left on the news.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						reactionCounts := requestState(p.Context).reactions.load(p.Context, p.Source.(entities.News).ID)
+						return func() (any, error) {
+							counts, err := reactionCounts()
+							if err != nil {
+								return nil, internalError(p.Context, "failed to count reactions", err)
+							}
+							reactions := []reaction{}
+							for _, kind := range entities.ReactionKinds {
+								if counts[kind] > 0 {
+									reactions = append(reactions, reaction{Kind: kind, Count: counts[kind]})
+								}
+							}
+							return reactions, nil
+						}, nil
+					},
+				},
+			}
+		}),
+	})
+
+	categoryType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Category",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": {
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return p.Source, nil
+					},
+				},
+				"news": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(newsType))),
+					Description: "Published news of the category, newest first.",
+					Args:        pageArgs,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						limit, offset, err := page(p)
+						if err != nil {
+							return nil, err
+						}
+						news, err := s.repos.News.ListPublishedNewsByCategory(p.Context, p.Source.(int), limit, offset)
+						if err != nil {
+							return nil, internalError(p.Context, "failed to retrieve news", err)
+						}
+						return news, nil
+					},
+				},
+			}
+		}),
+	})
+
+	// ownOnly resolves fields of the current user only.
+	ownOnly := func(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+		return func(p graphql.ResolveParams) (any, error) {
+			user, err := s.currentUser(p.Context)
+			if err != nil {
+				return nil, err
+			}
+			if p.Source.(entities.User).ID != user.ID {
+				return nil, fmt.Errorf("%s is only available for the current user", p.Info.FieldName)
+			}
+			return resolve(p)
+		}
+	}
+
+	userType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": {Type: graphql.NewNonNull(graphql.Int)},
+				"email": {
+					Type:        graphql.String,
+					Description: "Only visible for the current user.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						user, err := s.currentUser(p.Context)
+						if u := p.Source.(entities.User); err == nil && u.ID == user.ID {
+							return u.Email, nil
+						}
+						return nil, nil
+					},
+				},
+				"subscriptions": {
+					Type:        graphql.NewList(graphql.NewNonNull(categoryType)),
+					Description: "Followed categories, only for the current user.",
+					Resolve: ownOnly(func(p graphql.ResolveParams) (any, error) {
+						categories, err := s.repos.Subscriptions.ListSubscriptions(p.Context, p.Source.(entities.User).ID)
+						if err != nil {
+							return nil, internalError(p.Context, "failed to retrieve subscriptions", err)
+						}
+						if categories == nil {
+							categories = []int{}
+						}
+						return categories, nil
+					}),
+				},
+				"bookmarks": {
+					Type:        graphql.NewList(graphql.NewNonNull(newsType)),
+					Description: "Bookmarked news, most recent first, only for the current user.",
+					Args:        pageArgs,
+					Resolve: ownOnly(func(p graphql.ResolveParams) (any, error) {
+						limit, offset, err := page(p)
+						if err != nil {
+							return nil, err
+						}
+						news, err := s.repos.Bookmarks.ListBookmarkedNews(p.Context, p.Source.(entities.User).ID, limit, offset)
+						if err != nil {
+							return nil, internalError(p.Context, "failed to retrieve bookmarks", err)
+						}
+						return news, nil
+					}),
+				},
+			}
+		}),
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"news": {
+				Type:        newsType,
+				Description: "A news by id or slug, drafts included. Old slugs of a renamed news resolve to it.",
+				Args: graphql.FieldConfigArgument{
+					"id":   {Type: graphql.Int},
+					"slug": {Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, byID := p.Args["id"].(int)
+					slug, bySlug := p.Args["slug"].(string)
+					if byID == bySlug {
+						return nil, errors.New("exactly one of id and slug is required")
+					}
+					var news entities.News
+					var err error
+					if byID {
+						news, err = s.repos.News.FindNewsByID(p.Context, id)
+					} else {
+						news, err = s.repos.News.FindNewsBySlug(p.Context, slug)
+					}
+					if err != nil {
+						return nil, nil
+					}
+					return news, nil
+				},
+			},
+			"newsList": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(newsType))),
+				Description: "Published news, newest first.",
+				Args:        pageArgs,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					limit, offset, err := page(p)
+					if err != nil {
+						return nil, err
+					}
+					news, err := s.repos.News.ListPublishedNews(p.Context, limit, offset)
+					if err != nil {
+						return nil, internalError(p.Context, "failed to retrieve news", err)
+					}
+					return news, nil
+				},
+			},
+			"category": {
+				Type: graphql.NewNonNull(categoryType),
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Args["id"], nil
+				},
+			},
+			"me": {
+				Type: graphql.NewNonNull(userType),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return s.currentUser(p.Context)
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,182 @@
+// Package graphqlserver serves POST /graphql, a read-only GraphQL API over
+// the repositories behind the REST API. Categories, tags, media, comment
+// and reaction counts of news and authors of comments are batched per level
+// of the query, see loader, and queries are bounded in depth and
+// complexity, see checkLimits.
+package graphqlserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/config"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/jwt"
+	"news-service/internal/models"
+	"sync"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+type Repositories struct {
+	News           models.NewsRepository
+	NewsCategories models.NewsCategoriesRepository
+	Media          models.MediaRepository
+	Tags           models.TagsRepository
+	Comments       models.CommentsRepository
+	Reactions      models.ReactionsRepository
+	Bookmarks      models.BookmarksRepository
+	Subscriptions  models.SubscriptionsRepository
+	Users          userhandlers.User
+}
+
+// Request is the JSON body of POST /graphql.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+type Server struct {
+	log    *slog.Logger
+	cfg    config.GraphQLCfg
+	repos  Repositories
+	schema graphql.Schema
+}
+
+// New builds the schema, it expects the user to be authenticated by
+// jwt.TokenAuthMiddleware.
+func New(log *slog.Logger, cfg config.GraphQLCfg, repos Repositories) *Server {
+	s := &Server{log: log, cfg: cfg, repos: repos}
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: s.queryType()})
+	if err != nil {
+		panic(err)
+	}
+	s.schema = schema
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const loggerOptions = "graphqlserver.ServeHTTP"
+	log := s.log.With(
+		slog.String("options", loggerOptions),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	var req Request
+	if err := render.DecodeJSON(http.MaxBytesReader(w, r.Body, response.MaxBodySize), &req); err != nil || req.Query == "" {
+		log.Error("failed to decode request body", errMsg.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, failed(errors.New("the body must be a JSON object with a query")))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		render.JSON(w, r, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if err := checkLimits(&s.schema, doc, req.OperationName, req.Variables, s.cfg.MaxDepth, s.cfg.MaxComplexity); err != nil {
+		log.Info("query rejected", errMsg.Err(err))
+		render.JSON(w, r, failed(err))
+		return
+	}
+	if validation := graphql.ValidateDocument(&s.schema, doc, nil); !validation.IsValid {
+		render.JSON(w, r, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+
+	render.JSON(w, r, graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(r.Context(), stateKey{}, s.newState(r.Context(), log)),
+	}))
+}
+
+func failed(err error) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())}}
+}
+
+type stateKey struct{}
+
+// state is shared by the resolvers of one request.
+type state struct {
+	log        *slog.Logger
+	categories *loader[int, []int]
+	tags       *loader[int, []string]
+	media      *loader[int, []entities.Media]
+	comments   *loader[int, int]
+	reactions  *loader[int, map[string]int]
+	users      *loader[int, entities.User]
+
+	viewerOnce sync.Once
+	viewer     entities.User
+	viewerErr  error
+}
+
+func (s *Server) newState(ctx context.Context, log *slog.Logger) *state {
+	return &state{
+		log: log,
+		categories: newLoader(func(ctx context.Context, newsIDs []int) (map[int][]int, error) {
+			return s.repos.NewsCategories.ListCategoriesByNews(ctx, newsIDs)
+		}),
+		tags:      newLoader(s.repos.Tags.ListTagsByNews),
+		media:     newLoader(s.repos.Media.ListMediaByNews),
+		comments:  newLoader(s.repos.Comments.CountCommentsByNews),
+		reactions: newLoader(s.repos.Reactions.CountReactionsByNews),
+		// Users are only looked up one by one, the loader still spares
+		// the lookups of authors of several comments.
+		users: newLoader(func(ctx context.Context, ids []int) (map[int]entities.User, error) {
+			users := make(map[int]entities.User, len(ids))
+			for _, id := range ids {
+				user, err := s.repos.Users.FindUserById(ctx, id)
+				if err == nil {
+					users[id] = user
+				}
+			}
+			return users, nil
+		}),
+	}
+}
+
+func requestState(ctx context.Context) *state {
+	return ctx.Value(stateKey{}).(*state)
+}
+
+// currentUser returns the user authenticated by jwt.TokenAuthMiddleware.
+func (s *Server) currentUser(ctx context.Context) (entities.User, error) {
+	st := requestState(ctx)
+	st.viewerOnce.Do(func() {
+		email, ok := jwt.EmailFromContext(ctx)
+		if !ok {
+			st.viewerErr = userhandlers.ErrUnauthorized
+			return
+		}
+		st.viewer, st.viewerErr = s.repos.Users.FindUserByEmail(ctx, email)
+		if st.viewerErr != nil {
+			st.viewerErr = userhandlers.ErrUnauthorized
+		}
+	})
+	return st.viewer, st.viewerErr
+}
+
+// internalError logs err and answers with msg only, the way the REST
+// handlers hide database errors.
+func internalError(ctx context.Context, msg string, err error) error {
+	requestState(ctx).log.Error(msg, errMsg.Err(err))
+	return errors.New(msg)
+}
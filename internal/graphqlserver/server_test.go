@@ -0,0 +1,410 @@
+package graphqlserver_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"news-service/internal/config"
+	"news-service/internal/database/dbtest"
+	memoryrepo "news-service/internal/database/memoryRepo"
+	"news-service/internal/entities"
+	"news-service/internal/graphqlserver"
+	"news-service/internal/jwt"
+	"news-service/internal/models"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingCategories counts the batched category lookups.
+type countingCategories struct {
+	models.NewsCategoriesRepository
+	calls atomic.Int32
+}
+
+func (c *countingCategories) ListCategoriesByNews(ctx context.Context, newsIDs []int) (map[int][]int, error) {
+	c.calls.Add(1)
+	return c.NewsCategoriesRepository.ListCategoriesByNews(ctx, newsIDs)
+}
+
+// countingTags, countingMedia, countingComments and countingReactions
+// count the lookups of the other fields of news, batched or not.
+type countingTags struct {
+	models.TagsRepository
+	calls atomic.Int32
+}
+
+func (c *countingTags) ListNewsTags(ctx context.Context, newsID int) ([]string, error) {
+	c.calls.Add(1)
+	return c.TagsRepository.ListNewsTags(ctx, newsID)
+}
+
+func (c *countingTags) ListTagsByNews(ctx context.Context, newsIDs []int) (map[int][]string, error) {
+	c.calls.Add(1)
+	return c.TagsRepository.ListTagsByNews(ctx, newsIDs)
+}
+
+type countingMedia struct {
+	models.MediaRepository
+	calls atomic.Int32
+}
+
+func (c *countingMedia) ListMedia(ctx context.Context, newsID int) ([]entities.Media, error) {
+	c.calls.Add(1)
+	return c.MediaRepository.ListMedia(ctx, newsID)
+}
+
+func (c *countingMedia) ListMediaByNews(ctx context.Context, newsIDs []int) (map[int][]entities.Media, error) {
+	c.calls.Add(1)
+	return c.MediaRepository.ListMediaByNews(ctx, newsIDs)
+}
+
+type countingComments struct {
+	models.CommentsRepository
+	calls atomic.Int32
+}
+
+func (c *countingComments) CountComments(ctx context.Context, newsID int) (int, error) {
+	c.calls.Add(1)
+	return c.CommentsRepository.CountComments(ctx, newsID)
+}
+
+func (c *countingComments) CountCommentsByNews(ctx context.Context, newsIDs []int) (map[int]int, error) {
+	c.calls.Add(1)
+	return c.CommentsRepository.CountCommentsByNews(ctx, newsIDs)
+}
+
+type countingReactions struct {
+	models.ReactionsRepository
+	calls atomic.Int32
+}
+
+func (c *countingReactions) CountReactions(ctx context.Context, newsID int) (map[string]int, error) {
+	c.calls.Add(1)
+	return c.ReactionsRepository.CountReactions(ctx, newsID)
+}
+
+func (c *countingReactions) CountReactionsByNews(ctx context.Context, newsIDs []int) (map[int]map[string]int, error) {
+	c.calls.Add(1)
+	return c.ReactionsRepository.CountReactionsByNews(ctx, newsIDs)
+}
+
+type fixture struct {
+	handler    http.Handler
+	jwt        *jwt.JWTManager
+	categories *countingCategories
+	tags       *countingTags
+	media      *countingMedia
+	comments   *countingComments
+	reactions  *countingReactions
+	alice, bob entities.User
+}
+
+// newFixture seeds three published news in categories 10 and 20, each
+// tagged, with one media, commented by alice and bob and liked by alice.
+func newFixture(t *testing.T, cfg config.GraphQLCfg) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	log := dbtest.Logger()
+	store := memoryrepo.NewStore()
+	users := memoryrepo.NewUserRepository(store, log)
+	news := memoryrepo.NewNewsRepository(store, log)
+	categories := memoryrepo.NewCategoriesRepository(store, log)
+	comments := memoryrepo.NewCommentsRepository(store, log)
+	f := &fixture{
+		jwt:        jwt.NewJWTManager("test-secret", log),
+		categories: &countingCategories{NewsCategoriesRepository: memoryrepo.NewNewsCategoriesRepository(store, log)},
+		tags:       &countingTags{TagsRepository: memoryrepo.NewTagsRepository(store, log)},
+		media:      &countingMedia{MediaRepository: memoryrepo.NewMediaRepository(store, log)},
+		comments:   &countingComments{CommentsRepository: comments},
+		reactions:  &countingReactions{ReactionsRepository: memoryrepo.NewReactionsRepository(store, log)},
+		alice:      entities.User{Email: "alice@example.com", Password: "x"},
+		bob:        entities.User{Email: "bob@example.com", Password: "x"},
+	}
+
+	for _, u := range []*entities.User{&f.alice, &f.bob} {
+		if err := users.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var ids []int
+	for _, name := range []int{10, 20} {
+		c := entities.Categorie{Name: name}
+		if err := categories.CreateCategorie(ctx, &c); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, c.ID)
+	}
+	for i := 0; i < 3; i++ {
+		n := entities.News{Title: "News " + string(rune('A'+i)), Content: "text", ContentFormat: "plain", Published: true}
+		if err := news.CreateNews(ctx, &n); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range ids {
+			if err := f.categories.Create(ctx, &entities.NewsCategories{NewsID: n.ID, CategoryID: id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, u := range []entities.User{f.alice, f.bob} {
+			c := entities.Comment{NewsID: n.ID, UserID: u.ID, Body: "hi", Status: entities.CommentApproved}
+			if err := comments.CreateComment(ctx, &c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := f.tags.SetNewsTags(ctx, n.ID, []string{"go"}); err != nil {
+			t.Fatal(err)
+		}
+		media := entities.Media{NewsID: n.ID, Key: "news/" + n.Slug, URL: "/media/news/" + n.Slug, FileName: "a.pdf", ContentType: "application/pdf"}
+		if err := f.media.CreateMedia(ctx, &media); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.reactions.AddReaction(ctx, n.ID, f.alice.ID, "like"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.categories.calls.Store(0)
+
+	server := graphqlserver.New(log, cfg, graphqlserver.Repositories{
+		News:           news,
+		NewsCategories: f.categories,
+		Media:          f.media,
+		Tags:           f.tags,
+		Comments:       f.comments,
+		Reactions:      f.reactions,
+		Bookmarks:      memoryrepo.NewBookmarksRepository(store, log),
+		Subscriptions:  memoryrepo.NewSubscriptionsRepository(store, log),
+		Users:          users,
+	})
+	f.handler = jwt.TokenAuthMiddleware(f.jwt, server)
+	return f
+}
+
+type result struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (f *fixture) query(t *testing.T, user entities.User, query string, variables map[string]any) result {
+	t.Helper()
+	body, err := json.Marshal(graphqlserver.Request{Query: query, Variables: variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if user.Email != "" {
+		token, err := f.jwt.GenerateToken(user.Email, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	f.handler.ServeHTTP(rec, req)
+
+	var res result
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return res
+}
+
+func TestUnauthenticated(t *testing.T) {
+	f := newFixture(t, config.GraphQLCfg{})
+	if res := f.query(t, entities.User{}, "{ me { id } }", nil); res.Status != "Error" || res.Data != nil {
+		t.Fatalf("anonymous query answered: %+v", res)
+	}
+}
+
+func TestNestedQueryBatchesCategories(t *testing.T) {
+	f := newFixture(t, config.GraphQLCfg{MaxDepth: 8, MaxComplexity: 1000})
+	res := f.query(t, f.alice, `{
+		newsList(limit: 10) {
+			title
+			categories { id news(limit: 2) { id categories { id } } }
+			comments { body author { id email } }
+		}
+	}`, nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors: %+v", res.Errors)
+	}
+
+	var data struct {
+		NewsList []struct {
+			Title      string
+			Categories []struct {
+				ID   int
+				News []struct {
+					ID         int
+					Categories []struct{ ID int }
+				}
+			}
+			Comments []struct {
+				Body   string
+				Author struct {
+					ID    int
+					Email *string
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(res.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.NewsList) != 3 {
+		t.Fatalf("got %d news, want 3", len(data.NewsList))
+	}
+	for _, n := range data.NewsList {
+		if len(n.Categories) != 2 || n.Categories[0].ID != 10 || n.Categories[1].ID != 20 {
+			t.Fatalf("%s: categories %+v", n.Title, n.Categories)
+		}
+		if len(n.Categories[0].News) != 2 || len(n.Categories[0].News[0].Categories) != 2 {
+			t.Fatalf("%s: nested news %+v", n.Title, n.Categories[0].News)
+		}
+		if len(n.Comments) != 2 {
+			t.Fatalf("%s: comments %+v", n.Title, n.Comments)
+		}
+		for _, c := range n.Comments {
+			switch c.Author.ID {
+			case f.alice.ID:
+				if c.Author.Email == nil || *c.Author.Email != f.alice.Email {
+					t.Fatalf("own email = %v", c.Author.Email)
+				}
+			case f.bob.ID:
+				if c.Author.Email != nil {
+					t.Fatalf("email of another user leaked: %s", *c.Author.Email)
+				}
+			default:
+				t.Fatalf("author = %+v", c.Author)
+			}
+		}
+	}
+	// One lookup for the news of the list, the news of their categories
+	// are the same ones and come from the cache.
+	if calls := f.categories.calls.Load(); calls != 1 {
+		t.Fatalf("categories were looked up %d times, want 1", calls)
+	}
+}
+
+func TestNewsFieldsAreBatched(t *testing.T) {
+	f := newFixture(t, config.GraphQLCfg{})
+	res := f.query(t, f.alice, `{
+		newsList(limit: 10) {
+			tags
+			media { fileName }
+			commentsCount
+			reactions { kind count }
+		}
+	}`, nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors: %+v", res.Errors)
+	}
+
+	var data struct {
+		NewsList []struct {
+			Tags          []string
+			Media         []struct{ FileName string }
+			CommentsCount int
+			Reactions     []struct {
+				Kind  string
+				Count int
+			}
+		}
+	}
+	if err := json.Unmarshal(res.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.NewsList) != 3 {
+		t.Fatalf("got %d news, want 3", len(data.NewsList))
+	}
+	for _, n := range data.NewsList {
+		if len(n.Tags) != 1 || n.Tags[0] != "go" || len(n.Media) != 1 || n.Media[0].FileName != "a.pdf" ||
+			n.CommentsCount != 2 || len(n.Reactions) != 1 || n.Reactions[0].Kind != "like" || n.Reactions[0].Count != 1 {
+			t.Fatalf("news = %+v", n)
+		}
+	}
+	for name, calls := range map[string]int32{
+		"tags":      f.tags.calls.Load(),
+		"media":     f.media.calls.Load(),
+		"comments":  f.comments.calls.Load(),
+		"reactions": f.reactions.calls.Load(),
+	} {
+		if calls != 1 {
+			t.Errorf("%s were looked up %d times, want 1", name, calls)
+		}
+	}
+}
+
+func TestMe(t *testing.T) {
+	f := newFixture(t, config.GraphQLCfg{})
+	res := f.query(t, f.bob, "{ me { id email subscriptions { id } bookmarks { id } } }", nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors: %+v", res.Errors)
+	}
+	want := `{"me":{"bookmarks":[],"email":"bob@example.com","id":2,"subscriptions":[]}}`
+	if string(res.Data) != want {
+		t.Fatalf("data = %s, want %s", res.Data, want)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	f := newFixture(t, config.GraphQLCfg{MaxDepth: 4, MaxComplexity: 100})
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		err       string
+	}{
+		{
+			name:  "depth",
+			query: "{ newsList(limit: 1) { categories { news(limit: 1) { categories { id } } } } }",
+			err:   "query depth 5 exceeds the limit of 4",
+		},
+		{
+			name:  "depth through fragments",
+			query: "{ newsList(limit: 1) { ...deep } } fragment deep on News { categories { news(limit: 1) { categories { id } } } }",
+			err:   "query depth 5 exceeds the limit of 4",
+		},
+		{
+			name:  "default limit",
+			query: "{ newsList { categories { news { id } } } }",
+			err:   "query complexity 441 exceeds the limit of 100",
+		},
+		{
+			name:      "limit variable",
+			query:     "query($n: Int) { newsList(limit: $n) { id title } }",
+			variables: map[string]any{"n": 50},
+			err:       "query complexity 101 exceeds the limit of 100",
+		},
+		{
+			name:  "within limits",
+			query: "{ newsList(limit: 3) { categories { news(limit: 3) { id } } } }",
+		},
+		{
+			name:  "page bounds",
+			query: "{ newsList(limit: 0) { id } }",
+			err:   "limit must be between 1 and 100",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := f.query(t, f.alice, tt.query, tt.variables)
+			if tt.err == "" {
+				if len(res.Errors) > 0 {
+					t.Fatalf("errors: %+v", res.Errors)
+				}
+				return
+			}
+			if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, tt.err) {
+				t.Fatalf("errors = %+v, want %q", res.Errors, tt.err)
+			}
+		})
+	}
+}
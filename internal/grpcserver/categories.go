@@ -0,0 +1,72 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	newsv1 "news-service/api/proto/news/v1"
+	"news-service/internal/service"
+)
+
+type categoryServer struct {
+	newsv1.UnimplementedCategoryServiceServer
+	log   *slog.Logger
+	repos service.Repositories
+}
+
+func (s *categoryServer) ListCategoryNews(ctx context.Context, req *newsv1.ListCategoryNewsRequest) (*newsv1.ListNewsResponse, error) {
+	log := s.log.With(slog.String("options", "grpcserver.ListCategoryNews"))
+
+	limit, offset, err := page(req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+	news, err := s.repos.News.ListPublishedNewsByCategory(ctx, int(req.Category), limit, offset)
+	if err != nil {
+		return nil, internalError(log, "failed to retrieve news", err)
+	}
+	return newsList(ctx, log, s.repos, news)
+}
+
+func (s *categoryServer) ListSubscriptions(ctx context.Context, req *newsv1.ListSubscriptionsRequest) (*newsv1.Subscriptions, error) {
+	log := s.log.With(slog.String("options", "grpcserver.ListSubscriptions"))
+
+	user, err := currentUser(ctx, s.repos.Users)
+	if err != nil {
+		return nil, err
+	}
+	return s.subscriptions(ctx, log, user.ID)
+}
+
+func (s *categoryServer) Subscribe(ctx context.Context, req *newsv1.SubscriptionRequest) (*newsv1.Subscriptions, error) {
+	log := s.log.With(slog.String("options", "grpcserver.Subscribe"))
+
+	user, err := currentUser(ctx, s.repos.Users)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repos.Subscriptions.Subscribe(ctx, user.ID, int(req.Category)); err != nil {
+		return nil, internalError(log, "failed to save subscription", err)
+	}
+	return s.subscriptions(ctx, log, user.ID)
+}
+
+func (s *categoryServer) Unsubscribe(ctx context.Context, req *newsv1.SubscriptionRequest) (*newsv1.Subscriptions, error) {
+	log := s.log.With(slog.String("options", "grpcserver.Unsubscribe"))
+
+	user, err := currentUser(ctx, s.repos.Users)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repos.Subscriptions.Unsubscribe(ctx, user.ID, int(req.Category)); err != nil {
+		return nil, internalError(log, "failed to save subscription", err)
+	}
+	return s.subscriptions(ctx, log, user.ID)
+}
+
+func (s *categoryServer) subscriptions(ctx context.Context, log *slog.Logger, userID int) (*newsv1.Subscriptions, error) {
+	categories, err := s.repos.Subscriptions.ListSubscriptions(ctx, userID)
+	if err != nil {
+		return nil, internalError(log, "failed to retrieve subscriptions", err)
+	}
+	return &newsv1.Subscriptions{Categories: int32s(categories)}, nil
+}
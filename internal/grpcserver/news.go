@@ -0,0 +1,225 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	newsv1 "news-service/api/proto/news/v1"
+	"news-service/internal/entities"
+	newshandler "news-service/internal/handlers/NewsHandler"
+	"news-service/internal/models"
+	"news-service/internal/service"
+	"news-service/internal/tag"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultTagsLimit = 10
+	maxTagsLimit     = 100
+)
+
+type newsServer struct {
+	newsv1.UnimplementedNewsServiceServer
+	log    *slog.Logger
+	repos  service.Repositories
+	writer service.NewsWriter
+}
+
+func (s *newsServer) CreateNews(ctx context.Context, req *newsv1.CreateNewsRequest) (*newsv1.News, error) {
+	log := s.log.With(slog.String("options", "grpcserver.CreateNews"))
+
+	news, _, err := s.writer.Create(ctx, log, service.NewsInput{
+		Title:         req.Title,
+		Slug:          req.Slug,
+		Summary:       req.Summary,
+		Content:       req.Content,
+		ContentFormat: req.ContentFormat,
+		Categories:    ints(req.Categories),
+		Tags:          req.Tags,
+		Published:     req.Published,
+	})
+	if err != nil {
+		return nil, writeError(log, "failed to create news", err)
+	}
+	return oneNews(ctx, log, s.repos, news)
+}
+
+func (s *newsServer) UpdateNews(ctx context.Context, req *newsv1.UpdateNewsRequest) (*newsv1.News, error) {
+	log := s.log.With(slog.String("options", "grpcserver.UpdateNews"))
+
+	update := service.NewsUpdate{
+		ID:            int(req.Id),
+		Title:         req.Title,
+		Slug:          req.Slug,
+		Summary:       req.Summary,
+		Content:       req.Content,
+		ContentFormat: req.ContentFormat,
+		Categories:    ints(req.Categories),
+		Published:     req.Published,
+	}
+	if req.Tags != nil {
+		update.Tags = append([]string{}, req.Tags.Names...)
+	}
+	news, err := s.writer.Update(ctx, log, update)
+	if err != nil {
+		return nil, writeError(log, "failed to update news", err)
+	}
+	return oneNews(ctx, log, s.repos, news)
+}
+
+// writeError answers an error of service.NewsWriter.
+func writeError(log *slog.Logger, msg string, err error) error {
+	var invalid *service.InvalidError
+	switch {
+	case errors.Is(err, models.ErrNewsNotFound):
+		return status.Error(codes.NotFound, "news not found")
+	case errors.As(err, &invalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return internalError(log, msg, err)
+	}
+}
+
+func (s *newsServer) ListNews(ctx context.Context, req *newsv1.ListNewsRequest) (*newsv1.ListNewsResponse, error) {
+	log := s.log.With(slog.String("options", "grpcserver.ListNews"))
+
+	news, err := s.repos.News.ListNews(ctx)
+	if err != nil {
+		return nil, internalError(log, "failed to retrieve news", err)
+	}
+	return newsList(ctx, log, s.repos, news)
+}
+
+func (s *newsServer) ListPublishedNews(ctx context.Context, req *newsv1.ListPublishedNewsRequest) (*newsv1.ListNewsResponse, error) {
+	log := s.log.With(slog.String("options", "grpcserver.ListPublishedNews"))
+
+	limit, offset, err := page(req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+	news, err := s.repos.News.ListPublishedNews(ctx, limit, offset)
+	if err != nil {
+		return nil, internalError(log, "failed to retrieve news", err)
+	}
+	return newsList(ctx, log, s.repos, news)
+}
+
+func (s *newsServer) GetPublishedNews(ctx context.Context, req *newsv1.GetPublishedNewsRequest) (*newsv1.News, error) {
+	log := s.log.With(slog.String("options", "grpcserver.GetPublishedNews"))
+
+	var (
+		news entities.News
+		err  error
+	)
+	switch key := req.Key.(type) {
+	case *newsv1.GetPublishedNewsRequest_Id:
+		news, err = s.repos.News.FindNewsByID(ctx, int(key.Id))
+	case *newsv1.GetPublishedNewsRequest_Slug:
+		news, err = s.repos.News.FindNewsBySlug(ctx, key.Slug)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "id or slug is required")
+	}
+	if err != nil || !news.Published {
+		return nil, status.Error(codes.NotFound, "news not found")
+	}
+	return oneNews(ctx, log, s.repos, news)
+}
+
+func (s *newsServer) ListTags(ctx context.Context, req *newsv1.ListTagsRequest) (*newsv1.ListTagsResponse, error) {
+	log := s.log.With(slog.String("options", "grpcserver.ListTags"))
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultTagsLimit
+	}
+	if limit < 1 || limit > maxTagsLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", maxTagsLimit)
+	}
+	tags, err := s.repos.Tags.ListTags(ctx, tag.Normalize(req.Prefix), limit)
+	if err != nil {
+		return nil, internalError(log, "failed to retrieve tags", err)
+	}
+
+	resp := &newsv1.ListTagsResponse{Tags: make([]*newsv1.Tag, len(tags))}
+	for i, t := range tags {
+		resp.Tags[i] = &newsv1.Tag{Name: t.Name, Count: int32(t.Count)}
+	}
+	return resp, nil
+}
+
+func oneNews(ctx context.Context, log *slog.Logger, repos service.Repositories, news entities.News) (*newsv1.News, error) {
+	list, err := newsList(ctx, log, repos, []entities.News{news})
+	if err != nil {
+		return nil, err
+	}
+	return list.News[0], nil
+}
+
+// newsList completes news like the REST lists, see newshandler.NewsItems.
+func newsList(ctx context.Context, log *slog.Logger, repos service.Repositories, news []entities.News) (*newsv1.ListNewsResponse, error) {
+	items, err := newshandler.NewsItems(ctx, news, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions)
+	if err != nil {
+		return nil, internalError(log, "failed to retrieve news", err)
+	}
+
+	resp := &newsv1.ListNewsResponse{News: make([]*newsv1.News, len(items))}
+	for i, item := range items {
+		resp.News[i] = newsMessage(item)
+	}
+	return resp, nil
+}
+
+func newsMessage(item newshandler.NewsItem) *newsv1.News {
+	news := &newsv1.News{
+		Id:            int32(item.ID),
+		Title:         item.Title,
+		Slug:          item.Slug,
+		Summary:       item.Summary,
+		Content:       item.Content,
+		ContentFormat: item.ContentFormat,
+		ContentHtml:   item.ContentHTML,
+		Published:     item.Published,
+		Categories:    int32s(item.Categories),
+		Tags:          item.Tags,
+		Media:         make([]*newsv1.Media, len(item.Media)),
+		CommentsCount: int32(item.CommentsCount),
+		Reactions:     make(map[string]int32, len(item.Reactions)),
+		CreatedAt:     timestamppb.New(item.CreatedAt),
+		UpdatedAt:     timestamppb.New(item.UpdatedAt),
+	}
+	for i, m := range item.Media {
+		news.Media[i] = &newsv1.Media{
+			Id:           int32(m.ID),
+			Url:          m.URL,
+			ThumbnailUrl: m.ThumbnailURL,
+			FileName:     m.FileName,
+			ContentType:  m.ContentType,
+			Size:         m.Size,
+			Width:        int32(m.Width),
+			Height:       int32(m.Height),
+		}
+	}
+	for kind, count := range item.Reactions {
+		news.Reactions[kind] = int32(count)
+	}
+	return news
+}
+
+func ints(values []int32) []int {
+	result := make([]int, len(values))
+	for i, v := range values {
+		result[i] = int(v)
+	}
+	return result
+}
+
+func int32s(values []int) []int32 {
+	result := make([]int32, len(values))
+	for i, v := range values {
+		result[i] = int32(v)
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+// Package grpcserver serves the gRPC API of api/proto/news/v1 from the
+// repositories behind the REST API.
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	newsv1 "news-service/api/proto/news/v1"
+	"news-service/api/response"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/jwt"
+	"news-service/internal/service"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods are called without a token, like the routes outside the
+// authenticated group of router.New.
+var publicMethods = map[string]bool{
+	newsv1.NewsService_ListPublishedNews_FullMethodName:    true,
+	newsv1.NewsService_GetPublishedNews_FullMethodName:     true,
+	newsv1.CategoryService_ListCategoryNews_FullMethodName: true,
+	newsv1.UserService_CreateUser_FullMethodName:           true,
+	newsv1.UserService_Login_FullMethodName:                true,
+}
+
+// New serves the API, throttle is shared with POST /login and POST
+// /users/new through repos.RateLimits, see router.NewThrottle, and accounts
+// with the email verification of the HTTP API, see service.NewAccounts.
+func New(log *slog.Logger, repos service.Repositories, jwtManager *jwt.JWTManager, throttle userhandlers.Throttle, accounts userhandlers.Accounts) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		logging(log),
+		recovery(log),
+		jwt.UnaryServerInterceptor(jwtManager, func(fullMethod string) bool { return publicMethods[fullMethod] }),
+	))
+	newsv1.RegisterNewsServiceServer(server, &newsServer{log: log, repos: repos, writer: service.NewNewsWriter(repos)})
+	newsv1.RegisterCategoryServiceServer(server, &categoryServer{log: log, repos: repos})
+	newsv1.RegisterUserServiceServer(server, &userServer{log: log, repos: repos, jwt: jwtManager, throttle: throttle, accounts: accounts})
+	return server
+}
+
+// logging logs every call with its outcome, like middleware.Logger does for
+// HTTP requests.
+func logging(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		started := time.Now()
+		resp, err := handler(ctx, req)
+		log.Info("grpc call",
+			slog.String("method", info.FullMethod),
+			slog.String("code", status.Code(err).String()),
+			slog.Duration("duration", time.Since(started)))
+		return resp, err
+	}
+}
+
+// recovery answers a panicking call with codes.Internal instead of taking
+// the process down, like middleware.Recoverer.
+func recovery(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Error("grpc call panicked", slog.String("method", info.FullMethod), slog.String("panic", fmt.Sprint(p)))
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// internalError logs err and answers with msg only, the way the REST
+// handlers hide database errors.
+func internalError(log *slog.Logger, msg string, err error) error {
+	log.Error(msg, errMsg.Err(err))
+	return status.Error(codes.Internal, msg)
+}
+
+// page applies the defaults and bounds of the REST pagination.
+func page(limit, offset int32) (int, int, error) {
+	if limit == 0 {
+		limit = response.DefaultPageSize
+	}
+	if limit < 1 || limit > response.MaxPageSize {
+		return 0, 0, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", response.MaxPageSize)
+	}
+	if offset < 0 {
+		return 0, 0, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+	return int(limit), int(offset), nil
+}
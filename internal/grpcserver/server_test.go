@@ -0,0 +1,170 @@
+package grpcserver_test
+
+import (
+	"context"
+	"net"
+	newsv1 "news-service/api/proto/news/v1"
+	"news-service/internal/config"
+	"news-service/internal/database/dbtest"
+	memoryrepo "news-service/internal/database/memoryRepo"
+	"news-service/internal/grpcserver"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/jwt"
+	"news-service/internal/ratelimit"
+	"news-service/internal/service"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	store := memoryrepo.NewStore()
+	log := dbtest.Logger()
+	repos := service.Repositories{
+		News:           memoryrepo.NewNewsRepository(store, log),
+		Categories:     memoryrepo.NewCategoriesRepository(store, log),
+		NewsCategories: memoryrepo.NewNewsCategoriesRepository(store, log),
+		Users:          memoryrepo.NewUserRepository(store, log),
+		UserTokens:     memoryrepo.NewUserTokensRepository(store, log),
+		Media:          memoryrepo.NewMediaRepository(store, log),
+		Tags:           memoryrepo.NewTagsRepository(store, log),
+		Comments:       memoryrepo.NewCommentsRepository(store, log),
+		Reactions:      memoryrepo.NewReactionsRepository(store, log),
+		Subscriptions:  memoryrepo.NewSubscriptionsRepository(store, log),
+		Webhooks:       memoryrepo.NewWebhooksRepository(store, log),
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpcserver.New(log, repos, jwt.NewJWTManager("test-secret", log), userhandlers.Throttle{
+		Lockout: ratelimit.NewLockout(ratelimit.NewMemory(), "login", 2, time.Minute, time.Hour),
+	}, service.NewAccounts(config.MailCfg{}, repos))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestNewsAPI(t *testing.T) {
+	conn := newClient(t)
+	users := newsv1.NewUserServiceClient(conn)
+	news := newsv1.NewNewsServiceClient(conn)
+	categories := newsv1.NewCategoryServiceClient(conn)
+	ctx := context.Background()
+
+	if _, err := news.ListNews(ctx, &newsv1.ListNewsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("ListNews without a token: %v", err)
+	}
+	bad := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer nonsense")
+	if _, err := news.ListNews(bad, &newsv1.ListNewsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("ListNews with a bad token: %v", err)
+	}
+
+	if _, err := users.CreateUser(ctx, &newsv1.CreateUserRequest{Email: "editor@example.com", Password: "secret"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	// Wrong passwords and unknown emails are answered alike.
+	for _, email := range []string{"editor@example.com", "nobody@example.com"} {
+		_, err := users.Login(ctx, &newsv1.LoginRequest{Email: email, Password: "wrong"})
+		if status.Code(err) != codes.Unauthenticated || status.Convert(err).Message() != "invalid credentials" {
+			t.Fatalf("Login as %s with a wrong password: %v", email, err)
+		}
+	}
+	users.Login(ctx, &newsv1.LoginRequest{Email: "nobody@example.com", Password: "wrong"})
+	if _, err := users.Login(ctx, &newsv1.LoginRequest{Email: "nobody@example.com", Password: "wrong"}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Login after the lockout threshold: %v", err)
+	}
+	login, err := users.Login(ctx, &newsv1.LoginRequest{Email: "editor@example.com", Password: "secret"})
+	if err != nil || login.Token == "" || login.User.Email != "editor@example.com" {
+		t.Fatalf("Login = %v, %v", login, err)
+	}
+	auth := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+login.Token)
+
+	draft := false
+	created, err := news.CreateNews(auth, &newsv1.CreateNewsRequest{
+		Title:         "Hello gRPC",
+		Content:       "**bold**",
+		ContentFormat: "markdown",
+		Categories:    []int32{7},
+		Tags:          []string{"Go", "gRPC"},
+		Published:     &draft,
+	})
+	if err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	if created.Id == 0 || created.Slug == "" || created.Published || created.ContentHtml == "" ||
+		!slices.Equal(created.Categories, []int32{7}) || len(created.Tags) != 2 || created.CreatedAt == nil {
+		t.Fatalf("CreateNews = %v", created)
+	}
+	if _, err := news.CreateNews(auth, &newsv1.CreateNewsRequest{Title: "x", ContentFormat: "rtf"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("CreateNews with an unknown format: %v", err)
+	}
+
+	if _, err := news.GetPublishedNews(ctx, &newsv1.GetPublishedNewsRequest{Key: &newsv1.GetPublishedNewsRequest_Id{Id: created.Id}}); status.Code(err) != codes.NotFound {
+		t.Fatalf("GetPublishedNews of a draft: %v", err)
+	}
+
+	published := true
+	updated, err := news.UpdateNews(auth, &newsv1.UpdateNewsRequest{
+		Id:         created.Id,
+		Title:      "Hello again",
+		Content:    "plain text",
+		Categories: []int32{7, 8},
+		Published:  &published,
+	})
+	if err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+	if updated.Title != "Hello again" || !updated.Published || updated.ContentFormat != "markdown" || len(updated.Tags) != 2 || len(updated.Categories) != 2 {
+		t.Fatalf("UpdateNews = %v", updated)
+	}
+	if _, err := news.UpdateNews(auth, &newsv1.UpdateNewsRequest{Id: 999}); status.Code(err) != codes.NotFound {
+		t.Fatalf("UpdateNews of a missing news: %v", err)
+	}
+
+	bySlug, err := news.GetPublishedNews(ctx, &newsv1.GetPublishedNewsRequest{Key: &newsv1.GetPublishedNewsRequest_Slug{Slug: updated.Slug}})
+	if err != nil || bySlug.Id != created.Id {
+		t.Fatalf("GetPublishedNews by slug = %v, %v", bySlug, err)
+	}
+	list, err := news.ListPublishedNews(ctx, &newsv1.ListPublishedNewsRequest{})
+	if err != nil || len(list.News) != 1 {
+		t.Fatalf("ListPublishedNews = %v, %v", list, err)
+	}
+	if _, err := news.ListPublishedNews(ctx, &newsv1.ListPublishedNewsRequest{Limit: 1000}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("ListPublishedNews over the limit: %v", err)
+	}
+	tags, err := news.ListTags(auth, &newsv1.ListTagsRequest{Prefix: "g"})
+	if err != nil || len(tags.Tags) != 2 {
+		t.Fatalf("ListTags = %v, %v", tags, err)
+	}
+
+	byCategory, err := categories.ListCategoryNews(ctx, &newsv1.ListCategoryNewsRequest{Category: 8})
+	if err != nil || len(byCategory.News) != 1 {
+		t.Fatalf("ListCategoryNews = %v, %v", byCategory, err)
+	}
+	if _, err := categories.Subscribe(ctx, &newsv1.SubscriptionRequest{Category: 8}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Subscribe without a token: %v", err)
+	}
+	subscriptions, err := categories.Subscribe(auth, &newsv1.SubscriptionRequest{Category: 8})
+	if err != nil || !slices.Equal(subscriptions.Categories, []int32{8}) {
+		t.Fatalf("Subscribe = %v, %v", subscriptions, err)
+	}
+	subscriptions, err = categories.Unsubscribe(auth, &newsv1.SubscriptionRequest{Category: 8})
+	if err != nil || len(subscriptions.Categories) != 0 {
+		t.Fatalf("Unsubscribe = %v, %v", subscriptions, err)
+	}
+}
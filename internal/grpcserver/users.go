@@ -0,0 +1,111 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	newsv1 "news-service/api/proto/news/v1"
+	auth "news-service/internal/auth/pass"
+	"news-service/internal/entities"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/jwt"
+	"news-service/internal/service"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// tokenExpiration matches the tokens issued by POST /login.
+const tokenExpiration = 600 * time.Second
+
+type userServer struct {
+	newsv1.UnimplementedUserServiceServer
+	log      *slog.Logger
+	repos    service.Repositories
+	jwt      *jwt.JWTManager
+	throttle userhandlers.Throttle
+	accounts userhandlers.Accounts
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *newsv1.CreateUserRequest) (*newsv1.User, error) {
+	log := s.log.With(slog.String("options", "grpcserver.CreateUser"))
+
+	if req.Email == "" || req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and password are required")
+	}
+	if err := s.throttle.Allow(ctx, peerIP(ctx), req.Email); err != nil {
+		return nil, throttleError(log, err)
+	}
+	hashPass, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return nil, internalError(log, "failed to create user", err)
+	}
+	user := entities.User{Email: req.Email, Password: hashPass}
+	if err := s.repos.Users.CreateUser(ctx, &user); err != nil {
+		return nil, internalError(log, "failed to create user", err)
+	}
+	s.accounts.VerifyInBackground(ctx, log, user)
+	return &newsv1.User{Id: int32(user.ID), Email: user.Email}, nil
+}
+
+func (s *userServer) Login(ctx context.Context, req *newsv1.LoginRequest) (*newsv1.LoginResponse, error) {
+	log := s.log.With(slog.String("options", "grpcserver.Login"))
+
+	if req.Email == "" || req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and password are required")
+	}
+	user, err := s.throttle.Authenticate(ctx, s.repos.Users, peerIP(ctx), req.Email, req.Password)
+	if errors.Is(err, userhandlers.ErrInvalidCredentials) {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	if err != nil {
+		return nil, throttleError(log, err)
+	}
+	if err := s.accounts.CheckVerified(user); err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	token, err := s.jwt.GenerateToken(user.Email, tokenExpiration)
+	if err != nil {
+		return nil, internalError(log, "failed to authorize", err)
+	}
+	return &newsv1.LoginResponse{User: &newsv1.User{Id: int32(user.ID), Email: user.Email}, Token: token}, nil
+}
+
+// throttleError answers a rejection of userhandlers.Throttle with
+// codes.ResourceExhausted, the counterpart of 429 Too Many Requests.
+func throttleError(log *slog.Logger, err error) error {
+	var throttled *userhandlers.ThrottledError
+	if errors.As(err, &throttled) {
+		return status.Error(codes.ResourceExhausted, throttled.Error())
+	}
+	return internalError(log, "failed to check rate limits", err)
+}
+
+// peerIP keys calls by client address like ratelimit.ByIP.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// currentUser returns the user authenticated by jwt.UnaryServerInterceptor.
+func currentUser(ctx context.Context, users userhandlers.User) (entities.User, error) {
+	email, ok := jwt.EmailFromContext(ctx)
+	if !ok {
+		return entities.User{}, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	user, err := users.FindUserByEmail(ctx, email)
+	if err != nil {
+		return entities.User{}, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return user, nil
+}
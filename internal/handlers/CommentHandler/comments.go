@@ -0,0 +1,126 @@
+// Package commenthandler serves the comments of news: reading threads,
+// writing, editing and deleting comments and the moderation queue.
+package commenthandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/entities"
+	"news-service/internal/jwt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/render"
+)
+
+// MaxBodyLength is the longest comment accepted, in characters.
+const MaxBodyLength = 2000
+
+type CommentItem struct {
+	ID        int           `json:"id"`
+	NewsID    int           `json:"news_id"`
+	ParentID  int           `json:"parent_id,omitempty"`
+	UserID    int           `json:"user_id"`
+	Body      string        `json:"body"`
+	Status    string        `json:"status"`
+	Edited    bool          `json:"edited"`
+	Deleted   bool          `json:"deleted"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Replies   []CommentItem `json:"replies,omitempty"`
+}
+
+type ResponseComment struct {
+	response.Response
+	Comment CommentItem `json:"comment"`
+}
+
+type ResponseComments struct {
+	response.Response
+	Comments []CommentItem `json:"comments"`
+}
+
+type RequestComment struct {
+	Body string `json:"body"`
+	// ParentID is the comment replied to, omitted for top-level comments.
+	ParentID int `json:"parent_id"`
+}
+
+func commentItem(c entities.Comment) CommentItem {
+	return CommentItem{
+		ID:        c.ID,
+		NewsID:    c.NewsID,
+		ParentID:  c.ParentID,
+		UserID:    c.UserID,
+		Body:      c.Body,
+		Status:    c.Status,
+		Edited:    c.UpdatedAt.After(c.CreatedAt),
+		Deleted:   c.DeletedAt != nil,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+// thread nests comments under their parents. Replies to comments that are
+// not in the list are dropped, and so are deleted comments without replies.
+func thread(comments []entities.Comment) []CommentItem {
+	children := make(map[int][]entities.Comment)
+	for _, c := range comments {
+		children[c.ParentID] = append(children[c.ParentID], c)
+	}
+
+	var build func(parentID int) []CommentItem
+	build = func(parentID int) []CommentItem {
+		items := []CommentItem{}
+		for _, c := range children[parentID] {
+			item := commentItem(c)
+			item.Replies = build(c.ID)
+			if item.Deleted && len(item.Replies) == 0 {
+				continue
+			}
+			items = append(items, item)
+		}
+		return items
+	}
+	return build(0)
+}
+
+// normalizeBody trims a comment and checks its length.
+func normalizeBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("comment is empty")
+	}
+	if utf8.RuneCountInString(body) > MaxBodyLength {
+		return "", fmt.Errorf("comment is longer than %d characters", MaxBodyLength)
+	}
+	return body, nil
+}
+
+// isModerator reports whether email may moderate comments. Nobody may when
+// no moderators are configured, the queue then only fills up.
+func isModerator(moderators []string, email string) bool {
+	return email != "" && slices.ContainsFunc(moderators, func(m string) bool {
+		return strings.EqualFold(m, email)
+	})
+}
+
+// RequireModerator answers 403 Forbidden to users that may not moderate
+// comments. It expects jwt.TokenAuthMiddleware to run first.
+func RequireModerator(moderators []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			email, _ := jwt.EmailFromContext(r.Context())
+			if !isModerator(moderators, email) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, response.Error("forbidden"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
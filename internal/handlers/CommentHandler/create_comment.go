@@ -0,0 +1,84 @@
+package commenthandler
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// CreateComment serves POST /news/{id}/comments. The comment waits for
+// moderation; replies may only be made to approved comments of the same
+// news.
+func CreateComment(log *slog.Logger, newsRepository models.NewsRepository, userRepository userhandlers.User, commentsRepository models.CommentsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.createComment"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		newsID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("failed to convert request parameter id", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid news id"))
+			return
+		}
+		news, err := newsRepository.FindNewsByID(r.Context(), newsID)
+		if err != nil || !news.Published {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("news not found"))
+			return
+		}
+
+		var req RequestComment
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+			return
+		}
+		body, err := normalizeBody(req.Body)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		if req.ParentID != 0 {
+			parent, err := commentsRepository.FindComment(r.Context(), req.ParentID)
+			if err != nil || parent.NewsID != news.ID || parent.Status != entities.CommentApproved || parent.DeletedAt != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("invalid parent comment"))
+				return
+			}
+		}
+
+		comment := entities.Comment{NewsID: news.ID, UserID: user.ID, ParentID: req.ParentID, Body: body}
+		if err := commentsRepository.CreateComment(r.Context(), &comment); err != nil {
+			log.Error("failed to create comment", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to create comment"))
+			return
+		}
+
+		log.Info("comment added", slog.Int("comment_id", comment.ID), slog.Int("news_id", news.ID))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, ResponseComment{Response: response.OK(), Comment: commentItem(comment)})
+	}
+}
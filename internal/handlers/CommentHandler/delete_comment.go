@@ -0,0 +1,51 @@
+package commenthandler
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// DeleteComment serves DELETE /comments/{id} for the author and moderators.
+// The comment is emptied but keeps its place in the thread while it has
+// replies.
+func DeleteComment(log *slog.Logger, moderators []string, userRepository userhandlers.User, commentsRepository models.CommentsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.deleteComment"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		comment, ok := findComment(w, r, commentsRepository)
+		if !ok {
+			return
+		}
+		if comment.UserID != user.ID && !isModerator(moderators, user.Email) {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+			return
+		}
+
+		if err := commentsRepository.DeleteComment(r.Context(), comment.ID); err != nil {
+			log.Error("failed to delete comment", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to delete comment"))
+			return
+		}
+		log.Info("comment deleted", slog.Int("comment_id", comment.ID), slog.Int("user_id", user.ID))
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,94 @@
+package commenthandler
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type RequestEditComment struct {
+	Body string `json:"body"`
+}
+
+// EditComment serves PATCH /comments/{id}. Authors may edit their comments
+// within editWindow of posting them, the edited comment is moderated again.
+func EditComment(log *slog.Logger, editWindow time.Duration, userRepository userhandlers.User, commentsRepository models.CommentsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.editComment"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		comment, ok := findComment(w, r, commentsRepository)
+		if !ok {
+			return
+		}
+		if comment.UserID != user.ID {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("only the author may edit a comment"))
+			return
+		}
+		if time.Since(comment.CreatedAt) > editWindow {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("the comment can no longer be edited"))
+			return
+		}
+
+		var req RequestEditComment
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+			return
+		}
+		comment.Body, err = normalizeBody(req.Body)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		if err := commentsRepository.EditComment(r.Context(), &comment); err != nil {
+			log.Error("failed to edit comment", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to edit comment"))
+			return
+		}
+		render.JSON(w, r, ResponseComment{Response: response.OK(), Comment: commentItem(comment)})
+	}
+}
+
+// findComment loads the comment of the {id} URL parameter and answers the
+// request itself when there is none. Deleted comments are not found.
+func findComment(w http.ResponseWriter, r *http.Request, commentsRepository models.CommentsRepository) (entities.Comment, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid comment id"))
+		return entities.Comment{}, false
+	}
+	comment, err := commentsRepository.FindComment(r.Context(), id)
+	if err != nil || comment.DeletedAt != nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, response.Error("comment not found"))
+		return entities.Comment{}, false
+	}
+	return comment, true
+}
@@ -0,0 +1,50 @@
+package commenthandler
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// ListComments serves GET /news/{id}/comments with the approved comments of
+// a published news as a tree of replies.
+func ListComments(log *slog.Logger, newsRepository models.NewsRepository, commentsRepository models.CommentsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.listComments"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		newsID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("failed to convert request parameter id", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid news id"))
+			return
+		}
+		news, err := newsRepository.FindNewsByID(r.Context(), newsID)
+		if err != nil || !news.Published {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("news not found"))
+			return
+		}
+
+		comments, err := commentsRepository.ListComments(r.Context(), news.ID)
+		if err != nil {
+			log.Error("Failed to retrieve comments", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve comments"))
+			return
+		}
+
+		render.JSON(w, r, ResponseComments{Response: response.OK(), Comments: thread(comments)})
+	}
+}
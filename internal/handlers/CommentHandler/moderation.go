@@ -0,0 +1,95 @@
+package commenthandler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// ListPendingComments serves GET /comments/pending?limit=&offset=, the
+// moderation queue, oldest comments first.
+func ListPendingComments(log *slog.Logger, commentsRepository models.CommentsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.listPendingComments"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, offset, err := response.Pagination(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		comments, err := commentsRepository.ListPendingComments(r.Context(), limit, offset)
+		if err != nil {
+			log.Error("Failed to retrieve pending comments", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve comments"))
+			return
+		}
+
+		items := make([]CommentItem, len(comments))
+		for i, c := range comments {
+			items[i] = commentItem(c)
+		}
+		render.JSON(w, r, ResponseComments{Response: response.OK(), Comments: items})
+	}
+}
+
+// ModerateComment serves POST /comments/{id}/approve and
+// POST /comments/{id}/reject, status is the resulting comment status.
+// Moderators may not approve their own comments, and only pending comments
+// are moderated.
+func ModerateComment(log *slog.Logger, status string, userRepository userhandlers.User, commentsRepository models.CommentsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.moderateComment"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		comment, ok := findComment(w, r, commentsRepository)
+		if !ok {
+			return
+		}
+		if status == entities.CommentApproved && comment.UserID == user.ID {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("moderators may not approve their own comments"))
+			return
+		}
+		// The comment is only moderated as it was read, not once its
+		// author edited it or someone else moderated it.
+		err = commentsRepository.SetCommentStatus(r.Context(), comment.ID, comment.UpdatedAt, status)
+		if errors.Is(err, models.ErrCommentChanged) {
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("comment is not pending moderation or changed meanwhile"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to moderate comment", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to moderate comment"))
+			return
+		}
+		comment.Status = status
+
+		log.Info("comment moderated", slog.Int("comment_id", comment.ID), slog.String("status", status))
+		render.JSON(w, r, ResponseComment{Response: response.OK(), Comment: commentItem(comment)})
+	}
+}
@@ -0,0 +1,113 @@
+package newshandler
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type ResponseBookmark struct {
+	response.Response
+	Bookmarked bool `json:"bookmarked"`
+}
+
+// SetBookmark serves PUT /news/{id}/bookmark when bookmarked is set and
+// DELETE otherwise. Both are idempotent. Only published news may be
+// bookmarked.
+func SetBookmark(log *slog.Logger, bookmarked bool, newsRepository models.NewsRepository, userRepository userhandlers.User, bookmarksRepository models.BookmarksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.setBookmark"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		newsID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("failed to convert request parameter id", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid news id"))
+			return
+		}
+		news, err := newsRepository.FindNewsByID(r.Context(), newsID)
+		if err != nil || (bookmarked && !news.Published) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("news not found"))
+			return
+		}
+
+		if bookmarked {
+			err = bookmarksRepository.AddBookmark(r.Context(), user.ID, news.ID)
+		} else {
+			err = bookmarksRepository.RemoveBookmark(r.Context(), user.ID, news.ID)
+		}
+		if err != nil {
+			log.Error("failed to save bookmark", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to save bookmark"))
+			return
+		}
+
+		render.JSON(w, r, ResponseBookmark{Response: response.OK(), Bookmarked: bookmarked})
+	}
+}
+
+// ListBookmarks serves GET /users/me/bookmarks?limit=&offset= with the
+// published news bookmarked by the current user, latest bookmark first.
+func ListBookmarks(log *slog.Logger, userRepository userhandlers.User, bookmarksRepository models.BookmarksRepository, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.listBookmarks"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		limit, offset, err := response.Pagination(r)
+		if err != nil {
+			log.Error("invalid pagination", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		newsArray, err := bookmarksRepository.ListBookmarkedNews(r.Context(), user.ID, limit, offset)
+		if err != nil {
+			log.Error("Failed to retrieve bookmarks", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		result, err := NewsItems(r.Context(), newsArray, newsCategoriesRepository, mediaRepository, tagsRepository, commentsRepository, reactionsRepository)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		responseOKgetNews(w, r, result)
+	}
+}
@@ -0,0 +1,19 @@
+package newshandler
+
+import (
+	"news-service/internal/content"
+	"news-service/internal/entities"
+)
+
+// ContentHTML returns the cached HTML of news. News stored before the
+// rendering was cached are rendered on the fly until their next update.
+func ContentHTML(news entities.News) string {
+	if news.ContentHTML != "" || news.Content == "" {
+		return news.ContentHTML
+	}
+	f, err := content.ParseFormat(news.ContentFormat)
+	if err != nil {
+		f = content.Plain
+	}
+	return content.Render(f, news.Content)
+}
@@ -1,12 +1,13 @@
 package newshandler
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"news-service/api/response"
-	"news-service/internal/entities"
+	apiv1 "news-service/api/v1"
 	errMsg "news-service/internal/err"
-	"news-service/internal/models"
+	"news-service/internal/service"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
@@ -14,20 +15,36 @@ import (
 )
 
 type RequestNews struct {
-	Title      string `json:"Title"`
-	Content    string `json:"Content"`
-	Categories []int  `json:"Categories"`
+	Title string `json:"Title"`
+	// Slug is derived from Title when empty.
+	Slug string `json:"Slug"`
+	// Summary is a plain text lede shown in lists and feeds.
+	Summary string `json:"Summary"`
+	Content string `json:"Content"`
+	// ContentFormat is one of plain (default), markdown or html.
+	ContentFormat string `json:"ContentFormat"`
+	Categories    []int  `json:"Categories"`
+	// Tags are free-form, see tag.Normalize.
+	Tags []string `json:"Tags"`
+	// Published defaults to true, drafts are hidden from the public API.
+	Published *bool `json:"Published"`
 }
 
 type ResponseNews struct {
 	response.Response
-	ID         int    `json:"id"`
-	Title      string `json:"title"`
-	Content    string `json:"content"`
-	Categories []int  `json:"categories"`
+	ID            int         `json:"id"`
+	Title         string      `json:"title"`
+	Slug          string      `json:"slug"`
+	Summary       string      `json:"summary"`
+	Content       string      `json:"content"`
+	ContentFormat string      `json:"content_format"`
+	ContentHTML   string      `json:"content_html"`
+	Categories    []int       `json:"categories"`
+	Tags          []string    `json:"tags"`
+	Media         []MediaItem `json:"media"`
 }
 
-func NewNews(log *slog.Logger, NewsRepository models.NewsRepository, CategoriesRepository models.CategoriesRepository, NewsCategoriesRepository models.NewsCategoriesRepository) http.HandlerFunc {
+func NewNews(log *slog.Logger, writer service.NewsWriter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const loggerOptions = "handlers.createNews.New"
 
@@ -36,8 +53,7 @@ func NewNews(log *slog.Logger, NewsRepository models.NewsRepository, CategoriesR
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		var req RequestNews
-		err := render.DecodeJSON(r.Body, &req)
+		req, err := decodeNews(r)
 		if err != nil {
 			log.Error("failed to decode request body", errMsg.Err(err))
 			render.JSON(w, r, response.Error("failed to decode request"))
@@ -52,39 +68,67 @@ func NewNews(log *slog.Logger, NewsRepository models.NewsRepository, CategoriesR
 			return
 		}
 
-		news := entities.News{Title: req.Title, Content: req.Content}
-		err = NewsRepository.CreateNews(r.Context(), &news)
+		news, tags, err := writer.Create(r.Context(), log, service.NewsInput{
+			Title:         req.Title,
+			Slug:          req.Slug,
+			Summary:       req.Summary,
+			Content:       req.Content,
+			ContentFormat: req.ContentFormat,
+			Categories:    req.Categories,
+			Tags:          req.Tags,
+			Published:     req.Published,
+		})
+		var invalid *service.InvalidError
+		if errors.As(err, &invalid) {
+			log.Error("invalid content", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
 		if err != nil {
 			log.Error("failed to create news", errMsg.Err((err)))
+			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, response.Error("failed to create  news"))
 			return
 		}
-		for _, categorie_name := range req.Categories {
-			categorie := entities.Categorie{Name: categorie_name}
-			err = CategoriesRepository.CreateCategorie(r.Context(), &categorie)
-			if err != nil {
-				log.Error("could not add categorie to the table", errMsg.Err(err))
-				return
-			}
-
-			newsCategories := entities.NewsCategories{CategoryID: categorie.ID, NewsID: news.ID}
-			err = NewsCategoriesRepository.Create(r.Context(), &newsCategories)
-			if err != nil {
-				log.Error("could not add Newscategories relation to the table", errMsg.Err(err))
-				return
-			}
-		}
 		log.Info("news added to postgres")
-		responseOK(w, r, news.ID, news.Title, news.Content, req.Categories)
+		responseOK(w, r, NewsItem{
+			ID:            news.ID,
+			Title:         news.Title,
+			Slug:          news.Slug,
+			Summary:       news.Summary,
+			Content:       news.Content,
+			ContentFormat: news.ContentFormat,
+			ContentHTML:   ContentHTML(news),
+			Published:     news.Published,
+			Categories:    req.Categories,
+			Tags:          tags,
+			Media:         []MediaItem{},
+			Reactions:     map[string]int{},
+			CreatedAt:     news.CreatedAt,
+			UpdatedAt:     news.UpdatedAt,
+		})
 	}
 }
 
-func responseOK(w http.ResponseWriter, r *http.Request, id int, title, content string, categories []int) {
+// responseOK renders a single news, as ResponseNews or apiv1.NewsResponse
+// for /v1 requests.
+func responseOK(w http.ResponseWriter, r *http.Request, item NewsItem) {
+	if apiv1.Requested(r) {
+		render.JSON(w, r, apiv1.NewsResponse{Response: response.OK(), News: newsDTO(item)})
+		return
+	}
 	render.JSON(w, r, ResponseNews{
-		response.OK(),
-		id,
-		title,
-		content,
-		categories,
+		Response:      response.OK(),
+		ID:            item.ID,
+		Title:         item.Title,
+		Slug:          item.Slug,
+		Summary:       item.Summary,
+		Content:       item.Content,
+		ContentFormat: item.ContentFormat,
+		ContentHTML:   item.ContentHTML,
+		Categories:    item.Categories,
+		Tags:          item.Tags,
+		Media:         item.Media,
 	})
 }
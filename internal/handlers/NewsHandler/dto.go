@@ -0,0 +1,81 @@
+package newshandler
+
+import (
+	"net/http"
+	apiv1 "news-service/api/v1"
+
+	"github.com/go-chi/render"
+)
+
+// decodeNews reads the body of POST /news, apiv1.NewsRequest for /v1
+// requests.
+func decodeNews(r *http.Request) (RequestNews, error) {
+	if !apiv1.Requested(r) {
+		var req RequestNews
+		err := render.DecodeJSON(r.Body, &req)
+		return req, err
+	}
+	var req apiv1.NewsRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		return RequestNews{}, err
+	}
+	return RequestNews{
+		Title:         req.Title,
+		Slug:          req.Slug,
+		Summary:       req.Summary,
+		Content:       req.Content,
+		ContentFormat: req.ContentFormat,
+		Categories:    req.Categories,
+		Tags:          req.Tags,
+		Published:     req.Published,
+	}, nil
+}
+
+// decodeUpdateNews reads the body of PATCH /news/edit/{id},
+// apiv1.NewsUpdateRequest for /v1 requests.
+func decodeUpdateNews(r *http.Request) (RequestUpdateNews, error) {
+	if !apiv1.Requested(r) {
+		var req RequestUpdateNews
+		err := render.DecodeJSON(r.Body, &req)
+		return req, err
+	}
+	var req apiv1.NewsUpdateRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		return RequestUpdateNews{}, err
+	}
+	return RequestUpdateNews{
+		Title:         req.Title,
+		Slug:          req.Slug,
+		Summary:       req.Summary,
+		Content:       req.Content,
+		ContentFormat: req.ContentFormat,
+		Categories:    req.Categories,
+		Tags:          req.Tags,
+		Published:     req.Published,
+	}, nil
+}
+
+// newsDTO converts a news to its /v1 form.
+func newsDTO(item NewsItem) apiv1.News {
+	media := make([]apiv1.Media, len(item.Media))
+	for i, m := range item.Media {
+		media[i] = apiv1.Media(m)
+	}
+	return apiv1.News{
+		ID:            item.ID,
+		Title:         item.Title,
+		Slug:          item.Slug,
+		Summary:       item.Summary,
+		Content:       item.Content,
+		ContentFormat: item.ContentFormat,
+		ContentHTML:   item.ContentHTML,
+		Published:     item.Published,
+		Categories:    item.Categories,
+		Tags:          item.Tags,
+		Media:         media,
+		CommentsCount: item.CommentsCount,
+		Reactions:     item.Reactions,
+		CreatedAt:     item.CreatedAt,
+		UpdatedAt:     item.UpdatedAt,
+	}
+}
@@ -0,0 +1,135 @@
+package newshandler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/config"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/feed"
+	"news-service/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Feed serves /feed.rss, /feed.atom and the same documents per category
+// under /categories/{category}/feed. The format comes from the URL
+// extension parsed by middleware.URLFormat.
+func Feed(log *slog.Logger, cfg config.FeedCfg, newsRepository models.NewsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.feed"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string)
+		if format != "rss" && format != "atom" {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("unknown feed format"))
+			return
+		}
+
+		limit := cfg.Items
+		if limit <= 0 {
+			limit = response.DefaultPageSize
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > response.MaxPageSize {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error(fmt.Sprintf("limit must be between 1 and %d", response.MaxPageSize)))
+				return
+			}
+			limit = n
+		}
+
+		baseURL := strings.TrimSuffix(cfg.BaseURL, "/")
+		if baseURL == "" {
+			baseURL = requestBaseURL(r)
+		}
+
+		channel := feed.Channel{
+			Title:       cfg.Title,
+			Description: cfg.Description,
+			Link:        baseURL + "/news",
+			Self:        baseURL + r.URL.Path,
+		}
+
+		var (
+			newsArray []entities.News
+			err       error
+		)
+		if c := chi.URLParam(r, "category"); c != "" {
+			category, convErr := strconv.Atoi(c)
+			if convErr != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("invalid category"))
+				return
+			}
+			channel.Title = fmt.Sprintf("%s: %d", cfg.Title, category)
+			channel.Link = fmt.Sprintf("%s/categories/%d/news", baseURL, category)
+			newsArray, err = newsRepository.ListPublishedNewsByCategory(r.Context(), category, limit, 0)
+		} else {
+			newsArray, err = newsRepository.ListPublishedNews(r.Context(), limit, 0)
+		}
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		items := make([]feed.Item, len(newsArray))
+		for i, news := range newsArray {
+			items[i] = feed.Item{
+				ID:        fmt.Sprintf("%s/news/%d", baseURL, news.ID),
+				Title:     news.Title,
+				Link:      baseURL + "/news/by-slug/" + news.Slug,
+				Summary:   news.Summary,
+				Content:   ContentHTML(news),
+				Published: news.CreatedAt,
+				Updated:   news.UpdatedAt,
+			}
+		}
+
+		var body []byte
+		if format == "rss" {
+			body, err = feed.RSS(channel, items)
+			w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		} else {
+			body, err = feed.Atom(channel, items)
+			w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		}
+		if err != nil {
+			log.Error("failed to render feed", errMsg.Err(err))
+			w.Header().Del("Content-Type")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to render feed"))
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		// ServeContent answers If-None-Match and If-Modified-Since with
+		// 304 Not Modified.
+		http.ServeContent(w, r, "", feed.Updated(items), bytes.NewReader(body))
+	}
+}
+
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -1,21 +1,39 @@
 package newshandler
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"news-service/api/response"
+	apiv1 "news-service/api/v1"
+	"news-service/internal/entities"
 	errMsg "news-service/internal/err"
 	"news-service/internal/models"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 )
 
 type NewsItem struct {
-	ID         int    `json:"Id"`
-	Title      string `json:"Title"`
-	Content    string `json:"Content"`
-	Categories []int  `json:"Categories"`
+	ID            int            `json:"Id"`
+	Title         string         `json:"Title"`
+	Slug          string         `json:"Slug"`
+	Summary       string         `json:"Summary"`
+	Content       string         `json:"Content"`
+	ContentFormat string         `json:"ContentFormat"`
+	ContentHTML   string         `json:"ContentHTML"`
+	Published     bool           `json:"Published"`
+	Categories    []int          `json:"Categories"`
+	Tags          []string       `json:"Tags"`
+	Media         []MediaItem    `json:"Media"`
+	CommentsCount int            `json:"CommentsCount"`
+	Reactions     map[string]int `json:"Reactions"`
+	CreatedAt     time.Time      `json:"CreatedAt"`
+	UpdatedAt     time.Time      `json:"UpdatedAt"`
 }
 
 type ResponseNewsList struct {
@@ -23,7 +41,7 @@ type ResponseNewsList struct {
 	News    []NewsItem `json:"News"`
 }
 
-func ListAllNews(log *slog.Logger, newsRepository models.NewsRepository, newsCategoriesRepository models.NewsCategoriesRepository) http.HandlerFunc {
+func ListAllNews(log *slog.Logger, newsRepository models.NewsRepository, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const loggerOptions = "handlers.listAllNews"
 		log = log.With(
@@ -37,32 +55,177 @@ func ListAllNews(log *slog.Logger, newsRepository models.NewsRepository, newsCat
 			render.JSON(w, r, response.Error("Failed to retrieve news"))
 			return
 		}
-		result := make([]NewsItem, len(newsArray))
-		for i, news := range newsArray {
-			var categories []int
-
-			categories, err := newsCategoriesRepository.ListCategories(r.Context(), news.ID)
-			if err != nil {
-				log.Error("Failed to retrieve news", errMsg.Err(err))
-				render.JSON(w, r, response.Error("Failed to retrieve news"))
-				return
-			}
-
-			result[i] = NewsItem{
-				ID:         news.ID,
-				Title:      news.Title,
-				Content:    news.Content,
-				Categories: categories,
-			}
+		result, err := NewsItems(r.Context(), newsArray, newsCategoriesRepository, mediaRepository, tagsRepository, commentsRepository, reactionsRepository)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
 		}
 
 		responseOKgetNews(w, r, result)
 	}
 }
 
+// NewsItems completes news with their categories, media, tags, comment
+// counts and reactions.
+func NewsItems(ctx context.Context, newsArray []entities.News, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) ([]NewsItem, error) {
+	ids := make([]int, len(newsArray))
+	for i, news := range newsArray {
+		ids[i] = news.ID
+	}
+	categories, err := newsCategoriesRepository.ListCategoriesByNews(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := commentsRepository.CountCommentsByNews(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	reactions, err := reactionsRepository.CountReactionsByNews(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	media, err := mediaRepository.ListMediaByNews(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := tagsRepository.ListTagsByNews(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]NewsItem, len(newsArray))
+	for i, news := range newsArray {
+		newsTags := tags[news.ID]
+		if newsTags == nil {
+			newsTags = []string{}
+		}
+		counts := reactions[news.ID]
+		if counts == nil {
+			counts = map[string]int{}
+		}
+
+		result[i] = NewsItem{
+			ID:            news.ID,
+			Title:         news.Title,
+			Slug:          news.Slug,
+			Summary:       news.Summary,
+			Content:       news.Content,
+			ContentFormat: news.ContentFormat,
+			ContentHTML:   ContentHTML(news),
+			Published:     news.Published,
+			Categories:    categories[news.ID],
+			Tags:          newsTags,
+			Media:         mediaItems(media[news.ID]),
+			CommentsCount: comments[news.ID],
+			Reactions:     counts,
+			CreatedAt:     news.CreatedAt,
+			UpdatedAt:     news.UpdatedAt,
+		}
+	}
+	return result, nil
+}
+
+// responseOKgetNews renders a news list in the format negotiated by
+// response.RenderList: the ResponseNewsList JSON by default, JSON Feed, CSV
+// or NDJSON on request.
 func responseOKgetNews(w http.ResponseWriter, r *http.Request, news []NewsItem) {
-	render.JSON(w, r, ResponseNewsList{
-		News:    news,
-		Success: true,
+	response.RenderList(w, r, newsListing{
+		news:    news,
+		v1:      apiv1.Requested(r),
+		baseURL: requestBaseURL(r),
+		self:    r.URL.String(),
 	})
 }
+
+// newsListing adapts a news list to response.Listing.
+type newsListing struct {
+	news []NewsItem
+	// v1 renders apiv1 DTOs.
+	v1      bool
+	baseURL string
+	self    string
+}
+
+func (l newsListing) Envelope() any {
+	if l.v1 {
+		news := make([]apiv1.News, len(l.news))
+		for i, item := range l.news {
+			news[i] = newsDTO(item)
+		}
+		return apiv1.NewsListResponse{Response: response.OK(), News: news}
+	}
+	return ResponseNewsList{
+		News:    l.news,
+		Success: true,
+	}
+}
+
+func (l newsListing) Records() []any {
+	records := make([]any, len(l.news))
+	for i, item := range l.news {
+		if l.v1 {
+			records[i] = newsDTO(item)
+		} else {
+			records[i] = item
+		}
+	}
+	return records
+}
+
+func (l newsListing) Table() ([]string, [][]string) {
+	header := []string{"id", "title", "slug", "published", "categories", "tags", "created_at", "updated_at", "summary", "content_format", "content"}
+	rows := make([][]string, len(l.news))
+	for i, item := range l.news {
+		categories := make([]string, len(item.Categories))
+		for j, c := range item.Categories {
+			categories[j] = strconv.Itoa(c)
+		}
+		rows[i] = []string{
+			strconv.Itoa(item.ID),
+			item.Title,
+			item.Slug,
+			strconv.FormatBool(item.Published),
+			strings.Join(categories, " "),
+			strings.Join(item.Tags, ","),
+			item.CreatedAt.Format(time.RFC3339),
+			item.UpdatedAt.Format(time.RFC3339),
+			item.Summary,
+			item.ContentFormat,
+			item.Content,
+		}
+	}
+	return header, rows
+}
+
+func (l newsListing) JSONFeed() response.JSONFeed {
+	base := l.baseURL
+	if l.v1 {
+		base += apiv1.Prefix
+	}
+	items := make([]response.JSONFeedItem, len(l.news))
+	for i, item := range l.news {
+		created, updated := item.CreatedAt, item.UpdatedAt
+		tags := make([]string, 0, len(item.Categories)+len(item.Tags))
+		for _, c := range item.Categories {
+			tags = append(tags, strconv.Itoa(c))
+		}
+		tags = append(tags, item.Tags...)
+		items[i] = response.JSONFeedItem{
+			ID:            fmt.Sprintf("%s/news/%d", base, item.ID),
+			URL:           base + "/news/by-slug/" + item.Slug,
+			Title:         item.Title,
+			ContentHTML:   item.ContentHTML,
+			Summary:       item.Summary,
+			DatePublished: &created,
+			DateModified:  &updated,
+			Tags:          tags,
+		}
+	}
+	return response.JSONFeed{
+		Title:       "News",
+		HomePageURL: base + "/news",
+		FeedURL:     l.baseURL + l.self,
+		Items:       items,
+	}
+}
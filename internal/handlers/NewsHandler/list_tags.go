@@ -0,0 +1,68 @@
+package newshandler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"news-service/internal/tag"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultTagsLimit = 10
+	maxTagsLimit     = 100
+)
+
+type TagItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type ResponseTags struct {
+	response.Response
+	Tags []TagItem `json:"tags"`
+}
+
+// ListTags serves GET /tags?prefix=&limit= for tag autocomplete. The prefix
+// is normalized like the tags themselves and the tags in use are returned
+// with the number of news they mark, most used first.
+func ListTags(log *slog.Logger, tagsRepository models.TagsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.listTags"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit := defaultTagsLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			var err error
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maxTagsLimit {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error(fmt.Sprintf("limit must be between 1 and %d", maxTagsLimit)))
+				return
+			}
+		}
+
+		tags, err := tagsRepository.ListTags(r.Context(), tag.Normalize(r.URL.Query().Get("prefix")), limit)
+		if err != nil {
+			log.Error("Failed to retrieve tags", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve tags"))
+			return
+		}
+
+		items := make([]TagItem, len(tags))
+		for i, t := range tags {
+			items[i] = TagItem{Name: t.Name, Count: t.Count}
+		}
+		render.JSON(w, r, ResponseTags{Response: response.OK(), Tags: items})
+	}
+}
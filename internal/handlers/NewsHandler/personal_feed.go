@@ -0,0 +1,79 @@
+package newshandler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// PersonalFeed serves GET /feed/me?limit=&before= with the published news
+// in the categories the current user follows, newest first. Pages are
+// chained by news id: a full page carries a Link header with rel="next"
+// pointing to the page of news older than its last one.
+func PersonalFeed(log *slog.Logger, userRepository userhandlers.User, subscriptionsRepository models.SubscriptionsRepository, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.personalFeed"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		limit, err := response.PageLimit(r)
+		if err != nil {
+			log.Error("invalid pagination", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		before := 0
+		if v := r.URL.Query().Get("before"); v != "" {
+			before, err = strconv.Atoi(v)
+			if err != nil || before < 1 {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("before must be a news id"))
+				return
+			}
+		}
+
+		newsArray, err := subscriptionsRepository.ListFeedNews(r.Context(), user.ID, before, limit)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		result, err := NewsItems(r.Context(), newsArray, newsCategoriesRepository, mediaRepository, tagsRepository, commentsRepository, reactionsRepository)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		if len(newsArray) == limit {
+			next := *r.URL
+			query := next.Query()
+			query.Set("before", strconv.Itoa(newsArray[len(newsArray)-1].ID))
+			query.Set("limit", strconv.Itoa(limit))
+			next.RawQuery = query.Encode()
+			w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+		}
+		responseOKgetNews(w, r, result)
+	}
+}
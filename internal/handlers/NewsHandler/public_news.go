@@ -0,0 +1,172 @@
+package newshandler
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"news-service/api/response"
+	apiv1 "news-service/api/v1"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// ListPublishedNews serves GET /news for anonymous readers.
+func ListPublishedNews(log *slog.Logger, newsRepository models.NewsRepository, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.listPublishedNews"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, offset, err := response.Pagination(r)
+		if err != nil {
+			log.Error("invalid pagination", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		newsArray, err := newsRepository.ListPublishedNews(r.Context(), limit, offset)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		result, err := NewsItems(r.Context(), newsArray, newsCategoriesRepository, mediaRepository, tagsRepository, commentsRepository, reactionsRepository)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		responseOKgetNews(w, r, result)
+	}
+}
+
+// ListNewsByCategory serves GET /categories/{category}/news for anonymous
+// readers.
+func ListNewsByCategory(log *slog.Logger, newsRepository models.NewsRepository, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.listNewsByCategory"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		category, err := strconv.Atoi(chi.URLParam(r, "category"))
+		if err != nil {
+			log.Error("failed to convert request parameter category", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid category"))
+			return
+		}
+
+		limit, offset, err := response.Pagination(r)
+		if err != nil {
+			log.Error("invalid pagination", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		newsArray, err := newsRepository.ListPublishedNewsByCategory(r.Context(), category, limit, offset)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		result, err := NewsItems(r.Context(), newsArray, newsCategoriesRepository, mediaRepository, tagsRepository, commentsRepository, reactionsRepository)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		responseOKgetNews(w, r, result)
+	}
+}
+
+// GetPublishedNews serves GET /news/{id} for anonymous readers. Drafts are
+// reported as not found.
+func GetPublishedNews(log *slog.Logger, newsRepository models.NewsRepository, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.getPublishedNews"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		newsID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("failed to convert request parameter id", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid news id"))
+			return
+		}
+
+		news, err := newsRepository.FindNewsByID(r.Context(), newsID)
+		if err != nil || !news.Published {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("news not found"))
+			return
+		}
+
+		items, err := NewsItems(r.Context(), []entities.News{news}, newsCategoriesRepository, mediaRepository, tagsRepository, commentsRepository, reactionsRepository)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		responseOK(w, r, items[0])
+	}
+}
+
+// GetPublishedNewsBySlug serves GET /news/by-slug/{slug}. Old slugs of a
+// renamed news are answered with 301 Moved Permanently to the current one.
+func GetPublishedNewsBySlug(log *slog.Logger, newsRepository models.NewsRepository, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.getPublishedNewsBySlug"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		requested := chi.URLParam(r, "slug")
+		news, err := newsRepository.FindNewsBySlug(r.Context(), requested)
+		if err != nil || !news.Published {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("news not found"))
+			return
+		}
+
+		if news.Slug != requested {
+			http.Redirect(w, r, apiv1.Path(r, "/news/by-slug/"+url.PathEscape(news.Slug)), http.StatusMovedPermanently)
+			return
+		}
+
+		items, err := NewsItems(r.Context(), []entities.News{news}, newsCategoriesRepository, mediaRepository, tagsRepository, commentsRepository, reactionsRepository)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		responseOK(w, r, items[0])
+	}
+}
@@ -0,0 +1,97 @@
+package newshandler
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type ResponseReactions struct {
+	response.Response
+	Reactions map[string]int `json:"reactions"`
+	// Mine lists the kinds left by the current user.
+	Mine []string `json:"mine"`
+}
+
+// SetReaction serves PUT /news/{id}/reactions/{kind} when reacted is set
+// and DELETE otherwise. Both are idempotent and answer with the reaction
+// counts of the news. Only published news may get new reactions.
+func SetReaction(log *slog.Logger, reacted bool, newsRepository models.NewsRepository, userRepository userhandlers.User, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.setReaction"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		newsID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("failed to convert request parameter id", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid news id"))
+			return
+		}
+		kind := chi.URLParam(r, "kind")
+		if !slices.Contains(entities.ReactionKinds, kind) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("unknown reaction, expected one of: "+strings.Join(entities.ReactionKinds, ", ")))
+			return
+		}
+		news, err := newsRepository.FindNewsByID(r.Context(), newsID)
+		if err != nil || (reacted && !news.Published) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("news not found"))
+			return
+		}
+
+		if reacted {
+			err = reactionsRepository.AddReaction(r.Context(), news.ID, user.ID, kind)
+		} else {
+			err = reactionsRepository.RemoveReaction(r.Context(), news.ID, user.ID, kind)
+		}
+		if err != nil {
+			log.Error("failed to save reaction", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to save reaction"))
+			return
+		}
+
+		counts, err := reactionsRepository.CountReactions(r.Context(), news.ID)
+		if err != nil {
+			log.Error("failed to count reactions", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to retrieve reactions"))
+			return
+		}
+		mine, err := reactionsRepository.ListUserReactions(r.Context(), news.ID, user.ID)
+		if err != nil {
+			log.Error("failed to list user reactions", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to retrieve reactions"))
+			return
+		}
+		if mine == nil {
+			mine = []string{}
+		}
+
+		render.JSON(w, r, ResponseReactions{Response: response.OK(), Reactions: counts, Mine: mine})
+	}
+}
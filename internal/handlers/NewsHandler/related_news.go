@@ -0,0 +1,79 @@
+package newshandler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/config"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"news-service/internal/related"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const defaultRelatedLimit = 5
+
+// RelatedNews serves GET /news/{id}/related?limit= with the published news
+// closest to a published news, see related.Score. The limit defaults to
+// cfg.Limit.
+func RelatedNews(log *slog.Logger, cfg config.RelatedCfg, newsRepository models.NewsRepository, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
+	if cfg.Limit <= 0 {
+		cfg.Limit = defaultRelatedLimit
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.relatedNews"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		newsID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("failed to convert request parameter id", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid news id"))
+			return
+		}
+
+		limit := cfg.Limit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > response.MaxPageSize {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error(fmt.Sprintf("limit must be between 1 and %d", response.MaxPageSize)))
+				return
+			}
+		}
+
+		news, err := newsRepository.FindNewsByID(r.Context(), newsID)
+		if err != nil || !news.Published {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("news not found"))
+			return
+		}
+
+		newsArray, err := newsRepository.ListRelatedNews(r.Context(), news.ID, related.Options{Limit: limit, HalfLife: cfg.HalfLife})
+		if err != nil {
+			log.Error("Failed to retrieve related news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		result, err := NewsItems(r.Context(), newsArray, newsCategoriesRepository, mediaRepository, tagsRepository, commentsRepository, reactionsRepository)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		responseOKgetNews(w, r, result)
+	}
+}
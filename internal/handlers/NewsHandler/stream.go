@@ -0,0 +1,161 @@
+package newshandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	apiv1 "news-service/api/v1"
+	"news-service/internal/config"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"news-service/internal/stream"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	// replayPageSize is the number of missed events read at once on resume.
+	replayPageSize   = 100
+	defaultHeartbeat = 15 * time.Second
+)
+
+// StreamNews serves GET /news/stream, Server-Sent Events named
+// news.created and news.updated carrying the news like GET /list. A client
+// reconnecting with Last-Event-ID, or ?last_event_id= on the first
+// connection, first gets the events it missed. Comments are sent as
+// heartbeats so that idle connections are not closed by proxies.
+func StreamNews(log *slog.Logger, cfg config.StreamCfg, hub *stream.Hub, eventsRepository models.NewsEventsRepository, newsRepository models.NewsRepository, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.streamNews"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		lastID := r.Header.Get("Last-Event-ID")
+		if lastID == "" {
+			lastID = r.URL.Query().Get("last_event_id")
+		}
+		resume := lastID != ""
+		last := 0
+		if resume {
+			var err error
+			last, err = strconv.Atoi(lastID)
+			if err != nil || last < 0 {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("invalid last event id"))
+				return
+			}
+		}
+
+		// The stream outlives the write timeout of the server.
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Error("failed to clear write deadline", errMsg.Err(err))
+		}
+
+		// Subscribing before reading the missed events leaves no gap
+		// between the two, events in both are sent once.
+		sub := hub.Subscribe()
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if cfg.Retry > 0 {
+			fmt.Fprintf(w, "retry: %d\n\n", cfg.Retry.Milliseconds())
+		}
+		if err := rc.Flush(); err != nil {
+			log.Error("streaming is not supported", errMsg.Err(err))
+			return
+		}
+
+		send := func(event entities.NewsEvent) error {
+			if event.ID <= last {
+				return nil
+			}
+			// The event was just committed, a replica may not have the
+			// news yet.
+			news, err := newsRepository.FindNewsByID(models.ReadFromPrimary(r.Context()), event.NewsID)
+			if errors.Is(err, models.ErrNewsNotFound) {
+				last = event.ID
+				return nil
+			}
+			if err != nil {
+				log.Error("failed to find streamed news", slog.Int("news_id", event.NewsID), errMsg.Err(err))
+				return err
+			}
+			last = event.ID
+			items, err := NewsItems(r.Context(), []entities.News{news}, newsCategoriesRepository, mediaRepository, tagsRepository, commentsRepository, reactionsRepository)
+			if err != nil {
+				return err
+			}
+			var payload any = items[0]
+			if apiv1.Requested(r) {
+				payload = newsDTO(items[0])
+			}
+			data, err := json.Marshal(payload)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Kind, data); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+
+		for resume {
+			events, err := eventsRepository.ListNewsEvents(r.Context(), last, replayPageSize)
+			if err != nil {
+				log.Error("failed to read missed news events", errMsg.Err(err))
+				return
+			}
+			for _, event := range events {
+				if err := send(event); err != nil {
+					log.Debug("news stream closed", errMsg.Err(err))
+					return
+				}
+			}
+			resume = len(events) == replayPageSize
+		}
+
+		heartbeat := cfg.Heartbeat
+		if heartbeat <= 0 {
+			heartbeat = defaultHeartbeat
+		}
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case event, ok := <-sub.Events():
+				if !ok {
+					// Fell behind or shutting down, the client resumes
+					// from the last event it got.
+					return
+				}
+				if err := send(event); err != nil {
+					log.Debug("news stream closed", errMsg.Err(err))
+					return
+				}
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				if err := rc.Flush(); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
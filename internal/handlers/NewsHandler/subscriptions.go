@@ -0,0 +1,97 @@
+package newshandler
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type ResponseSubscriptions struct {
+	response.Response
+	Categories []int `json:"categories"`
+}
+
+// ListSubscriptions serves GET /users/me/subscriptions with the categories
+// the current user follows.
+func ListSubscriptions(log *slog.Logger, userRepository userhandlers.User, subscriptionsRepository models.SubscriptionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.listSubscriptions"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		renderSubscriptions(w, r, log, user.ID, subscriptionsRepository)
+	}
+}
+
+// SetSubscription serves PUT /users/me/subscriptions/{category} when
+// subscribed is set and DELETE otherwise. Both are idempotent and answer
+// with the categories the current user follows.
+func SetSubscription(log *slog.Logger, subscribed bool, userRepository userhandlers.User, subscriptionsRepository models.SubscriptionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.setSubscription"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		category, err := strconv.Atoi(chi.URLParam(r, "category"))
+		if err != nil {
+			log.Error("failed to convert request parameter category", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid category"))
+			return
+		}
+
+		if subscribed {
+			err = subscriptionsRepository.Subscribe(r.Context(), user.ID, category)
+		} else {
+			err = subscriptionsRepository.Unsubscribe(r.Context(), user.ID, category)
+		}
+		if err != nil {
+			log.Error("failed to save subscription", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to save subscription"))
+			return
+		}
+
+		renderSubscriptions(w, r, log, user.ID, subscriptionsRepository)
+	}
+}
+
+func renderSubscriptions(w http.ResponseWriter, r *http.Request, log *slog.Logger, userID int, subscriptionsRepository models.SubscriptionsRepository) {
+	categories, err := subscriptionsRepository.ListSubscriptions(r.Context(), userID)
+	if err != nil {
+		log.Error("failed to list subscriptions", errMsg.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to retrieve subscriptions"))
+		return
+	}
+	if categories == nil {
+		categories = []int{}
+	}
+	render.JSON(w, r, ResponseSubscriptions{Response: response.OK(), Categories: categories})
+}
@@ -1,12 +1,13 @@
 package newshandler
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"news-service/api/response"
-	"news-service/internal/entities"
 	errMsg "news-service/internal/err"
 	"news-service/internal/models"
+	"news-service/internal/service"
 	"strconv"
 
 	"github.com/go-chi/chi/middleware"
@@ -15,13 +16,22 @@ import (
 )
 
 type RequestUpdateNews struct {
-	ID         int    `json:"Id" validate:"required"`
-	Title      string `json:"Title"`
-	Content    string `json:"Content"`
-	Categories []int  `json:"Categories"`
+	ID    int    `json:"Id" validate:"required"`
+	Title string `json:"Title"`
+	// Slug renames the news, the old slug keeps redirecting to it.
+	Slug    *string `json:"Slug"`
+	Summary *string `json:"Summary"`
+	Content string  `json:"Content"`
+	// ContentFormat keeps the current format when omitted.
+	ContentFormat *string `json:"ContentFormat"`
+	Categories    []int   `json:"Categories"`
+	// Tags replace the current tags, they are kept when omitted and
+	// cleared by an empty list.
+	Tags      []string `json:"Tags"`
+	Published *bool    `json:"Published"`
 }
 
-func UpdateNews(log *slog.Logger, NewsRepository models.NewsRepository, CategoriesRepository models.CategoriesRepository, NewsCategoriesRepository models.NewsCategoriesRepository) http.HandlerFunc {
+func UpdateNews(log *slog.Logger, writer service.NewsWriter) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
@@ -39,8 +49,7 @@ func UpdateNews(log *slog.Logger, NewsRepository models.NewsRepository, Categori
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		var req RequestUpdateNews
-		err = render.DecodeJSON(r.Body, &req)
+		req, err := decodeUpdateNews(r)
 		if err != nil {
 			log.Error("failed to decode request body", errMsg.Err(err))
 			render.JSON(w, r, response.Error("failed to decode request"))
@@ -48,41 +57,37 @@ func UpdateNews(log *slog.Logger, NewsRepository models.NewsRepository, Categori
 		}
 		log.Info("request body request", slog.Any("request", req))
 
-		news, err := NewsRepository.FindNewsByID(r.Context(), newsID)
-		if err != nil {
+		_, err = writer.Update(r.Context(), log, service.NewsUpdate{
+			ID:            newsID,
+			Title:         req.Title,
+			Slug:          req.Slug,
+			Summary:       req.Summary,
+			Content:       req.Content,
+			ContentFormat: req.ContentFormat,
+			Categories:    req.Categories,
+			Tags:          req.Tags,
+			Published:     req.Published,
+		})
+		var invalid *service.InvalidError
+		switch {
+		case errors.Is(err, models.ErrNewsNotFound):
 			render.Status(r, http.StatusNotFound)
 			log.Error("Failed to find news")
 			render.JSON(w, r, response.Error("news not found"))
 			return
-		}
-		news.ID = newsID
-		news.Content = req.Content
-		news.Title = req.Title
-		err = NewsRepository.UpdateNews(r.Context(), &news)
-		if err != nil {
+		case errors.As(err, &invalid):
+			log.Error("invalid content", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		case err != nil:
 			render.Status(r, http.StatusInternalServerError)
-			log.Error("Failed to update news")
+			log.Error("Failed to update news", errMsg.Err(err))
 			render.JSON(w, r, response.Error("Failed to update news"))
 			return
 		}
-		NewsCategoriesRepository.DeleteCategories(r.Context(), news.ID)
-		for _, categorie_name := range req.Categories {
-			categorie := entities.Categorie{Name: categorie_name}
-			err = CategoriesRepository.CreateCategorie(r.Context(), &categorie)
-			if err != nil {
-				log.Error("could not update categorie to the table", errMsg.Err(err))
-				return
-			}
-
-			err = NewsCategoriesRepository.UpdateNewsCategories(r.Context(), categorie.ID, newsID)
-			if err != nil {
-				log.Error("could not update Newscategories relation to the table", errMsg.Err(err))
-				return
-			}
-		}
 
 		log.Info("news updated")
-
 		render.JSON(w, r, response.OK())
 
 	}
@@ -0,0 +1,324 @@
+package newshandler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/config"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"news-service/internal/storage"
+	"news-service/internal/thumbnail"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type MediaItem struct {
+	ID           int    `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
+
+type ResponseMedia struct {
+	response.Response
+	Media []MediaItem `json:"media"`
+}
+
+// extensions names stored files by their detected type, the extension of
+// the uploaded file name is not trusted.
+var extensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// Defaults for a zero config.MediaCfg.
+const (
+	defaultMaxUploadSize = 10 << 20
+	defaultMaxFiles      = 10
+	defaultThumbnailSize = 320
+	defaultMaxPixels     = 40_000_000
+)
+
+var defaultAllowedTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"}
+
+// upload is a validated file of a multipart request.
+type upload struct {
+	fileName    string
+	contentType string
+	data        []byte
+}
+
+// UploadMedia serves POST /news/{id}/media. Every part named "file" of the
+// multipart/form-data body is attached to the news. The type of a file is
+// detected from its content and must be in cfg.AllowedTypes; images get a
+// thumbnail. All files are validated before the first one is stored.
+func UploadMedia(log *slog.Logger, cfg config.MediaCfg, newsRepository models.NewsRepository, mediaRepository models.MediaRepository, store storage.Storage) http.HandlerFunc {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultMaxUploadSize
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = defaultMaxFiles
+	}
+	if cfg.ThumbnailSize <= 0 {
+		cfg.ThumbnailSize = defaultThumbnailSize
+	}
+	if len(cfg.AllowedTypes) == 0 {
+		cfg.AllowedTypes = defaultAllowedTypes
+	}
+	if cfg.MaxPixels <= 0 {
+		cfg.MaxPixels = defaultMaxPixels
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.uploadMedia"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		newsID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("failed to convert request parameter id", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid news id"))
+			return
+		}
+		if _, err := newsRepository.FindNewsByID(r.Context(), newsID); err != nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("news not found"))
+			return
+		}
+
+		uploads, status, err := readUploads(w, r, cfg)
+		if err != nil {
+			log.Error("invalid upload", errMsg.Err(err))
+			render.Status(r, status)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		var (
+			stored []string
+			media  []entities.Media
+		)
+		// Objects already stored are removed when a later file fails.
+		rollback := func() {
+			for _, key := range stored {
+				if err := store.Delete(context.WithoutCancel(r.Context()), key); err != nil {
+					log.Error("failed to remove stored media", slog.String("key", key), errMsg.Err(err))
+				}
+			}
+		}
+
+		for _, u := range uploads {
+			item, keys, err := storeUpload(r.Context(), store, cfg, newsID, u)
+			stored = append(stored, keys...)
+			if errors.Is(err, errInvalidImage) {
+				rollback()
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error(fmt.Sprintf("%s: %v", u.fileName, err)))
+				return
+			}
+			if errors.Is(err, errImageTooLarge) {
+				rollback()
+				render.Status(r, http.StatusRequestEntityTooLarge)
+				render.JSON(w, r, response.Error(fmt.Sprintf("%s: %v", u.fileName, err)))
+				return
+			}
+			if err != nil {
+				log.Error("failed to store media", errMsg.Err(err))
+				rollback()
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, response.Error("failed to store media"))
+				return
+			}
+			media = append(media, item)
+		}
+
+		for i := range media {
+			if err := mediaRepository.CreateMedia(r.Context(), &media[i]); err != nil {
+				log.Error("failed to save media", errMsg.Err(err))
+				rollback()
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, response.Error("failed to store media"))
+				return
+			}
+		}
+
+		log.Info("media uploaded", slog.Int("news_id", newsID), slog.Int("files", len(media)))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, ResponseMedia{Response: response.OK(), Media: mediaItems(media)})
+	}
+}
+
+// readUploads reads and validates the files of a multipart request. The
+// returned status describes the error.
+func readUploads(w http.ResponseWriter, r *http.Request, cfg config.MediaCfg) ([]upload, int, error) {
+	maxFiles := cfg.MaxFiles
+	// Leave room for the multipart framing and small form fields.
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxSize*int64(maxFiles)+1<<20)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("expected a multipart/form-data body")
+	}
+
+	var uploads []upload
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			return nil, http.StatusRequestEntityTooLarge, errors.New("request body is too large")
+		}
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("malformed multipart body")
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		if len(uploads) == maxFiles {
+			return nil, http.StatusBadRequest, fmt.Errorf("at most %d files may be uploaded at once", maxFiles)
+		}
+
+		fileName := cleanFileName(part.FileName())
+		data, err := io.ReadAll(io.LimitReader(part, cfg.MaxSize+1))
+		if errors.As(err, &maxBytes) || int64(len(data)) > cfg.MaxSize {
+			return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("%s is larger than %d bytes", fileName, cfg.MaxSize)
+		}
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("malformed multipart body")
+		}
+		if len(data) == 0 {
+			return nil, http.StatusBadRequest, fmt.Errorf("%s is empty", fileName)
+		}
+
+		contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+		if !slices.Contains(cfg.AllowedTypes, contentType) {
+			return nil, http.StatusUnsupportedMediaType, fmt.Errorf("%s: files of type %s are not allowed", fileName, contentType)
+		}
+		uploads = append(uploads, upload{fileName: fileName, contentType: contentType, data: data})
+	}
+
+	if len(uploads) == 0 {
+		return nil, http.StatusBadRequest, errors.New(`no file in the "file" field`)
+	}
+	return uploads, 0, nil
+}
+
+var (
+	errInvalidImage = errors.New("invalid image")
+	// errImageTooLarge rejects images declaring more than cfg.MaxPixels
+	// pixels. A small file may decode to gigabytes, so the size from the
+	// header is checked before decoding.
+	errImageTooLarge = errors.New("image has too many pixels")
+)
+
+// storeUpload puts the file and, for images, its thumbnail into store and
+// returns the media to save along with the keys it stored.
+func storeUpload(ctx context.Context, store storage.Storage, cfg config.MediaCfg, newsID int, u upload) (entities.Media, []string, error) {
+	name, err := randomName()
+	if err != nil {
+		return entities.Media{}, nil, err
+	}
+	media := entities.Media{
+		NewsID:      newsID,
+		Key:         fmt.Sprintf("news/%d/%s%s", newsID, name, extensions[u.contentType]),
+		FileName:    u.fileName,
+		ContentType: u.contentType,
+		Size:        int64(len(u.data)),
+	}
+
+	var thumb thumbnail.Thumbnail
+	if strings.HasPrefix(u.contentType, "image/") {
+		media.Width, media.Height, err = thumbnail.Size(u.data)
+		if err == nil && int64(media.Width)*int64(media.Height) > cfg.MaxPixels {
+			return entities.Media{}, nil, errImageTooLarge
+		}
+		if err == nil {
+			thumb, err = thumbnail.Make(u.data, cfg.ThumbnailSize)
+		}
+		if err != nil && !errors.Is(err, thumbnail.ErrUnsupported) {
+			return entities.Media{}, nil, errInvalidImage
+		}
+	}
+
+	var stored []string
+	if err := store.Put(ctx, media.Key, media.ContentType, bytes.NewReader(u.data), media.Size); err != nil {
+		return entities.Media{}, stored, err
+	}
+	stored = append(stored, media.Key)
+	media.URL = store.URL(media.Key)
+
+	if thumb.Data != nil {
+		media.ThumbnailKey = fmt.Sprintf("news/%d/%s_thumb%s", newsID, name, extensions[thumb.ContentType])
+		if err := store.Put(ctx, media.ThumbnailKey, thumb.ContentType, bytes.NewReader(thumb.Data), int64(len(thumb.Data))); err != nil {
+			return entities.Media{}, stored, err
+		}
+		stored = append(stored, media.ThumbnailKey)
+		media.ThumbnailURL = store.URL(media.ThumbnailKey)
+	}
+	return media, stored, nil
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// cleanFileName keeps the base name of an uploaded file for display.
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" {
+		name = "file"
+	}
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[:255], "")
+	}
+	return name
+}
+
+func mediaItems(media []entities.Media) []MediaItem {
+	items := make([]MediaItem, len(media))
+	for i, m := range media {
+		items[i] = MediaItem{
+			ID:           m.ID,
+			URL:          m.URL,
+			ThumbnailURL: m.ThumbnailURL,
+			FileName:     m.FileName,
+			ContentType:  m.ContentType,
+			Size:         m.Size,
+			Width:        m.Width,
+			Height:       m.Height,
+		}
+	}
+	return items
+}
@@ -0,0 +1,112 @@
+package newshandler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/config"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"news-service/internal/ratelimit"
+	"news-service/internal/views"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 30 * 24 * time.Hour
+)
+
+type ResponseView struct {
+	response.Response
+	// Counted is false for a repeated view within the de-duplication
+	// window.
+	Counted bool `json:"counted"`
+}
+
+// RecordView serves POST /news/{id}/views. Views of published news are
+// buffered by counter, a client is identified by its address and user
+// agent.
+func RecordView(log *slog.Logger, newsRepository models.NewsRepository, counter *views.Counter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.recordView"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		newsID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("failed to convert request parameter id", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid news id"))
+			return
+		}
+		news, err := newsRepository.FindNewsByID(r.Context(), newsID)
+		if err != nil || !news.Published {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("news not found"))
+			return
+		}
+
+		counted := counter.Record(news.ID, ratelimit.ByIP(r)+"\x00"+r.UserAgent())
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, ResponseView{Response: response.OK(), Counted: counted})
+	}
+}
+
+// TrendingNews serves GET /news/trending?window=24h&limit= with the
+// published news most viewed within the window, every view weighing half
+// as much per cfg.HalfLife of age.
+func TrendingNews(log *slog.Logger, cfg config.ViewsCfg, viewsRepository models.ViewsRepository, newsCategoriesRepository models.NewsCategoriesRepository, mediaRepository models.MediaRepository, tagsRepository models.TagsRepository, commentsRepository models.CommentsRepository, reactionsRepository models.ReactionsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.trendingNews"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		window := defaultTrendingWindow
+		if v := r.URL.Query().Get("window"); v != "" {
+			var err error
+			window, err = time.ParseDuration(v)
+			if err != nil || window < views.BucketSize || window > maxTrendingWindow {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error(fmt.Sprintf("window must be a duration between %s and %s", views.BucketSize, maxTrendingWindow)))
+				return
+			}
+		}
+		limit, _, err := response.Pagination(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		// The bucket the window starts in is included.
+		since := time.Now().Add(-window).Truncate(views.BucketSize)
+		newsArray, err := viewsRepository.ListTrendingNews(r.Context(), since, cfg.HalfLife, limit)
+		if err != nil {
+			log.Error("Failed to retrieve trending news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		result, err := NewsItems(r.Context(), newsArray, newsCategoriesRepository, mediaRepository, tagsRepository, commentsRepository, reactionsRepository)
+		if err != nil {
+			log.Error("Failed to retrieve news", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve news"))
+			return
+		}
+
+		responseOKgetNews(w, r, result)
+	}
+}
@@ -0,0 +1,81 @@
+package webhookhandler
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/config"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+	"news-service/internal/webhook"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// CreateWebhook serves POST /webhooks. The answer is the only place the
+// signing secret is shown. Unless cfg.AllowPrivateNetworks, URLs resolving
+// to addresses that are not public are refused, see webhook.CheckURL.
+func CreateWebhook(log *slog.Logger, cfg config.WebhooksCfg, userRepository userhandlers.User, webhooksRepository models.WebhooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.createWebhook"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		var req RequestWebhook
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+			return
+		}
+		if err := validateWebhook(&req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		if !cfg.AllowPrivateNetworks {
+			if err := webhook.CheckURL(r.Context(), req.URL); err != nil {
+				log.Info("webhook url refused", errMsg.Err(err))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("url must resolve to public addresses"))
+				return
+			}
+		}
+		if req.Secret == "" {
+			secret, err := webhook.NewSecret()
+			if err != nil {
+				log.Error("failed to generate secret", errMsg.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, response.Error("failed to create webhook"))
+				return
+			}
+			req.Secret = secret
+		}
+
+		wh := entities.Webhook{UserID: user.ID, URL: req.URL, Secret: req.Secret, Events: req.Events}
+		if err := webhooksRepository.CreateWebhook(r.Context(), &wh); err != nil {
+			log.Error("failed to create webhook", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to create webhook"))
+			return
+		}
+
+		log.Info("webhook added", slog.Int("webhook_id", wh.ID))
+		item := webhookItem(wh)
+		item.Secret = wh.Secret
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, ResponseWebhook{Response: response.OK(), Webhook: item})
+	}
+}
@@ -0,0 +1,122 @@
+package webhookhandler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// ListDeliveries serves GET /webhooks/{id}/deliveries?limit=&offset=, the
+// delivery log of a webhook, newest first.
+func ListDeliveries(log *slog.Logger, userRepository userhandlers.User, webhooksRepository models.WebhooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.listDeliveries"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		webhook, ok := findWebhook(w, r, userRepository, webhooksRepository)
+		if !ok {
+			return
+		}
+		limit, offset, err := response.Pagination(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		deliveries, err := webhooksRepository.ListDeliveries(r.Context(), webhook.ID, limit, offset)
+		if err != nil {
+			log.Error("Failed to retrieve deliveries", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve deliveries"))
+			return
+		}
+
+		items := make([]DeliveryItem, len(deliveries))
+		for i, d := range deliveries {
+			items[i] = deliveryItem(d)
+		}
+		render.JSON(w, r, ResponseDeliveries{Response: response.OK(), Deliveries: items})
+	}
+}
+
+// GetDelivery serves GET /webhooks/{id}/deliveries/{delivery} with the
+// payload and every attempt made.
+func GetDelivery(log *slog.Logger, userRepository userhandlers.User, webhooksRepository models.WebhooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.getDelivery"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		delivery, ok := findDelivery(w, r, userRepository, webhooksRepository)
+		if !ok {
+			return
+		}
+		attempts, err := webhooksRepository.ListAttempts(r.Context(), delivery.ID)
+		if err != nil {
+			log.Error("Failed to retrieve attempts", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve delivery"))
+			return
+		}
+
+		item := deliveryItem(delivery)
+		item.Payload = json.RawMessage(delivery.Payload)
+		item.Log = make([]AttemptItem, len(attempts))
+		for i, a := range attempts {
+			item.Log[i] = attemptItem(a)
+		}
+		render.JSON(w, r, ResponseDelivery{Response: response.OK(), Delivery: item})
+	}
+}
+
+// RetryDelivery serves POST /webhooks/{id}/deliveries/{delivery}/retry.
+// Only dead-lettered deliveries may be retried, they get a fresh set of
+// attempts.
+func RetryDelivery(log *slog.Logger, userRepository userhandlers.User, webhooksRepository models.WebhooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.retryDelivery"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		delivery, ok := findDelivery(w, r, userRepository, webhooksRepository)
+		if !ok {
+			return
+		}
+		if delivery.Status != entities.DeliveryDead {
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("only dead deliveries may be retried"))
+			return
+		}
+		if err := webhooksRepository.RetryDelivery(r.Context(), delivery.ID); err != nil {
+			log.Error("failed to retry delivery", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to retry delivery"))
+			return
+		}
+		delivery, err := webhooksRepository.FindDelivery(r.Context(), delivery.ID)
+		if err != nil {
+			log.Error("failed to find delivery", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to retry delivery"))
+			return
+		}
+
+		log.Info("delivery queued again", slog.Int("delivery_id", delivery.ID))
+		render.JSON(w, r, ResponseDelivery{Response: response.OK(), Delivery: deliveryItem(delivery)})
+	}
+}
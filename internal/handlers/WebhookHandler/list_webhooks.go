@@ -0,0 +1,82 @@
+package webhookhandler
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	errMsg "news-service/internal/err"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// ListWebhooks serves GET /webhooks, the webhooks of the current user.
+func ListWebhooks(log *slog.Logger, userRepository userhandlers.User, webhooksRepository models.WebhooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.listWebhooks"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, err := userhandlers.CurrentUser(r, userRepository)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		webhooks, err := webhooksRepository.ListWebhooks(r.Context(), user.ID)
+		if err != nil {
+			log.Error("Failed to retrieve webhooks", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to retrieve webhooks"))
+			return
+		}
+
+		items := make([]WebhookItem, len(webhooks))
+		for i, wh := range webhooks {
+			items[i] = webhookItem(wh)
+		}
+		render.JSON(w, r, ResponseWebhooks{Response: response.OK(), Webhooks: items})
+	}
+}
+
+// GetWebhook serves GET /webhooks/{id}.
+func GetWebhook(log *slog.Logger, userRepository userhandlers.User, webhooksRepository models.WebhooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhook, ok := findWebhook(w, r, userRepository, webhooksRepository)
+		if !ok {
+			return
+		}
+		render.JSON(w, r, ResponseWebhook{Response: response.OK(), Webhook: webhookItem(webhook)})
+	}
+}
+
+// DeleteWebhook serves DELETE /webhooks/{id}. Its pending deliveries are
+// dropped with it.
+func DeleteWebhook(log *slog.Logger, userRepository userhandlers.User, webhooksRepository models.WebhooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.deleteWebhook"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		webhook, ok := findWebhook(w, r, userRepository, webhooksRepository)
+		if !ok {
+			return
+		}
+		if err := webhooksRepository.DeleteWebhook(r.Context(), webhook.ID); err != nil {
+			log.Error("failed to delete webhook", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to delete webhook"))
+			return
+		}
+
+		log.Info("webhook deleted", slog.Int("webhook_id", webhook.ID))
+		render.JSON(w, r, response.OK())
+	}
+}
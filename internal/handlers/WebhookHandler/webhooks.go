@@ -0,0 +1,179 @@
+// Package webhookhandler manages webhooks: registering endpoints for news
+// events and inspecting or retrying their deliveries.
+package webhookhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"news-service/api/response"
+	"news-service/internal/entities"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/models"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type RequestWebhook struct {
+	URL string `json:"url"`
+	// Events are the subscribed events, see entities.WebhookEvents.
+	Events []string `json:"events"`
+	// Secret signs the payloads, a random one is generated when empty.
+	Secret string `json:"secret"`
+}
+
+// WebhookItem describes a webhook. Secret is only returned on creation.
+type WebhookItem struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ResponseWebhook struct {
+	response.Response
+	Webhook WebhookItem `json:"webhook"`
+}
+
+type ResponseWebhooks struct {
+	response.Response
+	Webhooks []WebhookItem `json:"webhooks"`
+}
+
+type DeliveryItem struct {
+	ID            int             `json:"id"`
+	WebhookID     int             `json:"webhook_id"`
+	Event         string          `json:"event"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Log           []AttemptItem   `json:"log,omitempty"`
+}
+
+type AttemptItem struct {
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ResponseDelivery struct {
+	response.Response
+	Delivery DeliveryItem `json:"delivery"`
+}
+
+type ResponseDeliveries struct {
+	response.Response
+	Deliveries []DeliveryItem `json:"deliveries"`
+}
+
+func webhookItem(wh entities.Webhook) WebhookItem {
+	return WebhookItem{ID: wh.ID, URL: wh.URL, Events: wh.Events, CreatedAt: wh.CreatedAt}
+}
+
+func deliveryItem(d entities.WebhookDelivery) DeliveryItem {
+	item := DeliveryItem{
+		ID:          d.ID,
+		WebhookID:   d.WebhookID,
+		Event:       d.Event,
+		Status:      d.Status,
+		Attempts:    d.Attempts,
+		LastError:   d.LastError,
+		CreatedAt:   d.CreatedAt,
+		DeliveredAt: d.DeliveredAt,
+	}
+	if d.Status == entities.DeliveryPending {
+		next := d.NextAttemptAt
+		item.NextAttemptAt = &next
+	}
+	return item
+}
+
+func attemptItem(a entities.WebhookAttempt) AttemptItem {
+	return AttemptItem{
+		StatusCode: a.StatusCode,
+		Error:      a.Error,
+		DurationMS: a.Duration.Milliseconds(),
+		CreatedAt:  a.CreatedAt,
+	}
+}
+
+// validateWebhook checks that the URL is absolute http(s) and that every
+// event is known. Duplicate events are dropped.
+func validateWebhook(req *RequestWebhook) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if len(req.Events) == 0 {
+		return errors.New("at least one event is required")
+	}
+	events := []string{}
+	for _, event := range req.Events {
+		if !slices.Contains(entities.WebhookEvents, event) {
+			return fmt.Errorf("unknown event %q", event)
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	req.Events = events
+	return nil
+}
+
+// findWebhook loads the webhook in the {id} URL parameter, answering 401,
+// 400 or 404 when it cannot. The webhooks of other users are not found.
+func findWebhook(w http.ResponseWriter, r *http.Request, userRepository userhandlers.User, webhooksRepository models.WebhooksRepository) (entities.Webhook, bool) {
+	user, err := userhandlers.CurrentUser(r, userRepository)
+	if err != nil {
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, response.Error(err.Error()))
+		return entities.Webhook{}, false
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid webhook id"))
+		return entities.Webhook{}, false
+	}
+	webhook, err := webhooksRepository.FindWebhook(r.Context(), id)
+	if err != nil || webhook.UserID != user.ID {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, response.Error("webhook not found"))
+		return entities.Webhook{}, false
+	}
+	return webhook, true
+}
+
+// findDelivery loads the delivery in the {delivery} URL parameter of the
+// webhook in {id}.
+func findDelivery(w http.ResponseWriter, r *http.Request, userRepository userhandlers.User, webhooksRepository models.WebhooksRepository) (entities.WebhookDelivery, bool) {
+	webhook, ok := findWebhook(w, r, userRepository, webhooksRepository)
+	if !ok {
+		return entities.WebhookDelivery{}, false
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "delivery"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid delivery id"))
+		return entities.WebhookDelivery{}, false
+	}
+	delivery, err := webhooksRepository.FindDelivery(r.Context(), id)
+	if err != nil || delivery.WebhookID != webhook.ID {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, response.Error("delivery not found"))
+		return entities.WebhookDelivery{}, false
+	}
+	return delivery, true
+}
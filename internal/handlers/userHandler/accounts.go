@@ -0,0 +1,149 @@
+package userhandlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"news-service/internal/config"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/mailer"
+	"news-service/internal/models"
+	"sync"
+	"time"
+)
+
+// ErrEmailNotVerified rejects the logins of users who did not verify their
+// email yet, when verification is required.
+var ErrEmailNotVerified = errors.New("email is not verified")
+
+// Default lifetimes of the mailed tokens.
+const (
+	defaultVerificationTTL = 24 * time.Hour
+	defaultResetTTL        = time.Hour
+)
+
+// Accounts mails the tokens of email verification and password reset and
+// redeems them, for the HTTP and the gRPC API alike. Tokens are random and
+// only their SHA-256 hash is stored. Accounts without Tokens send no mails.
+// The mails of sign-ups and the ones asked for by email address are sent
+// in the background, counted in Pending when it is set so that shutdown may
+// wait for them.
+type Accounts struct {
+	Tokens  models.UserTokensRepository
+	Mailer  mailer.Mailer
+	Cfg     config.MailCfg
+	Pending *sync.WaitGroup
+}
+
+// background runs task without waiting for it, with a context outliving
+// the request of ctx.
+func (a Accounts) background(ctx context.Context, task func(ctx context.Context)) {
+	ctx = context.WithoutCancel(ctx)
+	if a.Pending != nil {
+		a.Pending.Add(1)
+	}
+	go func() {
+		if a.Pending != nil {
+			defer a.Pending.Done()
+		}
+		task(ctx)
+	}()
+}
+
+// VerifyInBackground mails user the link verifying their email without
+// waiting for the mail server. A lost mail is only logged, the user can ask
+// for another link.
+func (a Accounts) VerifyInBackground(ctx context.Context, log *slog.Logger, user entities.User) {
+	a.background(ctx, func(ctx context.Context) {
+		if err := a.SendVerification(ctx, user); err != nil {
+			log.Error("failed to send verification mail", errMsg.Err(err))
+		}
+	})
+}
+
+func (a Accounts) SendVerification(ctx context.Context, user entities.User) error {
+	ttl := a.Cfg.VerificationTTL
+	if ttl <= 0 {
+		ttl = defaultVerificationTTL
+	}
+	token, err := a.issue(ctx, user, entities.TokenVerifyEmail, ttl)
+	if err != nil || token == "" {
+		return err
+	}
+	return a.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Confirm your email by following %s\n\nor by posting the token %s to /users/verify.\n"+
+			"The link expires in %s.\n", a.Cfg.VerifyURL+url.QueryEscape(token), token, ttl),
+	})
+}
+
+func (a Accounts) SendPasswordReset(ctx context.Context, user entities.User) error {
+	ttl := a.Cfg.ResetTTL
+	if ttl <= 0 {
+		ttl = defaultResetTTL
+	}
+	token, err := a.issue(ctx, user, entities.TokenResetPassword, ttl)
+	if err != nil || token == "" {
+		return err
+	}
+	return a.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Choose a new password by following %s\n\nor by posting the token %s with it to /password/reset.\n"+
+			"The link expires in %s. Ignore this mail if you did not ask for it.\n", a.Cfg.ResetURL+url.QueryEscape(token), token, ttl),
+	})
+}
+
+// issue saves a new token of user for purpose and returns it, or an empty
+// token when mails are disabled.
+func (a Accounts) issue(ctx context.Context, user entities.User, purpose string, ttl time.Duration) (string, error) {
+	if a.Tokens == nil {
+		return "", nil
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	err := a.Tokens.CreateToken(ctx, &entities.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		Hash:      hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Redeem uses token up, see models.UserTokensRepository.ConsumeToken. It
+// returns models.ErrTokenInvalid for tokens that cannot be used.
+func (a Accounts) Redeem(ctx context.Context, purpose, token string) (entities.UserToken, error) {
+	if a.Tokens == nil || token == "" {
+		return entities.UserToken{}, models.ErrTokenInvalid
+	}
+	return a.Tokens.ConsumeToken(ctx, purpose, hashToken(token), time.Now())
+}
+
+// CheckVerified returns ErrEmailNotVerified for a user who may not log in
+// yet.
+func (a Accounts) CheckVerified(user entities.User) error {
+	if a.Cfg.RequireVerification && !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+func hashToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
@@ -2,12 +2,15 @@ package userhandlers
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"news-service/api/response"
+	apiv1 "news-service/api/v1"
 	auth "news-service/internal/auth/pass"
 	"news-service/internal/entities"
 	errMsg "news-service/internal/err"
+	"news-service/internal/ratelimit"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
@@ -33,7 +36,8 @@ type ResponseUser struct {
 	Email string `json:"email"`
 }
 
-func NewUser(log *slog.Logger, userRepository User) http.HandlerFunc {
+// NewUser signs a user up and mails them the link verifying their email.
+func NewUser(log *slog.Logger, userRepository User, throttle Throttle, accounts Accounts) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const loggerOptions = "handlers.createUser.New"
 		log = log.With(
@@ -47,13 +51,24 @@ func NewUser(log *slog.Logger, userRepository User) http.HandlerFunc {
 			render.JSON(w, r, response.Error("failed to decode request"))
 			return
 		}
-		log.Info("request body decoded", slog.Any("request", req))
+		log.Info("request body decoded", slog.String("email", req.Email))
 		if err := validator.New().Struct(req); err != nil {
 			validateErr := err.(validator.ValidationErrors)
 			log.Error("Invalid request", errMsg.Err(err))
 			render.JSON(w, r, response.ValidationError(validateErr))
 			return
 		}
+		var throttled *ThrottledError
+		if err := throttle.Allow(r.Context(), ratelimit.ByIP(r), req.Email); errors.As(err, &throttled) {
+			log.Warn("sign-up throttled", slog.Duration("wait", throttled.Wait))
+			renderThrottled(w, r, throttled)
+			return
+		} else if err != nil {
+			log.Error("failed to check rate limits", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("Failed to create user"))
+			return
+		}
 		hashPass, _ := auth.HashPassword(req.Password)
 		user := entities.User{Email: req.Email, Password: hashPass}
 		err = userRepository.CreateUser(r.Context(), &user)
@@ -63,11 +78,16 @@ func NewUser(log *slog.Logger, userRepository User) http.HandlerFunc {
 			return
 		}
 		log.Info("user added")
+		accounts.VerifyInBackground(r.Context(), log, user)
 		responseOK(w, r, req.Email, user.ID)
 	}
 }
 
 func responseOK(w http.ResponseWriter, r *http.Request, email string, userID int) {
+	if apiv1.Requested(r) {
+		render.JSON(w, r, apiv1.UserResponse{Response: response.OK(), User: apiv1.User{ID: userID, Email: email}})
+		return
+	}
 	render.JSON(w, r, ResponseUser{
 		response.OK(),
 		userID,
@@ -0,0 +1,37 @@
+package userhandlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"news-service/internal/entities"
+	"news-service/internal/jwt"
+	"time"
+)
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+// CurrentUser returns the user authenticated by jwt.TokenAuthMiddleware.
+func CurrentUser(r *http.Request, userRepository User) (entities.User, error) {
+	email, ok := jwt.EmailFromContext(r.Context())
+	if !ok {
+		return entities.User{}, ErrUnauthorized
+	}
+	user, err := userRepository.FindUserByEmail(r.Context(), email)
+	if err != nil {
+		return entities.User{}, ErrUnauthorized
+	}
+	return user, nil
+}
+
+// PasswordChangedAt returns when the password of the user with an email
+// last changed, for jwt.JWTManager.RevokeBefore.
+func PasswordChangedAt(userRepository User) func(ctx context.Context, email string) (time.Time, error) {
+	return func(ctx context.Context, email string) (time.Time, error) {
+		user, err := userRepository.FindUserByEmail(ctx, email)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return user.PasswordChangedAt, nil
+	}
+}
@@ -1,12 +1,14 @@
 package userhandlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"news-service/api/response"
-	auth "news-service/internal/auth/pass"
+	apiv1 "news-service/api/v1"
 	errMsg "news-service/internal/err"
 	"news-service/internal/jwt"
+	"news-service/internal/ratelimit"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -21,7 +23,7 @@ type ResponseAuthUser struct {
 	Token string `json:"token"`
 }
 
-func LoginFunc(log *slog.Logger, userRepository User, jwt *jwt.JWTManager) http.HandlerFunc {
+func LoginFunc(log *slog.Logger, userRepository User, jwt *jwt.JWTManager, throttle Throttle, accounts Accounts) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log = log.With(
 			slog.String("request_id", middleware.GetReqID(r.Context())),
@@ -34,24 +36,34 @@ func LoginFunc(log *slog.Logger, userRepository User, jwt *jwt.JWTManager) http.
 			render.JSON(w, r, response.Error("Failed to decode request"))
 			return
 		}
-		log.Info("request body decoded", slog.Any("request", req))
+		log.Info("request body decoded", slog.String("email", req.Email))
 		if err := validator.New().Struct(req); err != nil {
 			validateErr := err.(validator.ValidationErrors)
 			log.Error("Invalid request", errMsg.Err(err))
 			render.JSON(w, r, response.ValidationError(validateErr))
 			return
 		}
-		user, err := userRepository.FindUserByEmail(r.Context(), req.Email)
-		if err != nil {
-			log.Error("User not found with email")
-			render.JSON(w, r, response.Error("Invalid email"))
+		user, err := throttle.Authenticate(r.Context(), userRepository, ratelimit.ByIP(r), req.Email, req.Password)
+		var throttled *ThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			log.Warn("login throttled", slog.Duration("wait", throttled.Wait))
+			renderThrottled(w, r, throttled)
+			return
+		case errors.Is(err, ErrInvalidCredentials):
+			log.Info("invalid credentials")
+			render.JSON(w, r, response.Error("Invalid credentials"))
+			return
+		case err != nil:
+			log.Error("failed to authenticate", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to authenticate"))
 			return
 		}
-
-		errAuth := auth.ComparePasswordHash(req.Password, user.Password)
-		if errAuth != nil {
-			log.Error("Invalid password")
-			render.JSON(w, r, response.Error("Invalid password"))
+		if err := accounts.CheckVerified(user); err != nil {
+			log.Info("email not verified")
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("email is not verified"))
 			return
 		}
 		token, err := jwt.GenerateToken(user.Email, time.Second*600)
@@ -66,6 +78,11 @@ func LoginFunc(log *slog.Logger, userRepository User, jwt *jwt.JWTManager) http.
 }
 
 func responseAuthOK(w http.ResponseWriter, r *http.Request, email string, userID int, token string) {
+	if apiv1.Requested(r) {
+		render.JSON(w, r, apiv1.SessionResponse{Response: response.OK(),
+			User: apiv1.User{ID: userID, Email: email}, Token: token})
+		return
+	}
 	render.JSON(w, r, ResponseAuthUser{Response: response.OK(),
 		Email: email, ID: userID, Token: token})
 }
@@ -0,0 +1,72 @@
+package userhandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	auth "news-service/internal/auth/pass"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type RequestResetPassword struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ForgotPassword serves POST /password/forgot, it mails a password reset
+// link. Like ResendVerification it answers OK for unknown emails too.
+func ForgotPassword(log *slog.Logger, userRepository User, accounts Accounts, throttle Throttle) http.HandlerFunc {
+	return mailUser(log, "handlers.forgotPassword", userRepository, accounts, throttle, accounts.SendPasswordReset)
+}
+
+// ResetPassword serves POST /password/reset. The token proves the user
+// reads their mail, so it also verifies their email and lifts the lockout
+// of their account. The tokens issued before are revoked.
+func ResetPassword(log *slog.Logger, userRepository User, accounts Accounts, throttle Throttle) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.resetPassword"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestResetPassword
+		if err := render.DecodeJSON(r.Body, &req); err != nil || req.Password == "" {
+			log.Error("failed to decode request body", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+			return
+		}
+		user, ok := redeem(log, w, r, userRepository, accounts, entities.TokenResetPassword, req.Token)
+		if !ok {
+			return
+		}
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			log.Error("failed to hash password", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to reset password"))
+			return
+		}
+		user.Password = hash
+		user.PasswordChangedAt = time.Now()
+		user.EmailVerified = true
+		if err := userRepository.UpdateUser(r.Context(), &user); err != nil {
+			log.Error("failed to update user", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to reset password"))
+			return
+		}
+		if err := throttle.Lockout.Reset(r.Context(), accountKey(user.Email)); err != nil {
+			log.Error("failed to lift lockout", errMsg.Err(err))
+		}
+
+		log.Info("password reset", slog.Int("user_id", user.ID))
+		render.JSON(w, r, response.OK())
+	}
+}
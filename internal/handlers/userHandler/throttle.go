@@ -0,0 +1,101 @@
+package userhandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"news-service/api/response"
+	auth "news-service/internal/auth/pass"
+	"news-service/internal/entities"
+	"news-service/internal/ratelimit"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// ErrInvalidCredentials answers both unknown emails and wrong passwords, so
+// that logins do not tell which accounts exist.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ThrottledError rejects an attempt until Wait has passed.
+type ThrottledError struct {
+	Wait time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("too many attempts, retry in %s", e.Wait.Round(time.Second))
+}
+
+// Throttle protects sign-ins and sign-ups from brute force, for the HTTP
+// and the gRPC API alike. IPs limits attempts per client address, Accounts
+// per email, and Lockout locks an email out after repeated failed logins.
+// Nil fields are disabled.
+type Throttle struct {
+	IPs      *ratelimit.Limiter
+	Accounts *ratelimit.Limiter
+	Lockout  *ratelimit.Lockout
+}
+
+// Allow takes a token from the buckets of ip and email.
+func (t Throttle) Allow(ctx context.Context, ip, email string) error {
+	for _, check := range []struct {
+		limiter *ratelimit.Limiter
+		key     string
+	}{{t.IPs, ip}, {t.Accounts, accountKey(email)}} {
+		ok, wait, err := check.limiter.Allow(ctx, check.key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &ThrottledError{Wait: wait}
+		}
+	}
+	return nil
+}
+
+// Authenticate checks the credentials of a login. Emails are locked out
+// whether they belong to a user or not, and unknown emails are rejected
+// after a dummy password comparison, both keep the response the same for
+// existing and unknown accounts.
+func (t Throttle) Authenticate(ctx context.Context, users User, ip, email, password string) (entities.User, error) {
+	if err := t.Allow(ctx, ip, email); err != nil {
+		return entities.User{}, err
+	}
+	key := accountKey(email)
+	locked, err := t.Lockout.Locked(ctx, key)
+	if err != nil {
+		return entities.User{}, err
+	}
+	if locked > 0 {
+		return entities.User{}, &ThrottledError{Wait: locked}
+	}
+
+	user, err := users.FindUserByEmail(ctx, email)
+	if err != nil {
+		auth.CompareDummyHash(password)
+	} else {
+		err = auth.ComparePasswordHash(password, user.Password)
+	}
+	if err != nil {
+		if _, err := t.Lockout.Fail(ctx, key); err != nil {
+			return entities.User{}, err
+		}
+		return entities.User{}, ErrInvalidCredentials
+	}
+	return user, t.Lockout.Reset(ctx, key)
+}
+
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// renderThrottled answers 429 Too Many Requests like ratelimit.Middleware.
+func renderThrottled(w http.ResponseWriter, r *http.Request, err *ThrottledError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(err.Wait.Seconds()))))
+	render.Status(r, http.StatusTooManyRequests)
+	render.JSON(w, r, response.Error("too many attempts, try again later"))
+}
@@ -8,6 +8,7 @@ import (
 	auth "news-service/internal/auth/pass"
 	errMsg "news-service/internal/err"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -49,8 +50,12 @@ func NewUpdateUserHandler(userRepo User, logger *slog.Logger) http.HandlerFunc {
 		}
 
 		user.ID = userID
+		if user.Email != req.Email {
+			user.EmailVerified = false
+		}
 		user.Email = req.Email
 		user.Password, _ = auth.HashPassword(req.Password)
+		user.PasswordChangedAt = time.Now()
 
 		err = userRepo.UpdateUser(r.Context(), &user)
 		if err != nil {
@@ -0,0 +1,146 @@
+package userhandlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"news-service/api/response"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"news-service/internal/ratelimit"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type RequestToken struct {
+	Token string `json:"token"`
+}
+
+type RequestEmail struct {
+	Email string `json:"email"`
+}
+
+// VerifyEmail serves POST /users/verify, it marks the email of the user
+// verified. The token only verifies the address it was mailed to.
+func VerifyEmail(log *slog.Logger, userRepository User, accounts Accounts) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const loggerOptions = "handlers.verifyEmail"
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestToken
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+			return
+		}
+		user, ok := redeem(log, w, r, userRepository, accounts, entities.TokenVerifyEmail, req.Token)
+		if !ok {
+			return
+		}
+		if user.EmailVerified {
+			render.JSON(w, r, response.OK())
+			return
+		}
+		user.EmailVerified = true
+		if err := userRepository.UpdateUser(r.Context(), &user); err != nil {
+			log.Error("failed to update user", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to verify email"))
+			return
+		}
+
+		log.Info("email verified", slog.Int("user_id", user.ID))
+		render.JSON(w, r, response.OK())
+	}
+}
+
+// ResendVerification serves POST /users/verification, it mails a new link
+// to an unverified user. It answers OK whether the email belongs to a user
+// or not, so that it does not tell which accounts exist.
+func ResendVerification(log *slog.Logger, userRepository User, accounts Accounts, throttle Throttle) http.HandlerFunc {
+	return mailUser(log, "handlers.resendVerification", userRepository, accounts, throttle, func(ctx context.Context, user entities.User) error {
+		if user.EmailVerified {
+			return nil
+		}
+		return accounts.SendVerification(ctx, user)
+	})
+}
+
+// mailUser answers a request for a mail to the user with the posted email.
+// Requests are throttled like sign-ins, and answered OK whether the email
+// belongs to a user or not. The user is looked up and mailed once the
+// request is answered, so that the answer takes as long for unknown emails
+// too.
+func mailUser(log *slog.Logger, loggerOptions string, userRepository User, accounts Accounts, throttle Throttle,
+	send func(ctx context.Context, user entities.User) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("options", loggerOptions),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestEmail
+		if err := render.DecodeJSON(r.Body, &req); err != nil || req.Email == "" {
+			log.Error("failed to decode request body", errMsg.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+			return
+		}
+		var throttled *ThrottledError
+		if err := throttle.Allow(r.Context(), ratelimit.ByIP(r), req.Email); errors.As(err, &throttled) {
+			log.Warn("mail request throttled", slog.Duration("wait", throttled.Wait))
+			renderThrottled(w, r, throttled)
+			return
+		} else if err != nil {
+			log.Error("failed to check rate limits", errMsg.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to send mail"))
+			return
+		}
+
+		accounts.background(r.Context(), func(ctx context.Context) {
+			user, err := userRepository.FindUserByEmail(ctx, req.Email)
+			if err != nil {
+				log.Info("mail requested for an unknown email")
+				return
+			}
+			if err := send(ctx, user); err != nil {
+				log.Error("failed to send mail", errMsg.Err(err))
+			}
+		})
+		render.JSON(w, r, response.OK())
+	}
+}
+
+// redeem uses token of purpose up and returns its user. It answers the
+// request itself when the token cannot be used.
+func redeem(log *slog.Logger, w http.ResponseWriter, r *http.Request, userRepository User, accounts Accounts,
+	purpose, token string) (entities.User, bool) {
+	redeemed, err := accounts.Redeem(r.Context(), purpose, token)
+	if errors.Is(err, models.ErrTokenInvalid) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error(models.ErrTokenInvalid.Error()))
+		return entities.User{}, false
+	}
+	if err != nil {
+		log.Error("failed to redeem token", errMsg.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to redeem token"))
+		return entities.User{}, false
+	}
+	user, err := userRepository.FindUserById(r.Context(), redeemed.UserID)
+	if err != nil || user.Email != redeemed.Email {
+		// The user was deleted or changed their email since the mail.
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error(models.ErrTokenInvalid.Error()))
+		return entities.User{}, false
+	}
+	return user, true
+}
@@ -0,0 +1,38 @@
+package jwt
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor is the gRPC counterpart of TokenAuthMiddleware: the
+// "authorization" metadata must carry "Bearer <token>", the claims are then
+// available to EmailFromContext. Methods public reports true for, by their
+// full name like "/news.v1.UserService/Login", are called without a token.
+func UnaryServerInterceptor(jwtManager *JWTManager, public func(fullMethod string) bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if public(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		token, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		claims, err := jwtManager.Authenticate(ctx, token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		return handler(context.WithValue(ctx, claimsKey{}, claims), req)
+	}
+}
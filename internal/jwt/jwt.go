@@ -1,38 +1,126 @@
 package jwt
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	errMsg "news-service/internal/err"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 type Claims struct {
 	Username string `json:"username"`
 	Role     string `json:"role"`
-	jwt.StandardClaims
+	jwt.RegisteredClaims
 }
 
+// Options tune the claims of the tokens. Issuer and Audience are set in
+// the issued tokens and required from the verified ones when not empty.
+// Leeway tolerates clock skew between the issuer and the verifiers in exp,
+// nbf and iat.
+type Options struct {
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+	// SigningKey is the ID of the key signing tokens, the first key by
+	// default.
+	SigningKey string
+}
+
+// ErrTokenRevoked rejects the tokens issued before the time RevokeBefore
+// returns for their user.
+var ErrTokenRevoked = errors.New("token was revoked")
+
 type JWTManager struct {
-	secret []byte
-	log    *slog.Logger
+	keys       []Key
+	byID       map[string]Key
+	signing    Key
+	opts       Options
+	parser     *jwt.Parser
+	validAfter func(ctx context.Context, email string) (time.Time, error)
+	log        *slog.Logger
 }
 
+// NewJWTManager signs and verifies HS256 tokens with secret, without an
+// issuer or an audience.
 func NewJWTManager(secret string, log *slog.Logger) *JWTManager {
-	return &JWTManager{secret: []byte(secret), log: log}
+	manager, err := New(log, Options{}, NewHMACKey("", []byte(secret)))
+	if err != nil {
+		panic(err)
+	}
+	return manager
+}
+
+// New signs tokens with the key opts.SigningKey names and verifies them
+// with the key their kid header names, tokens without kid with the key
+// without ID. Keeping the previous key among keys lets the tokens it signed
+// verify until they expire, adding the next one before it signs publishes
+// it in JWKS ahead of its use.
+func New(log *slog.Logger, opts Options, keys ...Key) (*JWTManager, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no jwt keys")
+	}
+	manager := &JWTManager{keys: keys, byID: make(map[string]Key, len(keys)), opts: opts, log: log}
+	var methods []string
+	for _, key := range keys {
+		if _, ok := manager.byID[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.ID)
+		}
+		manager.byID[key.ID] = key
+		methods = append(methods, key.method.Alg())
+	}
+
+	manager.signing = keys[0]
+	if opts.SigningKey != "" {
+		signing, ok := manager.byID[opts.SigningKey]
+		if !ok {
+			return nil, fmt.Errorf("unknown jwt signing key %q", opts.SigningKey)
+		}
+		manager.signing = signing
+	}
+	if manager.signing.private == nil {
+		return nil, fmt.Errorf("jwt key %q has no private key to sign with", manager.signing.ID)
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(opts.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	manager.parser = jwt.NewParser(parserOpts...)
+	return manager, nil
 }
 
 func (manager *JWTManager) GenerateToken(email string, expiration time.Duration) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"email": email,
-		"exp":   time.Now().Add(expiration).Unix(),
+		"iat":   now.Unix(),
+		"nbf":   now.Unix(),
+		"exp":   now.Add(expiration).Unix(),
+	}
+	if manager.opts.Issuer != "" {
+		claims["iss"] = manager.opts.Issuer
+	}
+	if manager.opts.Audience != "" {
+		claims["aud"] = manager.opts.Audience
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString(manager.secret)
+	token := jwt.NewWithClaims(manager.signing.method, claims)
+	if manager.signing.ID != "" {
+		token.Header["kid"] = manager.signing.ID
+	}
+	signedToken, err := token.SignedString(manager.signing.private)
 	if err != nil {
 		manager.log.Error("Failed to sign token")
 		return "", fmt.Errorf("failed to sign token: %w", err)
@@ -41,28 +129,68 @@ func (manager *JWTManager) GenerateToken(email string, expiration time.Duration)
 	return signedToken, nil
 }
 
+// VerifyToken checks the signature, exp, nbf and iat of the token, and its
+// iss and aud when the manager has an issuer and an audience.
 func (manager *JWTManager) VerifyToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			manager.log.Error("Unexpected signing method")
-			return nil, errors.New("unexpected signing method")
-		}
-		return manager.secret, nil
-	})
+	claims := jwt.MapClaims{}
+	_, err := manager.parser.ParseWithClaims(tokenString, claims, manager.verificationKey)
 	if err != nil {
 		manager.log.Error("failed to parse token", errMsg.Err(err))
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		manager.log.Error("invalid token")
-		return nil, errors.New("invalid token")
-	}
+	return claims, nil
+}
 
+// RevokeBefore makes Authenticate reject the tokens of a user issued before
+// the time validAfter returns for their email, such as their last password
+// change. iat counts whole seconds, so tokens issued in the second of the
+// change stay valid. An error of validAfter rejects the token.
+func (manager *JWTManager) RevokeBefore(validAfter func(ctx context.Context, email string) (time.Time, error)) {
+	manager.validAfter = validAfter
+}
+
+// Authenticate is VerifyToken also rejecting the tokens revoked through
+// RevokeBefore.
+func (manager *JWTManager) Authenticate(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	claims, err := manager.VerifyToken(tokenString)
+	if err != nil || manager.validAfter == nil {
+		return claims, err
+	}
+	email, _ := claims["email"].(string)
+	validAfter, err := manager.validAfter(ctx, email)
+	if err != nil {
+		manager.log.Error("failed to check token revocation", errMsg.Err(err))
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		if validAfter.IsZero() {
+			return claims, nil
+		}
+		return nil, ErrTokenRevoked
+	}
+	if issuedAt.Before(validAfter.Truncate(time.Second)) {
+		return nil, ErrTokenRevoked
+	}
 	return claims, nil
 }
 
+// verificationKey picks the key of the kid header. The algorithm of the
+// token must be the one of the key, so that a public key is never used as
+// an HMAC secret.
+func (manager *JWTManager) verificationKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := manager.byID[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("key %q does not verify %s", kid, token.Method.Alg())
+	}
+	return key.public, nil
+}
+
 func (manager *JWTManager) ExtractRoleFromToken(tokenString string) (string, error) {
 	claims, err := manager.VerifyToken(tokenString)
 	if err != nil {
@@ -82,23 +210,11 @@ func (manager *JWTManager) ExtractRoleFromToken(tokenString string) (string, err
 func (manager *JWTManager) ExtractRoleAndUsernameFromToken(tokenString string) (string, string, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			manager.log.Error("Unexpected signing method")
-			return nil, errors.New("unexpected signing method")
-		}
-		return manager.secret, nil
-	})
-
+	_, err := manager.parser.ParseWithClaims(tokenString, claims, manager.verificationKey)
 	if err != nil {
 		manager.log.Error("Failed to parse token", errMsg.Err(err))
 		return "", "", fmt.Errorf("failed to parse token: %w", err)
 	}
 
-	if !token.Valid {
-		manager.log.Error("Invalid token")
-		return "", "", errors.New("invalid token")
-	}
-
 	return claims.Username, claims.Role, nil
 }
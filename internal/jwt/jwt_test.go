@@ -0,0 +1,399 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"news-service/internal/config"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var (
+	rsaOnce sync.Once
+	rsaKey  *rsa.PrivateKey
+)
+
+// testRSAKey generates a single key for the tests, generating them is slow.
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	rsaOnce.Do(func() {
+		var err error
+		if rsaKey, err = rsa.GenerateKey(rand.Reader, minRSABits); err != nil {
+			t.Fatalf("generate rsa key: %v", err)
+		}
+	})
+	return rsaKey
+}
+
+func testEdKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	return private
+}
+
+func mustKey(t *testing.T, id string, key any) Key {
+	t.Helper()
+	k, err := NewKey(id, key)
+	if err != nil {
+		t.Fatalf("NewKey(%q): %v", id, err)
+	}
+	return k
+}
+
+func mustNew(t *testing.T, opts Options, keys ...Key) *JWTManager {
+	t.Helper()
+	manager, err := New(testLog, opts, keys...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return manager
+}
+
+// sign signs claims like GenerateToken but lets the tests pick them.
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed
+}
+
+func TestRoundTrip(t *testing.T) {
+	keys := map[string]Key{
+		"RS256": mustKey(t, "rsa-1", testRSAKey(t)),
+		"EdDSA": mustKey(t, "ed-1", testEdKey(t)),
+		"HS256": NewHMACKey("", []byte("secret")),
+	}
+	for alg, key := range keys {
+		t.Run(alg, func(t *testing.T) {
+			manager := mustNew(t, Options{Issuer: "news-service", Audience: "news-service"}, key)
+			token, err := manager.GenerateToken("alice@example.com", time.Minute)
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified: %v", err)
+			}
+			if parsed.Method.Alg() != alg {
+				t.Errorf("alg = %s, want %s", parsed.Method.Alg(), alg)
+			}
+			if kid, _ := parsed.Header["kid"].(string); kid != key.ID {
+				t.Errorf("kid = %q, want %q", kid, key.ID)
+			}
+
+			claims, err := manager.VerifyToken(token)
+			if err != nil {
+				t.Fatalf("VerifyToken: %v", err)
+			}
+			if claims["email"] != "alice@example.com" || claims["iss"] != "news-service" || claims["aud"] != "news-service" {
+				t.Errorf("claims = %v", claims)
+			}
+		})
+	}
+}
+
+func TestRevokeBefore(t *testing.T) {
+	manager := NewJWTManager("secret", testLog)
+	token, err := manager.GenerateToken("alice@example.com", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	changes := map[string]time.Time{}
+	lookupErr := errors.New("lookup failed")
+	manager.RevokeBefore(func(_ context.Context, email string) (time.Time, error) {
+		if email == "" {
+			return time.Time{}, lookupErr
+		}
+		return changes[email], nil
+	})
+	if _, err := manager.Authenticate(context.Background(), token); err != nil {
+		t.Fatalf("Authenticate without a password change: %v", err)
+	}
+	changes["alice@example.com"] = time.Now().Add(-time.Hour)
+	if _, err := manager.Authenticate(context.Background(), token); err != nil {
+		t.Fatalf("Authenticate after an older change: %v", err)
+	}
+	changes["alice@example.com"] = time.Now().Add(time.Hour)
+	if _, err := manager.Authenticate(context.Background(), token); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("Authenticate after a newer change: %v, want ErrTokenRevoked", err)
+	}
+
+	anonymous, err := manager.GenerateToken("", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := manager.Authenticate(context.Background(), anonymous); !errors.Is(err, lookupErr) {
+		t.Fatalf("Authenticate with a failing lookup: %v, want the lookup error", err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldKey := mustKey(t, "2024-01", testRSAKey(t))
+	newKey := mustKey(t, "2024-06", testEdKey(t))
+
+	before := mustNew(t, Options{}, oldKey)
+	oldToken, err := before.GenerateToken("alice@example.com", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	// The new key is published first, then signs while the old one verifies.
+	during := mustNew(t, Options{SigningKey: "2024-06"}, oldKey, newKey)
+	if _, err := during.VerifyToken(oldToken); err != nil {
+		t.Errorf("token of the previous key: %v", err)
+	}
+	newToken, err := during.GenerateToken("alice@example.com", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := before.VerifyToken(newToken); err == nil {
+		t.Error("a manager without the new key verified its token")
+	}
+
+	after := mustNew(t, Options{}, newKey)
+	if _, err := after.VerifyToken(newToken); err != nil {
+		t.Errorf("token of the new key: %v", err)
+	}
+	if _, err := after.VerifyToken(oldToken); err == nil {
+		t.Error("token of a removed key verified")
+	}
+}
+
+func TestNewRejects(t *testing.T) {
+	private := testEdKey(t)
+	public := mustKey(t, "public", private.Public())
+	tests := map[string]struct {
+		opts Options
+		keys []Key
+	}{
+		"no keys":        {Options{}, nil},
+		"duplicate id":   {Options{}, []Key{mustKey(t, "a", private), NewHMACKey("a", []byte("secret"))}},
+		"unknown signer": {Options{SigningKey: "b"}, []Key{mustKey(t, "a", private)}},
+		"public signer":  {Options{}, []Key{public}},
+	}
+	for name, tt := range tests {
+		if _, err := New(testLog, tt.opts, tt.keys...); err == nil {
+			t.Errorf("%s: New succeeded", name)
+		}
+	}
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	private := testRSAKey(t)
+	manager := mustNew(t, Options{}, mustKey(t, "rsa-1", private), NewHMACKey("", []byte("secret")))
+	claims := jwt.MapClaims{"email": "mallory@example.com", "iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix()}
+
+	// The public key is no secret, an HMAC made with it must not verify.
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	for _, secret := range [][]byte{der, publicPEM} {
+		if _, err := manager.VerifyToken(sign(t, jwt.SigningMethodHS256, "rsa-1", secret, claims)); err == nil {
+			t.Error("HS256 token with the kid of an RSA key verified")
+		}
+	}
+
+	unsigned := sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, claims)
+	if _, err := manager.VerifyToken(unsigned); err == nil {
+		t.Error("unsigned token verified")
+	}
+
+	if _, err := manager.VerifyToken(sign(t, jwt.SigningMethodHS256, "rsa-2", []byte("secret"), claims)); err == nil {
+		t.Error("token of an unknown kid verified")
+	}
+}
+
+func TestClaims(t *testing.T) {
+	key := testEdKey(t)
+	manager := mustNew(t, Options{Issuer: "news-service", Audience: "news-service", Leeway: 30 * time.Second}, mustKey(t, "ed-1", key))
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"email": "alice@example.com",
+			"iss":   "news-service",
+			"aud":   "news-service",
+			"iat":   now.Unix(),
+			"nbf":   now.Unix(),
+			"exp":   now.Add(time.Minute).Unix(),
+		}
+	}
+
+	tests := map[string]struct {
+		change func(jwt.MapClaims)
+		ok     bool
+	}{
+		"valid":            {func(jwt.MapClaims) {}, true},
+		"other issuer":     {func(c jwt.MapClaims) { c["iss"] = "billing" }, false},
+		"no issuer":        {func(c jwt.MapClaims) { delete(c, "iss") }, false},
+		"other audience":   {func(c jwt.MapClaims) { c["aud"] = "billing" }, false},
+		"audience list":    {func(c jwt.MapClaims) { c["aud"] = []string{"billing", "news-service"} }, true},
+		"no expiration":    {func(c jwt.MapClaims) { delete(c, "exp") }, false},
+		"expired":          {func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }, false},
+		"expired in skew":  {func(c jwt.MapClaims) { c["exp"] = now.Add(-10 * time.Second).Unix() }, true},
+		"not yet valid":    {func(c jwt.MapClaims) { c["nbf"] = now.Add(time.Minute).Unix() }, false},
+		"valid in skew":    {func(c jwt.MapClaims) { c["nbf"] = now.Add(10 * time.Second).Unix() }, true},
+		"issued in future": {func(c jwt.MapClaims) { c["iat"] = now.Add(time.Minute).Unix() }, false},
+	}
+	for name, tt := range tests {
+		claims := valid()
+		tt.change(claims)
+		_, err := manager.VerifyToken(sign(t, jwt.SigningMethodEdDSA, "ed-1", key, claims))
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: token verified", name)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaPrivate := testRSAKey(t)
+	edPrivate := testEdKey(t)
+	manager := mustNew(t, Options{},
+		mustKey(t, "rsa-1", rsaPrivate),
+		NewHMACKey("", []byte("secret")),
+		mustKey(t, "ed-1", edPrivate.Public()),
+	)
+
+	set := manager.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("keys = %+v, want the RSA and the Ed25519 key", set.Keys)
+	}
+
+	rsaJWK := set.Keys[0]
+	if rsaJWK.Kty != "RSA" || rsaJWK.Kid != "rsa-1" || rsaJWK.Use != "sig" || rsaJWK.Alg != "RS256" || rsaJWK.E != "AQAB" {
+		t.Errorf("rsa key = %+v", rsaJWK)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	if err != nil || new(big.Int).SetBytes(n).Cmp(rsaPrivate.N) != 0 {
+		t.Errorf("n = %q does not encode the modulus: %v", rsaJWK.N, err)
+	}
+
+	edJWK := set.Keys[1]
+	if edJWK.Kty != "OKP" || edJWK.Crv != "Ed25519" || edJWK.Kid != "ed-1" || edJWK.Alg != "EdDSA" || edJWK.N != "" {
+		t.Errorf("ed25519 key = %+v", edJWK)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(edJWK.X)
+	if err != nil || !ed25519.PublicKey(x).Equal(edPrivate.Public()) {
+		t.Errorf("x = %q does not encode the public key: %v", edJWK.X, err)
+	}
+}
+
+// writePEM writes the PEM block of key to a file and returns its path.
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewFromConfig(t *testing.T) {
+	edPrivate := testEdKey(t)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPrivate := testRSAKey(t)
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaPrivate.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edFile := writePEM(t, "ed.pem", "PRIVATE KEY", edDER)
+	rsaFile := writePEM(t, "rsa.pub.pem", "PUBLIC KEY", rsaDER)
+
+	cfg := config.JWTCfg{
+		Secret: "secret",
+		Keys: []config.JWTKeyCfg{
+			{ID: "rsa-old", PublicKeyFile: rsaFile},
+			{ID: "ed-1", PrivateKeyFile: edFile},
+		},
+		SigningKey: "ed-1",
+		Issuer:     "news-service",
+		Audience:   "news-service",
+	}
+	manager, err := NewFromConfig(cfg, testLog)
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	token, err := manager.GenerateToken("alice@example.com", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{}); err != nil || parsed.Header["kid"] != "ed-1" {
+		t.Errorf("token %q is not signed with the signing key: %v", token, err)
+	}
+	if _, err := manager.VerifyToken(token); err != nil {
+		t.Errorf("VerifyToken: %v", err)
+	}
+	// The secret still verifies the tokens signed before the keys.
+	legacy := sign(t, jwt.SigningMethodHS256, "", []byte("secret"), jwt.MapClaims{
+		"email": "alice@example.com", "iss": "news-service", "aud": "news-service",
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix(),
+	})
+	if _, err := manager.VerifyToken(legacy); err != nil {
+		t.Errorf("HS256 token: %v", err)
+	}
+	if got := len(manager.JWKS().Keys); got != 2 {
+		t.Errorf("JWKS has %d keys, want 2", got)
+	}
+
+	short, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortFile := writePEM(t, "short.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(short))
+
+	bad := map[string]config.JWTCfg{
+		"no id":         {Keys: []config.JWTKeyCfg{{PrivateKeyFile: edFile}}},
+		"missing file":  {Keys: []config.JWTKeyCfg{{ID: "a", PrivateKeyFile: filepath.Join(t.TempDir(), "none.pem")}}},
+		"not pem":       {Keys: []config.JWTKeyCfg{{ID: "a", PrivateKeyFile: writeFile(t, "key.txt", "secret")}}},
+		"short rsa key": {Keys: []config.JWTKeyCfg{{ID: "a", PrivateKeyFile: shortFile}}},
+		"public signer": {Keys: []config.JWTKeyCfg{{ID: "a", PublicKeyFile: rsaFile}}},
+		"no keys":       {},
+	}
+	for name, cfg := range bad {
+		if _, err := NewFromConfig(cfg, testLog); err == nil {
+			t.Errorf("%s: NewFromConfig succeeded", name)
+		}
+	}
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,114 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"news-service/internal/config"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits rejects RSA keys too short to sign safely.
+const minRSABits = 2048
+
+// Key signs and verifies tokens, or only verifies them without its private
+// half. RS256 and EdDSA keys are published by JWKS, HS256 secrets are not.
+type Key struct {
+	ID      string
+	method  jwt.SigningMethod
+	private any
+	public  any
+}
+
+func NewHMACKey(id string, secret []byte) Key {
+	return Key{ID: id, method: jwt.SigningMethodHS256, private: secret, public: secret}
+}
+
+// NewKey returns the RS256 key of an *rsa.PrivateKey or the EdDSA key of an
+// ed25519.PrivateKey. Their public halves return keys that only verify.
+func NewKey(id string, key any) (Key, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("rsa key %q has %d bits, at least %d are required", id, k.N.BitLen(), minRSABits)
+		}
+		return Key{ID: id, method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("rsa key %q has %d bits, at least %d are required", id, k.N.BitLen(), minRSABits)
+		}
+		return Key{ID: id, method: jwt.SigningMethodRS256, public: k}, nil
+	case ed25519.PrivateKey:
+		return Key{ID: id, method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return Key{ID: id, method: jwt.SigningMethodEdDSA, public: k}, nil
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// ParsePEM parses a PKCS #8 or PKCS #1 private key, or a PKIX or PKCS #1
+// public key, as written by openssl.
+func ParsePEM(data []byte) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// NewFromConfig loads the keys of cfg, see config.JWTCfg.
+func NewFromConfig(cfg config.JWTCfg, log *slog.Logger) (*JWTManager, error) {
+	var keys []Key
+	for _, keyCfg := range cfg.Keys {
+		if keyCfg.ID == "" {
+			return nil, errors.New("jwt keys need an id")
+		}
+		file := keyCfg.PrivateKeyFile
+		if file == "" {
+			file = keyCfg.PublicKeyFile
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", keyCfg.ID, err)
+		}
+		parsed, err := ParsePEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", keyCfg.ID, err)
+		}
+		key, err := NewKey(keyCfg.ID, parsed)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	// The secret verifies the tokens issued before the keys, it only signs
+	// when there are no keys.
+	if cfg.Secret != "" {
+		keys = append(keys, NewHMACKey("", []byte(cfg.Secret)))
+	}
+
+	return New(log, Options{
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		Leeway:     cfg.Leeway,
+		SigningKey: cfg.SigningKey,
+	}, keys...)
+}
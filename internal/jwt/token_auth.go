@@ -1,11 +1,13 @@
 package jwt
 
 import (
+	"context"
 	"net/http"
 	"news-service/api/response"
 	"strings"
 
 	"github.com/go-chi/render"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func TokenAuthMiddleware(jwtManager *JWTManager, next http.Handler) http.Handler {
@@ -22,15 +24,28 @@ func TokenAuthMiddleware(jwtManager *JWTManager, next http.Handler) http.Handler
 			return
 		}
 
-		_, err := jwtManager.VerifyToken(token[1])
+		claims, err := jwtManager.Authenticate(r.Context(), token[1])
 		if err != nil {
 			render.JSON(w, r, response.Error("unauthorized"))
 			return
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
 	})
 }
 
+type claimsKey struct{}
+
+// EmailFromContext returns the email of the user authenticated by
+// TokenAuthMiddleware.
+func EmailFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(jwt.MapClaims)
+	if !ok {
+		return "", false
+	}
+	email, ok := claims["email"].(string)
+	return email, ok && email != ""
+}
+
 func TokenAuthAndRoleMiddleware(jwtManager *JWTManager, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tokenString := r.Header.Get("Authorization")
@@ -45,7 +60,7 @@ func TokenAuthAndRoleMiddleware(jwtManager *JWTManager, next http.Handler) http.
 			return
 		}
 
-		claims, err := jwtManager.VerifyToken(token[1])
+		claims, err := jwtManager.Authenticate(r.Context(), token[1])
 		if err != nil {
 			render.JSON(w, r, response.Error("Unauthorized"))
 			return
@@ -0,0 +1,160 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var message = Message{To: "alice@example.com", Subject: "Réinitialisation", Body: "Follow https://example.com/reset?token=abc\nThanks"}
+
+// checkMessage parses data and compares it with message.
+func checkMessage(t *testing.T, data []byte) {
+	t.Helper()
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("parse %q: %v", data, err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != message.Subject {
+		t.Fatalf("Subject = %q, %v", subject, err)
+	}
+	if to := parsed.Header.Get("To"); to != message.To {
+		t.Fatalf("To = %q", to)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.ReplaceAll(strings.TrimSpace(string(body)), "\r\n", "\n"); got != message.Body {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestFormatRejectsHeaderInjection(t *testing.T) {
+	msg := message
+	msg.Subject = "hi\r\nBcc: mallory@example.com"
+	if _, err := format("news@example.com", msg, time.Now()); err == nil {
+		t.Fatal("format accepted a line break in a header")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Send(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "To: alice@example.com") || !strings.Contains(buf.String(), "token=abc") {
+		t.Fatalf("wrote %q", buf.String())
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewDir(dir, "news@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := m.Send(context.Background(), message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("files = %v, %v", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMessage(t, data)
+}
+
+func TestSMTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	type session struct {
+		commands []string
+		data     []byte
+	}
+	done := make(chan session, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var s session
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				done <- s
+				return
+			}
+			command := strings.TrimSpace(line)
+			s.commands = append(s.commands, command)
+			switch {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case strings.HasPrefix(command, "AUTH PLAIN"):
+				reply("235 accepted")
+			case command == "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					s.data = append(s.data, line...)
+				}
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				done <- s
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	m, err := NewSMTP(listener.Addr().String(), "user", "secret", "News <news@example.com>", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	s := <-done
+	want := []string{"AUTH PLAIN", "MAIL FROM:<news@example.com>", "RCPT TO:<alice@example.com>", "DATA", "QUIT"}
+	var got []string
+	for _, c := range s.commands[1:] {
+		if strings.HasPrefix(c, "AUTH PLAIN") {
+			c = "AUTH PLAIN"
+		}
+		got = append(got, c)
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("commands = %q, want %q", got, want)
+	}
+	checkMessage(t, s.data)
+}
@@ -2,14 +2,46 @@ package models
 
 import (
 	"context"
+	"errors"
 	"news-service/internal/entities"
+	"news-service/internal/related"
+	"time"
 )
 
+// ErrNewsNotFound is returned when no news has the id or slug looked for.
+var ErrNewsNotFound = errors.New("news not found")
+
+// ErrCommentChanged rejects the moderation of a comment that was edited,
+// moderated or deleted since the moderator read it.
+var ErrCommentChanged = errors.New("comment changed since it was read")
+
+type primaryKey struct{}
+
+// ReadFromPrimary makes the repositories reading from a replica read from
+// the primary within ctx, for reads that must see a write just committed.
+func ReadFromPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadsFromPrimary reports whether ctx comes from ReadFromPrimary.
+func ReadsFromPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
 type NewsRepository interface {
 	CreateNews(ctx context.Context, news *entities.News) error
 	ListNews(ctx context.Context) ([]entities.News, error)
 	UpdateNews(ctx context.Context, news *entities.News) error
 	FindNewsByID(ctx context.Context, id int) (entities.News, error)
+	// FindNewsBySlug also resolves slugs the news had before it was
+	// renamed, the returned news then carries its current slug.
+	FindNewsBySlug(ctx context.Context, slug string) (entities.News, error)
+	ListPublishedNews(ctx context.Context, limit, offset int) ([]entities.News, error)
+	ListPublishedNewsByCategory(ctx context.Context, category, limit, offset int) ([]entities.News, error)
+	// ListRelatedNews returns the published news closest to newsID, best
+	// first, see related.Score.
+	ListRelatedNews(ctx context.Context, newsID int, opts related.Options) ([]entities.News, error)
 }
 
 type CategoriesRepository interface {
@@ -19,6 +51,177 @@ type CategoriesRepository interface {
 type NewsCategoriesRepository interface {
 	Create(ctx context.Context, NC *entities.NewsCategories) error
 	ListCategories(ctx context.Context, id int) ([]int, error)
+	// ListCategoriesByNews is ListCategories for several news at once,
+	// news without categories are missing from the result.
+	ListCategoriesByNews(ctx context.Context, newsIDs []int) (map[int][]int, error)
 	UpdateNewsCategories(ctx context.Context, categoryID, newsID int) error
 	DeleteCategories(ctx context.Context, newsID int) error
 }
+
+type MediaRepository interface {
+	CreateMedia(ctx context.Context, media *entities.Media) error
+	// ListMedia returns the media of a news in upload order.
+	ListMedia(ctx context.Context, newsID int) ([]entities.Media, error)
+	// ListMediaByNews is ListMedia for several news at once, news without
+	// media are omitted.
+	ListMediaByNews(ctx context.Context, newsIDs []int) (map[int][]entities.Media, error)
+}
+
+// TagsRepository stores normalized tag names, see tag.Normalize.
+type TagsRepository interface {
+	// SetNewsTags replaces the tags of a news, creating missing tags.
+	SetNewsTags(ctx context.Context, newsID int, tags []string) error
+	ListNewsTags(ctx context.Context, newsID int) ([]string, error)
+	// ListTagsByNews is ListNewsTags for several news at once, news
+	// without tags are omitted.
+	ListTagsByNews(ctx context.Context, newsIDs []int) (map[int][]string, error)
+	// ListTags returns tags starting with prefix that are used by at least
+	// one news, most used first.
+	ListTags(ctx context.Context, prefix string, limit int) ([]entities.Tag, error)
+}
+
+type ViewsRepository interface {
+	// AddViews adds counts to the stored ones, counts of missing news are
+	// dropped.
+	AddViews(ctx context.Context, counts []entities.ViewCount) error
+	// ListTrendingNews ranks published news by their views in buckets
+	// starting at or after since, every view halving in weight per
+	// halfLife of age.
+	ListTrendingNews(ctx context.Context, since time.Time, halfLife time.Duration, limit int) ([]entities.News, error)
+}
+
+type CommentsRepository interface {
+	CreateComment(ctx context.Context, comment *entities.Comment) error
+	FindComment(ctx context.Context, id int) (entities.Comment, error)
+	// EditComment replaces the body and sends the comment back to
+	// moderation.
+	EditComment(ctx context.Context, comment *entities.Comment) error
+	// SetCommentStatus moderates a pending comment last updated at
+	// updatedAt, ErrCommentChanged when there is none.
+	SetCommentStatus(ctx context.Context, id int, updatedAt time.Time, status string) error
+	// DeleteComment empties the comment and marks it deleted.
+	DeleteComment(ctx context.Context, id int) error
+	// ListComments returns the approved comments of a news, deleted ones
+	// included, oldest first.
+	ListComments(ctx context.Context, newsID int) ([]entities.Comment, error)
+	// ListPendingComments is the moderation queue, oldest first.
+	ListPendingComments(ctx context.Context, limit, offset int) ([]entities.Comment, error)
+	// CountComments counts the approved comments of a news that are not
+	// deleted.
+	CountComments(ctx context.Context, newsID int) (int, error)
+	// CountCommentsByNews is CountComments for several news at once, news
+	// without comments are omitted.
+	CountCommentsByNews(ctx context.Context, newsIDs []int) (map[int]int, error)
+}
+
+// ReactionsRepository stores reactions of users to news, kinds are
+// entities.ReactionKinds.
+type ReactionsRepository interface {
+	// AddReaction and RemoveReaction are idempotent.
+	AddReaction(ctx context.Context, newsID, userID int, kind string) error
+	RemoveReaction(ctx context.Context, newsID, userID int, kind string) error
+	// CountReactions returns the number of reactions of every kind left on
+	// a news, kinds nobody used are omitted.
+	CountReactions(ctx context.Context, newsID int) (map[string]int, error)
+	// CountReactionsByNews is CountReactions for several news at once,
+	// news without reactions are omitted.
+	CountReactionsByNews(ctx context.Context, newsIDs []int) (map[int]map[string]int, error)
+	// ListUserReactions returns the kinds userID left on a news.
+	ListUserReactions(ctx context.Context, newsID, userID int) ([]string, error)
+}
+
+type BookmarksRepository interface {
+	// AddBookmark and RemoveBookmark are idempotent.
+	AddBookmark(ctx context.Context, userID, newsID int) error
+	RemoveBookmark(ctx context.Context, userID, newsID int) error
+	// ListBookmarkedNews returns the published news bookmarked by userID,
+	// most recently bookmarked first.
+	ListBookmarkedNews(ctx context.Context, userID, limit, offset int) ([]entities.News, error)
+}
+
+// SubscriptionsRepository stores the categories users follow. Categories
+// are identified by their name, like in ListPublishedNewsByCategory.
+type SubscriptionsRepository interface {
+	// Subscribe creates the category when it does not exist yet. Subscribe
+	// and Unsubscribe are idempotent.
+	Subscribe(ctx context.Context, userID, category int) error
+	Unsubscribe(ctx context.Context, userID, category int) error
+	ListSubscriptions(ctx context.Context, userID int) ([]int, error)
+	// ListFeedNews returns the published news in categories userID follows
+	// with an id below before, newest first. A zero before starts from the
+	// newest news.
+	ListFeedNews(ctx context.Context, userID, before, limit int) ([]entities.News, error)
+}
+
+type WebhooksRepository interface {
+	CreateWebhook(ctx context.Context, webhook *entities.Webhook) error
+	FindWebhook(ctx context.Context, id int) (entities.Webhook, error)
+	// ListWebhooks returns the webhooks owned by userID.
+	ListWebhooks(ctx context.Context, userID int) ([]entities.Webhook, error)
+	// DeleteWebhook also drops its deliveries.
+	DeleteWebhook(ctx context.Context, id int) error
+	// EnqueueEvent queues payload for every webhook subscribed to event.
+	EnqueueEvent(ctx context.Context, event string, payload []byte) error
+	// ClaimDeliveries returns up to limit pending deliveries due at now,
+	// oldest first, and postpones them by lease so that a concurrent
+	// dispatcher does not claim them too.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entities.WebhookDelivery, error)
+	// RecordAttempt logs attempt and saves the status, attempts, next
+	// attempt, last error and delivery time of delivery.
+	RecordAttempt(ctx context.Context, delivery *entities.WebhookDelivery, attempt *entities.WebhookAttempt) error
+	FindDelivery(ctx context.Context, id int) (entities.WebhookDelivery, error)
+	// ListDeliveries returns the deliveries of a webhook, newest first.
+	ListDeliveries(ctx context.Context, webhookID, limit, offset int) ([]entities.WebhookDelivery, error)
+	// ListAttempts returns the attempts of a delivery, oldest first.
+	ListAttempts(ctx context.Context, deliveryID int) ([]entities.WebhookAttempt, error)
+	// RetryDelivery queues a dead delivery again with a fresh attempt
+	// count.
+	RetryDelivery(ctx context.Context, id int) error
+}
+
+// NewsEventsRepository is read by the news stream. Events are written by
+// the NewsRepository in the transaction of the change.
+type NewsEventsRepository interface {
+	// ListNewsEvents returns up to limit events with an id above after,
+	// oldest first.
+	ListNewsEvents(ctx context.Context, after, limit int) ([]entities.NewsEvent, error)
+	// LastNewsEventID returns zero when there are no events.
+	LastNewsEventID(ctx context.Context) (int, error)
+	// DeleteNewsEvents deletes the events made before before.
+	DeleteNewsEvents(ctx context.Context, before time.Time) (int, error)
+	// Listen calls notify whenever events are saved, by this process or
+	// another replica, until ctx is done or the connection fails. notify
+	// is also called once listening started, calls may be coalesced.
+	Listen(ctx context.Context, notify func()) error
+}
+
+// OutboxRepository is read by the relay. Messages are written by the
+// repositories making the changes, in the same transaction.
+type OutboxRepository interface {
+	// ClaimOutbox returns up to limit unpublished messages due at now,
+	// oldest first, and postpones them by lease so that a concurrent relay
+	// does not claim them too.
+	ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entities.OutboxMessage, error)
+	MarkPublished(ctx context.Context, id int) error
+	// MarkFailed counts a failed attempt and schedules the next one at
+	// next.
+	MarkFailed(ctx context.Context, id int, lastError string, next time.Time) error
+	// PurgePublished deletes the messages published before before and
+	// returns their number.
+	PurgePublished(ctx context.Context, before time.Time) (int, error)
+}
+
+// ErrTokenInvalid is returned for unknown, used and expired user tokens.
+var ErrTokenInvalid = errors.New("invalid or expired token")
+
+// UserTokensRepository stores the tokens of email verification and
+// password reset by their hash.
+type UserTokensRepository interface {
+	// CreateToken also deletes the used and expired tokens of the user.
+	CreateToken(ctx context.Context, token *entities.UserToken) error
+	// ConsumeToken marks the unused token of purpose with hash used, when
+	// it has not expired at now, and returns it. The other unused tokens
+	// of the user with the same purpose are used up too: once a mail
+	// served its purpose, the older ones do not work anymore.
+	ConsumeToken(ctx context.Context, purpose string, hash []byte, now time.Time) (entities.UserToken, error)
+}
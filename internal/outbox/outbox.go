@@ -0,0 +1,76 @@
+// Package outbox publishes domain events reliably. The repositories save
+// an event in the transaction of the change it describes, so that neither
+// is lost without the other, and a Relay publishes the saved events to a
+// Sink afterwards. A message is published at least once: consumers drop
+// repeats by its idempotency key.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"news-service/internal/entities"
+	"time"
+)
+
+// Event is the JSON payload of a message. ID is the idempotency key of the
+// message.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// NewsPublishedData is the data of news.published, emitted when a news is
+// created published or a draft is published.
+type NewsPublishedData struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Slug    string `json:"slug"`
+	Summary string `json:"summary"`
+}
+
+// UserRegisteredData is the data of user.registered.
+type UserRegisteredData struct {
+	ID    int    `json:"id"`
+	Email string `json:"email"`
+}
+
+// Sink is where a Relay publishes messages. Publish returns once the
+// message is accepted; it may be called again with the same message after
+// a failure or a crash.
+type Sink interface {
+	Publish(ctx context.Context, msg entities.OutboxMessage) error
+}
+
+// NewMessage returns a message of topic carrying data under a fresh
+// idempotency key.
+func NewMessage(topic string, data any) (entities.OutboxMessage, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return entities.OutboxMessage{}, err
+	}
+	key := hex.EncodeToString(id)
+	payload, err := json.Marshal(Event{ID: key, Type: topic, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return entities.OutboxMessage{}, err
+	}
+	return entities.OutboxMessage{Key: key, Topic: topic, Payload: payload}, nil
+}
+
+// NewsPublished returns the news.published message of news.
+func NewsPublished(news entities.News) (entities.OutboxMessage, error) {
+	return NewMessage(entities.EventNewsPublished, NewsPublishedData{
+		ID:      news.ID,
+		Title:   news.Title,
+		Slug:    news.Slug,
+		Summary: news.Summary,
+	})
+}
+
+// UserRegistered returns the user.registered message of user.
+func UserRegistered(user entities.User) (entities.OutboxMessage, error) {
+	return NewMessage(entities.EventUserRegistered, UserRegisteredData{ID: user.ID, Email: user.Email})
+}
@@ -0,0 +1,214 @@
+package outbox_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	memoryrepo "news-service/internal/database/memoryRepo"
+	"news-service/internal/entities"
+	"news-service/internal/outbox"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+type fakeSink struct {
+	published []entities.OutboxMessage
+	fail      map[string]bool
+}
+
+func (s *fakeSink) Publish(ctx context.Context, msg entities.OutboxMessage) error {
+	if s.fail[msg.Topic] {
+		return errors.New("sink down")
+	}
+	s.published = append(s.published, msg)
+	return nil
+}
+
+func TestRelayPublishesAtLeastOnce(t *testing.T) {
+	ctx := context.Background()
+	store := memoryrepo.NewStore()
+	news := memoryrepo.NewNewsRepository(store, discard())
+	users := memoryrepo.NewUserRepository(store, discard())
+
+	users.CreateUser(ctx, &entities.User{Email: "a@example.com"})
+	news.CreateNews(ctx, &entities.News{Title: "one", Published: true})
+	news.CreateNews(ctx, &entities.News{Title: "draft"})
+
+	sink := &fakeSink{fail: map[string]bool{entities.EventUserRegistered: true}}
+	relay := outbox.NewRelay(memoryrepo.NewOutboxRepository(store, discard()), sink,
+		outbox.Options{RetryBase: time.Hour}, discard())
+
+	if n, err := relay.PublishDue(ctx); err != nil || n != 2 {
+		t.Fatalf("PublishDue = %d, %v, want both messages tried", n, err)
+	}
+	if len(sink.published) != 1 || sink.published[0].Topic != entities.EventNewsPublished {
+		t.Fatalf("published %+v, want the news.published message past the failing one", sink.published)
+	}
+	if n, _ := relay.PublishDue(ctx); n != 0 {
+		t.Fatalf("PublishDue = %d, a failed message waits for its backoff", n)
+	}
+
+	sink.fail = nil
+	outbox.SetClock(relay, func() time.Time { return time.Now().Add(2 * time.Hour) })
+	if n, err := relay.PublishDue(ctx); err != nil || n != 1 {
+		t.Fatalf("PublishDue after the backoff = %d, %v", n, err)
+	}
+	var event outbox.Event
+	if err := json.Unmarshal(sink.published[1].Payload, &event); err != nil || event.Type != entities.EventUserRegistered || event.ID != sink.published[1].Key {
+		t.Fatalf("payload %s: %v", sink.published[1].Payload, err)
+	}
+	if n, _ := relay.PublishDue(ctx); n != 0 {
+		t.Fatalf("PublishDue = %d, published messages are not sent again", n)
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	msg, _ := outbox.NewMessage("news.published", map[string]int{"id": 1})
+	if err := outbox.NewWriterSink(&buf).Publish(context.Background(), msg); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	var line struct {
+		Key     string       `json:"key"`
+		Topic   string       `json:"topic"`
+		Payload outbox.Event `json:"payload"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil || line.Key != msg.Key || line.Topic != msg.Topic || line.Payload.ID != msg.Key {
+		t.Fatalf("line %q: %v", buf.String(), err)
+	}
+}
+
+// natsServer is a stand-in for a NATS server. It acknowledges PINGs and
+// passes every HPUB to published, answering -ERR to subjects in reject.
+func natsServer(t *testing.T, published chan<- [3]string, reject string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte(`INFO {"server_id":"test","headers":true}` + "\r\n"))
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					switch {
+					case len(fields) == 0:
+					case fields[0] == "PING":
+						conn.Write([]byte("PONG\r\n"))
+					case fields[0] == "HPUB" && len(fields) == 4:
+						headerLen, _ := strconv.Atoi(fields[2])
+						total, _ := strconv.Atoi(fields[3])
+						body := make([]byte, total+2)
+						if _, err := io.ReadFull(r, body); err != nil {
+							return
+						}
+						if fields[1] == reject {
+							conn.Write([]byte("-ERR 'Permissions Violation'\r\n"))
+							continue
+						}
+						published <- [3]string{fields[1], string(body[:headerLen]), string(body[headerLen:total])}
+					}
+				}
+			}()
+		}
+	}()
+	return "nats://" + ln.Addr().String()
+}
+
+func TestNATSSink(t *testing.T) {
+	published := make(chan [3]string, 10)
+	sink, err := outbox.NewNATSSink(natsServer(t, published, "events.user.registered"), "events.")
+	if err != nil {
+		t.Fatalf("NewNATSSink: %v", err)
+	}
+	defer sink.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, _ := outbox.NewMessage(entities.EventNewsPublished, map[string]int{"id": 1})
+	for i := 0; i < 2; i++ {
+		if err := sink.Publish(ctx, msg); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		got := <-published
+		if got[0] != "events.news.published" || !strings.Contains(got[1], "Nats-Msg-Id: "+msg.Key+"\r\n") || got[2] != string(msg.Payload) {
+			t.Fatalf("published %q", got)
+		}
+	}
+
+	rejected, _ := outbox.NewMessage(entities.EventUserRegistered, nil)
+	if err := sink.Publish(ctx, rejected); err == nil || !strings.Contains(err.Error(), "Permissions Violation") {
+		t.Fatalf("Publish to a rejected subject = %v", err)
+	}
+	if err := sink.Publish(ctx, msg); err != nil {
+		t.Fatalf("Publish after an error should reconnect: %v", err)
+	}
+}
+
+func TestKafkaRESTSink(t *testing.T) {
+	type record struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}
+	var (
+		path    string
+		records []record
+	)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		var body struct {
+			Records []record `json:"records"`
+		}
+		if r.Header.Get("Content-Type") != "application/vnd.kafka.json.v2+json" || json.NewDecoder(r.Body).Decode(&body) != nil {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			w.Write([]byte(`{"error_code":415,"message":"unsupported"}`))
+			return
+		}
+		records = append(records, body.Records...)
+		if strings.HasSuffix(path, "user.registered") {
+			w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":50003,"error":"retriable"}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":7}]}`))
+	}))
+	defer proxy.Close()
+
+	sink := outbox.NewKafkaRESTSink(proxy.URL+"/", "news-", proxy.Client())
+	msg, _ := outbox.NewMessage(entities.EventNewsPublished, map[string]int{"id": 1})
+	if err := sink.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if path != "/topics/news-news.published" || len(records) != 1 || records[0].Key != msg.Key || string(records[0].Value) != string(msg.Payload) {
+		t.Fatalf("produced %s %+v", path, records)
+	}
+
+	failed, _ := outbox.NewMessage(entities.EventUserRegistered, nil)
+	if err := sink.Publish(context.Background(), failed); err == nil || !strings.Contains(err.Error(), "retriable") {
+		t.Fatalf("Publish with a record error = %v", err)
+	}
+}
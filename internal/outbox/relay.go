@@ -0,0 +1,143 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"news-service/internal/backoff"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"time"
+)
+
+// Options tune a Relay, zero fields take the defaults below.
+type Options struct {
+	// BatchSize is the number of messages claimed at once.
+	BatchSize int
+	// Timeout bounds a single Publish.
+	Timeout time.Duration
+	// RetryBase is the delay after the first failure of a message,
+	// doubled after every next one up to RetryMax.
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// Retention is how long published messages are kept.
+	Retention time.Duration
+}
+
+const (
+	DefaultBatchSize    = 100
+	DefaultTimeout      = 10 * time.Second
+	DefaultRetryBase    = time.Second
+	DefaultRetryMax     = 5 * time.Minute
+	DefaultRetention    = 7 * 24 * time.Hour
+	DefaultPollInterval = time.Second
+)
+
+// Relay publishes the messages saved in the outbox. Several relays may
+// share a repository; a message claimed by a relay that crashed before
+// marking it published is claimed again once its lease ends.
+type Relay struct {
+	repo models.OutboxRepository
+	sink Sink
+	opts Options
+	log  *slog.Logger
+	now  func() time.Time
+}
+
+func NewRelay(repo models.OutboxRepository, sink Sink, opts Options, log *slog.Logger) *Relay {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.RetryBase <= 0 {
+		opts.RetryBase = DefaultRetryBase
+	}
+	if opts.RetryMax < opts.RetryBase {
+		opts.RetryMax = max(DefaultRetryMax, opts.RetryBase)
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	return &Relay{repo: repo, sink: sink, opts: opts, log: log, now: time.Now}
+}
+
+// PublishDue publishes one batch of due messages in order and returns its
+// size. A message that fails is retried later and does not hold back the
+// rest of the batch.
+func (r *Relay) PublishDue(ctx context.Context) (int, error) {
+	// The messages are published one by one, the lease covers the whole
+	// batch.
+	lease := time.Duration(r.opts.BatchSize)*r.opts.Timeout + time.Minute
+	messages, err := r.repo.ClaimOutbox(ctx, r.now(), lease, r.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// A message being published is finished even when ctx is cancelled,
+	// the rest of the batch is left to the next relay.
+	publishCtx := context.WithoutCancel(ctx)
+	for i, msg := range messages {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+		sendCtx, cancel := context.WithTimeout(publishCtx, r.opts.Timeout)
+		err := r.sink.Publish(sendCtx, msg)
+		cancel()
+		if err != nil {
+			next := r.now().Add(backoff.Exponential(msg.Attempts+1, r.opts.RetryBase, r.opts.RetryMax))
+			r.log.Warn("failed to publish outbox message", slog.Int("outbox_id", msg.ID),
+				slog.String("topic", msg.Topic), errMsg.Err(err))
+			if err := r.repo.MarkFailed(publishCtx, msg.ID, err.Error(), next); err != nil {
+				r.log.Error("failed to record outbox failure", slog.Int("outbox_id", msg.ID), errMsg.Err(err))
+			}
+			continue
+		}
+		if err := r.repo.MarkPublished(publishCtx, msg.ID); err != nil {
+			// The message is published again after the lease, which
+			// at-least-once delivery allows.
+			r.log.Error("failed to mark outbox message published", slog.Int("outbox_id", msg.ID), errMsg.Err(err))
+		}
+	}
+	return len(messages), nil
+}
+
+// Purge deletes the messages published longer than the retention ago.
+func (r *Relay) Purge(ctx context.Context) (int, error) {
+	return r.repo.PurgePublished(ctx, r.now().Add(-r.opts.Retention))
+}
+
+// Run publishes due messages every interval until ctx is done. Full
+// batches are followed by the next one right away. Published messages are
+// purged about once an hour.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var purged time.Time
+	for {
+		for ctx.Err() == nil {
+			n, err := r.PublishDue(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				r.log.Error("failed to publish outbox", errMsg.Err(err))
+			}
+			if err != nil || n < r.opts.BatchSize {
+				break
+			}
+		}
+		if ctx.Err() == nil && r.now().Sub(purged) >= time.Hour {
+			if _, err := r.Purge(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				r.log.Error("failed to purge outbox", errMsg.Err(err))
+			}
+			purged = r.now()
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package outbox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"news-service/internal/entities"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriterSink writes every message as a JSON line, it backs the stdout sink.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+type writerLine struct {
+	Key     string          `json:"key"`
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func (s *WriterSink) Publish(ctx context.Context, msg entities.OutboxMessage) error {
+	line, err := json.Marshal(writerLine{Key: msg.Key, Topic: msg.Topic, Payload: msg.Payload})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// NATSSink publishes to a NATS server on the subject prefix+topic. The
+// idempotency key is sent as the Nats-Msg-Id header, which JetStream uses
+// to drop duplicates within its deduplication window.
+//
+// It speaks the plain NATS client protocol over a single connection and
+// waits for the server to acknowledge every message with a PING/PONG round
+// trip, so a returned nil means the server processed the message.
+type NATSSink struct {
+	addr   string
+	user   *url.Userinfo
+	prefix string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewNATSSink connects lazily to rawURL, for example nats://localhost:4222.
+// Credentials in the URL are sent on connect.
+func NewNATSSink(rawURL, subjectPrefix string) (*NATSSink, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "nats://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q", rawURL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &NATSSink{addr: addr, user: u.User, prefix: subjectPrefix}, nil
+}
+
+func (s *NATSSink) Publish(ctx context.Context, msg entities.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.publish(ctx, msg)
+	if err != nil && s.conn != nil {
+		// The connection state is unknown, the next message reconnects.
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func (s *NATSSink) publish(ctx context.Context, msg entities.OutboxMessage) error {
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultTimeout)
+	}
+	s.conn.SetDeadline(deadline)
+
+	headers := "NATS/1.0\r\nNats-Msg-Id: " + msg.Key + "\r\nContent-Type: application/json\r\n\r\n"
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HPUB %s %d %d\r\n%s", s.prefix+msg.Topic, len(headers), len(headers)+len(msg.Payload), headers)
+	buf.Write(msg.Payload)
+	buf.WriteString("\r\nPING\r\n")
+	if _, err := s.conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return s.awaitPong()
+}
+
+func (s *NATSSink) connect(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting %q", strings.TrimSpace(line))
+	}
+
+	options := map[string]any{"verbose": false, "pedantic": false, "headers": true, "name": "news-service", "lang": "go", "protocol": 1}
+	if s.user != nil {
+		options["user"] = s.user.Username()
+		if pass, ok := s.user.Password(); ok {
+			options["pass"] = pass
+		}
+	}
+	connect, _ := json.Marshal(options)
+	if _, err := conn.Write([]byte("CONNECT " + string(connect) + "\r\n")); err != nil {
+		conn.Close()
+		return err
+	}
+	s.conn, s.r = conn, r
+	return nil
+}
+
+// awaitPong reads until the PONG answering the PING after a message.
+// Errors reported by the server before it are returned.
+func (s *NATSSink) awaitPong() error {
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// Close closes the connection, the next Publish reconnects.
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// KafkaRESTSink produces to Kafka through a REST Proxy (API v2) on the
+// topic prefix+topic. The idempotency key is the record key, so the
+// messages of a key land in one partition and consumers, or a compacted
+// topic, can drop duplicates.
+type KafkaRESTSink struct {
+	baseURL string
+	prefix  string
+	client  *http.Client
+}
+
+// NewKafkaRESTSink produces through the proxy at baseURL, for example
+// http://localhost:8082. A nil client uses http.DefaultClient.
+func NewKafkaRESTSink(baseURL, topicPrefix string, client *http.Client) *KafkaRESTSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &KafkaRESTSink{baseURL: strings.TrimSuffix(baseURL, "/"), prefix: topicPrefix, client: client}
+}
+
+type kafkaRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Error string `json:"error"`
+	} `json:"offsets"`
+	Message string `json:"message"`
+}
+
+func (s *KafkaRESTSink) Publish(ctx context.Context, msg entities.OutboxMessage) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{"records": {{Key: msg.Key, Value: msg.Payload}}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		s.baseURL+"/topics/"+url.PathEscape(s.prefix+msg.Topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var produced kafkaProduceResponse
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&produced)
+	if resp.StatusCode != http.StatusOK {
+		if produced.Message != "" {
+			return fmt.Errorf("kafka rest proxy: %s: %s", resp.Status, produced.Message)
+		}
+		return fmt.Errorf("kafka rest proxy: %s", resp.Status)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("kafka rest proxy: %w", decodeErr)
+	}
+	for _, offset := range produced.Offsets {
+		if offset.Error != "" {
+			return errors.New("kafka rest proxy: " + offset.Error)
+		}
+	}
+	return nil
+}
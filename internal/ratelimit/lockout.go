@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Lockout locks keys out after Threshold consecutive failures, for Base
+// doubled after every further failure up to Max. Failures are forgotten
+// once Max has passed since the last one.
+type Lockout struct {
+	store     Store
+	name      string
+	threshold int
+	base, max time.Duration
+	now       func() time.Time
+}
+
+// NewLockout keeps the failures in store under keys prefixed by name, like
+// NewShared. A threshold below one disables the lockout.
+func NewLockout(store Store, name string, threshold int, base, max time.Duration) *Lockout {
+	if threshold < 1 {
+		return nil
+	}
+	return &Lockout{store: store, name: name, threshold: threshold, base: base, max: max, now: time.Now}
+}
+
+// Locked returns how long key stays locked out, zero when it is not. A nil
+// Lockout never locks out.
+func (l *Lockout) Locked(ctx context.Context, key string) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	f, err := l.store.Failures(ctx, l.name+":"+key, l.max)
+	if err != nil {
+		return 0, err
+	}
+	return l.remaining(f), nil
+}
+
+// Fail counts a failure of key and returns how long key is locked out
+// after it.
+func (l *Lockout) Fail(ctx context.Context, key string) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	f, err := l.store.Fail(ctx, l.name+":"+key, l.max)
+	if err != nil {
+		return 0, err
+	}
+	return l.remaining(f), nil
+}
+
+// Reset forgets the failures of key after a success.
+func (l *Lockout) Reset(ctx context.Context, key string) error {
+	if l == nil {
+		return nil
+	}
+	return l.store.Reset(ctx, l.name+":"+key)
+}
+
+func (l *Lockout) remaining(f Failures) time.Duration {
+	if f.Count < l.threshold {
+		return 0
+	}
+	lock := l.base
+	for i := l.threshold; i < f.Count && lock < l.max; i++ {
+		lock *= 2
+	}
+	return max(0, f.Last.Add(min(lock, l.max)).Sub(l.now()))
+}
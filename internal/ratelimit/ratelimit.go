@@ -0,0 +1,85 @@
+// Package ratelimit implements per-key token bucket rate limiting and
+// lockouts after repeated failures, kept in memory or in a Store shared by
+// the instances of the service.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"news-service/api/response"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// Limiter allows Requests per Per for every key with bursts of up to Burst
+// requests.
+type Limiter struct {
+	store Store
+	name  string
+	rate  float64
+	burst float64
+}
+
+// New returns a Limiter keeping its buckets in memory.
+func New(requests int, per time.Duration, burst int) *Limiter {
+	return NewShared(NewMemory(), "", requests, per, burst)
+}
+
+// NewShared returns a Limiter keeping its buckets in store, under keys
+// prefixed by name so that limiters may share a store.
+func NewShared(store Store, name string, requests int, per time.Duration, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		store: store,
+		name:  name,
+		rate:  float64(requests) / per.Seconds(),
+		burst: float64(burst),
+	}
+}
+
+// Allow takes a token from the bucket of key. When the bucket is empty it
+// returns false and the time until the next token is available. A nil
+// Limiter allows every request.
+func (l *Limiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	if l == nil {
+		return true, 0, nil
+	}
+	return l.store.Take(ctx, l.name+":"+key, l.rate, l.burst)
+}
+
+// Middleware rejects requests with 429 Too Many Requests once the bucket
+// selected by key is empty. A nil limiter disables limiting.
+func Middleware(l *Limiter, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// A failing shared store does not take the API down with it,
+			// the request is let through.
+			if ok, wait, err := l.Allow(r.Context(), key(r)); err == nil && !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, response.Error("too many requests"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ByIP keys requests by client address. It expects RealIP to run first when
+// the service is behind a proxy.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	l := NewShared(m, "test", 1, time.Second, 2)
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := l.Allow(ctx, "a"); !ok {
+			t.Fatalf("request %d within burst was rejected", i)
+		}
+	}
+	ok, wait, _ := l.Allow(ctx, "a")
+	if ok {
+		t.Fatal("request over burst was allowed")
+	}
+	if wait != time.Second {
+		t.Fatalf("wait = %v, want 1s", wait)
+	}
+	if ok, _, _ := l.Allow(ctx, "b"); !ok {
+		t.Fatal("keys must not share a bucket")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _, _ := l.Allow(ctx, "a"); ok {
+		t.Fatal("half a token must not be enough")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, _, _ := l.Allow(ctx, "a"); !ok {
+		t.Fatal("bucket did not refill")
+	}
+}
+
+func TestSweepForgetsFullBuckets(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	l := NewShared(m, "test", 1, time.Second, 1)
+
+	l.Allow(ctx, "a")
+	m.Fail(ctx, "b", time.Minute)
+	now = now.Add(time.Second)
+	m.sweep(now)
+	if len(m.buckets) != 0 {
+		t.Fatalf("expected no buckets, got %d", len(m.buckets))
+	}
+	if len(m.failures) != 1 {
+		t.Fatal("failures within their window were forgotten")
+	}
+	now = now.Add(time.Minute)
+	m.sweep(now)
+	if len(m.failures) != 0 {
+		t.Fatalf("expected no failures, got %d", len(m.failures))
+	}
+}
+
+func TestLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	l := NewLockout(m, "login", 3, time.Minute, 4*time.Minute)
+	l.now = m.now
+
+	for i := 1; i < 3; i++ {
+		if lock, _ := l.Fail(ctx, "a"); lock != 0 {
+			t.Fatalf("locked out after %d failures", i)
+		}
+	}
+	// Every failure from the threshold on doubles the lockout, up to max.
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		if lock, _ := l.Fail(ctx, "a"); lock != want {
+			t.Fatalf("lockout = %v, want %v", lock, want)
+		}
+	}
+	if lock, _ := l.Locked(ctx, "b"); lock != 0 {
+		t.Fatal("keys must not share failures")
+	}
+
+	now = now.Add(time.Minute)
+	if lock, _ := l.Locked(ctx, "a"); lock != 3*time.Minute {
+		t.Fatalf("remaining lockout = %v, want 3m", lock)
+	}
+	now = now.Add(3 * time.Minute)
+	if lock, _ := l.Locked(ctx, "a"); lock != 0 {
+		t.Fatalf("still locked out after the lockout: %v", lock)
+	}
+	if lock, _ := l.Fail(ctx, "a"); lock != 4*time.Minute {
+		t.Fatalf("failures were forgotten before max passed, lockout = %v", lock)
+	}
+
+	l.Reset(ctx, "a")
+	if lock, _ := l.Fail(ctx, "a"); lock != 0 {
+		t.Fatalf("locked out after a reset: %v", lock)
+	}
+	now = now.Add(5 * time.Minute)
+	if f, _ := m.Failures(ctx, "login:a", 4*time.Minute); f.Count != 0 {
+		t.Fatalf("failures older than the window: %+v", f)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	h := Middleware(New(1, time.Minute, 1), ByIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first request: status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("Retry-After = %q", rec.Header().Get("Retry-After"))
+	}
+
+	req.RemoteAddr = "10.0.0.2:1234"
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("other client: status %d", rec.Code)
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+	for name, tc := range map[string]struct {
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		"direct":                      {"203.0.113.7:1234", nil, "203.0.113.7"},
+		"forged by a client":          {"203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7"},
+		"forged real ip":              {"203.0.113.7:1234", map[string]string{"X-Real-IP": "198.51.100.1"}, "203.0.113.7"},
+		"trusted proxy":               {"10.0.0.2:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		"chain of trusted proxies":    {"10.0.0.2:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 10.0.0.3"}, "198.51.100.1"},
+		"client prepending a forgery": {"10.0.0.2:1234", map[string]string{"X-Forwarded-For": "192.0.2.9, 198.51.100.1"}, "198.51.100.1"},
+		"trusted real ip":             {"[fd00::2]:1234", map[string]string{"X-Real-IP": "2001:db8::1"}, "2001:db8::1"},
+		"invalid header":              {"10.0.0.2:1234", map[string]string{"X-Forwarded-For": "nonsense"}, "10.0.0.2"},
+	} {
+		var got string
+		h := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = ByIP(r)
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remote
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if got != tc.want {
+			t.Errorf("%s: ByIP = %q, want %q", name, got, tc.want)
+		}
+	}
+}
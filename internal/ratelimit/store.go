@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store keeps token buckets and failure counts. Memory keeps them for one
+// instance of the service, a shared Store like the Postgres one limits all
+// instances together.
+type Store interface {
+	// Take refills the bucket of key at rate tokens per second up to burst
+	// and takes a token from it. When the bucket is empty it returns false
+	// and the time until the next token is available.
+	Take(ctx context.Context, key string, rate, burst float64) (bool, time.Duration, error)
+	// Fail counts a failure of key and returns the consecutive failures
+	// including it. Failures more than window apart are not consecutive.
+	Fail(ctx context.Context, key string, window time.Duration) (Failures, error)
+	// Failures returns the consecutive failures of key, none once window
+	// has passed since the last one.
+	Failures(ctx context.Context, key string, window time.Duration) (Failures, error)
+	// Reset forgets the failures of key.
+	Reset(ctx context.Context, key string) error
+}
+
+// Failures are the consecutive failures of a key.
+type Failures struct {
+	Count int
+	Last  time.Time
+}
+
+// Refill returns the tokens of a bucket holding tokens elapsed ago, once it
+// took a token if it could. When it could not, wait is the time until the
+// next token is available.
+func Refill(tokens float64, elapsed time.Duration, rate, burst float64) (left float64, ok bool, wait time.Duration) {
+	tokens = math.Min(burst, tokens+elapsed.Seconds()*rate)
+	if tokens < 1 {
+		return tokens, false, time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return tokens - 1, true, 0
+}
+
+// FullAt returns when a bucket holding tokens at now has refilled
+// completely. A full bucket behaves exactly like a new one, it can be
+// forgotten from then on.
+func FullAt(now time.Time, tokens, rate, burst float64) time.Time {
+	return now.Add(time.Duration((burst - tokens) / rate * float64(time.Second)))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+type failures struct {
+	Failures
+	window time.Duration
+}
+
+// Memory is a Store for a single instance of the service.
+type Memory struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	failures map[string]failures
+	calls    int
+	now      func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]failures),
+		now:      time.Now,
+	}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, rate, burst float64) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.tick(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		m.buckets[key] = b
+	}
+
+	var wait time.Duration
+	b.tokens, ok, wait = Refill(b.tokens, now.Sub(b.last), rate, burst)
+	b.last = now
+	b.full = FullAt(now, b.tokens, rate, burst)
+	return ok, wait, nil
+}
+
+func (m *Memory) Fail(ctx context.Context, key string, window time.Duration) (Failures, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.tick(now)
+
+	f := m.current(key, window, now)
+	f.Count++
+	f.Last = now
+	m.failures[key] = failures{f, window}
+	return f, nil
+}
+
+func (m *Memory) Failures(ctx context.Context, key string, window time.Duration) (Failures, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.current(key, window, m.now()), nil
+}
+
+func (m *Memory) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.failures, key)
+	return nil
+}
+
+// current returns the failures of key within window. The caller must hold
+// the lock.
+func (m *Memory) current(key string, window time.Duration, now time.Time) Failures {
+	f, ok := m.failures[key]
+	if !ok || now.Sub(f.Last) > window {
+		return Failures{}
+	}
+	return f.Failures
+}
+
+// tick sweeps every 1024 calls. The caller must hold the lock.
+func (m *Memory) tick(now time.Time) {
+	m.calls++
+	if m.calls%1024 == 0 {
+		m.sweep(now)
+	}
+}
+
+// sweep forgets buckets that have refilled completely, they behave exactly
+// like new ones, and failures older than their window. The caller must hold
+// the lock.
+func (m *Memory) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if !b.full.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, f := range m.failures {
+		if now.Sub(f.Last) > f.window {
+			delete(m.failures, key)
+		}
+	}
+}
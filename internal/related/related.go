@@ -0,0 +1,63 @@
+// Package related scores how close a news is to another one for the
+// "related news" block of an article page. A candidate earns points for
+// every category and tag it shares with the article and for the words
+// their titles and summaries have in common; the total then halves every
+// HalfLife of the candidate's age, so fresh news win among equals.
+package related
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Weights of the signals. Text similarity is a Jaccard index in [0, 1].
+const (
+	CategoryWeight = 3.0
+	TagWeight      = 2.0
+	TextWeight     = 2.0
+)
+
+// Options of a related news query.
+type Options struct {
+	Limit int
+	// HalfLife of the score, zero disables the recency decay.
+	HalfLife time.Duration
+}
+
+// Score combines the signals of a candidate of the given age.
+func Score(sharedCategories, sharedTags int, text float64, age, halfLife time.Duration) float64 {
+	score := CategoryWeight*float64(sharedCategories) + TagWeight*float64(sharedTags) + TextWeight*text
+	if halfLife > 0 && age > 0 {
+		score *= math.Pow(0.5, age.Seconds()/halfLife.Seconds())
+	}
+	return score
+}
+
+// Terms returns the distinct lower-cased words of text. It approximates the
+// lexemes of the Postgres "simple" text search configuration.
+func Terms(text string) map[string]struct{} {
+	terms := make(map[string]struct{})
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		terms[strings.ToLower(word)] = struct{}{}
+	}
+	return terms
+}
+
+// Jaccard is the number of terms a and b share divided by the number of
+// terms in either of them.
+func Jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for term := range a {
+		if _, ok := b[term]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
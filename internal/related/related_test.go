@@ -0,0 +1,48 @@
+package related
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTerms(t *testing.T) {
+	terms := Terms("Go 1.22: Новый релиз, go!")
+	for _, want := range []string{"go", "1", "22", "новый", "релиз"} {
+		if _, ok := terms[want]; !ok {
+			t.Errorf("Terms should contain %q, got %v", want, terms)
+		}
+	}
+	if len(terms) != 5 {
+		t.Errorf("Terms = %v, want 5 distinct terms", terms)
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"go release", "go release", 1},
+		{"go release", "go conference", 1.0 / 3},
+		{"go", "rust", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		if got := Jaccard(Terms(tt.a), Terms(tt.b)); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Jaccard(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	if got := Score(1, 1, 0.5, 0, time.Hour); got != CategoryWeight+TagWeight+TextWeight/2 {
+		t.Errorf("Score without age = %v", got)
+	}
+	if got := Score(1, 0, 0, 2*time.Hour, time.Hour); got != CategoryWeight/4 {
+		t.Errorf("Score after two half-lives = %v, want %v", got, CategoryWeight/4)
+	}
+	if got := Score(1, 0, 0, 2*time.Hour, 0); got != CategoryWeight {
+		t.Errorf("Score without decay = %v", got)
+	}
+}
@@ -0,0 +1,328 @@
+package router_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"news-service/api/openapi"
+	"news-service/api/response"
+	apiv1 "news-service/api/v1"
+	"news-service/internal/config"
+	"news-service/internal/database/dbtest"
+	"news-service/internal/graphqlserver"
+	commenthandler "news-service/internal/handlers/CommentHandler"
+	newshandler "news-service/internal/handlers/NewsHandler"
+	webhookhandler "news-service/internal/handlers/WebhookHandler"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/jwt"
+	"news-service/internal/router"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// specPaths maps the chi patterns that openapi.json spells differently.
+var specPaths = map[string]string{
+	// middleware.URLFormat routes /feed.rss and /feed.atom to /feed.
+	"/feed":                       "/feed.{format}",
+	"/categories/{category}/feed": "/categories/{category}/feed.{format}",
+	"/media/*":                    "/media/{key}",
+	"/openapi":                    "/openapi.json",
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	doc := openapi.MustLoad()
+	log := dbtest.Logger()
+	mux := router.New(log, &config.Config{}, backends["memory"](t), jwt.NewJWTManager("test-secret", log))
+
+	routed := map[string]bool{}
+	err := chi.Walk(mux, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		version, path := "", route
+		if rest, ok := strings.CutPrefix(route, apiv1.Prefix+"/"); ok {
+			version, path = apiv1.Prefix, "/"+rest
+		}
+		if path, ok := specPaths[path]; ok {
+			route = version + path
+		}
+		// Media files are mounted with r.Handle, only GET is documented.
+		if route == "/media/{key}" && method != http.MethodGet {
+			return nil
+		}
+		routed[strings.ToLower(method)+" "+route] = true
+
+		op := doc.Paths[route][strings.ToLower(method)]
+		if op == nil {
+			t.Errorf("%s %s is not described in openapi.json", method, route)
+			return nil
+		}
+		var params []string
+		for _, p := range op.Parameters {
+			if p.Ref != "" {
+				p = specParameter(t, p.Ref)
+			}
+			if p.In == "path" {
+				params = append(params, p.Name)
+			}
+		}
+		want := pathParams(route)
+		slices.Sort(params)
+		slices.Sort(want)
+		if !slices.Equal(params, want) {
+			t.Errorf("%s %s: path parameters %v in openapi.json, want %v", method, route, params, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			if !routed[method+" "+path] {
+				t.Errorf("%s %s (%s) in openapi.json is not routed", strings.ToUpper(method), path, op.OperationID)
+			}
+		}
+	}
+}
+
+func pathParams(route string) []string {
+	var names []string
+	for _, m := range regexp.MustCompile(`\{([^}:]+)`).FindAllStringSubmatch(route, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+func specParameter(t *testing.T, ref string) openapi.Parameter {
+	t.Helper()
+	var doc struct {
+		Components struct {
+			Parameters map[string]openapi.Parameter `json:"parameters"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(openapi.Spec(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	p, ok := doc.Components.Parameters[strings.TrimPrefix(ref, "#/components/parameters/")]
+	if !ok {
+		t.Fatalf("unknown parameter %s", ref)
+	}
+	return p
+}
+
+// TestOpenAPISchemas checks that the schemas of openapi.json list the JSON
+// fields of the structs the handlers decode and render, with matching
+// types.
+func TestOpenAPISchemas(t *testing.T) {
+	doc := openapi.MustLoad()
+	types := map[string]any{
+		"Response":              response.Response{},
+		"RequestUser":           userhandlers.RequestUser{},
+		"RequestToken":          userhandlers.RequestToken{},
+		"RequestEmail":          userhandlers.RequestEmail{},
+		"RequestResetPassword":  userhandlers.RequestResetPassword{},
+		"ResponseUser":          userhandlers.ResponseUser{},
+		"ResponseAuthUser":      userhandlers.ResponseAuthUser{},
+		"RequestNews":           newshandler.RequestNews{},
+		"RequestUpdateNews":     newshandler.RequestUpdateNews{},
+		"ResponseNews":          newshandler.ResponseNews{},
+		"MediaItem":             newshandler.MediaItem{},
+		"NewsItem":              newshandler.NewsItem{},
+		"ResponseNewsList":      newshandler.ResponseNewsList{},
+		"JSONFeed":              response.JSONFeed{},
+		"JSONFeedItem":          response.JSONFeedItem{},
+		"ResponseMedia":         newshandler.ResponseMedia{},
+		"TagItem":               newshandler.TagItem{},
+		"ResponseTags":          newshandler.ResponseTags{},
+		"ResponseView":          newshandler.ResponseView{},
+		"ResponseReactions":     newshandler.ResponseReactions{},
+		"ResponseBookmark":      newshandler.ResponseBookmark{},
+		"ResponseSubscriptions": newshandler.ResponseSubscriptions{},
+		"RequestComment":        commenthandler.RequestComment{},
+		"RequestEditComment":    commenthandler.RequestEditComment{},
+		"CommentItem":           commenthandler.CommentItem{},
+		"ResponseComment":       commenthandler.ResponseComment{},
+		"ResponseComments":      commenthandler.ResponseComments{},
+		"RequestWebhook":        webhookhandler.RequestWebhook{},
+		"WebhookItem":           webhookhandler.WebhookItem{},
+		"ResponseWebhook":       webhookhandler.ResponseWebhook{},
+		"ResponseWebhooks":      webhookhandler.ResponseWebhooks{},
+		"AttemptItem":           webhookhandler.AttemptItem{},
+		"DeliveryItem":          webhookhandler.DeliveryItem{},
+		"ResponseDelivery":      webhookhandler.ResponseDelivery{},
+		"ResponseDeliveries":    webhookhandler.ResponseDeliveries{},
+		"News":                  apiv1.News{},
+		"Media":                 apiv1.Media{},
+		"NewsResponse":          apiv1.NewsResponse{},
+		"NewsListResponse":      apiv1.NewsListResponse{},
+		"NewsRequest":           apiv1.NewsRequest{},
+		"NewsUpdateRequest":     apiv1.NewsUpdateRequest{},
+		"User":                  apiv1.User{},
+		"UserResponse":          apiv1.UserResponse{},
+		"SessionResponse":       apiv1.SessionResponse{},
+		"GraphQLRequest":        graphqlserver.Request{},
+		"JWK":                   jwt.JWK{},
+		"JWKSet":                jwt.JWKSet{},
+	}
+
+	for name := range doc.Components.Schemas {
+		if _, ok := types[name]; !ok {
+			t.Errorf("schema %s is not checked against a struct", name)
+		}
+	}
+	for name, v := range types {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s is missing", name)
+			continue
+		}
+		checkSchema(t, doc, name, schema, reflect.TypeOf(v), map[reflect.Type]bool{})
+	}
+}
+
+// checkSchema compares schema to typ, structs in seen are only checked
+// once, which ends recursive types like CommentItem.
+func checkSchema(t *testing.T, doc *openapi.Document, path string, schema *openapi.Schema, typ reflect.Type, seen map[reflect.Type]bool) {
+	t.Helper()
+	schema = doc.Resolve(schema)
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	want := ""
+	switch {
+	case typ == reflect.TypeOf(time.Time{}):
+		want = "string"
+	case typ == reflect.TypeOf(json.RawMessage{}):
+		// Any JSON value.
+	case typ.Kind() == reflect.Struct || typ.Kind() == reflect.Map:
+		want = "object"
+	case typ.Kind() == reflect.Slice:
+		want = "array"
+	case typ.Kind() == reflect.String:
+		want = "string"
+	case typ.Kind() == reflect.Bool:
+		want = "boolean"
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Int64:
+		want = "integer"
+	}
+	if schema.Type != want {
+		t.Errorf("%s: type %q in openapi.json, want %q for %s", path, schema.Type, want, typ)
+		return
+	}
+
+	switch {
+	case want == "object" && typ.Kind() == reflect.Map:
+		checkSchema(t, doc, path+"[]", schema.AdditionalProperties, typ.Elem(), seen)
+	case want == "object" && !seen[typ]:
+		seen[typ] = true
+		fields := jsonFields(typ)
+		for name, field := range fields {
+			property, ok := schema.Properties[name]
+			if !ok {
+				t.Errorf("%s: field %s is missing in openapi.json", path, name)
+				continue
+			}
+			checkSchema(t, doc, path+"."+name, property, field, seen)
+		}
+		for name := range schema.Properties {
+			if _, ok := fields[name]; !ok {
+				t.Errorf("%s: property %s in openapi.json is not a field of %s", path, name, typ)
+			}
+		}
+	case want == "array":
+		checkSchema(t, doc, path+"[]", schema.Items, typ.Elem(), seen)
+	}
+}
+
+// jsonFields returns the types of the fields encoding/json uses, by name.
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	forEachBackend(t, &config.Config{}, func(t *testing.T, srv *httptest.Server) {
+		resp, err := srv.Client().Get(srv.URL + "/openapi.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		var doc struct {
+			OpenAPI string `json:"openapi"`
+		}
+		if resp.StatusCode != http.StatusOK || json.Unmarshal(body, &doc) != nil || doc.OpenAPI != "3.1.0" {
+			t.Fatalf("GET /openapi.json: %d %.100s", resp.StatusCode, body)
+		}
+
+		resp, err = srv.Client().Get(srv.URL + "/docs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"/openapi.json"`) {
+			t.Fatalf("GET /docs: %d %s", resp.StatusCode, body)
+		}
+	})
+}
+
+func TestRequestValidation(t *testing.T) {
+	forEachBackend(t, &config.Config{}, func(t *testing.T, srv *httptest.Server) {
+		var out statusResponse
+		if code := do(t, srv, http.MethodPost, "/users/new", "", map[string]any{"email": "a@example.com"}, &out); code != http.StatusBadRequest ||
+			out.Error != "field password is a required field" {
+			t.Fatalf("POST /users/new without a password: %d %+v", code, out)
+		}
+		token := register(t, srv, "editor@example.com", "secret")
+
+		cases := []struct {
+			body any
+			want string
+		}{
+			{map[string]any{"Title": 5}, "field Title must be a string"},
+			{map[string]any{"Title": "x", "ContentFormat": "rtf"}, "field ContentFormat must be one of plain, markdown, html"},
+			{map[string]any{"Title": "x", "Categories": []any{1, "two"}}, "field Categories[1] must be a number"},
+			{map[string]any{"Title": "x", "Categories": []any{1.5}}, "field Categories[0] must be an integer"},
+			{[]string{"x"}, "field body must be an object"},
+		}
+		for _, c := range cases {
+			out = statusResponse{}
+			if code := do(t, srv, http.MethodPost, "/news", token, c.body, &out); code != http.StatusBadRequest || out.Error != c.want {
+				t.Errorf("POST /news %v: %d %+v, want %q", c.body, code, out, c.want)
+			}
+		}
+
+		out = statusResponse{}
+		large := map[string]any{"Title": "x", "Content": strings.Repeat("x", response.MaxBodySize)}
+		if code := do(t, srv, http.MethodPost, "/news", token, large, &out); code != http.StatusRequestEntityTooLarge || out.Error != "request body too large" {
+			t.Errorf("POST /news over the size limit: %d %+v", code, out)
+		}
+
+		// Nulls are like omitted fields.
+		var news newsResponse
+		if code := do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "x", "Published": nil}, &news); code != http.StatusOK || news.Status != "OK" {
+			t.Fatalf("POST /news with a null: %d %+v", code, news)
+		}
+	})
+}
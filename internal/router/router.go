@@ -0,0 +1,223 @@
+package router
+
+import (
+	"log/slog"
+	"net/http"
+	"news-service/api/openapi"
+	apiv1 "news-service/api/v1"
+	"news-service/internal/config"
+	"news-service/internal/entities"
+	"news-service/internal/graphqlserver"
+	commenthandler "news-service/internal/handlers/CommentHandler"
+	newshandler "news-service/internal/handlers/NewsHandler"
+	webhookhandler "news-service/internal/handlers/WebhookHandler"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/jwt"
+	"news-service/internal/ratelimit"
+	"news-service/internal/service"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func New(log *slog.Logger, cfg *config.Config, repos service.Repositories, jwtManager *jwt.JWTManager) *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(ratelimit.RealIP(cfg.HTTPServer.TrustedProxies))
+	router.Use(middleware.Logger)
+	router.Use(middleware.Recoverer)
+	router.Use(urlFormat)
+
+	// middleware.URLFormat strips the extension of /openapi.json.
+	router.Get("/openapi", openapi.Handler())
+	router.Get("/docs", openapi.SwaggerUI("/openapi.json"))
+	// The keys verifying tokens, for other services. Like the spec, it is
+	// not versioned.
+	router.Get("/.well-known/jwks.json", jwt.JWKSHandler(jwtManager))
+
+	// The API is served under /v1 and, until its sunset, at the root for
+	// the clients of the unversioned API. Both share the rate limits.
+	store := repos.RateLimits
+	if store == nil {
+		store = ratelimit.NewMemory()
+	}
+	limits := limiters{
+		public:        newLimiter(store, "public", cfg.RateLimit.Public),
+		authenticated: newLimiter(store, "authenticated", cfg.RateLimit.Authenticated),
+		comments:      newLimiter(store, "comments", cfg.Comments.RateLimit),
+		login:         NewThrottle(cfg.RateLimit, store),
+	}
+	accounts := service.NewAccounts(cfg.Mail, repos)
+	api := apiRoutes(log, cfg, repos, jwtManager, limits, accounts)
+	router.Route(apiv1.Prefix, func(r chi.Router) {
+		r.Use(apiv1.Versioned)
+		api(r)
+	})
+	router.Group(func(r chi.Router) {
+		r.Use(apiv1.Deprecated(cfg.LegacyAPI.Deprecated, cfg.LegacyAPI.Sunset))
+		api(r)
+	})
+
+	// The GraphQL schema evolves without versions.
+	router.Group(func(r chi.Router) {
+		r.Use(ratelimit.Middleware(limits.authenticated, ratelimit.ByIP))
+		r.Use(func(next http.Handler) http.Handler {
+			return jwt.TokenAuthMiddleware(jwtManager, next)
+		})
+		r.Post("/graphql", graphqlserver.New(log, cfg.GraphQL, graphqlserver.Repositories{
+			News:           repos.News,
+			NewsCategories: repos.NewsCategories,
+			Media:          repos.Media,
+			Tags:           repos.Tags,
+			Comments:       repos.Comments,
+			Reactions:      repos.Reactions,
+			Bookmarks:      repos.Bookmarks,
+			Subscriptions:  repos.Subscriptions,
+			Users:          repos.Users,
+		}).ServeHTTP)
+	})
+
+	// Links to media files are stored with the news, they are not versioned.
+	if files, ok := repos.MediaStorage.(http.Handler); ok {
+		router.With(ratelimit.Middleware(newLimiter(store, "media", cfg.RateLimit.Public), ratelimit.ByIP)).
+			Handle("/media/*", http.StripPrefix("/media", files))
+	}
+
+	return router
+}
+
+// urlFormat is middleware.URLFormat, except for the paths under
+// /.well-known/, which other services spell out and are routed as they are.
+func urlFormat(next http.Handler) http.Handler {
+	formatted := middleware.URLFormat(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/.well-known/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		formatted.ServeHTTP(w, r)
+	})
+}
+
+type limiters struct {
+	public, authenticated, comments *ratelimit.Limiter
+	login                           userhandlers.Throttle
+}
+
+// apiRoutes returns a function registering the API routes on a router.
+func apiRoutes(log *slog.Logger, cfg *config.Config, repos service.Repositories, jwtManager *jwt.JWTManager, limits limiters, accounts userhandlers.Accounts) func(chi.Router) {
+	// JSON bodies are checked against openapi.json before they reach the
+	// handlers.
+	spec := openapi.MustLoad()
+	writer := service.NewNewsWriter(repos)
+
+	return func(router chi.Router) {
+		router.With(spec.ValidateRequests).Post("/users/new", userhandlers.NewUser(log, repos.Users, limits.login, accounts))
+		router.With(spec.ValidateRequests).Post("/login", userhandlers.LoginFunc(log, repos.Users, jwtManager, limits.login, accounts))
+		// Requests for mails are throttled like sign-ins.
+		router.With(spec.ValidateRequests).Post("/users/verification", userhandlers.ResendVerification(log, repos.Users, accounts, limits.login))
+		router.With(spec.ValidateRequests).Post("/password/forgot", userhandlers.ForgotPassword(log, repos.Users, accounts, limits.login))
+		// The mailed tokens are too long to guess, redeeming them is only
+		// limited like the public API.
+		router.Group(func(r chi.Router) {
+			r.Use(ratelimit.Middleware(limits.public, ratelimit.ByIP))
+			r.Use(spec.ValidateRequests)
+
+			r.Post("/users/verify", userhandlers.VerifyEmail(log, repos.Users, accounts))
+			r.Post("/password/reset", userhandlers.ResetPassword(log, repos.Users, accounts, limits.login))
+		})
+
+		// Read-only API for anonymous readers, only published news are visible.
+		router.Group(func(r chi.Router) {
+			r.Use(ratelimit.Middleware(limits.public, ratelimit.ByIP))
+			r.Use(spec.ValidateRequests)
+
+			r.Get("/news", newshandler.ListPublishedNews(log, repos.News, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions))
+			r.Get("/news/{id}", newshandler.GetPublishedNews(log, repos.News, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions))
+			r.Get("/news/trending", newshandler.TrendingNews(log, cfg.Views, repos.Views, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions))
+			r.Post("/news/{id}/views", newshandler.RecordView(log, repos.News, repos.ViewCounter))
+			r.Get("/news/{id}/comments", commenthandler.ListComments(log, repos.News, repos.Comments))
+			r.Get("/news/{id}/related", newshandler.RelatedNews(log, cfg.Related, repos.News, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions))
+			r.Get("/news/by-slug/{slug}", newshandler.GetPublishedNewsBySlug(log, repos.News, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions))
+			r.Get("/categories/{category}/news", newshandler.ListNewsByCategory(log, repos.News, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions))
+
+			// middleware.URLFormat strips the extension, so /feed.rss and
+			// /feed.atom are both routed to /feed.
+			r.Get("/feed", newshandler.Feed(log, cfg.Feed, repos.News))
+			r.Get("/categories/{category}/feed", newshandler.Feed(log, cfg.Feed, repos.News))
+		})
+
+		router.Group(func(r chi.Router) {
+			r.Use(ratelimit.Middleware(limits.authenticated, ratelimit.ByIP))
+			r.Use(func(next http.Handler) http.Handler {
+				return jwt.TokenAuthMiddleware(jwtManager, next)
+			})
+			r.Use(spec.ValidateRequests)
+
+			r.Post("/news", newshandler.NewNews(log, writer))
+			r.Get("/list", newshandler.ListAllNews(log, repos.News, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions))
+			r.Get("/news/stream", newshandler.StreamNews(log, cfg.Stream, repos.NewsStream, repos.NewsEvents, repos.News, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions))
+			r.Patch("/news/edit/{id}", newshandler.UpdateNews(log, writer))
+			r.Get("/tags", newshandler.ListTags(log, repos.Tags))
+			r.Post("/news/{id}/media", newshandler.UploadMedia(log, cfg.Media, repos.News, repos.Media, repos.MediaStorage))
+
+			r.With(ratelimit.Middleware(limits.comments, byUser)).
+				Post("/news/{id}/comments", commenthandler.CreateComment(log, repos.News, repos.Users, repos.Comments))
+			r.Put("/news/{id}/reactions/{kind}", newshandler.SetReaction(log, true, repos.News, repos.Users, repos.Reactions))
+			r.Delete("/news/{id}/reactions/{kind}", newshandler.SetReaction(log, false, repos.News, repos.Users, repos.Reactions))
+			r.Put("/news/{id}/bookmark", newshandler.SetBookmark(log, true, repos.News, repos.Users, repos.Bookmarks))
+			r.Delete("/news/{id}/bookmark", newshandler.SetBookmark(log, false, repos.News, repos.Users, repos.Bookmarks))
+			r.Get("/users/me/bookmarks", newshandler.ListBookmarks(log, repos.Users, repos.Bookmarks, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions))
+			r.Get("/users/me/subscriptions", newshandler.ListSubscriptions(log, repos.Users, repos.Subscriptions))
+			r.Put("/users/me/subscriptions/{category}", newshandler.SetSubscription(log, true, repos.Users, repos.Subscriptions))
+			r.Delete("/users/me/subscriptions/{category}", newshandler.SetSubscription(log, false, repos.Users, repos.Subscriptions))
+			r.Get("/feed/me", newshandler.PersonalFeed(log, repos.Users, repos.Subscriptions, repos.NewsCategories, repos.Media, repos.Tags, repos.Comments, repos.Reactions))
+
+			r.Post("/webhooks", webhookhandler.CreateWebhook(log, cfg.Webhooks, repos.Users, repos.Webhooks))
+			r.Get("/webhooks", webhookhandler.ListWebhooks(log, repos.Users, repos.Webhooks))
+			r.Get("/webhooks/{id}", webhookhandler.GetWebhook(log, repos.Users, repos.Webhooks))
+			r.Delete("/webhooks/{id}", webhookhandler.DeleteWebhook(log, repos.Users, repos.Webhooks))
+			r.Get("/webhooks/{id}/deliveries", webhookhandler.ListDeliveries(log, repos.Users, repos.Webhooks))
+			r.Get("/webhooks/{id}/deliveries/{delivery}", webhookhandler.GetDelivery(log, repos.Users, repos.Webhooks))
+			r.Post("/webhooks/{id}/deliveries/{delivery}/retry", webhookhandler.RetryDelivery(log, repos.Users, repos.Webhooks))
+
+			r.Patch("/comments/{id}", commenthandler.EditComment(log, cfg.Comments.EditWindow, repos.Users, repos.Comments))
+			r.Delete("/comments/{id}", commenthandler.DeleteComment(log, cfg.Comments.Moderators, repos.Users, repos.Comments))
+			r.Group(func(r chi.Router) {
+				r.Use(commenthandler.RequireModerator(cfg.Comments.Moderators))
+				r.Get("/comments/pending", commenthandler.ListPendingComments(log, repos.Comments))
+				r.Post("/comments/{id}/approve", commenthandler.ModerateComment(log, entities.CommentApproved, repos.Users, repos.Comments))
+				r.Post("/comments/{id}/reject", commenthandler.ModerateComment(log, entities.CommentRejected, repos.Users, repos.Comments))
+			})
+		})
+	}
+}
+
+// byUser keys requests by the user authenticated by jwt.TokenAuthMiddleware.
+func byUser(r *http.Request) string {
+	email, _ := jwt.EmailFromContext(r.Context())
+	return email
+}
+
+func newLimiter(store ratelimit.Store, name string, cfg config.LimitCfg) *ratelimit.Limiter {
+	if cfg.Requests <= 0 {
+		return nil
+	}
+	if cfg.Per <= 0 {
+		cfg.Per = time.Minute
+	}
+	return ratelimit.NewShared(store, name, cfg.Requests, cfg.Per, cfg.Burst)
+}
+
+// NewThrottle returns the brute-force protection of sign-ins and sign-ups
+// described by cfg.Login.
+func NewThrottle(cfg config.RateLimitCfg, store ratelimit.Store) userhandlers.Throttle {
+	lockout := cfg.Login.Lockout
+	return userhandlers.Throttle{
+		IPs:      newLimiter(store, "login-ip", cfg.Login.PerIP),
+		Accounts: newLimiter(store, "login-account", cfg.Login.PerAccount),
+		Lockout:  ratelimit.NewLockout(store, "login-lockout", lockout.Threshold, lockout.Base, lockout.Max),
+	}
+}
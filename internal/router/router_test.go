@@ -0,0 +1,2251 @@
+package router_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"news-service/internal/config"
+	bookmarksrepo "news-service/internal/database/bookmarksRepo"
+	categoriesrepo "news-service/internal/database/categoriesRepo"
+	commentsrepo "news-service/internal/database/commentsRepo"
+	"news-service/internal/database/dbtest"
+	mediarepo "news-service/internal/database/mediaRepo"
+	memoryrepo "news-service/internal/database/memoryRepo"
+	newscategoriesrepo "news-service/internal/database/newsCategoriesRepo"
+	newseventsrepo "news-service/internal/database/newsEventsRepo"
+	newsrepo "news-service/internal/database/newsRepo"
+	reactionsrepo "news-service/internal/database/reactionsRepo"
+	subscriptionsrepo "news-service/internal/database/subscriptionsRepo"
+	tagsrepo "news-service/internal/database/tagsRepo"
+	usertokensrepo "news-service/internal/database/userTokensRepo"
+	usersrepo "news-service/internal/database/usersRepo"
+	viewsrepo "news-service/internal/database/viewsRepo"
+	webhooksrepo "news-service/internal/database/webhooksRepo"
+	"news-service/internal/entities"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/jwt"
+	"news-service/internal/mailer"
+	"news-service/internal/models"
+	"news-service/internal/router"
+	"news-service/internal/service"
+	"news-service/internal/storage"
+	"news-service/internal/stream"
+	"news-service/internal/views"
+	"news-service/internal/webhook"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+var backends = map[string]func(t *testing.T) service.Repositories{
+	"postgres": func(t *testing.T) service.Repositories {
+		pg := dbtest.New(t)
+		log := dbtest.Logger()
+		return service.Repositories{
+			News:           newsrepo.NewNewsRepository(pg.Db, log),
+			Categories:     categoriesrepo.NewCategoriesRepository(pg.Db, log),
+			NewsCategories: newscategoriesrepo.NewNewsCategoriesRepository(pg.Db, log),
+			Users:          usersrepo.NewUserRepository(pg.Db, log),
+			UserTokens:     usertokensrepo.NewUserTokensRepository(pg.Db, log),
+			Media:          mediarepo.NewMediaRepository(pg.Db, log),
+			Tags:           tagsrepo.NewTagsRepository(pg.Db, log),
+			Views:          viewsrepo.NewViewsRepository(pg.Db, log),
+			Comments:       commentsrepo.NewCommentsRepository(pg.Db, log),
+			Reactions:      reactionsrepo.NewReactionsRepository(pg.Db, log),
+			Bookmarks:      bookmarksrepo.NewBookmarksRepository(pg.Db, log),
+			Subscriptions:  subscriptionsrepo.NewSubscriptionsRepository(pg.Db, log),
+			Webhooks:       webhooksrepo.NewWebhooksRepository(pg.Db, log),
+			NewsEvents:     newseventsrepo.NewNewsEventsRepository(pg.Db, log),
+			MediaStorage:   localStorage(t),
+		}
+	},
+	"memory": func(t *testing.T) service.Repositories {
+		store := memoryrepo.NewStore()
+		log := dbtest.Logger()
+		return service.Repositories{
+			News:           memoryrepo.NewNewsRepository(store, log),
+			Categories:     memoryrepo.NewCategoriesRepository(store, log),
+			NewsCategories: memoryrepo.NewNewsCategoriesRepository(store, log),
+			Users:          memoryrepo.NewUserRepository(store, log),
+			UserTokens:     memoryrepo.NewUserTokensRepository(store, log),
+			Media:          memoryrepo.NewMediaRepository(store, log),
+			Tags:           memoryrepo.NewTagsRepository(store, log),
+			Views:          memoryrepo.NewViewsRepository(store, log),
+			Comments:       memoryrepo.NewCommentsRepository(store, log),
+			Reactions:      memoryrepo.NewReactionsRepository(store, log),
+			Bookmarks:      memoryrepo.NewBookmarksRepository(store, log),
+			Subscriptions:  memoryrepo.NewSubscriptionsRepository(store, log),
+			Webhooks:       memoryrepo.NewWebhooksRepository(store, log),
+			NewsEvents:     memoryrepo.NewNewsEventsRepository(store, log),
+			MediaStorage:   localStorage(t),
+		}
+	},
+}
+
+func localStorage(t *testing.T) *storage.Local {
+	s, err := storage.NewLocal(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// forEachBackend runs fn against a server backed by every repository
+// implementation.
+func forEachBackend(t *testing.T, cfg *config.Config, fn func(t *testing.T, srv *httptest.Server)) {
+	for name, newRepos := range backends {
+		t.Run(name, func(t *testing.T) {
+			log := dbtest.Logger()
+			repos := newRepos(t)
+			repos.ViewCounter = views.NewCounter(repos.Views, time.Hour, log)
+			srv := httptest.NewServer(router.New(log, cfg, repos, newJWTManager(log, repos)))
+			t.Cleanup(srv.Close)
+			fn(t, srv)
+		})
+	}
+}
+
+// newJWTManager revokes the tokens issued before a password change, like
+// the service does.
+func newJWTManager(log *slog.Logger, repos service.Repositories) *jwt.JWTManager {
+	jwtManager := jwt.NewJWTManager("test-secret", log)
+	jwtManager.RevokeBefore(userhandlers.PasswordChangedAt(repos.Users))
+	return jwtManager
+}
+
+func do(t *testing.T, srv *httptest.Server, method, path, token string, body any, out any) int {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, srv.URL+path, &buf)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("decode %s %s: %v", method, path, err)
+	}
+	return resp.StatusCode
+}
+
+type statusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+type authResponse struct {
+	statusResponse
+	ID    int    `json:"user_id"`
+	Token string `json:"token"`
+}
+
+type newsResponse struct {
+	statusResponse
+	ID int `json:"id"`
+}
+
+type listResponse struct {
+	statusResponse
+	Success bool `json:"Success"`
+	News    []struct {
+		ID         int    `json:"Id"`
+		Title      string `json:"Title"`
+		Content    string `json:"Content"`
+		Published  bool   `json:"Published"`
+		Categories []int  `json:"Categories"`
+	} `json:"News"`
+}
+
+func register(t *testing.T, srv *httptest.Server, email, password string) string {
+	t.Helper()
+
+	var created authResponse
+	do(t, srv, http.MethodPost, "/users/new", "", map[string]string{"email": email, "password": password}, &created)
+	if created.Status != "OK" || created.ID == 0 {
+		t.Fatalf("register: %+v", created)
+	}
+
+	var login authResponse
+	do(t, srv, http.MethodPost, "/login", "", map[string]string{"email": email, "password": password}, &login)
+	if login.Status != "OK" || login.Token == "" {
+		t.Fatalf("login: %+v", login)
+	}
+	return login.Token
+}
+
+func TestAuthFlow(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testAuthFlow)
+}
+
+func testAuthFlow(t *testing.T, srv *httptest.Server) {
+	register(t, srv, "user@example.com", "secret")
+
+	var dup authResponse
+	do(t, srv, http.MethodPost, "/users/new", "", map[string]string{"email": "user@example.com", "password": "x"}, &dup)
+	if dup.Status != "Error" {
+		t.Fatal("registering the same email twice should fail")
+	}
+
+	var missing authResponse
+	do(t, srv, http.MethodPost, "/users/new", "", map[string]string{"email": "other@example.com"}, &missing)
+	if missing.Status != "Error" {
+		t.Fatal("registering without a password should fail")
+	}
+
+	var wrong authResponse
+	do(t, srv, http.MethodPost, "/login", "", map[string]string{"email": "user@example.com", "password": "wrong"}, &wrong)
+	if wrong.Status != "Error" || wrong.Token != "" {
+		t.Fatalf("login with a wrong password: %+v", wrong)
+	}
+
+	var unknown authResponse
+	do(t, srv, http.MethodPost, "/login", "", map[string]string{"email": "nobody@example.com", "password": "secret"}, &unknown)
+	if unknown.Status != "Error" || unknown.Token != "" {
+		t.Fatalf("login with an unknown email: %+v", unknown)
+	}
+	if wrong.Error != "Invalid credentials" || unknown.Error != wrong.Error {
+		t.Fatalf("logins must not tell unknown emails from wrong passwords: %q, %q", wrong.Error, unknown.Error)
+	}
+}
+
+func TestProtectedRoutesRequireToken(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testProtectedRoutesRequireToken)
+}
+
+func testProtectedRoutesRequireToken(t *testing.T, srv *httptest.Server) {
+	for _, token := range []string{"", "garbage"} {
+		var list listResponse
+		do(t, srv, http.MethodGet, "/list", token, nil, &list)
+		if list.Status != "Error" || list.Error != "unauthorized" {
+			t.Fatalf("GET /list with token %q: %+v", token, list)
+		}
+
+		var created newsResponse
+		do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "t", "Content": "c"}, &created)
+		if created.Status != "Error" || created.Error != "unauthorized" {
+			t.Fatalf("POST /news with token %q: %+v", token, created)
+		}
+	}
+}
+
+func TestNewsLifecycle(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testNewsLifecycle)
+}
+
+func testNewsLifecycle(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	var created newsResponse
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Title", "Content": "Content", "Categories": []int{1, 2}}, &created)
+	if created.Status != "OK" || created.ID == 0 {
+		t.Fatalf("create news: %+v", created)
+	}
+
+	var list listResponse
+	do(t, srv, http.MethodGet, "/list", token, nil, &list)
+	if !list.Success || len(list.News) != 1 {
+		t.Fatalf("list news: %+v", list)
+	}
+	got := list.News[0]
+	slices.Sort(got.Categories)
+	if got.ID != created.ID || got.Title != "Title" || !slices.Equal(got.Categories, []int{1, 2}) {
+		t.Fatalf("unexpected news: %+v", got)
+	}
+
+	var updated statusResponse
+	do(t, srv, http.MethodPatch, "/news/edit/"+strconv.Itoa(created.ID), token, map[string]any{"Id": created.ID, "Title": "New", "Content": "New content", "Categories": []int{3}}, &updated)
+	if updated.Status != "OK" {
+		t.Fatalf("update news: %+v", updated)
+	}
+
+	do(t, srv, http.MethodGet, "/list", token, nil, &list)
+	got = list.News[0]
+	if got.Title != "New" || got.Content != "New content" || !slices.Equal(got.Categories, []int{3}) {
+		t.Fatalf("update was not applied: %+v", got)
+	}
+
+	var notFound statusResponse
+	do(t, srv, http.MethodPatch, "/news/edit/"+strconv.Itoa(created.ID+100), token, map[string]any{"Id": 1, "Title": "x"}, &notFound)
+	if notFound.Status != "Error" {
+		t.Fatal("updating a missing news should fail")
+	}
+}
+
+// laggingReplica is a news repository whose replica has not caught up yet:
+// it only finds the news read from the primary.
+type laggingReplica struct {
+	models.NewsRepository
+}
+
+func (r laggingReplica) FindNewsByID(ctx context.Context, id int) (entities.News, error) {
+	if !models.ReadsFromPrimary(ctx) {
+		return entities.News{}, models.ErrNewsNotFound
+	}
+	return r.NewsRepository.FindNewsByID(ctx, id)
+}
+
+// TestUpdateNewsLaggingReplica builds its servers itself, the news
+// repository lags behind the writes.
+func TestUpdateNewsLaggingReplica(t *testing.T) {
+	for name, newRepos := range backends {
+		t.Run(name, func(t *testing.T) {
+			log := dbtest.Logger()
+			repos := newRepos(t)
+			repos.News = laggingReplica{repos.News}
+			repos.ViewCounter = views.NewCounter(repos.Views, time.Hour, log)
+			srv := httptest.NewServer(router.New(log, &config.Config{}, repos, jwt.NewJWTManager("test-secret", log)))
+			t.Cleanup(srv.Close)
+
+			token := register(t, srv, "editor@example.com", "secret")
+			var created newsResponse
+			do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Title", "Content": "Content"}, &created)
+
+			var updated statusResponse
+			if status := do(t, srv, http.MethodPatch, "/news/edit/"+strconv.Itoa(created.ID), token, map[string]any{"Id": created.ID, "Title": "New", "Content": "New content"}, &updated); status != http.StatusOK || updated.Status != "OK" {
+				t.Fatalf("update news not replicated yet: status %d, %+v", status, updated)
+			}
+			news, err := repos.News.FindNewsByID(models.ReadFromPrimary(context.Background()), created.ID)
+			if err != nil || news.Title != "New" || news.Content != "New content" {
+				t.Fatalf("update was not applied: %+v, %v", news, err)
+			}
+		})
+	}
+}
+
+func TestPublicAPI(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testPublicAPI)
+}
+
+func testPublicAPI(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	var published, draft, other newsResponse
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Published", "Content": "c", "Categories": []int{1}}, &published)
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Draft", "Content": "c", "Categories": []int{1}, "Published": false}, &draft)
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Other", "Content": "c", "Categories": []int{2}}, &other)
+
+	var list listResponse
+	if status := do(t, srv, http.MethodGet, "/news", "", nil, &list); status != http.StatusOK {
+		t.Fatalf("GET /news: status %d", status)
+	}
+	if len(list.News) != 2 || list.News[0].ID != other.ID || list.News[1].ID != published.ID {
+		t.Fatalf("GET /news should list published news only: %+v", list.News)
+	}
+
+	do(t, srv, http.MethodGet, "/news?limit=1&offset=1", "", nil, &list)
+	if len(list.News) != 1 || list.News[0].ID != published.ID {
+		t.Fatalf("GET /news with pagination: %+v", list.News)
+	}
+
+	var bad statusResponse
+	if status := do(t, srv, http.MethodGet, "/news?limit=1000", "", nil, &bad); status != http.StatusBadRequest {
+		t.Fatalf("GET /news with a huge limit: status %d", status)
+	}
+
+	do(t, srv, http.MethodGet, "/categories/1/news", "", nil, &list)
+	if len(list.News) != 1 || list.News[0].ID != published.ID {
+		t.Fatalf("GET /categories/1/news: %+v", list.News)
+	}
+
+	var single struct {
+		statusResponse
+		ID    int    `json:"id"`
+		Title string `json:"title"`
+	}
+	if status := do(t, srv, http.MethodGet, "/news/"+strconv.Itoa(published.ID), "", nil, &single); status != http.StatusOK || single.Title != "Published" {
+		t.Fatalf("GET /news/{id}: status %d, %+v", status, single)
+	}
+	if status := do(t, srv, http.MethodGet, "/news/"+strconv.Itoa(draft.ID), "", nil, &single); status != http.StatusNotFound {
+		t.Fatalf("GET /news/{id} for a draft: status %d", status)
+	}
+
+	var all listResponse
+	do(t, srv, http.MethodGet, "/list", token, nil, &all)
+	if len(all.News) != 3 {
+		t.Fatalf("GET /list should include drafts: %+v", all.News)
+	}
+
+	var created newsResponse
+	do(t, srv, http.MethodPost, "/news", "", map[string]any{"Title": "t", "Content": "c"}, &created)
+	if created.Status != "Error" {
+		t.Fatal("POST /news must still require a token")
+	}
+}
+
+func TestPublicRateLimit(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.RateLimit.Public = config.LimitCfg{Requests: 1, Per: time.Hour, Burst: 2}
+
+	forEachBackend(t, cfg, func(t *testing.T, srv *httptest.Server) {
+		var list listResponse
+		for i := 0; i < 2; i++ {
+			if status := do(t, srv, http.MethodGet, "/news", "", nil, &list); status != http.StatusOK {
+				t.Fatalf("request %d: status %d", i, status)
+			}
+		}
+		var limited statusResponse
+		if status := do(t, srv, http.MethodGet, "/news", "", nil, &limited); status != http.StatusTooManyRequests {
+			t.Fatalf("request over the limit: status %d", status)
+		}
+
+		// The authenticated API has its own budget.
+		token := register(t, srv, "user@example.com", "secret")
+		if status := do(t, srv, http.MethodGet, "/list", token, nil, &list); status != http.StatusOK || !list.Success {
+			t.Fatalf("GET /list after the public limit was hit: status %d", status)
+		}
+	})
+}
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	get := func(t *testing.T, srv *httptest.Server, forwardedFor string) int {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/news", nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("GET /news: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// A client may not pick a new address to get a new budget.
+	cfg := &config.Config{}
+	cfg.RateLimit.Public = config.LimitCfg{Requests: 1, Per: time.Hour, Burst: 1}
+	forEachBackend(t, cfg, func(t *testing.T, srv *httptest.Server) {
+		if status := get(t, srv, "198.51.100.1"); status != http.StatusOK {
+			t.Fatalf("first request: status %d", status)
+		}
+		if status := get(t, srv, "198.51.100.2"); status != http.StatusTooManyRequests {
+			t.Fatalf("request with a forged X-Forwarded-For: status %d", status)
+		}
+	})
+
+	// Behind a trusted proxy, the clients it forwards have their own.
+	trusted := &config.Config{}
+	trusted.RateLimit.Public = cfg.RateLimit.Public
+	trusted.HTTPServer.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+	forEachBackend(t, trusted, func(t *testing.T, srv *httptest.Server) {
+		for _, client := range []string{"198.51.100.1", "198.51.100.2"} {
+			if status := get(t, srv, client); status != http.StatusOK {
+				t.Fatalf("first request of %s: status %d", client, status)
+			}
+		}
+		if status := get(t, srv, "198.51.100.1"); status != http.StatusTooManyRequests {
+			t.Fatalf("second request of a forwarded client: status %d", status)
+		}
+	})
+}
+
+func TestLoginThrottle(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.RateLimit.Login.Lockout = config.LockoutCfg{Threshold: 2, Base: time.Minute, Max: time.Hour}
+
+	forEachBackend(t, cfg, func(t *testing.T, srv *httptest.Server) {
+		register(t, srv, "user@example.com", "secret")
+
+		// A success resets the failures.
+		login := func(email, password string) (int, authResponse) {
+			var resp authResponse
+			return do(t, srv, http.MethodPost, "/login", "", map[string]string{"email": email, "password": password}, &resp), resp
+		}
+		login("user@example.com", "wrong")
+		if _, ok := login("user@example.com", "secret"); ok.Token == "" {
+			t.Fatalf("login after one failure: %+v", ok)
+		}
+
+		// Unknown emails are locked out like existing ones.
+		for _, email := range []string{"user@example.com", "nobody@example.com"} {
+			for i := 0; i < 2; i++ {
+				if status, resp := login(email, "wrong"); status != http.StatusOK || resp.Error != "Invalid credentials" {
+					t.Fatalf("failure %d of %s: status %d, %+v", i, email, status, resp)
+				}
+			}
+			status, resp := login(email, "secret")
+			if status != http.StatusTooManyRequests || resp.Token != "" {
+				t.Fatalf("login of %s after the lockout threshold: status %d, %+v", email, status, resp)
+			}
+		}
+
+		// Emails are locked out whatever the case they are written in,
+		// other emails are not.
+		if status, _ := login("USER@example.com", "secret"); status != http.StatusTooManyRequests {
+			t.Fatalf("login with another case: status %d", status)
+		}
+		register(t, srv, "other@example.com", "secret")
+	})
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.RateLimit.Login.PerIP = config.LimitCfg{Requests: 1, Per: time.Hour, Burst: 3}
+	cfg.RateLimit.Login.PerAccount = config.LimitCfg{Requests: 1, Per: time.Hour, Burst: 2}
+
+	forEachBackend(t, cfg, func(t *testing.T, srv *httptest.Server) {
+		register(t, srv, "user@example.com", "secret")
+
+		var login authResponse
+		if status := do(t, srv, http.MethodPost, "/login", "", map[string]string{"email": "user@example.com", "password": "secret"}, &login); status != http.StatusTooManyRequests {
+			t.Fatalf("login over the per-account limit: status %d, %+v", status, login)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/users/new", strings.NewReader(`{"email": "other@example.com", "password": "secret"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		// The sign-ups and logins before took a few seconds of the hour
+		// when hashing is slow.
+		retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if resp.StatusCode != http.StatusTooManyRequests || retryAfter < 3500 || retryAfter > 3600 {
+			t.Fatalf("sign-up over the per-IP limit: status %d, Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+		}
+	})
+}
+
+// mailbox keeps the mails sent by the server under test. The mails sent in
+// the background are waited for through pending.
+type mailbox struct {
+	mu      sync.Mutex
+	sent    []mailer.Message
+	pending sync.WaitGroup
+	held    chan struct{}
+}
+
+// hold makes Send wait, like a slow mail server, until release is called
+// or a few seconds have passed.
+func (m *mailbox) hold() (release func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	held := make(chan struct{})
+	m.held = held
+	return func() { close(held) }
+}
+
+func (m *mailbox) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	held := m.held
+	m.mu.Unlock()
+	if held != nil {
+		select {
+		case <-held:
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var mailedToken = regexp.MustCompile(`the token ([A-Za-z0-9_-]+)`)
+
+// tokens returns the tokens mailed to to with subject, oldest first.
+func (m *mailbox) tokens(to, subject string) []string {
+	m.pending.Wait()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var tokens []string
+	for _, msg := range m.sent {
+		if match := mailedToken.FindStringSubmatch(msg.Body); msg.To == to && msg.Subject == subject && match != nil {
+			tokens = append(tokens, match[1])
+		}
+	}
+	return tokens
+}
+
+// forEachBackendWithMail is forEachBackend with the mails kept in a
+// mailbox.
+func forEachBackendWithMail(t *testing.T, cfg *config.Config, fn func(t *testing.T, srv *httptest.Server, mails *mailbox)) {
+	for name, newRepos := range backends {
+		t.Run(name, func(t *testing.T) {
+			log := dbtest.Logger()
+			repos := newRepos(t)
+			mails := &mailbox{}
+			repos.Mailer = mails
+			repos.PendingMails = &mails.pending
+			srv := httptest.NewServer(router.New(log, cfg, repos, newJWTManager(log, repos)))
+			t.Cleanup(srv.Close)
+			fn(t, srv, mails)
+		})
+	}
+}
+
+func TestSignUpDoesNotWaitForMail(t *testing.T) {
+	forEachBackendWithMail(t, &config.Config{}, func(t *testing.T, srv *httptest.Server, mails *mailbox) {
+		release := mails.hold()
+		var created authResponse
+		if status := do(t, srv, http.MethodPost, "/v1/users/new", "", map[string]string{"email": "user@example.com", "password": "secret"}, &created); status != http.StatusOK || created.Status != "OK" {
+			t.Fatalf("sign-up: status %d, %+v", status, created)
+		}
+		mails.mu.Lock()
+		sent := len(mails.sent)
+		mails.mu.Unlock()
+		if sent != 0 {
+			t.Fatal("sign-up waited for the mail server")
+		}
+
+		release()
+		if tokens := mails.tokens("user@example.com", "Confirm your email"); len(tokens) != 1 {
+			t.Fatalf("verification mails after sign-up: %v", tokens)
+		}
+	})
+}
+
+func TestEmailVerification(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Mail.RequireVerification = true
+	cfg.Mail.VerifyURL = "https://news.example.com/verify?token="
+
+	const subject = "Confirm your email"
+	forEachBackendWithMail(t, cfg, func(t *testing.T, srv *httptest.Server, mails *mailbox) {
+		credentials := map[string]string{"email": "user@example.com", "password": "secret"}
+		var created authResponse
+		do(t, srv, http.MethodPost, "/v1/users/new", "", credentials, &created)
+		if created.Status != "OK" {
+			t.Fatalf("sign-up: %+v", created)
+		}
+		tokens := mails.tokens("user@example.com", subject)
+		if len(tokens) != 1 || !strings.Contains(mails.sent[0].Body, cfg.Mail.VerifyURL+tokens[0]) {
+			t.Fatalf("mails after sign-up: %+v", mails.sent)
+		}
+
+		var login authResponse
+		if status := do(t, srv, http.MethodPost, "/v1/login", "", credentials, &login); status != http.StatusForbidden || login.Token != "" {
+			t.Fatalf("login before verification: status %d, %+v", status, login)
+		}
+
+		// A new link does not invalidate the first one until one is used.
+		var resp statusResponse
+		for _, email := range []string{"user@example.com", "nobody@example.com"} {
+			if status := do(t, srv, http.MethodPost, "/v1/users/verification", "", map[string]string{"email": email}, &resp); status != http.StatusOK || resp.Status != "OK" {
+				t.Fatalf("resend to %s: status %d, %+v", email, status, resp)
+			}
+		}
+		tokens = mails.tokens("user@example.com", subject)
+		if len(tokens) != 2 || len(mails.sent) != 2 {
+			t.Fatalf("mails after resend: %+v", mails.sent)
+		}
+
+		verify := func(token string) (int, statusResponse) {
+			var resp statusResponse
+			return do(t, srv, http.MethodPost, "/v1/users/verify", "", map[string]string{"token": token}, &resp), resp
+		}
+		if status, resp := verify("forged"); status != http.StatusBadRequest || resp.Error != "invalid or expired token" {
+			t.Fatalf("verify with a forged token: status %d, %+v", status, resp)
+		}
+		if status, resp := verify(tokens[0]); status != http.StatusOK || resp.Status != "OK" {
+			t.Fatalf("verify: status %d, %+v", status, resp)
+		}
+		for _, token := range tokens {
+			if status, _ := verify(token); status != http.StatusBadRequest {
+				t.Fatalf("verify with a used token: status %d", status)
+			}
+		}
+		if status := do(t, srv, http.MethodPost, "/v1/login", "", credentials, &login); status != http.StatusOK || login.Token == "" {
+			t.Fatalf("login after verification: status %d, %+v", status, login)
+		}
+
+		// Verified users get no more links.
+		do(t, srv, http.MethodPost, "/v1/users/verification", "", map[string]string{"email": "user@example.com"}, &resp)
+		if len(mails.tokens("user@example.com", subject)) != 2 {
+			t.Fatalf("mails after resend to a verified user: %+v", mails.sent)
+		}
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.RateLimit.Login.Lockout = config.LockoutCfg{Threshold: 1, Base: time.Hour, Max: time.Hour}
+
+	const subject = "Reset your password"
+	forEachBackendWithMail(t, cfg, func(t *testing.T, srv *httptest.Server, mails *mailbox) {
+		session := register(t, srv, "user@example.com", "secret")
+		login := func(password string) (int, authResponse) {
+			var resp authResponse
+			return do(t, srv, http.MethodPost, "/login", "", map[string]string{"email": "user@example.com", "password": password}, &resp), resp
+		}
+		// The user forgot their password and locked themselves out.
+		login("wrong")
+		if status, _ := login("secret"); status != http.StatusTooManyRequests {
+			t.Fatalf("login after the lockout: status %d", status)
+		}
+
+		var resp statusResponse
+		for _, email := range []string{"user@example.com", "user@example.com", "nobody@example.com"} {
+			if status := do(t, srv, http.MethodPost, "/password/forgot", "", map[string]string{"email": email}, &resp); status != http.StatusOK || resp.Status != "OK" {
+				t.Fatalf("forgot password of %s: status %d, %+v", email, status, resp)
+			}
+		}
+		tokens := mails.tokens("user@example.com", subject)
+		if len(tokens) != 2 || tokens[0] == tokens[1] {
+			t.Fatalf("reset tokens: %v", tokens)
+		}
+		if len(mails.tokens("nobody@example.com", subject)) != 0 {
+			t.Fatal("a reset link was mailed to an unknown email")
+		}
+
+		reset := func(token, password string) (int, statusResponse) {
+			var resp statusResponse
+			return do(t, srv, http.MethodPost, "/password/reset", "", map[string]string{"token": token, "password": password}, &resp), resp
+		}
+		// Verification tokens do not reset passwords.
+		if status, _ := reset(mails.tokens("user@example.com", "Confirm your email")[0], "new-secret"); status != http.StatusBadRequest {
+			t.Fatalf("reset with a verification token: status %d", status)
+		}
+		var bookmarks statusResponse
+		if do(t, srv, http.MethodGet, "/users/me/bookmarks", session, nil, &bookmarks); bookmarks.Error != "" {
+			t.Fatalf("session before the reset: %+v", bookmarks)
+		}
+		// Tokens issued in the second of the reset stay valid, iat
+		// counts whole seconds.
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+		if status, resp := reset(tokens[1], "new-secret"); status != http.StatusOK || resp.Status != "OK" {
+			t.Fatalf("reset: status %d, %+v", status, resp)
+		}
+		bookmarks = statusResponse{}
+		if do(t, srv, http.MethodGet, "/users/me/bookmarks", session, nil, &bookmarks); bookmarks.Error != "unauthorized" {
+			t.Fatalf("session after the reset: %+v", bookmarks)
+		}
+		if status, resp := reset(tokens[0], "other-secret"); status != http.StatusBadRequest || resp.Error != "invalid or expired token" {
+			t.Fatalf("reset with a used up token: status %d, %+v", status, resp)
+		}
+
+		// The reset lifted the lockout.
+		status, relogin := login("new-secret")
+		if status != http.StatusOK || relogin.Token == "" {
+			t.Fatalf("login with the new password: status %d, %+v", status, relogin)
+		}
+		bookmarks = statusResponse{}
+		if do(t, srv, http.MethodGet, "/users/me/bookmarks", relogin.Token, nil, &bookmarks); bookmarks.Error != "" {
+			t.Fatalf("session after logging in again: %+v", bookmarks)
+		}
+		if status, resp := login("secret"); status != http.StatusOK || resp.Error != "Invalid credentials" {
+			t.Fatalf("login with the old password: status %d, %+v", status, resp)
+		}
+	})
+}
+
+func TestSlugs(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testSlugs)
+}
+
+func testSlugs(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	var created struct {
+		newsResponse
+		Slug string `json:"slug"`
+	}
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Первая новость", "Content": "c"}, &created)
+	if created.Slug != "pervaya-novost" {
+		t.Fatalf("slug = %q", created.Slug)
+	}
+
+	var single struct {
+		statusResponse
+		ID   int    `json:"id"`
+		Slug string `json:"slug"`
+	}
+	if status := do(t, srv, http.MethodGet, "/news/by-slug/pervaya-novost", "", nil, &single); status != http.StatusOK || single.ID != created.ID {
+		t.Fatalf("GET /news/by-slug: status %d, %+v", status, single)
+	}
+
+	var updated statusResponse
+	do(t, srv, http.MethodPatch, "/news/edit/"+strconv.Itoa(created.ID), token, map[string]any{"Id": created.ID, "Title": "t", "Content": "c", "Slug": "Renamed News"}, &updated)
+	if updated.Status != "OK" {
+		t.Fatalf("rename: %+v", updated)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(srv.URL + "/news/by-slug/pervaya-novost")
+	if err != nil {
+		t.Fatalf("GET old slug: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "/news/by-slug/renamed-news" {
+		t.Fatalf("old slug: status %d, Location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	if status := do(t, srv, http.MethodGet, "/news/by-slug/pervaya-novost", "", nil, &single); status != http.StatusOK || single.Slug != "renamed-news" {
+		t.Fatalf("following the redirect: status %d, %+v", status, single)
+	}
+}
+
+func TestFeeds(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Feed = config.FeedCfg{Title: "News", BaseURL: "https://news.example.com", Items: 2}
+	forEachBackend(t, cfg, testFeeds)
+}
+
+func testFeeds(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	for _, news := range []map[string]any{
+		{"Title": "Oldest", "Content": "c", "Categories": []int{1}},
+		{"Title": "Middle", "Content": "c", "Categories": []int{2}},
+		{"Title": "Draft", "Content": "c", "Categories": []int{1}, "Published": false},
+		{"Title": "Newest", "Content": "c", "Categories": []int{1}},
+	} {
+		var created newsResponse
+		do(t, srv, http.MethodPost, "/news", token, news, &created)
+	}
+
+	get := func(path string, header http.Header) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := get("/feed.rss", nil)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/rss+xml") {
+		t.Fatalf("GET /feed.rss: status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(body, "<title>Newest</title>") || !strings.Contains(body, "<title>Middle</title>") ||
+		strings.Contains(body, "Oldest") || strings.Contains(body, "Draft") {
+		t.Fatalf("feed should hold the two newest published news:\n%s", body)
+	}
+	if !strings.Contains(body, "https://news.example.com/news/by-slug/newest") {
+		t.Fatalf("feed links should use the base URL:\n%s", body)
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("missing validators: ETag %q, Last-Modified %q", etag, lastModified)
+	}
+	if resp, _ := get("/feed.rss", http.Header{"If-None-Match": {etag}}); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("If-None-Match: status %d", resp.StatusCode)
+	}
+	if resp, _ := get("/feed.rss", http.Header{"If-Modified-Since": {lastModified}}); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("If-Modified-Since: status %d", resp.StatusCode)
+	}
+
+	resp, body = get("/feed.atom?limit=1", nil)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/atom+xml") {
+		t.Fatalf("GET /feed.atom: status %d", resp.StatusCode)
+	}
+	if strings.Count(body, "<entry>") != 1 || resp.Header.Get("ETag") == etag {
+		t.Fatalf("unexpected atom feed:\n%s", body)
+	}
+
+	_, body = get("/categories/1/feed.rss", nil)
+	if !strings.Contains(body, "Newest") || !strings.Contains(body, "Oldest") || strings.Contains(body, "Middle") {
+		t.Fatalf("category feed:\n%s", body)
+	}
+
+	if resp, _ := get("/feed.json", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET /feed.json: status %d", resp.StatusCode)
+	}
+}
+
+func TestListFormats(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testListFormats)
+}
+
+func testListFormats(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	for _, news := range []map[string]any{
+		{"Title": "Second", "Content": "with, comma", "Categories": []int{2}},
+		{"Title": "First", "Content": "plain", "Categories": []int{1, 2}},
+	} {
+		var created newsResponse
+		do(t, srv, http.MethodPost, "/news", token, news, &created)
+	}
+
+	get := func(path, accept string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	tests := []struct {
+		name, path, accept, contentType string
+	}{
+		{"default", "/list", "", "application/json"},
+		{"wildcard", "/list", "*/*", "application/json"},
+		{"unsupported accept", "/list", "image/png", "application/json"},
+		{"accept json feed", "/list", "application/feed+json", "application/feed+json"},
+		{"accept quality", "/list", "text/csv;q=0.5, application/x-ndjson", "application/x-ndjson"},
+		{"query", "/list?format=csv", "application/json", "text/csv"},
+		{"extension", "/list.ndjson", "", "application/x-ndjson"},
+	}
+	for _, tt := range tests {
+		resp, _ := get(tt.path, tt.accept)
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), tt.contentType) {
+			t.Errorf("%s: status %d, Content-Type %q, want %q", tt.name, resp.StatusCode, resp.Header.Get("Content-Type"), tt.contentType)
+		}
+	}
+
+	if resp, _ := get("/list?format=xml", ""); resp.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("unknown format: status %d", resp.StatusCode)
+	}
+
+	_, body := get("/list?format=jsonfeed", "")
+	var feed struct {
+		Version string `json:"version"`
+		Items   []struct {
+			ID          string   `json:"id"`
+			URL         string   `json:"url"`
+			Title       string   `json:"title"`
+			ContentHTML string   `json:"content_html"`
+			Tags        []string `json:"tags"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(body), &feed); err != nil {
+		t.Fatalf("decode json feed: %v\n%s", err, body)
+	}
+	if feed.Version != "https://jsonfeed.org/version/1.1" || len(feed.Items) != 2 {
+		t.Fatalf("unexpected json feed:\n%s", body)
+	}
+	if item := feed.Items[0]; item.Title != "First" || !strings.HasSuffix(item.URL, "/news/by-slug/first") ||
+		item.ContentHTML != "<p>plain</p>" || !slices.Equal(item.Tags, []string{"1", "2"}) {
+		t.Fatalf("unexpected json feed item: %+v", item)
+	}
+
+	_, body = get("/list?format=csv", "")
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "id,title,slug,published,categories") ||
+		!strings.HasSuffix(lines[2], `"with, comma"`) {
+		t.Fatalf("unexpected csv:\n%s", body)
+	}
+
+	_, body = get("/list", "application/x-ndjson")
+	lines = strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want one line per news, got:\n%s", body)
+	}
+	var item struct {
+		Title string `json:"Title"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &item); err != nil || item.Title != "Second" {
+		t.Fatalf("unexpected ndjson line %q: %v", lines[1], err)
+	}
+}
+
+func TestRichContent(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testRichContent)
+}
+
+func testRichContent(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	type richResponse struct {
+		statusResponse
+		ID            int    `json:"id"`
+		Slug          string `json:"slug"`
+		Summary       string `json:"summary"`
+		Content       string `json:"content"`
+		ContentFormat string `json:"content_format"`
+		ContentHTML   string `json:"content_html"`
+	}
+
+	var created richResponse
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{
+		"Title":         "Markdown",
+		"Summary":       "  The lede  ",
+		"Content":       "**Bold** [link](javascript:alert(1))<script>alert(1)</script>",
+		"ContentFormat": "markdown",
+	}, &created)
+	if created.Status != "OK" || created.Summary != "The lede" || created.ContentFormat != "markdown" ||
+		created.Content != "**Bold** [link](javascript:alert(1))<script>alert(1)</script>" ||
+		created.ContentHTML != "<p><strong>Bold</strong> <a>link</a></p>" {
+		t.Fatalf("create markdown news: %+v", created)
+	}
+
+	path := "/news/edit/" + strconv.Itoa(created.ID)
+	var updated statusResponse
+	do(t, srv, http.MethodPatch, path, token, map[string]any{
+		"Title":         "Markdown",
+		"Content":       `<p onclick="x()">Hi</p><iframe src="//evil"></iframe>`,
+		"ContentFormat": "html",
+	}, &updated)
+	if updated.Status != "OK" {
+		t.Fatalf("update news: %+v", updated)
+	}
+
+	var got richResponse
+	do(t, srv, http.MethodGet, "/news/by-slug/"+created.Slug, "", nil, &got)
+	if got.Summary != "The lede" || got.ContentFormat != "html" || got.Content != "<p>Hi</p>" || got.ContentHTML != "<p>Hi</p>" {
+		t.Fatalf("html content should be sanitized before it is stored: %+v", got)
+	}
+
+	if code := do(t, srv, http.MethodPatch, path, token, map[string]any{"Title": "x", "ContentFormat": "rtf"}, &updated); code != http.StatusBadRequest {
+		t.Fatalf("unknown content format: status %d", code)
+	}
+	long := strings.Repeat("я", 501)
+	if code := do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "x", "Summary": long}, &created); code != http.StatusBadRequest {
+		t.Fatalf("too long summary: status %d", code)
+	}
+
+	var plain richResponse
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Plain", "Content": "a < b\nc"}, &plain)
+	if plain.ContentFormat != "plain" || plain.ContentHTML != "<p>a &lt; b<br>\nc</p>" {
+		t.Fatalf("plain content: %+v", plain)
+	}
+}
+
+func TestTags(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testTags)
+}
+
+func testTags(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	type taggedResponse struct {
+		statusResponse
+		ID   int      `json:"id"`
+		Slug string   `json:"slug"`
+		Tags []string `json:"tags"`
+	}
+
+	var first, second taggedResponse
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "First", "Tags": []string{"#Go", " go ", "Release  Notes"}}, &first)
+	if first.Status != "OK" || !slices.Equal(first.Tags, []string{"go", "release notes"}) {
+		t.Fatalf("create tagged news: %+v", first)
+	}
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Second", "Tags": []string{"GO", "gopher"}}, &second)
+
+	type tagsResponse struct {
+		statusResponse
+		Tags []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		} `json:"tags"`
+	}
+	var tags tagsResponse
+	do(t, srv, http.MethodGet, "/tags?prefix=%23GO", token, nil, &tags)
+	if tags.Status != "OK" || len(tags.Tags) != 2 || tags.Tags[0].Name != "go" || tags.Tags[0].Count != 2 || tags.Tags[1].Name != "gopher" {
+		t.Fatalf("GET /tags?prefix=#GO = %+v", tags)
+	}
+	if code := do(t, srv, http.MethodGet, "/tags?limit=0", token, nil, &tags); code != http.StatusBadRequest {
+		t.Fatalf("invalid limit: status %d", code)
+	}
+
+	// Omitted tags are kept, an empty list clears them.
+	path := "/news/edit/" + strconv.Itoa(first.ID)
+	var updated statusResponse
+	do(t, srv, http.MethodPatch, path, token, map[string]any{"Title": "First"}, &updated)
+	var got taggedResponse
+	do(t, srv, http.MethodGet, "/news/by-slug/"+first.Slug, "", nil, &got)
+	if !slices.Equal(got.Tags, []string{"go", "release notes"}) {
+		t.Fatalf("tags should be kept when omitted: %+v", got)
+	}
+	do(t, srv, http.MethodPatch, path, token, map[string]any{"Title": "First", "Tags": []string{}}, &updated)
+	do(t, srv, http.MethodGet, "/news/by-slug/"+first.Slug, "", nil, &got)
+	if updated.Status != "OK" || len(got.Tags) != 0 {
+		t.Fatalf("tags should be cleared by an empty list: %+v", got)
+	}
+	do(t, srv, http.MethodGet, "/tags", token, nil, &tags)
+	if len(tags.Tags) != 2 || tags.Tags[0].Name != "go" || tags.Tags[0].Count != 1 {
+		t.Fatalf("GET /tags after clearing = %+v", tags)
+	}
+
+	var list struct {
+		News []struct {
+			Tags []string `json:"Tags"`
+		} `json:"News"`
+	}
+	do(t, srv, http.MethodGet, "/news", "", nil, &list)
+	if len(list.News) != 2 || !slices.Equal(list.News[0].Tags, []string{"go", "gopher"}) {
+		t.Fatalf("GET /news tags = %+v", list)
+	}
+
+	if code := do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "x", "Tags": []string{"a,b"}}, &got); code != http.StatusBadRequest {
+		t.Fatalf("invalid tag: status %d", code)
+	}
+}
+
+func TestRelatedNews(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testRelatedNews)
+}
+
+func testRelatedNews(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	create := func(body map[string]any) int {
+		var created struct {
+			statusResponse
+			ID int `json:"id"`
+		}
+		do(t, srv, http.MethodPost, "/news", token, body, &created)
+		if created.Status != "OK" {
+			t.Fatalf("create news: %+v", created)
+		}
+		return created.ID
+	}
+	src := create(map[string]any{"Title": "Go release", "Categories": []int{1}, "Tags": []string{"go"}})
+	byCategory := create(map[string]any{"Title": "Alpha", "Categories": []int{1}})
+	byTag := create(map[string]any{"Title": "Beta", "Tags": []string{"go"}})
+	create(map[string]any{"Title": "Weather"})
+	draft := create(map[string]any{"Title": "Go release draft", "Categories": []int{1}, "Published": false})
+
+	var list struct {
+		News []struct {
+			ID int `json:"Id"`
+		} `json:"News"`
+	}
+	path := "/news/" + strconv.Itoa(src) + "/related"
+	do(t, srv, http.MethodGet, path, "", nil, &list)
+	if len(list.News) != 2 || list.News[0].ID != byCategory || list.News[1].ID != byTag {
+		t.Fatalf("GET %s = %+v", path, list)
+	}
+	do(t, srv, http.MethodGet, path+"?limit=1", "", nil, &list)
+	if len(list.News) != 1 || list.News[0].ID != byCategory {
+		t.Fatalf("GET %s?limit=1 = %+v", path, list)
+	}
+
+	var out statusResponse
+	if code := do(t, srv, http.MethodGet, path+"?limit=0", "", nil, &out); code != http.StatusBadRequest {
+		t.Fatalf("invalid limit: status %d", code)
+	}
+	if code := do(t, srv, http.MethodGet, "/news/"+strconv.Itoa(draft)+"/related", "", nil, &out); code != http.StatusNotFound {
+		t.Fatalf("related news of a draft: status %d", code)
+	}
+}
+
+// TestViews builds its servers itself, the test flushes the counter.
+func TestViews(t *testing.T) {
+	for name, newRepos := range backends {
+		t.Run(name, func(t *testing.T) {
+			log := dbtest.Logger()
+			repos := newRepos(t)
+			repos.ViewCounter = views.NewCounter(repos.Views, time.Hour, log)
+			srv := httptest.NewServer(router.New(log, &config.Config{}, repos, jwt.NewJWTManager("test-secret", log)))
+			t.Cleanup(srv.Close)
+			testViews(t, srv, repos.ViewCounter)
+		})
+	}
+}
+
+func testViews(t *testing.T, srv *httptest.Server, counter *views.Counter) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	var popular, quiet, draft newsResponse
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Popular"}, &popular)
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Quiet"}, &quiet)
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Draft", "Published": false}, &draft)
+
+	view := func(id int, userAgent string) (int, bool) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/news/"+strconv.Itoa(id)+"/views", nil)
+		req.Header.Set("User-Agent", userAgent)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("view: %v", err)
+		}
+		defer resp.Body.Close()
+		var out struct {
+			statusResponse
+			Counted bool `json:"counted"`
+		}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out.Counted
+	}
+
+	if code, counted := view(popular.ID, "a"); code != http.StatusAccepted || !counted {
+		t.Fatalf("first view: status %d, counted %v", code, counted)
+	}
+	if _, counted := view(popular.ID, "a"); counted {
+		t.Fatal("a repeated view should not be counted")
+	}
+	view(popular.ID, "b")
+	view(quiet.ID, "a")
+	if code, _ := view(draft.ID, "a"); code != http.StatusNotFound {
+		t.Fatalf("view of a draft: status %d", code)
+	}
+
+	var list struct {
+		News []struct {
+			ID int `json:"Id"`
+		} `json:"News"`
+	}
+	do(t, srv, http.MethodGet, "/news/trending", "", nil, &list)
+	if len(list.News) != 0 {
+		t.Fatalf("views should be buffered until flushed: %+v", list)
+	}
+
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	do(t, srv, http.MethodGet, "/news/trending?window=1h", "", nil, &list)
+	if len(list.News) != 2 || list.News[0].ID != popular.ID || list.News[1].ID != quiet.ID {
+		t.Fatalf("GET /news/trending = %+v", list)
+	}
+	do(t, srv, http.MethodGet, "/news/trending?limit=1", "", nil, &list)
+	if len(list.News) != 1 || list.News[0].ID != popular.ID {
+		t.Fatalf("GET /news/trending?limit=1 = %+v", list)
+	}
+
+	var out statusResponse
+	for _, query := range []string{"window=1m", "window=1000h", "window=day"} {
+		if code := do(t, srv, http.MethodGet, "/news/trending?"+query, "", nil, &out); code != http.StatusBadRequest {
+			t.Fatalf("GET /news/trending?%s: status %d", query, code)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Comments = config.CommentsCfg{
+		EditWindow: time.Hour,
+		RateLimit:  config.LimitCfg{Requests: 1, Per: time.Hour, Burst: 3},
+		Moderators: []string{"mod@example.com", "mod2@example.com"},
+	}
+	forEachBackend(t, cfg, testComments)
+}
+
+// TestCommentsWithoutModerators checks that nobody moderates when no
+// moderators are configured.
+func TestCommentsWithoutModerators(t *testing.T) {
+	forEachBackend(t, &config.Config{}, func(t *testing.T, srv *httptest.Server) {
+		token := register(t, srv, "reader@example.com", "secret")
+		var news newsResponse
+		do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Discussed"}, &news)
+		var created struct {
+			Comment struct {
+				ID int `json:"id"`
+			} `json:"comment"`
+		}
+		do(t, srv, http.MethodPost, "/news/"+strconv.Itoa(news.ID)+"/comments", token, map[string]any{"body": "Mine"}, &created)
+
+		var out map[string]any
+		if code := do(t, srv, http.MethodGet, "/comments/pending", token, nil, &out); code != http.StatusForbidden {
+			t.Errorf("moderation queue: status %d", code)
+		}
+		if code := do(t, srv, http.MethodPost, "/comments/"+strconv.Itoa(created.Comment.ID)+"/approve", token, nil, &out); code != http.StatusForbidden {
+			t.Errorf("approve comment: status %d", code)
+		}
+	})
+}
+
+func testComments(t *testing.T, srv *httptest.Server) {
+	reader := register(t, srv, "reader@example.com", "secret")
+	mod := register(t, srv, "mod@example.com", "secret")
+
+	var news newsResponse
+	do(t, srv, http.MethodPost, "/news", mod, map[string]any{"Title": "Discussed"}, &news)
+	path := "/news/" + strconv.Itoa(news.ID) + "/comments"
+
+	type comment struct {
+		ID       int       `json:"id"`
+		ParentID int       `json:"parent_id"`
+		Body     string    `json:"body"`
+		Status   string    `json:"status"`
+		Edited   bool      `json:"edited"`
+		Deleted  bool      `json:"deleted"`
+		Replies  []comment `json:"replies"`
+	}
+	type commentResponse struct {
+		statusResponse
+		Comment comment `json:"comment"`
+	}
+	type commentsResponse struct {
+		statusResponse
+		Comments []comment `json:"comments"`
+	}
+	thread := func() []comment {
+		t.Helper()
+		var out commentsResponse
+		if code := do(t, srv, http.MethodGet, path, "", nil, &out); code != http.StatusOK {
+			t.Fatalf("GET %s: status %d", path, code)
+		}
+		return out.Comments
+	}
+	moderate := func(id int, action string) {
+		t.Helper()
+		var out commentResponse
+		if code := do(t, srv, http.MethodPost, "/comments/"+strconv.Itoa(id)+"/"+action, mod, nil, &out); code != http.StatusOK {
+			t.Fatalf("%s comment: status %d, %+v", action, code, out)
+		}
+	}
+
+	var first commentResponse
+	if code := do(t, srv, http.MethodPost, path, reader, map[string]any{"body": "  First!  "}, &first); code != http.StatusCreated ||
+		first.Comment.Body != "First!" || first.Comment.Status != "pending" {
+		t.Fatalf("create comment: status %d, %+v", code, first)
+	}
+	if got := thread(); len(got) != 0 {
+		t.Fatalf("pending comments should be hidden: %+v", got)
+	}
+
+	var queue commentsResponse
+	if code := do(t, srv, http.MethodGet, "/comments/pending", reader, nil, &queue); code != http.StatusForbidden {
+		t.Fatalf("moderation queue for a reader: status %d", code)
+	}
+	do(t, srv, http.MethodGet, "/comments/pending", mod, nil, &queue)
+	if len(queue.Comments) != 1 || queue.Comments[0].ID != first.Comment.ID {
+		t.Fatalf("moderation queue = %+v", queue)
+	}
+	moderate(first.Comment.ID, "approve")
+
+	var reply commentResponse
+	do(t, srv, http.MethodPost, path, mod, map[string]any{"body": "Reply", "parent_id": first.Comment.ID}, &reply)
+	var own commentResponse
+	if code := do(t, srv, http.MethodPost, "/comments/"+strconv.Itoa(reply.Comment.ID)+"/approve", mod, nil, &own); code != http.StatusForbidden {
+		t.Fatalf("approval of the moderator's own comment: status %d", code)
+	}
+	mod2 := register(t, srv, "mod2@example.com", "secret")
+	if code := do(t, srv, http.MethodPost, "/comments/"+strconv.Itoa(reply.Comment.ID)+"/approve", mod2, nil, &own); code != http.StatusOK {
+		t.Fatalf("approve comment: status %d, %+v", code, own)
+	}
+	got := thread()
+	if len(got) != 1 || got[0].Body != "First!" || len(got[0].Replies) != 1 || got[0].Replies[0].ID != reply.Comment.ID {
+		t.Fatalf("thread = %+v", got)
+	}
+
+	var list struct {
+		News []struct {
+			CommentsCount int `json:"CommentsCount"`
+		} `json:"News"`
+	}
+	do(t, srv, http.MethodGet, "/news", "", nil, &list)
+	if len(list.News) != 1 || list.News[0].CommentsCount != 2 {
+		t.Fatalf("comment count in the news list = %+v", list)
+	}
+
+	var out commentResponse
+	if code := do(t, srv, http.MethodPost, path, reader, map[string]any{"body": "x", "parent_id": reply.Comment.ID + 100}, &out); code != http.StatusBadRequest {
+		t.Fatalf("reply to a missing comment: status %d", code)
+	}
+	if code := do(t, srv, http.MethodPatch, "/comments/"+strconv.Itoa(reply.Comment.ID), reader, map[string]any{"body": "mine"}, &out); code != http.StatusForbidden {
+		t.Fatalf("edit of another user's comment: status %d", code)
+	}
+	if code := do(t, srv, http.MethodPatch, "/comments/"+strconv.Itoa(first.Comment.ID), reader, map[string]any{"body": "Edited"}, &out); code != http.StatusOK ||
+		out.Comment.Status != "pending" || !out.Comment.Edited {
+		t.Fatalf("edit comment: status %d, %+v", code, out)
+	}
+	if got := thread(); len(got) != 0 {
+		t.Fatalf("edited comments should be moderated again: %+v", got)
+	}
+	moderate(first.Comment.ID, "approve")
+	if code := do(t, srv, http.MethodPost, "/comments/"+strconv.Itoa(first.Comment.ID)+"/reject", mod, nil, &out); code != http.StatusConflict {
+		t.Fatalf("moderation of a comment no longer pending: status %d, %+v", code, out)
+	}
+
+	if code := do(t, srv, http.MethodDelete, "/comments/"+strconv.Itoa(reply.Comment.ID), reader, nil, &out); code != http.StatusForbidden {
+		t.Fatalf("delete of another user's comment: status %d", code)
+	}
+	do(t, srv, http.MethodDelete, "/comments/"+strconv.Itoa(first.Comment.ID), reader, nil, &out)
+	got = thread()
+	if len(got) != 1 || !got[0].Deleted || got[0].Body != "" || len(got[0].Replies) != 1 {
+		t.Fatalf("a deleted comment should keep its replies: %+v", got)
+	}
+
+	// The burst of 3 is used up by the reader's fourth comment.
+	if code := do(t, srv, http.MethodPost, path, reader, map[string]any{"body": "again"}, &out); code != http.StatusCreated {
+		t.Fatalf("third comment: status %d", code)
+	}
+	if code := do(t, srv, http.MethodPost, path, reader, map[string]any{"body": "spam"}, &out); code != http.StatusTooManyRequests {
+		t.Fatalf("comment over the rate limit: status %d", code)
+	}
+	if code := do(t, srv, http.MethodPost, path, mod, map[string]any{"body": "still fine"}, &out); code != http.StatusCreated {
+		t.Fatalf("the limit is per user: status %d", code)
+	}
+}
+
+func TestReactionsAndBookmarks(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testReactionsAndBookmarks)
+}
+
+func testReactionsAndBookmarks(t *testing.T, srv *httptest.Server) {
+	alice := register(t, srv, "alice@example.com", "secret")
+	bob := register(t, srv, "bob@example.com", "secret")
+
+	var first, second, draft newsResponse
+	do(t, srv, http.MethodPost, "/news", alice, map[string]any{"Title": "First"}, &first)
+	do(t, srv, http.MethodPost, "/news", alice, map[string]any{"Title": "Second"}, &second)
+	do(t, srv, http.MethodPost, "/news", alice, map[string]any{"Title": "Draft", "Published": false}, &draft)
+
+	type reactionsResponse struct {
+		statusResponse
+		Reactions map[string]int `json:"reactions"`
+		Mine      []string       `json:"mine"`
+	}
+	react := func(method, token string, id int, kind string) (int, reactionsResponse) {
+		t.Helper()
+		var out reactionsResponse
+		code := do(t, srv, method, "/news/"+strconv.Itoa(id)+"/reactions/"+kind, token, nil, &out)
+		return code, out
+	}
+
+	// Repeating a reaction does not count it twice.
+	react(http.MethodPut, alice, first.ID, "like")
+	react(http.MethodPut, alice, first.ID, "like")
+	react(http.MethodPut, alice, first.ID, "wow")
+	code, out := react(http.MethodPut, bob, first.ID, "like")
+	if code != http.StatusOK || out.Reactions["like"] != 2 || out.Reactions["wow"] != 1 || !slices.Equal(out.Mine, []string{"like"}) {
+		t.Fatalf("PUT reaction: status %d, %+v", code, out)
+	}
+	for i := 0; i < 2; i++ {
+		code, out = react(http.MethodDelete, alice, first.ID, "like")
+		if code != http.StatusOK || out.Reactions["like"] != 1 || !slices.Equal(out.Mine, []string{"wow"}) {
+			t.Fatalf("DELETE reaction: status %d, %+v", code, out)
+		}
+	}
+	if code, _ := react(http.MethodPut, alice, first.ID, "meh"); code != http.StatusBadRequest {
+		t.Fatalf("unknown reaction: status %d", code)
+	}
+	if code, _ := react(http.MethodPut, alice, draft.ID, "like"); code != http.StatusNotFound {
+		t.Fatalf("reaction to a draft: status %d", code)
+	}
+
+	var list struct {
+		News []struct {
+			ID        int            `json:"Id"`
+			Reactions map[string]int `json:"Reactions"`
+		} `json:"News"`
+	}
+	do(t, srv, http.MethodGet, "/news", "", nil, &list)
+	if len(list.News) != 2 || list.News[1].ID != first.ID || list.News[1].Reactions["like"] != 1 || list.News[1].Reactions["wow"] != 1 ||
+		list.News[0].Reactions == nil || len(list.News[0].Reactions) != 0 {
+		t.Fatalf("reaction counts in the news list = %+v", list)
+	}
+
+	bookmark := func(method string, id int) int {
+		t.Helper()
+		var out struct {
+			statusResponse
+			Bookmarked bool `json:"bookmarked"`
+		}
+		code := do(t, srv, method, "/news/"+strconv.Itoa(id)+"/bookmark", bob, nil, &out)
+		if code == http.StatusOK && out.Bookmarked != (method == http.MethodPut) {
+			t.Fatalf("%s bookmark: %+v", method, out)
+		}
+		return code
+	}
+	for _, id := range []int{first.ID, second.ID, first.ID} {
+		if code := bookmark(http.MethodPut, id); code != http.StatusOK {
+			t.Fatalf("PUT bookmark: status %d", code)
+		}
+	}
+	if code := bookmark(http.MethodPut, draft.ID); code != http.StatusNotFound {
+		t.Fatalf("bookmark of a draft: status %d", code)
+	}
+
+	bookmarks := func(token, query string) []int {
+		t.Helper()
+		list.News = nil
+		if code := do(t, srv, http.MethodGet, "/users/me/bookmarks"+query, token, nil, &list); code != http.StatusOK {
+			t.Fatalf("GET bookmarks: status %d", code)
+		}
+		var ids []int
+		for _, n := range list.News {
+			ids = append(ids, n.ID)
+		}
+		return ids
+	}
+	if got := bookmarks(bob, ""); !slices.Equal(got, []int{second.ID, first.ID}) {
+		t.Fatalf("bookmarks = %v", got)
+	}
+	if got := bookmarks(bob, "?limit=1&offset=1"); !slices.Equal(got, []int{first.ID}) {
+		t.Fatalf("second page of bookmarks = %v", got)
+	}
+	if got := bookmarks(alice, ""); len(got) != 0 {
+		t.Fatalf("bookmarks are per user, got %v", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := bookmark(http.MethodDelete, second.ID); code != http.StatusOK {
+			t.Fatalf("DELETE bookmark: status %d", code)
+		}
+	}
+	if got := bookmarks(bob, ""); !slices.Equal(got, []int{first.ID}) {
+		t.Fatalf("bookmarks after delete = %v", got)
+	}
+}
+
+func TestPersonalFeed(t *testing.T) {
+	forEachBackend(t, &config.Config{}, testPersonalFeed)
+}
+
+func testPersonalFeed(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "reader@example.com", "secret")
+
+	type subscriptionsResponse struct {
+		statusResponse
+		Categories []int `json:"categories"`
+	}
+	var subs subscriptionsResponse
+	for _, category := range []string{"2", "1", "1", "3"} {
+		if code := do(t, srv, http.MethodPut, "/users/me/subscriptions/"+category, token, nil, &subs); code != http.StatusOK {
+			t.Fatalf("subscribe to %s: status %d", category, code)
+		}
+	}
+	do(t, srv, http.MethodDelete, "/users/me/subscriptions/3", token, nil, &subs)
+	if !slices.Equal(subs.Categories, []int{1, 2}) {
+		t.Fatalf("subscriptions = %+v", subs)
+	}
+	if code := do(t, srv, http.MethodPut, "/users/me/subscriptions/news", token, nil, &subs); code != http.StatusBadRequest {
+		t.Fatalf("subscribe to an invalid category: status %d", code)
+	}
+	do(t, srv, http.MethodGet, "/users/me/subscriptions", token, nil, &subs)
+	if !slices.Equal(subs.Categories, []int{1, 2}) {
+		t.Fatalf("GET subscriptions = %+v", subs)
+	}
+
+	var want []int
+	for i, n := range []map[string]any{
+		{"Title": "One", "Categories": []int{1}},
+		{"Title": "Other", "Categories": []int{3}},
+		{"Title": "Two", "Categories": []int{2, 3}},
+		{"Title": "Draft", "Categories": []int{1}, "Published": false},
+		{"Title": "Both", "Categories": []int{1, 2}},
+	} {
+		var news newsResponse
+		do(t, srv, http.MethodPost, "/news", token, n, &news)
+		if i%2 == 0 {
+			want = append([]int{news.ID}, want...)
+		}
+	}
+
+	page := func(path string) ([]int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var list struct {
+			News []struct {
+				ID int `json:"Id"`
+			} `json:"News"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&list); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status %d, %v", path, resp.StatusCode, err)
+		}
+		var ids []int
+		for _, n := range list.News {
+			ids = append(ids, n.ID)
+		}
+		// Legacy routes also link to their /v1 successor.
+		next := ""
+		for _, link := range resp.Header.Values("Link") {
+			if strings.HasSuffix(link, `rel="next"`) {
+				next = link
+			}
+		}
+		return ids, next
+	}
+
+	if got, link := page("/feed/me"); !slices.Equal(got, want) || link != "" {
+		t.Fatalf("feed = %v, link %q; want %v", got, link, want)
+	}
+	got, link := page("/feed/me?limit=2")
+	nextPath := "/feed/me?before=" + strconv.Itoa(want[1]) + "&limit=2"
+	if !slices.Equal(got, want[:2]) || link != "<"+nextPath+`>; rel="next"` {
+		t.Fatalf("first page = %v, link %q", got, link)
+	}
+	if got, _ := page(nextPath); !slices.Equal(got, want[2:]) {
+		t.Fatalf("second page = %v, want %v", got, want[2:])
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	for name, newRepos := range backends {
+		t.Run(name, func(t *testing.T) {
+			log := dbtest.Logger()
+			repos := newRepos(t)
+			repos.ViewCounter = views.NewCounter(repos.Views, time.Hour, log)
+			// The receivers listen on loopback.
+			cfg := &config.Config{Webhooks: config.WebhooksCfg{AllowPrivateNetworks: true}}
+			srv := httptest.NewServer(router.New(log, cfg, repos, jwt.NewJWTManager("test-secret", log)))
+			t.Cleanup(srv.Close)
+			dispatcher := webhook.NewDispatcher(repos.Webhooks, webhook.Options{MaxAttempts: 1, AllowPrivateNetworks: true}, log)
+			testWebhooks(t, srv, dispatcher)
+		})
+	}
+}
+
+func testWebhooks(t *testing.T, srv *httptest.Server, dispatcher *webhook.Dispatcher) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 10)
+	var fail atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{r.Header, body}
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(receiver.Close)
+
+	type webhookResponse struct {
+		statusResponse
+		Webhook struct {
+			ID     int      `json:"id"`
+			URL    string   `json:"url"`
+			Events []string `json:"events"`
+			Secret string   `json:"secret"`
+		} `json:"webhook"`
+	}
+	var out statusResponse
+	for _, bad := range []map[string]any{
+		{"url": "ftp://example.com", "events": []string{"news.created"}},
+		{"url": receiver.URL},
+		{"url": receiver.URL, "events": []string{"news.read"}},
+	} {
+		if code := do(t, srv, http.MethodPost, "/webhooks", token, bad, &out); code != http.StatusBadRequest {
+			t.Fatalf("POST /webhooks %v: status %d", bad, code)
+		}
+	}
+	var created webhookResponse
+	code := do(t, srv, http.MethodPost, "/webhooks", token, map[string]any{"url": receiver.URL, "events": []string{"news.created", "news.created"}}, &created)
+	if code != http.StatusCreated || created.Webhook.Secret == "" || !slices.Equal(created.Webhook.Events, []string{"news.created"}) {
+		t.Fatalf("POST /webhooks: status %d, %+v", code, created)
+	}
+	var got webhookResponse
+	do(t, srv, http.MethodGet, "/webhooks/"+strconv.Itoa(created.Webhook.ID), token, nil, &got)
+	if got.Webhook.URL != receiver.URL || got.Webhook.Secret != "" {
+		t.Fatalf("GET webhook = %+v, want the secret hidden", got)
+	}
+
+	// Webhooks are only seen and managed by their owner.
+	other := register(t, srv, "other@example.com", "secret")
+	var list struct {
+		statusResponse
+		Webhooks []struct {
+			ID int `json:"id"`
+		} `json:"webhooks"`
+	}
+	do(t, srv, http.MethodGet, "/webhooks", other, nil, &list)
+	if len(list.Webhooks) != 0 {
+		t.Fatalf("GET /webhooks of another user = %+v", list)
+	}
+	id := strconv.Itoa(created.Webhook.ID)
+	for _, path := range []string{"/webhooks/" + id, "/webhooks/" + id + "/deliveries"} {
+		if code := do(t, srv, http.MethodGet, path, other, nil, &out); code != http.StatusNotFound {
+			t.Fatalf("GET %s of another user: status %d", path, code)
+		}
+	}
+	if code := do(t, srv, http.MethodDelete, "/webhooks/"+id, other, nil, &out); code != http.StatusNotFound {
+		t.Fatalf("DELETE webhook of another user: status %d", code)
+	}
+	do(t, srv, http.MethodGet, "/webhooks", token, nil, &list)
+	if len(list.Webhooks) != 1 || list.Webhooks[0].ID != created.Webhook.ID {
+		t.Fatalf("GET /webhooks = %+v", list)
+	}
+
+	var news newsResponse
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Hooked", "Categories": []int{1}, "Tags": []string{"go"}}, &news)
+	do(t, srv, http.MethodPatch, "/news/edit/"+strconv.Itoa(news.ID), token, map[string]any{"Id": news.ID, "Title": "Edited"}, &out)
+
+	if n, err := dispatcher.DeliverDue(context.Background()); err != nil || n != 1 {
+		t.Fatalf("DeliverDue = %d, %v, want only the news.created delivery", n, err)
+	}
+	req := <-requests
+	if req.header.Get(webhook.HeaderEvent) != "news.created" {
+		t.Fatalf("event header = %q", req.header.Get(webhook.HeaderEvent))
+	}
+	if err := webhook.Verify(created.Webhook.Secret, req.header.Get(webhook.HeaderSignature), req.body, time.Now(), time.Minute); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	var event struct {
+		Type string `json:"type"`
+		Data struct {
+			ID         int      `json:"id"`
+			Title      string   `json:"title"`
+			Categories []int    `json:"categories"`
+			Tags       []string `json:"tags"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(req.body, &event); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if event.Type != "news.created" || event.Data.ID != news.ID || event.Data.Title != "Hooked" ||
+		!slices.Equal(event.Data.Categories, []int{1}) || !slices.Equal(event.Data.Tags, []string{"go"}) {
+		t.Fatalf("payload = %s", req.body)
+	}
+
+	// A failing endpoint dead-letters the delivery after MaxAttempts.
+	fail.Store(true)
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Second"}, &news)
+	dispatcher.DeliverDue(context.Background())
+	<-requests
+
+	type deliveryItem struct {
+		ID       int    `json:"id"`
+		Event    string `json:"event"`
+		Status   string `json:"status"`
+		Attempts int    `json:"attempts"`
+		Log      []struct {
+			StatusCode int `json:"status_code"`
+		} `json:"log"`
+	}
+	var deliveries struct {
+		statusResponse
+		Deliveries []deliveryItem `json:"deliveries"`
+	}
+	path := "/webhooks/" + strconv.Itoa(created.Webhook.ID) + "/deliveries"
+	do(t, srv, http.MethodGet, path, token, nil, &deliveries)
+	if len(deliveries.Deliveries) != 2 || deliveries.Deliveries[0].Status != "dead" || deliveries.Deliveries[1].Status != "delivered" {
+		t.Fatalf("GET deliveries = %+v", deliveries)
+	}
+	dead := strconv.Itoa(deliveries.Deliveries[0].ID)
+	var delivery struct {
+		statusResponse
+		Delivery deliveryItem `json:"delivery"`
+	}
+	do(t, srv, http.MethodGet, path+"/"+dead, token, nil, &delivery)
+	if len(delivery.Delivery.Log) != 1 || delivery.Delivery.Log[0].StatusCode != http.StatusInternalServerError {
+		t.Fatalf("GET delivery = %+v", delivery)
+	}
+	delivered := strconv.Itoa(deliveries.Deliveries[1].ID)
+	if code := do(t, srv, http.MethodPost, path+"/"+delivered+"/retry", token, nil, &out); code != http.StatusConflict {
+		t.Fatalf("retry a delivered delivery: status %d", code)
+	}
+
+	fail.Store(false)
+	do(t, srv, http.MethodPost, path+"/"+dead+"/retry", token, nil, &delivery)
+	if delivery.Delivery.Status != "pending" || delivery.Delivery.Attempts != 0 {
+		t.Fatalf("retry = %+v", delivery)
+	}
+	if n, _ := dispatcher.DeliverDue(context.Background()); n != 1 {
+		t.Fatalf("DeliverDue after retry = %d", n)
+	}
+	<-requests
+	do(t, srv, http.MethodGet, path+"/"+dead, token, nil, &delivery)
+	if delivery.Delivery.Status != "delivered" || len(delivery.Delivery.Log) != 2 {
+		t.Fatalf("retried delivery = %+v", delivery)
+	}
+
+	do(t, srv, http.MethodDelete, "/webhooks/"+strconv.Itoa(created.Webhook.ID), token, nil, &out)
+	if code := do(t, srv, http.MethodGet, path, token, nil, &out); code != http.StatusNotFound {
+		t.Fatalf("deliveries of a deleted webhook: status %d", code)
+	}
+}
+
+func TestWebhooksRefusePrivateAddresses(t *testing.T) {
+	forEachBackend(t, &config.Config{}, func(t *testing.T, srv *httptest.Server) {
+		token := register(t, srv, "editor@example.com", "secret")
+		for _, url := range []string{
+			"http://127.0.0.1:8080/hook",
+			"http://localhost/hook",
+			"http://10.0.0.1/hook",
+			"http://169.254.169.254/latest/meta-data",
+			"http://[::1]/hook",
+			"http://0.0.0.0/hook",
+		} {
+			var out statusResponse
+			code := do(t, srv, http.MethodPost, "/webhooks", token, map[string]any{"url": url, "events": []string{"news.created"}}, &out)
+			if code != http.StatusBadRequest {
+				t.Fatalf("POST /webhooks %s: status %d", url, code)
+			}
+		}
+	})
+}
+
+func TestNewsStream(t *testing.T) {
+	for name, newRepos := range backends {
+		t.Run(name, func(t *testing.T) {
+			log := dbtest.Logger()
+			repos := newRepos(t)
+			repos.ViewCounter = views.NewCounter(repos.Views, time.Hour, log)
+			repos.NewsStream = stream.NewHub(repos.NewsEvents, stream.Options{}, log)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				repos.NewsStream.Run(ctx)
+			}()
+			t.Cleanup(func() {
+				cancel()
+				<-done
+			})
+			cfg := &config.Config{Stream: config.StreamCfg{Heartbeat: 50 * time.Millisecond, Retry: time.Second}}
+			srv := httptest.NewServer(router.New(log, cfg, repos, jwt.NewJWTManager("test-secret", log)))
+			t.Cleanup(srv.Close)
+			testNewsStream(t, srv)
+		})
+	}
+}
+
+type sseEvent struct {
+	id, event, data string
+}
+
+// openStream reads the Server-Sent Events of path, heartbeats are sent as
+// events named "heartbeat".
+func openStream(t *testing.T, srv *httptest.Server, path, token, lastEventID string) <-chan sseEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET %s: status %d, content type %q", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	t.Cleanup(func() {
+		cancel()
+		resp.Body.Close()
+	})
+
+	events := make(chan sseEvent, 100)
+	go func() {
+		defer close(events)
+		var event sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			field, value, _ := strings.Cut(line, ": ")
+			switch {
+			case line == "":
+				if event != (sseEvent{}) {
+					events <- event
+				}
+				event = sseEvent{}
+			case line == ": heartbeat":
+				event.event = "heartbeat"
+			case field == "id":
+				event.id = value
+			case field == "event":
+				event.event = value
+			case field == "data":
+				event.data = value
+			}
+		}
+	}()
+	return events
+}
+
+// nextEvent skips heartbeats and retry advice.
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("stream closed")
+			}
+			if event.event != "heartbeat" && event.event != "" {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("no event received")
+		}
+	}
+}
+
+func testNewsStream(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	var out statusResponse
+	do(t, srv, http.MethodGet, "/news/stream", "", nil, &out)
+	if out.Status != "Error" {
+		t.Fatalf("GET /news/stream without a token = %+v", out)
+	}
+
+	live := openStream(t, srv, "/news/stream", token, "")
+	var news newsResponse
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Breaking", "Published": false}, &news)
+	created := nextEvent(t, live)
+	var item struct {
+		ID        int    `json:"Id"`
+		Title     string `json:"Title"`
+		Published bool   `json:"Published"`
+	}
+	if err := json.Unmarshal([]byte(created.data), &item); err != nil || created.event != "news.created" || created.id == "" ||
+		item.ID != news.ID || item.Title != "Breaking" || item.Published {
+		t.Fatalf("first event = %+v, %v", created, err)
+	}
+
+	do(t, srv, http.MethodPatch, "/news/edit/"+strconv.Itoa(news.ID), token, map[string]any{"Id": news.ID, "Title": "Breaking: update", "Published": true}, &out)
+	updated := nextEvent(t, live)
+	json.Unmarshal([]byte(updated.data), &item)
+	if updated.event != "news.updated" || item.Title != "Breaking: update" || !item.Published || updated.id <= created.id {
+		t.Fatalf("second event = %+v", updated)
+	}
+
+	// Heartbeats keep the connection busy between events.
+	timeout := time.After(5 * time.Second)
+	for heartbeat := false; !heartbeat; {
+		select {
+		case event := <-live:
+			heartbeat = event.event == "heartbeat"
+		case <-timeout:
+			t.Fatal("no heartbeat")
+		}
+	}
+
+	// A reconnecting client gets what it missed, then live events.
+	resumed := openStream(t, srv, "/news/stream", token, created.id)
+	if event := nextEvent(t, resumed); event.id != updated.id {
+		t.Fatalf("resumed with %+v, want the missed update %s", event, updated.id)
+	}
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "Later"}, &news)
+	if event := nextEvent(t, resumed); event.event != "news.created" {
+		t.Fatalf("live event after resuming = %+v", event)
+	}
+	if event := nextEvent(t, live); event.event != "news.created" {
+		t.Fatalf("live event = %+v", event)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/news/stream?last_event_id=x", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid last_event_id: status %d", resp.StatusCode)
+	}
+}
+
+func TestMediaUpload(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Media = config.MediaCfg{MaxSize: 64 << 10, MaxFiles: 2, ThumbnailSize: 50, AllowedTypes: []string{"image/png", "application/pdf"}}
+	forEachBackend(t, cfg, testMediaUpload)
+}
+
+func testMediaUpload(t *testing.T, srv *httptest.Server) {
+	token := register(t, srv, "editor@example.com", "secret")
+
+	var created newsResponse
+	do(t, srv, http.MethodPost, "/news", token, map[string]any{"Title": "With media", "Content": "c"}, &created)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.NRGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+
+	type file struct {
+		name string
+		data []byte
+	}
+	upload := func(token string, newsID int, files ...file) (int, map[string]any) {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("caption", "ignored")
+		for _, f := range files {
+			fw, _ := mw.CreateFormFile("file", f.name)
+			fw.Write(f.data)
+		}
+		mw.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/news/"+strconv.Itoa(newsID)+"/media", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out map[string]any
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	pdf := []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\n")
+	code, out := upload(token, created.ID, file{"../photo.png", pngData.Bytes()}, file{"doc.pdf", pdf})
+	if code != http.StatusCreated {
+		t.Fatalf("upload: status %d, %v", code, out)
+	}
+	media := out["media"].([]any)
+	if len(media) != 2 {
+		t.Fatalf("upload should return both files: %v", out)
+	}
+	photo := media[0].(map[string]any)
+	if photo["file_name"] != "photo.png" || photo["content_type"] != "image/png" ||
+		photo["width"] != 200.0 || photo["height"] != 100.0 || photo["thumbnail_url"] == nil {
+		t.Fatalf("unexpected image media: %v", photo)
+	}
+	if doc := media[1].(map[string]any); doc["content_type"] != "application/pdf" || doc["thumbnail_url"] != nil {
+		t.Fatalf("unexpected pdf media: %v", doc)
+	}
+
+	resp, err := srv.Client().Get(srv.URL + photo["url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(stored, pngData.Bytes()) {
+		t.Fatalf("GET %s: status %d, %d bytes", photo["url"], resp.StatusCode, len(stored))
+	}
+	resp, err = srv.Client().Get(srv.URL + photo["thumbnail_url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, _, err := image.DecodeConfig(resp.Body)
+	resp.Body.Close()
+	if err != nil || thumb.Width != 50 || thumb.Height != 25 {
+		t.Fatalf("thumbnail: %+v, %v", thumb, err)
+	}
+
+	var news struct {
+		Media []struct {
+			URL string `json:"url"`
+		} `json:"media"`
+	}
+	do(t, srv, http.MethodGet, "/news/"+strconv.Itoa(created.ID), "", nil, &news)
+	if len(news.Media) != 2 || news.Media[0].URL != photo["url"] {
+		t.Fatalf("news should list its media: %+v", news)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		id    int
+		files []file
+		code  int
+	}{
+		{"missing news", token, created.ID + 100, []file{{"a.png", pngData.Bytes()}}, http.StatusNotFound},
+		{"no file", token, created.ID, nil, http.StatusBadRequest},
+		{"disallowed type", token, created.ID, []file{{"a.png", []byte("<html><script>x</script></html>")}}, http.StatusUnsupportedMediaType},
+		{"too large", token, created.ID, []file{{"a.pdf", append(pdf, make([]byte, 64<<10)...)}}, http.StatusRequestEntityTooLarge},
+		{"too many files", token, created.ID, []file{{"a.pdf", pdf}, {"b.pdf", pdf}, {"c.pdf", pdf}}, http.StatusBadRequest},
+		{"broken image", token, created.ID, []file{{"a.png", pngData.Bytes()[:100]}}, http.StatusBadRequest},
+		{"too many pixels", token, created.ID, []file{{"a.png", pngBomb(t, 50000, 50000)}}, http.StatusRequestEntityTooLarge},
+	}
+	if _, out := upload("", created.ID, file{"a.png", pngData.Bytes()}); out["status"] != "Error" {
+		t.Errorf("upload without a token: %v", out)
+	}
+	for _, tt := range tests {
+		if code, out := upload(tt.token, tt.id, tt.files...); code != tt.code {
+			t.Errorf("%s: status %d, want %d: %v", tt.name, code, tt.code, out)
+		}
+	}
+	do(t, srv, http.MethodGet, "/news/"+strconv.Itoa(created.ID), "", nil, &news)
+	if len(news.Media) != 2 {
+		t.Fatalf("rejected uploads must not be attached: %+v", news)
+	}
+}
+
+// pngBomb returns a tiny PNG whose header declares width×height pixels.
+func pngBomb(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// The IHDR chunk follows the 8 bytes of the signature: length, type,
+	// width, height, 5 more bytes and the CRC of type and data.
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestAPIVersions(t *testing.T) {
+	cfg := &config.Config{LegacyAPI: config.LegacyAPICfg{
+		Deprecated: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		Sunset:     time.Date(2027, 4, 19, 0, 0, 0, 0, time.UTC),
+	}}
+	forEachBackend(t, cfg, testAPIVersions)
+}
+
+func testAPIVersions(t *testing.T, srv *httptest.Server) {
+	type user struct {
+		ID    int    `json:"id"`
+		Email string `json:"email"`
+	}
+	var created struct {
+		statusResponse
+		User user `json:"user"`
+	}
+	credentials := map[string]string{"email": "editor@example.com", "password": "secret"}
+	if do(t, srv, http.MethodPost, "/v1/users/new", "", credentials, &created); created.Status != "OK" || created.User.ID == 0 || created.User.Email != "editor@example.com" {
+		t.Fatalf("POST /v1/users/new: %+v", created)
+	}
+	var session struct {
+		statusResponse
+		User  user   `json:"user"`
+		Token string `json:"token"`
+	}
+	if do(t, srv, http.MethodPost, "/v1/login", "", credentials, &session); session.Status != "OK" || session.User != created.User || session.Token == "" {
+		t.Fatalf("POST /v1/login: %+v", session)
+	}
+	token := session.Token
+
+	type news struct {
+		ID            int            `json:"id"`
+		Title         string         `json:"title"`
+		Slug          string         `json:"slug"`
+		ContentFormat string         `json:"content_format"`
+		ContentHTML   string         `json:"content_html"`
+		Published     bool           `json:"published"`
+		Categories    []int          `json:"categories"`
+		Tags          []string       `json:"tags"`
+		Media         []any          `json:"media"`
+		CommentsCount *int           `json:"comments_count"`
+		Reactions     map[string]int `json:"reactions"`
+		CreatedAt     time.Time      `json:"created_at"`
+	}
+	var single struct {
+		statusResponse
+		News news `json:"news"`
+	}
+	body := map[string]any{"title": "Versioned", "content": "**bold**", "content_format": "markdown", "categories": []int{3}, "tags": []string{"Go"}}
+	do(t, srv, http.MethodPost, "/v1/news", token, body, &single)
+	n := single.News
+	if single.Status != "OK" || n.ID == 0 || n.Slug != "versioned" || n.ContentFormat != "markdown" || n.ContentHTML == "" || !n.Published ||
+		!slices.Equal(n.Categories, []int{3}) || !slices.Equal(n.Tags, []string{"go"}) || n.Media == nil || n.CommentsCount == nil || n.Reactions == nil || n.CreatedAt.IsZero() {
+		t.Fatalf("POST /v1/news: %+v", single)
+	}
+
+	// The /v1 schemas are validated, not the legacy ones.
+	var failed statusResponse
+	if code := do(t, srv, http.MethodPost, "/v1/news", token, map[string]any{"title": 5}, &failed); code != http.StatusBadRequest || failed.Error != "field title must be a string" {
+		t.Fatalf("POST /v1/news with a bad title: %d %+v", code, failed)
+	}
+
+	path := "/v1/news/edit/" + strconv.Itoa(n.ID)
+	if do(t, srv, http.MethodPatch, path, token, map[string]any{"title": "Renamed", "content": "c", "slug": "renamed"}, &failed); failed.Status != "OK" {
+		t.Fatalf("PATCH %s: %+v", path, failed)
+	}
+	single = struct {
+		statusResponse
+		News news `json:"news"`
+	}{}
+	if code := do(t, srv, http.MethodGet, "/v1/news/"+strconv.Itoa(n.ID), "", nil, &single); code != http.StatusOK || single.News.Title != "Renamed" ||
+		single.News.ContentFormat != "markdown" || !slices.Equal(single.News.Tags, []string{"go"}) {
+		t.Fatalf("GET /v1/news/{id}: %d %+v", code, single)
+	}
+
+	var list struct {
+		statusResponse
+		News []news `json:"news"`
+	}
+	if do(t, srv, http.MethodGet, "/v1/news", "", nil, &list); list.Status != "OK" || len(list.News) != 1 || list.News[0].ID != n.ID || list.News[0].Slug != "renamed" {
+		t.Fatalf("GET /v1/news: %+v", list)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	get := func(path string) *http.Response {
+		t.Helper()
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	if resp := get("/v1/news/by-slug/versioned"); resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "/v1/news/by-slug/renamed" {
+		t.Fatalf("old slug under /v1: status %d, Location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if resp := get("/v1/feed.rss"); resp.StatusCode != http.StatusOK || resp.Header.Get("Deprecation") != "" {
+		t.Fatalf("GET /v1/feed.rss: status %d, Deprecation %q", resp.StatusCode, resp.Header.Get("Deprecation"))
+	}
+
+	resp := get("/news")
+	if resp.StatusCode != http.StatusOK ||
+		resp.Header.Get("Deprecation") != "@1792368000" ||
+		resp.Header.Get("Sunset") != "Mon, 19 Apr 2027 00:00:00 GMT" ||
+		resp.Header.Get("Link") != `</v1/news>; rel="successor-version"` {
+		t.Fatalf("GET /news: status %d, headers %v", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwt.NewKey("ed-1", private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := dbtest.Logger()
+	manager, err := jwt.New(log, jwt.Options{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(router.New(log, &config.Config{}, backends["memory"](t), manager))
+	t.Cleanup(srv.Close)
+
+	resp, err := srv.Client().Get(srv.URL + "/.well-known/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var set jwt.JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /.well-known/jwks.json: status %d, %v", resp.StatusCode, err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].Kid != "ed-1" {
+		t.Fatalf("GET /.well-known/jwks.json = %+v", set)
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"news-service/internal/content"
+	"news-service/internal/entities"
+	"strings"
+	"unicode/utf8"
+)
+
+// SetContent stores source on news in the given format together with its
+// rendered HTML. HTML sources are sanitized before they are stored, so
+// scripts and unsafe attributes never reach the database.
+func SetContent(news *entities.News, format, source string) error {
+	f, err := content.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	if f == content.HTML {
+		source = content.Sanitize(source)
+	}
+	news.Content = source
+	news.ContentFormat = string(f)
+	news.ContentHTML = content.Render(f, source)
+	return nil
+}
+
+// maxSummaryLength is the longest summary accepted, in characters.
+const maxSummaryLength = 500
+
+// NormalizeSummary trims a summary and checks its length.
+func NormalizeSummary(summary string) (string, error) {
+	summary = strings.TrimSpace(summary)
+	if utf8.RuneCountInString(summary) > maxSummaryLength {
+		return "", fmt.Errorf("summary must not be longer than %d characters", maxSummaryLength)
+	}
+	return summary, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"news-service/internal/webhook"
+)
+
+// NewsEventData is the data of the news.* webhook events.
+type NewsEventData struct {
+	ID            int      `json:"id"`
+	Title         string   `json:"title"`
+	Slug          string   `json:"slug"`
+	Summary       string   `json:"summary"`
+	ContentFormat string   `json:"content_format"`
+	Published     bool     `json:"published"`
+	Categories    []int    `json:"categories"`
+	Tags          []string `json:"tags"`
+}
+
+// NotifyWebhooks queues event for the subscribed webhooks. The news is
+// already saved, so a failure is only logged.
+func NotifyWebhooks(ctx context.Context, log *slog.Logger, webhooksRepository models.WebhooksRepository, event string, news entities.News, categories []int, tags []string) {
+	payload, err := webhook.NewPayload(event, NewsEventData{
+		ID:            news.ID,
+		Title:         news.Title,
+		Slug:          news.Slug,
+		Summary:       news.Summary,
+		ContentFormat: news.ContentFormat,
+		Published:     news.Published,
+		Categories:    categories,
+		Tags:          tags,
+	})
+	if err == nil {
+		err = webhooksRepository.EnqueueEvent(ctx, event, payload)
+	}
+	if err != nil {
+		log.Error("failed to queue webhook event", slog.String("event", event), errMsg.Err(err))
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"news-service/internal/slug"
+	"news-service/internal/tag"
+	"slices"
+)
+
+// InvalidError rejects the content of a news, its message tells the client
+// what to fix.
+type InvalidError struct {
+	Err error
+}
+
+func (e *InvalidError) Error() string { return e.Err.Error() }
+
+func (e *InvalidError) Unwrap() error { return e.Err }
+
+// NewsInput is a news to create, see NewsWriter.Create.
+type NewsInput struct {
+	Title string
+	// Slug is derived from Title when empty.
+	Slug          string
+	Summary       string
+	Content       string
+	ContentFormat string
+	Categories    []int
+	Tags          []string
+	// Published defaults to true.
+	Published *bool
+}
+
+// NewsUpdate changes a news, see NewsWriter.Update. Title, Content and
+// Categories are replaced, the other fields are kept when nil.
+type NewsUpdate struct {
+	ID            int
+	Title         string
+	Slug          *string
+	Summary       *string
+	Content       string
+	ContentFormat *string
+	Categories    []int
+	// Tags replace the current tags, an empty list clears them.
+	Tags      []string
+	Published *bool
+}
+
+// NewsWriter creates and updates news for the HTTP and the gRPC API alike,
+// and notifies the webhooks of the changes.
+type NewsWriter struct {
+	News           models.NewsRepository
+	Categories     models.CategoriesRepository
+	NewsCategories models.NewsCategoriesRepository
+	Tags           models.TagsRepository
+	Webhooks       models.WebhooksRepository
+}
+
+// NewNewsWriter returns the writer of the news of repos.
+func NewNewsWriter(repos Repositories) NewsWriter {
+	return NewsWriter{
+		News:           repos.News,
+		Categories:     repos.Categories,
+		NewsCategories: repos.NewsCategories,
+		Tags:           repos.Tags,
+		Webhooks:       repos.Webhooks,
+	}
+}
+
+// Create stores a news and returns it with its sorted tags. Content that
+// cannot be stored is rejected with an *InvalidError.
+func (w NewsWriter) Create(ctx context.Context, log *slog.Logger, in NewsInput) (entities.News, []string, error) {
+	news := entities.News{Title: in.Title, Published: true}
+	var err error
+	news.Summary, err = NormalizeSummary(in.Summary)
+	if err == nil {
+		err = SetContent(&news, in.ContentFormat, in.Content)
+	}
+	var tags []string
+	if err == nil {
+		tags, err = tag.NormalizeAll(in.Tags)
+	}
+	if err != nil {
+		return entities.News{}, nil, &InvalidError{Err: err}
+	}
+	if in.Slug != "" {
+		news.Slug = slug.Make(in.Slug)
+	}
+	if in.Published != nil {
+		news.Published = *in.Published
+	}
+
+	if err := w.News.CreateNews(ctx, &news); err != nil {
+		return entities.News{}, nil, err
+	}
+	if err := w.setCategories(ctx, news.ID, in.Categories); err != nil {
+		return entities.News{}, nil, err
+	}
+	if err := w.Tags.SetNewsTags(ctx, news.ID, tags); err != nil {
+		return entities.News{}, nil, err
+	}
+	slices.Sort(tags)
+	NotifyWebhooks(ctx, log, w.Webhooks, entities.EventNewsCreated, news, in.Categories, tags)
+	return news, tags, nil
+}
+
+// Update applies in to its news, models.ErrNewsNotFound when there is
+// none. Content that cannot be stored is rejected with an *InvalidError.
+func (w NewsWriter) Update(ctx context.Context, log *slog.Logger, in NewsUpdate) (entities.News, error) {
+	// The fields left out of in are written back, they must not come from
+	// a replica lagging behind.
+	news, err := w.News.FindNewsByID(models.ReadFromPrimary(ctx), in.ID)
+	if err != nil {
+		return entities.News{}, err
+	}
+	news.Title = in.Title
+	format := news.ContentFormat
+	if in.ContentFormat != nil {
+		format = *in.ContentFormat
+	}
+	err = SetContent(&news, format, in.Content)
+	if err == nil && in.Summary != nil {
+		news.Summary, err = NormalizeSummary(*in.Summary)
+	}
+	var tags []string
+	if err == nil && in.Tags != nil {
+		tags, err = tag.NormalizeAll(in.Tags)
+	}
+	if err != nil {
+		return entities.News{}, &InvalidError{Err: err}
+	}
+	if in.Published != nil {
+		news.Published = *in.Published
+	}
+	if in.Slug != nil {
+		news.Slug = slug.Make(*in.Slug)
+	}
+
+	if err := w.News.UpdateNews(ctx, &news); err != nil {
+		return entities.News{}, err
+	}
+	if in.Tags != nil {
+		if err := w.Tags.SetNewsTags(ctx, news.ID, tags); err != nil {
+			return entities.News{}, err
+		}
+	}
+	if err := w.setCategories(ctx, news.ID, in.Categories); err != nil {
+		return entities.News{}, err
+	}
+
+	tags, err = w.Tags.ListNewsTags(ctx, news.ID)
+	if err != nil {
+		log.Error("failed to retrieve tags", errMsg.Err(err))
+	}
+	NotifyWebhooks(ctx, log, w.Webhooks, entities.EventNewsUpdated, news, in.Categories, tags)
+	return news, nil
+}
+
+// setCategories replaces the categories of a news, creating missing ones.
+func (w NewsWriter) setCategories(ctx context.Context, newsID int, categories []int) error {
+	if err := w.NewsCategories.DeleteCategories(ctx, newsID); err != nil {
+		return err
+	}
+	for _, name := range categories {
+		categorie := entities.Categorie{Name: name}
+		if err := w.Categories.CreateCategorie(ctx, &categorie); err != nil {
+			return err
+		}
+		if err := w.NewsCategories.UpdateNewsCategories(ctx, categorie.ID, newsID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package service holds what the HTTP and the gRPC API share: the
+// repositories they are served from and the writes of news, so that both
+// APIs check and store them alike.
+package service
+
+import (
+	"io"
+	"news-service/internal/config"
+	userhandlers "news-service/internal/handlers/userHandler"
+	"news-service/internal/mailer"
+	"news-service/internal/models"
+	"news-service/internal/ratelimit"
+	"news-service/internal/storage"
+	"news-service/internal/stream"
+	"news-service/internal/views"
+	"sync"
+)
+
+type Repositories struct {
+	News           models.NewsRepository
+	Categories     models.CategoriesRepository
+	NewsCategories models.NewsCategoriesRepository
+	Users          userhandlers.User
+	Media          models.MediaRepository
+	Tags           models.TagsRepository
+	Views          models.ViewsRepository
+	Comments       models.CommentsRepository
+	Reactions      models.ReactionsRepository
+	Bookmarks      models.BookmarksRepository
+	Subscriptions  models.SubscriptionsRepository
+	Webhooks       models.WebhooksRepository
+	NewsEvents     models.NewsEventsRepository
+	// ViewCounter buffers the views recorded by POST /news/{id}/views,
+	// the caller runs its flushes.
+	ViewCounter *views.Counter
+	// NewsStream feeds GET /news/stream, the caller runs it.
+	NewsStream *stream.Hub
+	// RateLimits keeps the buckets of the rate limits, shared with the
+	// gRPC API. Every limiter keeps its own in memory when it is nil.
+	RateLimits ratelimit.Store
+	// UserTokens and Mailer back email verification and password reset,
+	// see NewAccounts. PendingMails counts the mails being sent in the
+	// background, the caller waits for it on shutdown.
+	UserTokens   models.UserTokensRepository
+	Mailer       mailer.Mailer
+	PendingMails *sync.WaitGroup
+	// MediaStorage keeps uploaded files. When it is a http.Handler, like
+	// storage.Local, the files are served under /media.
+	MediaStorage storage.Storage
+}
+
+// NewAccounts returns the email verification and password reset of both
+// APIs. Mails are dropped when repos.Mailer is nil.
+func NewAccounts(cfg config.MailCfg, repos Repositories) userhandlers.Accounts {
+	m := repos.Mailer
+	if m == nil {
+		m = mailer.NewWriter(io.Discard)
+	}
+	return userhandlers.Accounts{Tokens: repos.UserTokens, Mailer: m, Cfg: cfg, Pending: repos.PendingMails}
+}
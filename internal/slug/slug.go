@@ -0,0 +1,92 @@
+// Package slug builds URL-friendly identifiers from news titles.
+package slug
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest slug Make returns. Numeric suffixes added to
+// resolve collisions come on top of it.
+const MaxLength = 80
+
+// Fallback is used when a title has no letters or digits at all.
+const Fallback = "news"
+
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	// Ukrainian and Belarusian letters.
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g", 'ў': "u",
+}
+
+// Make transliterates s to ASCII and joins its words with hyphens, e.g.
+// "Новости Москвы 2024" becomes "novosti-moskvy-2024".
+func Make(s string) string {
+	var b strings.Builder
+	dash := false
+
+	for _, r := range norm.NFC.String(strings.ToLower(s)) {
+		part, ok := cyrillic[r]
+		if !ok {
+			part = ascii(r)
+		}
+
+		if part == "" {
+			// Hard and soft signs are dropped without splitting the word.
+			if !ok {
+				dash = b.Len() > 0
+			}
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(part)
+	}
+
+	slug := b.String()
+	if len(slug) > MaxLength {
+		slug = slug[:MaxLength]
+		if i := strings.LastIndexByte(slug, '-'); i > MaxLength/2 {
+			slug = slug[:i]
+		}
+		slug = strings.Trim(slug, "-")
+	}
+	if slug == "" {
+		return Fallback
+	}
+	return slug
+}
+
+// ascii keeps the ASCII letters and digits of r after removing accents,
+// "é" becomes "e" and "ß" is dropped.
+func ascii(r rune) string {
+	var b strings.Builder
+	for _, d := range norm.NFD.String(string(r)) {
+		if d >= 'a' && d <= 'z' || d >= '0' && d <= '9' {
+			b.WriteRune(d)
+		}
+	}
+	return b.String()
+}
+
+// Next returns base if it is not in taken, otherwise base-2, base-3 and so
+// on, whichever is free first.
+func Next(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, t := range taken {
+		used[t] = true
+	}
+	candidate := base
+	for i := 2; used[candidate]; i++ {
+		candidate = base + "-" + strconv.Itoa(i)
+	}
+	return candidate
+}
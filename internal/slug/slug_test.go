@@ -0,0 +1,55 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"  Go 1.22 released  ", "go-1-22-released"},
+		{"Новости Москвы 2024", "novosti-moskvy-2024"},
+		{"Съезд объявил: «Щедрый урожай»", "sezd-obyavil-shchedryy-urozhay"},
+		{"Ёлка и йогурт", "yolka-i-yogurt"},
+		{"Їжак і Ґанок", "yizhak-i-ganok"},
+		{"Café déjà vu", "cafe-deja-vu"},
+		{"!!!", Fallback},
+		{"", Fallback},
+		{"already-a-slug", "already-a-slug"},
+	}
+	for _, tt := range tests {
+		if got := Make(tt.in); got != tt.want {
+			t.Errorf("Make(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMakeTruncatesAtWordBoundary(t *testing.T) {
+	got := Make(strings.Repeat("word ", 30))
+	if len(got) > MaxLength {
+		t.Fatalf("slug is %d bytes long", len(got))
+	}
+	if strings.HasSuffix(got, "-") || !strings.HasSuffix(got, "word") {
+		t.Fatalf("slug was cut mid-word: %q", got)
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		taken []string
+		want  string
+	}{
+		{nil, "title"},
+		{[]string{"title-2"}, "title"},
+		{[]string{"title"}, "title-2"},
+		{[]string{"title", "title-2", "title-4"}, "title-3"},
+	}
+	for _, tt := range tests {
+		if got := Next("title", tt.taken); got != tt.want {
+			t.Errorf("Next(%v) = %q, want %q", tt.taken, got, tt.want)
+		}
+	}
+}
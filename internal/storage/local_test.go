@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocal(dir, "/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, s)
+
+	if got := s.URL("news/1/a.png"); got != "/media/news/1/a.png" {
+		t.Fatalf("URL = %q", got)
+	}
+	for _, key := range []string{"", "../escape", "a/../../b", "/abs", "a//b"} {
+		if err := s.Put(context.Background(), key, "", strings.NewReader("x"), 1); err == nil {
+			t.Errorf("Put(%q) should fail", key)
+		}
+	}
+	if err := s.Put(context.Background(), "short", "", strings.NewReader("x"), 2); err == nil {
+		t.Error("Put should fail when fewer bytes than announced arrive")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "short")); err == nil {
+		t.Error("a failed Put must not leave the object behind")
+	}
+}
+
+func TestLocalServeHTTP(t *testing.T) {
+	s, err := NewLocal(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(context.Background(), "news/1/a.txt", "text/plain", strings.NewReader("hello"), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	if code, body := get("/news/1/a.txt"); code != http.StatusOK || body != "hello" {
+		t.Fatalf("GET object: %d %q", code, body)
+	}
+	for _, path := range []string{"/news/1/", "/news", "/missing"} {
+		if code, _ := get(path); code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d", path, code)
+		}
+	}
+}
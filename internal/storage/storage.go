@@ -0,0 +1,21 @@
+// Package storage keeps uploaded files. Local stores them on disk, S3 in
+// any S3-compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type Storage interface {
+	// Put stores size bytes read from body under key.
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+	// Get opens the object stored under key, ErrNotFound when there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL is the address clients download key from.
+	URL(key string) string
+}
@@ -0,0 +1,230 @@
+// Package stream fans news events out to the clients of GET /news/stream.
+// A Hub listens for events saved by any replica, reads them once and hands
+// them to every subscriber of this process.
+package stream
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"news-service/internal/backoff"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"sync"
+	"time"
+)
+
+// Options tune a Hub, zero fields take the defaults below.
+type Options struct {
+	// Buffer is the number of events a subscriber may fall behind before
+	// it is dropped.
+	Buffer int
+	// Retention is how long events are kept for clients resuming the
+	// stream.
+	Retention time.Duration
+}
+
+const (
+	DefaultBuffer    = 64
+	DefaultRetention = 24 * time.Hour
+)
+
+// pageSize is the number of events read at once.
+const pageSize = 100
+
+// Listening failures are retried after a delay growing up to a minute.
+const (
+	retryBase = time.Second
+	retryMax  = time.Minute
+)
+
+type Hub struct {
+	repo models.NewsEventsRepository
+	opts Options
+	log  *slog.Logger
+	wake chan struct{}
+	// ready is closed once Run knows the last event, subscribers get the
+	// events after it.
+	ready chan struct{}
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	// last is the id of the last event handed to subscribers.
+	last    int
+	stopped bool
+}
+
+func NewHub(repo models.NewsEventsRepository, opts Options, log *slog.Logger) *Hub {
+	if opts.Buffer <= 0 {
+		opts.Buffer = DefaultBuffer
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	return &Hub{
+		repo:  repo,
+		opts:  opts,
+		log:   log,
+		wake:  make(chan struct{}, 1),
+		ready: make(chan struct{}),
+		subs:  make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the events saved after it was made.
+type Subscription struct {
+	hub    *Hub
+	events chan entities.NewsEvent
+}
+
+// Events is closed when the subscriber fell behind by more than the buffer
+// or the hub stopped. The client should then reconnect and resume.
+func (s *Subscription) Events() <-chan entities.NewsEvent {
+	return s.events
+}
+
+// Close unsubscribes.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
+
+func (h *Hub) Subscribe() *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := &Subscription{hub: h, events: make(chan entities.NewsEvent, h.opts.Buffer)}
+	if h.stopped {
+		close(s.events)
+		return s
+	}
+	h.subs[s] = struct{}{}
+	return s
+}
+
+// drop removes s, the caller must hold the lock.
+func (h *Hub) drop(s *Subscription) {
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.events)
+	}
+}
+
+// Run hands out events until ctx is done, then closes every subscription.
+// Old events are deleted about once an hour.
+func (h *Hub) Run(ctx context.Context) {
+	defer h.stop()
+
+	last, err := h.repo.LastNewsEventID(ctx)
+	for err != nil {
+		h.log.Error("failed to find the last news event", errMsg.Err(err))
+		select {
+		case <-time.After(retryBase):
+		case <-ctx.Done():
+			return
+		}
+		last, err = h.repo.LastNewsEventID(ctx)
+	}
+	h.mu.Lock()
+	h.last = last
+	h.mu.Unlock()
+	close(h.ready)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.listen(ctx)
+	}()
+	defer wg.Wait()
+
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+	for {
+		select {
+		case <-h.wake:
+			if err := h.dispatch(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				h.log.Error("failed to read news events", errMsg.Err(err))
+			}
+		case <-purge.C:
+			if _, err := h.repo.DeleteNewsEvents(ctx, time.Now().Add(-h.opts.Retention)); err != nil && !errors.Is(err, context.Canceled) {
+				h.log.Error("failed to delete old news events", errMsg.Err(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// listen keeps listening for events, reconnecting after failures. Events
+// saved while it was not listening are read after it listens again.
+func (h *Hub) listen(ctx context.Context) {
+	for failures := 0; ; {
+		started := time.Now()
+		err := h.repo.Listen(ctx, h.notify)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > retryMax {
+			failures = 0
+		}
+		failures++
+		delay := backoff.Exponential(failures, retryBase, retryMax)
+		h.log.Error("stopped listening for news events", slog.Duration("retry_in", delay), errMsg.Err(err))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Hub) notify() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch reads the events after the last one handed out and sends them
+// to the subscribers, dropping those whose buffer is full.
+func (h *Hub) dispatch(ctx context.Context) error {
+	for {
+		h.mu.Lock()
+		last := h.last
+		h.mu.Unlock()
+
+		events, err := h.repo.ListNewsEvents(ctx, last, pageSize)
+		if err != nil {
+			return err
+		}
+
+		h.mu.Lock()
+		for _, event := range events {
+			for s := range h.subs {
+				select {
+				case s.events <- event:
+				default:
+					h.drop(s)
+				}
+			}
+			h.last = event.ID
+		}
+		h.mu.Unlock()
+
+		if len(events) < pageSize {
+			return nil
+		}
+	}
+}
+
+func (h *Hub) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopped = true
+	for s := range h.subs {
+		h.drop(s)
+	}
+}
@@ -0,0 +1,106 @@
+package stream
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	memoryrepo "news-service/internal/database/memoryRepo"
+	"news-service/internal/entities"
+	"testing"
+	"time"
+)
+
+func newHub(t *testing.T, opts Options) (*Hub, *memoryrepo.NewsRepository, func()) {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := memoryrepo.NewStore()
+	news := memoryrepo.NewNewsRepository(store, log)
+	// An event made before the hub runs is not handed out.
+	news.CreateNews(context.Background(), &entities.News{Title: "old"})
+
+	hub := NewHub(memoryrepo.NewNewsEventsRepository(store, log), opts, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hub.Run(ctx)
+	}()
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	<-hub.ready
+	return hub, news, stop
+}
+
+func receive(t *testing.T, sub *Subscription) (entities.NewsEvent, bool) {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		return event, ok
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return entities.NewsEvent{}, false
+	}
+}
+
+func TestHubFansOutEvents(t *testing.T) {
+	hub, news, stop := newHub(t, Options{})
+	first, second := hub.Subscribe(), hub.Subscribe()
+
+	created := entities.News{Title: "new"}
+	news.CreateNews(context.Background(), &created)
+	for _, sub := range []*Subscription{first, second} {
+		event, ok := receive(t, sub)
+		if !ok || event.NewsID != created.ID || event.Kind != entities.EventNewsCreated {
+			t.Fatalf("received %+v, %v, want the creation of news %d", event, ok, created.ID)
+		}
+	}
+
+	second.Close()
+	created.Title = "edited"
+	news.UpdateNews(context.Background(), &created)
+	if event, ok := receive(t, first); !ok || event.Kind != entities.EventNewsUpdated {
+		t.Fatalf("received %+v, %v, want the update", event, ok)
+	}
+	if _, ok := <-second.Events(); ok {
+		t.Fatal("a closed subscription should receive nothing")
+	}
+
+	stop()
+	if _, ok := <-first.Events(); ok {
+		t.Fatal("subscriptions should be closed when the hub stops")
+	}
+	if _, ok := <-hub.Subscribe().Events(); ok {
+		t.Fatal("subscribing to a stopped hub should return a closed subscription")
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub, news, _ := newHub(t, Options{Buffer: 2})
+	slow, fast := hub.Subscribe(), hub.Subscribe()
+
+	received := make(chan int, 10)
+	go func() {
+		for event := range fast.Events() {
+			received <- event.ID
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		news.CreateNews(context.Background(), &entities.News{Title: "burst"})
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the fast subscriber should get every event")
+		}
+	}
+
+	n := 0
+	for range slow.Events() {
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("the slow subscriber got %d events before being dropped, want its buffer of 2", n)
+	}
+}
@@ -0,0 +1,52 @@
+// Package tag normalizes the free-form tags editors attach to news.
+package tag
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest tag accepted, in characters.
+const MaxLength = 50
+
+// MaxPerNews is the largest number of tags a news may have.
+const MaxPerNews = 20
+
+// Normalize makes tags that differ only in case, surrounding or repeated
+// whitespace, Unicode composition or a leading "#" equal:
+// "  #Breaking   NEWS " becomes "breaking news".
+func Normalize(name string) string {
+	name = norm.NFC.String(name)
+	name = strings.TrimLeftFunc(name, func(r rune) bool { return r == '#' || unicode.IsSpace(r) })
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// NormalizeAll normalizes names, drops empty and duplicate tags and checks
+// the limits. Commas are rejected since lists of tags are comma separated.
+// The order of first occurrence is kept.
+func NormalizeAll(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		t := Normalize(name)
+		if t == "" || seen[t] {
+			continue
+		}
+		if strings.ContainsRune(t, ',') {
+			return nil, fmt.Errorf("tag %q contains a comma", t)
+		}
+		if utf8.RuneCountInString(t) > MaxLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", t, MaxLength)
+		}
+		seen[t] = true
+		tags = append(tags, t)
+	}
+	if len(tags) > MaxPerNews {
+		return nil, fmt.Errorf("a news may have at most %d tags", MaxPerNews)
+	}
+	return tags, nil
+}
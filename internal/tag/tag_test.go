@@ -0,0 +1,49 @@
+package tag
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Go", "go"},
+		{"  #Breaking   NEWS ", "breaking news"},
+		{"##\tПолитика", "политика"},
+		{"Café", "café"},
+		{"C#", "c#"},
+		{" # ", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeAll(t *testing.T) {
+	got, err := NormalizeAll([]string{"Go", "go ", "", "#GO", "Москва", "москва"})
+	if err != nil || !slices.Equal(got, []string{"go", "москва"}) {
+		t.Fatalf("NormalizeAll = %q, %v", got, err)
+	}
+
+	if _, err := NormalizeAll([]string{strings.Repeat("я", MaxLength+1)}); err == nil {
+		t.Error("a too long tag should be rejected")
+	}
+
+	if _, err := NormalizeAll([]string{"a,b"}); err == nil {
+		t.Error("a tag with a comma should be rejected")
+	}
+
+	many := make([]string, MaxPerNews+1)
+	for i := range many {
+		many[i] = "tag" + strconv.Itoa(i)
+	}
+	if _, err := NormalizeAll(many); err == nil {
+		t.Error("too many tags should be rejected")
+	}
+}
@@ -0,0 +1,125 @@
+// Package thumbnail makes small previews of uploaded images.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
+)
+
+// ErrUnsupported is returned for images the standard library cannot decode,
+// such as WebP.
+var ErrUnsupported = errors.New("unsupported image format")
+
+// Thumbnail is an encoded preview.
+type Thumbnail struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Size returns the dimensions of the encoded image src.
+func Size(src []byte) (width, height int, err error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(src))
+	if errors.Is(err, image.ErrFormat) {
+		return 0, 0, ErrUnsupported
+	}
+	return cfg.Width, cfg.Height, err
+}
+
+// Make scales src down so that neither side exceeds maxSide, keeping the
+// aspect ratio; smaller images keep their size. Opaque images become JPEG,
+// images with transparency PNG.
+func Make(src []byte, maxSide int) (Thumbnail, error) {
+	img, _, err := image.Decode(bytes.NewReader(src))
+	if errors.Is(err, image.ErrFormat) {
+		return Thumbnail{}, ErrUnsupported
+	}
+	if err != nil {
+		return Thumbnail{}, err
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			w, h = maxSide, max(1, h*maxSide/w)
+		} else {
+			w, h = max(1, w*maxSide/h), maxSide
+		}
+	}
+	thumb := scale(img, w, h)
+
+	var buf bytes.Buffer
+	thumbnail := Thumbnail{Width: w, Height: h}
+	if opaque(thumb) {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		thumbnail.ContentType = "image/jpeg"
+	} else {
+		err = png.Encode(&buf, thumb)
+		thumbnail.ContentType = "image/png"
+	}
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	thumbnail.Data = buf.Bytes()
+	return thumbnail, nil
+}
+
+// scale resizes src to w×h averaging the source pixels covered by each
+// destination pixel.
+func scale(src image.Image, w, h int) *image.NRGBA {
+	b := src.Bounds()
+	rgba := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	if b.Dx() == w && b.Dy() == h {
+		return rgba
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*b.Dy()/h, max((y+1)*b.Dy()/h, y*b.Dy()/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*b.Dx()/w, max((x+1)*b.Dx()/w, x*b.Dx()/w+1)
+
+			// Colour channels are weighted by alpha so that transparent
+			// pixels do not darken the edges.
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := rgba.NRGBAAt(sx, sy)
+					r += uint64(c.R) * uint64(c.A)
+					g += uint64(c.G) * uint64(c.A)
+					bl += uint64(c.B) * uint64(c.A)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			if a == 0 {
+				continue
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / a),
+				G: uint8(g / a),
+				B: uint8(bl / a),
+				A: uint8(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+func opaque(img *image.NRGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
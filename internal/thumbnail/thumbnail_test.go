@@ -0,0 +1,75 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMake(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= 400 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	thumb, err := Make(encodePNG(t, img), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumb.Width != 100 || thumb.Height != 50 || thumb.ContentType != "image/jpeg" {
+		t.Fatalf("thumbnail %dx%d %s, want 100x50 image/jpeg", thumb.Width, thumb.Height, thumb.ContentType)
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(thumb.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, b, _ := decoded.At(10, 25).RGBA(); r>>8 < 200 || b>>8 > 50 {
+		t.Errorf("left half should stay red, got r=%d b=%d", r>>8, b>>8)
+	}
+	if r, _, b, _ := decoded.At(90, 25).RGBA(); b>>8 < 200 || r>>8 > 50 {
+		t.Errorf("right half should stay blue, got r=%d b=%d", r>>8, b>>8)
+	}
+}
+
+func TestMakeKeepsSmallImagesAndTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 30, 60))
+	img.SetNRGBA(0, 0, color.NRGBA{G: 255, A: 255})
+
+	thumb, err := Make(encodePNG(t, img), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumb.Width != 30 || thumb.Height != 60 || thumb.ContentType != "image/png" {
+		t.Fatalf("thumbnail %dx%d %s, want 30x60 image/png", thumb.Width, thumb.Height, thumb.ContentType)
+	}
+
+	w, h, err := Size(encodePNG(t, img))
+	if err != nil || w != 30 || h != 60 {
+		t.Fatalf("Size = %d, %d, %v", w, h, err)
+	}
+}
+
+func TestMakeUnsupported(t *testing.T) {
+	if _, err := Make([]byte("RIFF....WEBPVP8 "), 100); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("Make = %v, want ErrUnsupported", err)
+	}
+}
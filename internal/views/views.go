@@ -0,0 +1,131 @@
+// Package views counts news views. A client is counted once per news
+// within a de-duplication window and the counts are buffered in memory,
+// Flush writes them to the repository in one batch per hourly bucket.
+package views
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"sync"
+	"time"
+)
+
+// BucketSize is the granularity of stored view counts.
+const BucketSize = time.Hour
+
+// DefaultFlushInterval is used by Run for a non-positive interval.
+const DefaultFlushInterval = 10 * time.Second
+
+type seenKey struct {
+	newsID int
+	client uint64
+}
+
+type pendingKey struct {
+	newsID int
+	bucket time.Time
+}
+
+// Counter buffers views until they are flushed. It is safe for concurrent
+// use.
+type Counter struct {
+	mu      sync.Mutex
+	repo    models.ViewsRepository
+	log     *slog.Logger
+	window  time.Duration
+	seen    map[seenKey]time.Time
+	pending map[pendingKey]int
+	now     func() time.Time
+}
+
+// NewCounter counts a client at most once per news within window.
+func NewCounter(repo models.ViewsRepository, window time.Duration, log *slog.Logger) *Counter {
+	return &Counter{
+		repo:    repo,
+		log:     log,
+		window:  window,
+		seen:    make(map[seenKey]time.Time),
+		pending: make(map[pendingKey]int),
+		now:     time.Now,
+	}
+}
+
+// Record counts a view of newsID by client, any string identifying the
+// reader such as its address and user agent. It reports whether the view
+// was counted or is a repeat within the window.
+func (c *Counter) Record(newsID int, client string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(client))
+	key := seenKey{newsID, h.Sum64()}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if last, ok := c.seen[key]; ok && now.Sub(last) < c.window {
+		return false
+	}
+	c.seen[key] = now
+	c.pending[pendingKey{newsID, now.UTC().Truncate(BucketSize)}]++
+	return true
+}
+
+// Flush writes the buffered counts. They are kept for the next flush when
+// the repository fails.
+func (c *Counter) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[pendingKey]int)
+	now := c.now()
+	for key, last := range c.seen {
+		if now.Sub(last) >= c.window {
+			delete(c.seen, key)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	counts := make([]entities.ViewCount, 0, len(pending))
+	for key, views := range pending {
+		counts = append(counts, entities.ViewCount{NewsID: key.newsID, Bucket: key.bucket, Views: views})
+	}
+	if err := c.repo.AddViews(ctx, counts); err != nil {
+		c.mu.Lock()
+		for key, views := range pending {
+			c.pending[key] += views
+		}
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Run flushes every interval until ctx is done, then flushes once more.
+func (c *Counter) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Flush(ctx); err != nil {
+				c.log.Error("failed to flush views", errMsg.Err(err))
+			}
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			if err := c.Flush(flushCtx); err != nil {
+				c.log.Error("failed to flush views", errMsg.Err(err))
+			}
+			cancel()
+			return
+		}
+	}
+}
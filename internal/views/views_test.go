@@ -0,0 +1,105 @@
+package views
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"news-service/internal/entities"
+	"testing"
+	"time"
+)
+
+type fakeRepo struct {
+	counts []entities.ViewCount
+	err    error
+}
+
+func (f *fakeRepo) AddViews(ctx context.Context, counts []entities.ViewCount) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.counts = append(f.counts, counts...)
+	return nil
+}
+
+func (f *fakeRepo) ListTrendingNews(ctx context.Context, since time.Time, halfLife time.Duration, limit int) ([]entities.News, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) total(newsID int) int {
+	total := 0
+	for _, c := range f.counts {
+		if c.NewsID == newsID {
+			total += c.Views
+		}
+	}
+	return total
+}
+
+func TestRecordDeduplicatesWithinWindow(t *testing.T) {
+	repo := &fakeRepo{}
+	c := NewCounter(repo, 30*time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Date(2024, 5, 1, 10, 50, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	if !c.Record(1, "a") || c.Record(1, "a") {
+		t.Fatal("a repeated view within the window should not be counted")
+	}
+	if !c.Record(1, "b") || !c.Record(2, "a") {
+		t.Fatal("views of other clients and news should be counted")
+	}
+	now = now.Add(30 * time.Minute)
+	if !c.Record(1, "a") {
+		t.Fatal("a view after the window should be counted")
+	}
+
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if repo.total(1) != 3 || repo.total(2) != 1 || len(repo.counts) != 3 {
+		t.Fatalf("flushed %+v, want 3 views of news 1 in two buckets and 1 of news 2", repo.counts)
+	}
+	for _, count := range repo.counts {
+		if !count.Bucket.Equal(count.Bucket.Truncate(BucketSize)) {
+			t.Fatalf("bucket %v is not aligned", count.Bucket)
+		}
+	}
+
+	repo.counts = nil
+	if err := c.Flush(context.Background()); err != nil || len(repo.counts) != 0 {
+		t.Fatalf("second Flush = %+v, %v, want nothing", repo.counts, err)
+	}
+}
+
+func TestFlushKeepsCountsOnError(t *testing.T) {
+	repo := &fakeRepo{err: errors.New("down")}
+	c := NewCounter(repo, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	c.Record(1, "a")
+	if err := c.Flush(context.Background()); err == nil {
+		t.Fatal("Flush should report the repository error")
+	}
+	c.Record(1, "b")
+
+	repo.err = nil
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if repo.total(1) != 2 {
+		t.Fatalf("flushed %+v, want both views", repo.counts)
+	}
+}
+
+func TestFlushForgetsExpiredClients(t *testing.T) {
+	c := NewCounter(&fakeRepo{}, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.Record(1, "a")
+	now = now.Add(time.Minute)
+	c.Flush(context.Background())
+	if len(c.seen) != 0 {
+		t.Fatalf("seen = %v, want expired clients forgotten", c.seen)
+	}
+}
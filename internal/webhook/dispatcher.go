@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"news-service/internal/backoff"
+	"news-service/internal/entities"
+	errMsg "news-service/internal/err"
+	"news-service/internal/models"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Options tune a Dispatcher, zero fields take the defaults below.
+type Options struct {
+	// MaxAttempts is the number of failed attempts after which a delivery
+	// is dead-lettered.
+	MaxAttempts int
+	// RetryBase is the delay after the first failure, doubled after every
+	// next one up to RetryMax.
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// Timeout bounds a single request.
+	Timeout time.Duration
+	// BatchSize is the number of deliveries claimed and sent at once.
+	BatchSize int
+	// AllowPrivateNetworks lets webhooks reach addresses that are not
+	// public, see ErrPrivateAddress. Only for tests and trusted setups.
+	AllowPrivateNetworks bool
+}
+
+const (
+	DefaultMaxAttempts  = 10
+	DefaultRetryBase    = 30 * time.Second
+	DefaultRetryMax     = time.Hour
+	DefaultTimeout      = 10 * time.Second
+	DefaultBatchSize    = 20
+	DefaultPollInterval = 5 * time.Second
+)
+
+// maxResponseSize is the part of a response body read before the
+// connection is reused.
+const maxResponseSize = 64 << 10
+
+// Dispatcher sends the queued deliveries. Several dispatchers, in one
+// process or in several replicas, may share a repository.
+type Dispatcher struct {
+	repo   models.WebhooksRepository
+	opts   Options
+	client *http.Client
+	log    *slog.Logger
+	now    func() time.Time
+}
+
+func NewDispatcher(repo models.WebhooksRepository, opts Options, log *slog.Logger) *Dispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.RetryBase <= 0 {
+		opts.RetryBase = DefaultRetryBase
+	}
+	if opts.RetryMax < opts.RetryBase {
+		opts.RetryMax = max(DefaultRetryMax, opts.RetryBase)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	// Proxies are not used, the address dialed must be the one of the
+	// webhook for dialControl to check it.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	if !opts.AllowPrivateNetworks {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   dialControl,
+		}).DialContext
+	}
+	return &Dispatcher{
+		repo: repo,
+		opts: opts,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			// A redirect is reported as a failure rather than followed,
+			// the payload is signed for the registered URL only.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		log: log,
+		now: time.Now,
+	}
+}
+
+// DeliverDue sends one batch of due deliveries and returns its size.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	// Claimed deliveries are not handed to another dispatcher until the
+	// batch had time to finish.
+	lease := d.opts.Timeout + time.Minute
+	deliveries, err := d.repo.ClaimDeliveries(ctx, d.now(), lease, d.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	webhooks := make(map[int]entities.Webhook)
+	for _, delivery := range deliveries {
+		if _, ok := webhooks[delivery.WebhookID]; ok {
+			continue
+		}
+		webhook, err := d.repo.FindWebhook(ctx, delivery.WebhookID)
+		if err != nil {
+			// The webhook was deleted along with its deliveries.
+			continue
+		}
+		webhooks[delivery.WebhookID] = webhook
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(delivery entities.WebhookDelivery) {
+			defer wg.Done()
+			d.deliver(ctx, webhook, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// deliver makes one attempt and records its outcome. A request in flight
+// is finished even when ctx is cancelled, so that it is not reported as a
+// failure on shutdown.
+func (d *Dispatcher) deliver(ctx context.Context, webhook entities.Webhook, delivery entities.WebhookDelivery) {
+	ctx = context.WithoutCancel(ctx)
+	started := d.now()
+	code, err := d.send(ctx, webhook, delivery)
+	attempt := entities.WebhookAttempt{StatusCode: code, Duration: d.now().Sub(started)}
+
+	delivery.Attempts++
+	switch {
+	case err == nil:
+		delivered := d.now()
+		delivery.Status, delivery.DeliveredAt, delivery.LastError = entities.DeliveryDelivered, &delivered, ""
+	case delivery.Attempts >= d.opts.MaxAttempts:
+		delivery.Status, delivery.LastError = entities.DeliveryDead, err.Error()
+		attempt.Error = err.Error()
+	default:
+		delivery.NextAttemptAt = d.now().Add(backoff.Exponential(delivery.Attempts, d.opts.RetryBase, d.opts.RetryMax))
+		delivery.LastError, attempt.Error = err.Error(), err.Error()
+	}
+
+	if err := d.repo.RecordAttempt(ctx, &delivery, &attempt); err != nil {
+		d.log.Error("failed to record webhook attempt", slog.Int("delivery_id", delivery.ID), errMsg.Err(err))
+		return
+	}
+	if delivery.Status == entities.DeliveryDead {
+		d.log.Warn("webhook delivery dead-lettered", slog.Int("delivery_id", delivery.ID),
+			slog.Int("webhook_id", webhook.ID), slog.String("error", delivery.LastError))
+	}
+}
+
+// send posts the payload and returns the response status, zero when there
+// was no response.
+func (d *Dispatcher) send(ctx context.Context, webhook entities.Webhook, delivery entities.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "news-service-webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, d.now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New("unexpected response: " + resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Run sends due deliveries every interval until ctx is done. Full batches
+// are followed by the next one right away.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil {
+			n, err := d.DeliverDue(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				d.log.Error("failed to deliver webhooks", errMsg.Err(err))
+			}
+			if err != nil || n < d.opts.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
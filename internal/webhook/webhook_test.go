@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	memoryrepo "news-service/internal/database/memoryRepo"
+	"news-service/internal/entities"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"news.created"}`)
+	sent := time.Unix(1700000000, 0)
+	signature := Sign("secret", sent, body)
+
+	if err := Verify("secret", signature, body, sent.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	for name, tc := range map[string]struct {
+		secret, signature string
+		body              []byte
+		want              error
+	}{
+		"other secret":   {"other", signature, body, ErrInvalidSignature},
+		"tampered body":  {"secret", signature, []byte(`{"type":"news.deleted"}`), ErrInvalidSignature},
+		"missing header": {"secret", "", body, ErrInvalidSignature},
+		"replayed":       {"secret", Sign("secret", sent.Add(-time.Hour), body), body, ErrStaleSignature},
+	} {
+		if err := Verify(tc.secret, tc.signature, tc.body, sent, 5*time.Minute); !errors.Is(err, tc.want) {
+			t.Errorf("%s: Verify = %v, want %v", name, err, tc.want)
+		}
+	}
+}
+
+func newDispatcher(t *testing.T, handler http.HandlerFunc, opts Options) (*Dispatcher, *memoryrepo.WebhooksRepository, entities.Webhook) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memoryrepo.NewWebhooksRepository(memoryrepo.NewStore(), log)
+	webhook := entities.Webhook{URL: srv.URL, Secret: "secret", Events: []string{entities.EventNewsCreated}}
+	if err := repo.CreateWebhook(context.Background(), &webhook); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	return NewDispatcher(repo, opts, log), repo, webhook
+}
+
+func TestDispatcherDeliversSignedPayload(t *testing.T) {
+	ctx := context.Background()
+	received := make(chan *http.Request, 1)
+	var body []byte
+	d, repo, webhook := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+	}, Options{AllowPrivateNetworks: true})
+
+	payload, err := NewPayload(entities.EventNewsCreated, map[string]int{"id": 7})
+	if err != nil {
+		t.Fatalf("NewPayload: %v", err)
+	}
+	if err := repo.EnqueueEvent(ctx, entities.EventNewsCreated, payload); err != nil {
+		t.Fatalf("EnqueueEvent: %v", err)
+	}
+	if n, err := d.DeliverDue(ctx); n != 1 || err != nil {
+		t.Fatalf("DeliverDue = %d, %v", n, err)
+	}
+
+	r := <-received
+	if r.Header.Get(HeaderEvent) != entities.EventNewsCreated || r.Header.Get(HeaderDelivery) == "" {
+		t.Fatalf("unexpected headers %v", r.Header)
+	}
+	if err := Verify(webhook.Secret, r.Header.Get(HeaderSignature), body, time.Now(), time.Minute); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil || event.Type != entities.EventNewsCreated || event.ID == "" {
+		t.Fatalf("payload %s: %v", body, err)
+	}
+
+	deliveries, _ := repo.ListDeliveries(ctx, webhook.ID, 10, 0)
+	if len(deliveries) != 1 || deliveries[0].Status != entities.DeliveryDelivered || deliveries[0].Attempts != 1 {
+		t.Fatalf("deliveries = %+v", deliveries)
+	}
+	attempts, _ := repo.ListAttempts(ctx, deliveries[0].ID)
+	if len(attempts) != 1 || attempts[0].StatusCode != http.StatusOK || attempts[0].Error != "" {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+}
+
+func TestDispatcherRetriesAndDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int32
+	d, repo, webhook := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, Options{MaxAttempts: 3, RetryBase: time.Minute, RetryMax: time.Hour, AllowPrivateNetworks: true})
+	if err := repo.EnqueueEvent(ctx, entities.EventNewsCreated, []byte(`{}`)); err != nil {
+		t.Fatalf("EnqueueEvent: %v", err)
+	}
+	clock := time.Now()
+	d.now = func() time.Time { return clock }
+
+	for i, wait := range []time.Duration{time.Minute, 2 * time.Minute} {
+		if n, _ := d.DeliverDue(ctx); n != 1 {
+			t.Fatalf("attempt %d: DeliverDue = %d", i+1, n)
+		}
+		delivery, _ := repo.ListDeliveries(ctx, webhook.ID, 1, 0)
+		if delivery[0].Status != entities.DeliveryPending || !delivery[0].NextAttemptAt.Equal(clock.Add(wait).Truncate(time.Microsecond)) {
+			t.Fatalf("after attempt %d: %+v", i+1, delivery[0])
+		}
+		if n, _ := d.DeliverDue(ctx); n != 0 {
+			t.Fatalf("a delivery should wait for its backoff, %d sent", n)
+		}
+		clock = clock.Add(wait)
+	}
+
+	d.DeliverDue(ctx)
+	delivery, _ := repo.ListDeliveries(ctx, webhook.ID, 1, 0)
+	if delivery[0].Status != entities.DeliveryDead || delivery[0].Attempts != 3 || delivery[0].LastError == "" {
+		t.Fatalf("delivery should be dead-lettered: %+v", delivery[0])
+	}
+	attempts, _ := repo.ListAttempts(ctx, delivery[0].ID)
+	if len(attempts) != 3 || attempts[2].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+	clock = clock.Add(24 * time.Hour)
+	if n, _ := d.DeliverDue(ctx); n != 0 || calls.Load() != 3 {
+		t.Fatalf("dead deliveries should not be sent again: %d sent, %d calls", n, calls.Load())
+	}
+}
+
+func TestDispatcherRefusesPrivateAddresses(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int32
+	d, repo, webhook := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}, Options{MaxAttempts: 1})
+	if err := repo.EnqueueEvent(ctx, entities.EventNewsCreated, []byte(`{}`)); err != nil {
+		t.Fatalf("EnqueueEvent: %v", err)
+	}
+
+	d.DeliverDue(ctx)
+	delivery, _ := repo.ListDeliveries(ctx, webhook.ID, 1, 0)
+	if calls.Load() != 0 || delivery[0].Status != entities.DeliveryDead || !strings.Contains(delivery[0].LastError, ErrPrivateAddress.Error()) {
+		t.Fatalf("a loopback webhook should not be called: %d calls, %+v", calls.Load(), delivery[0])
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"0.0.0.0":          false,
+		"::":               false,
+		"100.100.100.200":  false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := PublicAddress(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("PublicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}